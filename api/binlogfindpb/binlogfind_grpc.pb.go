@@ -0,0 +1,287 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: binlogfindpb/binlogfind.proto
+
+package binlogfindpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	BinlogFind_Find_FullMethodName        = "/binlogfind.v1.BinlogFind/Find"
+	BinlogFind_ListBinlogs_FullMethodName = "/binlogfind.v1.BinlogFind/ListBinlogs"
+	BinlogFind_Range_FullMethodName       = "/binlogfind.v1.BinlogFind/Range"
+	BinlogFind_ResolveGTID_FullMethodName = "/binlogfind.v1.BinlogFind/ResolveGTID"
+	BinlogFind_ListServers_FullMethodName = "/binlogfind.v1.BinlogFind/ListServers"
+)
+
+// BinlogFindClient is the client API for BinlogFind service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// BinlogFind answers questions about which binlog holds a given point in time.
+type BinlogFindClient interface {
+	// Find returns the binlog file containing, or closest preceding, a timestamp.
+	Find(ctx context.Context, in *FindRequest, opts ...grpc.CallOption) (*FindResponse, error)
+	// ListBinlogs returns every binlog file the server currently retains.
+	ListBinlogs(ctx context.Context, in *ListBinlogsRequest, opts ...grpc.CallOption) (*ListBinlogsResponse, error)
+	// Range returns the binlog files spanning a time window.
+	Range(ctx context.Context, in *RangeRequest, opts ...grpc.CallOption) (*RangeResponse, error)
+	// ResolveGTID returns the binlog coordinates at which a GTID was written.
+	ResolveGTID(ctx context.Context, in *ResolveGTIDRequest, opts ...grpc.CallOption) (*ResolveGTIDResponse, error)
+	// ListServers returns the servers answered for and the state of their time index.
+	ListServers(ctx context.Context, in *ListServersRequest, opts ...grpc.CallOption) (*ListServersResponse, error)
+}
+
+type binlogFindClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBinlogFindClient(cc grpc.ClientConnInterface) BinlogFindClient {
+	return &binlogFindClient{cc}
+}
+
+func (c *binlogFindClient) Find(ctx context.Context, in *FindRequest, opts ...grpc.CallOption) (*FindResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FindResponse)
+	err := c.cc.Invoke(ctx, BinlogFind_Find_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *binlogFindClient) ListBinlogs(ctx context.Context, in *ListBinlogsRequest, opts ...grpc.CallOption) (*ListBinlogsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListBinlogsResponse)
+	err := c.cc.Invoke(ctx, BinlogFind_ListBinlogs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *binlogFindClient) Range(ctx context.Context, in *RangeRequest, opts ...grpc.CallOption) (*RangeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RangeResponse)
+	err := c.cc.Invoke(ctx, BinlogFind_Range_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *binlogFindClient) ResolveGTID(ctx context.Context, in *ResolveGTIDRequest, opts ...grpc.CallOption) (*ResolveGTIDResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResolveGTIDResponse)
+	err := c.cc.Invoke(ctx, BinlogFind_ResolveGTID_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *binlogFindClient) ListServers(ctx context.Context, in *ListServersRequest, opts ...grpc.CallOption) (*ListServersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListServersResponse)
+	err := c.cc.Invoke(ctx, BinlogFind_ListServers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BinlogFindServer is the server API for BinlogFind service.
+// All implementations must embed UnimplementedBinlogFindServer
+// for forward compatibility.
+//
+// BinlogFind answers questions about which binlog holds a given point in time.
+type BinlogFindServer interface {
+	// Find returns the binlog file containing, or closest preceding, a timestamp.
+	Find(context.Context, *FindRequest) (*FindResponse, error)
+	// ListBinlogs returns every binlog file the server currently retains.
+	ListBinlogs(context.Context, *ListBinlogsRequest) (*ListBinlogsResponse, error)
+	// Range returns the binlog files spanning a time window.
+	Range(context.Context, *RangeRequest) (*RangeResponse, error)
+	// ResolveGTID returns the binlog coordinates at which a GTID was written.
+	ResolveGTID(context.Context, *ResolveGTIDRequest) (*ResolveGTIDResponse, error)
+	// ListServers returns the servers answered for and the state of their time index.
+	ListServers(context.Context, *ListServersRequest) (*ListServersResponse, error)
+	mustEmbedUnimplementedBinlogFindServer()
+}
+
+// UnimplementedBinlogFindServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedBinlogFindServer struct{}
+
+func (UnimplementedBinlogFindServer) Find(context.Context, *FindRequest) (*FindResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Find not implemented")
+}
+func (UnimplementedBinlogFindServer) ListBinlogs(context.Context, *ListBinlogsRequest) (*ListBinlogsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListBinlogs not implemented")
+}
+func (UnimplementedBinlogFindServer) Range(context.Context, *RangeRequest) (*RangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Range not implemented")
+}
+func (UnimplementedBinlogFindServer) ResolveGTID(context.Context, *ResolveGTIDRequest) (*ResolveGTIDResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResolveGTID not implemented")
+}
+func (UnimplementedBinlogFindServer) ListServers(context.Context, *ListServersRequest) (*ListServersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListServers not implemented")
+}
+func (UnimplementedBinlogFindServer) mustEmbedUnimplementedBinlogFindServer() {}
+func (UnimplementedBinlogFindServer) testEmbeddedByValue()                    {}
+
+// UnsafeBinlogFindServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BinlogFindServer will
+// result in compilation errors.
+type UnsafeBinlogFindServer interface {
+	mustEmbedUnimplementedBinlogFindServer()
+}
+
+func RegisterBinlogFindServer(s grpc.ServiceRegistrar, srv BinlogFindServer) {
+	// If the following call pancis, it indicates UnimplementedBinlogFindServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&BinlogFind_ServiceDesc, srv)
+}
+
+func _BinlogFind_Find_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BinlogFindServer).Find(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BinlogFind_Find_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BinlogFindServer).Find(ctx, req.(*FindRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BinlogFind_ListBinlogs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListBinlogsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BinlogFindServer).ListBinlogs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BinlogFind_ListBinlogs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BinlogFindServer).ListBinlogs(ctx, req.(*ListBinlogsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BinlogFind_Range_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BinlogFindServer).Range(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BinlogFind_Range_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BinlogFindServer).Range(ctx, req.(*RangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BinlogFind_ResolveGTID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResolveGTIDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BinlogFindServer).ResolveGTID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BinlogFind_ResolveGTID_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BinlogFindServer).ResolveGTID(ctx, req.(*ResolveGTIDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BinlogFind_ListServers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListServersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BinlogFindServer).ListServers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BinlogFind_ListServers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BinlogFindServer).ListServers(ctx, req.(*ListServersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// BinlogFind_ServiceDesc is the grpc.ServiceDesc for BinlogFind service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BinlogFind_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "binlogfind.v1.BinlogFind",
+	HandlerType: (*BinlogFindServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Find",
+			Handler:    _BinlogFind_Find_Handler,
+		},
+		{
+			MethodName: "ListBinlogs",
+			Handler:    _BinlogFind_ListBinlogs_Handler,
+		},
+		{
+			MethodName: "Range",
+			Handler:    _BinlogFind_Range_Handler,
+		},
+		{
+			MethodName: "ResolveGTID",
+			Handler:    _BinlogFind_ResolveGTID_Handler,
+		},
+		{
+			MethodName: "ListServers",
+			Handler:    _BinlogFind_ListServers_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "binlogfindpb/binlogfind.proto",
+}
@@ -0,0 +1,10 @@
+// Package openapi holds the OpenAPI description of the REST API served by
+// binlog-find-time serve --http-listen, which the restclient package is generated from.
+package openapi
+
+import _ "embed"
+
+// JSON is the OpenAPI 3 document, as served at /openapi.json
+//
+//go:embed openapi.json
+var JSON []byte
@@ -0,0 +1,101 @@
+// Package restclient is a Go client of the REST API served by binlog-find-time serve
+// --http-listen. Its types and methods, in client.gen.go, are generated from the OpenAPI
+// document in api/openapi; run go generate after changing it.
+package restclient
+
+//go:generate go run gen.go
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client calls the REST API at a base URL
+type Client struct {
+	baseURL       *url.URL
+	httpClient    *http.Client
+	authorization string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sends the requests with the given client, e.g. one set up for mutual TLS,
+// instead of http.DefaultClient
+func WithHTTPClient(c *http.Client) Option {
+	return func(client *Client) { client.httpClient = c }
+}
+
+// WithBearerToken authenticates every request with the token, as serve --auth-token-file expects
+func WithBearerToken(token string) Option {
+	return func(client *Client) { client.authorization = "Bearer " + token }
+}
+
+// WithBasicAuth authenticates every request as the user, as serve --basic-auth-file expects
+func WithBasicAuth(user, password string) Option {
+	return func(client *Client) {
+		req := &http.Request{Header: make(http.Header)}
+		req.SetBasicAuth(user, password)
+		client.authorization = req.Header.Get("Authorization")
+	}
+}
+
+// New creates a client of the API at baseURL, such as https://binlog-index:8443
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	c := &Client{baseURL: u, httpClient: http.DefaultClient}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// StatusError is returned for a request the API refused or failed
+type StatusError struct {
+	// StatusCode is the HTTP status, e.g. 404 when nothing was found
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// get calls the API at path with the query, and decodes the JSON response into v
+func (c *Client) get(ctx context.Context, path string, query url.Values, v any) error {
+	u := c.baseURL.JoinPath(path)
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if c.authorization != "" {
+		req.Header.Set("Authorization", c.authorization)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		var apiErr Error
+		if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.Error == "" {
+			apiErr.Error = strings.TrimSpace(string(body))
+		}
+		return &StatusError{StatusCode: resp.StatusCode, Message: apiErr.Error}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode the response of %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package restclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+	"github.com/minuteman3/binlog-find-time/internal/grpcserver"
+)
+
+// newServer serves the REST API of a server for db1:3306, requiring the bearer token
+// "secret" or the basic authentication user alice:pass
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	creds := &grpcserver.Credentials{Tokens: []string{"secret"}, Users: map[string]string{"alice": "pass"}}
+	h := grpcserver.New(binlog.Server{Config: replication.BinlogSyncerConfig{Host: "db1", Port: 3306, ServerID: 100, Flavor: "mysql"}}).Handler()
+	srv := httptest.NewServer(creds.Middleware(h))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClientListServers(t *testing.T) {
+	srv := newServer(t)
+	for _, opt := range []Option{WithBearerToken("secret"), WithBasicAuth("alice", "pass")} {
+		c, err := New(srv.URL, opt, WithHTTPClient(srv.Client()))
+		require.NoError(t, err)
+		resp, err := c.ListServers(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []IndexedServer{{Server: "db1:3306"}}, resp.Servers)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	srv := newServer(t)
+	ctx := context.Background()
+
+	c, err := New(srv.URL)
+	require.NoError(t, err)
+	_, err = c.ListServers(ctx)
+	var statusErr *StatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusUnauthorized, statusErr.StatusCode)
+	assert.Equal(t, "missing or invalid credentials", statusErr.Message)
+
+	c, err = New(srv.URL, WithBearerToken("secret"))
+	require.NoError(t, err)
+	_, err = c.Find(ctx, FindParams{})
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusBadRequest, statusErr.StatusCode)
+	assert.Equal(t, "timestamp is required", statusErr.Message)
+
+	start := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	_, err = c.Range(ctx, RangeParams{Start: start, End: start.Add(-time.Hour)})
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusBadRequest, statusErr.StatusCode)
+	assert.Contains(t, statusErr.Message, "end must not be before start")
+
+	_, err = c.Find(ctx, FindParams{Timestamp: start, Server: "db2:3306"})
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+}
@@ -0,0 +1,22 @@
+//go:build ignore
+
+// gen.go writes client.gen.go from the OpenAPI document, run by go generate
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/minuteman3/binlog-find-time/api/openapi"
+	"github.com/minuteman3/binlog-find-time/internal/openapigen"
+)
+
+func main() {
+	src, err := openapigen.Generate(openapi.JSON, "restclient")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("client.gen.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
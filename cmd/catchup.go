@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// estimateCatchUp estimates the catch-up from the result's position to the head for
+// --apply-rate, or returns nil with a warning if it cannot be told
+func estimateCatchUp(res findResult, files []string, sizes map[string]int64, head *binlog.Status, applyRate int64) *binlog.CatchUp {
+	if head == nil {
+		slog.Warn("Cannot estimate the catch-up time without the server's binlog status")
+		return nil
+	}
+	written := res.Time
+	if written.IsZero() {
+		written = res.Target
+	}
+	pos := binlog.Position{File: res.File, Pos: res.Position}
+	c, err := binlog.EstimateCatchUp(pos, written, files, sizes, *head, time.Now(), applyRate)
+	if err != nil {
+		slog.Warn("Cannot estimate the catch-up time", "error", err)
+		return nil
+	}
+	return &c
+}
+
+// printCatchUp prints the catch-up estimate, if any
+func printCatchUp(w io.Writer, c *binlog.CatchUp) {
+	if c == nil {
+		return
+	}
+	p := newPalette(w)
+	fmt.Fprintf(w, "Backlog to the head: %s, written at %s/s since\n", formatByteSize(c.Backlog), formatByteSize(int64(c.WriteRate)))
+	d, ok := c.Duration()
+	if !ok {
+		fmt.Fprintf(w, "%s a replica applying %s/s never catches up, as the server writes at least as fast\n", p.paint(colorRed, "Catch-up:"), formatByteSize(int64(c.ApplyRate)))
+		return
+	}
+	fmt.Fprintf(w, "Catch-up at %s/s: %s (rough: assumes steady write and apply rates)\n", formatByteSize(int64(c.ApplyRate)), p.paint(colorBold, "~"+d.Round(time.Second).String()))
+}
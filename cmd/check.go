@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+	"github.com/minuteman3/binlog-find-time/internal/webhook"
+)
+
+// Nagios plugin exit codes
+const (
+	checkOK       = 0
+	checkWarning  = 1
+	checkCritical = 2
+	checkUnknown  = 3
+)
+
+var checkStatusNames = map[int]string{
+	checkOK:       "OK",
+	checkWarning:  "WARNING",
+	checkCritical: "CRITICAL",
+	checkUnknown:  "UNKNOWN",
+}
+
+// runCheck implements the check command, a Nagios/Sensu compatible binlog retention check
+func runCheck(args []string) {
+	// A usage error is UNKNOWN, rather than the exit code 2 of flag.ExitOnError, which
+	// monitoring would take for CRITICAL
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	common := registerCommonFlags(fs)
+	minRetention := fs.Duration("min-retention", 0, "Binlog retention below which the check is CRITICAL (e.g. 72h)")
+	warnRetention := fs.Duration("warn-retention", 0, "Binlog retention below which the check is WARNING (optional)")
+	hook := registerWebhookFlags(fs, "with the result of the check")
+	notifyOn := fs.String("notify-on", "WARNING,CRITICAL,UNKNOWN", "Statuses posted to --webhook-url and --slack-webhook-url")
+	pagerDutyKey := fs.String("pagerduty-routing-key", "", "Trigger PagerDuty alerts with this Events API v2 integration key, resolved once the check is OK")
+	pagerDutySeverity := fs.String("pagerduty-severity", "WARNING=warning,CRITICAL=critical,UNKNOWN=error", "PagerDuty severity of each status; statuses left out trigger no alert")
+	if err := fs.Parse(args); errors.Is(err, flag.ErrHelp) {
+		os.Exit(checkOK)
+	} else if err != nil {
+		checkExit(checkUnknown, fmt.Sprintf("error parsing flags: %v", err))
+	}
+
+	if *minRetention <= 0 {
+		checkExit(checkUnknown, "--min-retention is required")
+	}
+	alerts := &checkAlerts{notifyOn: make(map[string]bool)}
+	for _, name := range strings.Split(*notifyOn, ",") {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		switch {
+		case name == "":
+		case !slices.Contains([]string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}, name):
+			checkExit(checkUnknown, fmt.Sprintf("invalid --notify-on status %q, expected OK, WARNING, CRITICAL or UNKNOWN", name))
+		default:
+			alerts.notifyOn[name] = true
+		}
+	}
+	severities, err := webhook.ParseSeverities(*pagerDutySeverity)
+	if err != nil {
+		checkExit(checkUnknown, fmt.Sprintf("invalid --pagerduty-severity: %v", err))
+	}
+	alerts.severities = severities
+
+	cfg, err := common.load()
+	if err != nil {
+		checkExit(checkUnknown, fmt.Sprintf("error loading config: %v", err))
+	}
+	if err := cfg.resolveCluster(); err != nil {
+		checkExit(checkUnknown, fmt.Sprintf("error looking up the cluster: %v", err))
+	}
+	finder := cfg.finder()
+	finder.Source = binlog.TimestampHeader
+
+	// From here on every result is also sent to the configured webhooks and PagerDuty
+	alerts.server = net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	if alerts.notifiers, err = hook.notifiers(cfg); err != nil {
+		checkExit(checkUnknown, err.Error())
+	}
+	if *pagerDutyKey != "" {
+		cfg.PagerDutyRoutingKey = *pagerDutyKey
+	}
+	if cfg.PagerDutyRoutingKey != "" {
+		alerts.routingKey = cfg.PagerDutyRoutingKey
+		alerts.pagerDuty = &webhook.Notifier{URL: webhook.PagerDutyURL, Retries: *hook.retries, Backoff: time.Second}
+	}
+	exit := func(status int, message string) {
+		alerts.send(status, message)
+		checkExit(status, message)
+	}
+
+	files, err := finder.Binlogs()
+	if err != nil {
+		exit(checkUnknown, fmt.Sprintf("failed to get binlog files: %v", err))
+	}
+	if len(files) == 0 {
+		exit(checkCritical, "no binlog files found")
+	}
+
+	var totalBytes int64
+	for _, f := range files {
+		totalBytes += f.Size
+	}
+
+	// Only the start of the oldest file matters, so only its first event is read
+	oldest, err := finder.StartTime(files[0].Name)
+	if err != nil {
+		exit(checkUnknown, fmt.Sprintf("failed to read oldest binlog %s: %v", files[0].Name, err))
+	}
+
+	// Retention is how far back a point-in-time recovery can reach from now
+	retention := time.Since(oldest).Truncate(time.Second)
+
+	status := checkOK
+	if retention < *minRetention {
+		status = checkCritical
+	} else if retention < *warnRetention {
+		status = checkWarning
+	}
+
+	message := fmt.Sprintf("%s of binlogs retained (oldest event %s in %s, %d files, %d bytes) | retention=%.0fs;%.0f;%.0f files=%d bytes=%dB",
+		retention, oldest.Format("2006-01-02 15:04:05"), files[0].Name, len(files), totalBytes,
+		retention.Seconds(), warnRetention.Seconds(), minRetention.Seconds(), len(files), totalBytes)
+	exit(status, message)
+}
+
+// checkExit prints the one-line plugin status and exits with the matching code
+func checkExit(status int, message string) {
+	fmt.Printf("BINLOG RETENTION %s - %s\n", checkStatusNames[status], message)
+	os.Exit(status)
+}
+
+// checkStatusEmoji prefixes the Slack message of each status
+var checkStatusEmoji = map[int]string{
+	checkOK:       ":white_check_mark:",
+	checkWarning:  ":warning:",
+	checkCritical: ":red_circle:",
+	checkUnknown:  ":grey_question:",
+}
+
+// checkAlerts sends the result of a check to the webhooks, for the statuses in notifyOn,
+// and to PagerDuty, triggering an alert of the mapped severity or resolving it when OK
+type checkAlerts struct {
+	server     string
+	notifiers  *notifiers
+	notifyOn   map[string]bool
+	pagerDuty  *webhook.Notifier
+	routingKey string
+	severities map[string]string
+}
+
+// send sends a result, leaving out the performance data of its message
+func (a *checkAlerts) send(status int, message string) {
+	name := checkStatusNames[status]
+	summary, _, _ := strings.Cut(message, " | ")
+	summary = fmt.Sprintf("BINLOG RETENTION %s on %s - %s", name, a.server, summary)
+
+	if a.notifiers != nil && a.notifyOn[name] {
+		a.notifiers.notify("retention_check", a.server, checkStatusEmoji[status]+" "+summary, map[string]string{"status": name, "message": message})
+	}
+	if a.pagerDuty == nil {
+		return
+	}
+	// One alert per server, updated by every run until a run resolves it
+	dedupKey := "binlog-find-time/retention/" + a.server
+	severity, mapped := a.severities[name]
+	switch {
+	case status == checkOK:
+		deliver(a.pagerDuty, "retention_check", "PagerDuty", webhook.NewPagerDutyResolve(a.routingKey, dedupKey))
+	case mapped:
+		deliver(a.pagerDuty, "retention_check", "PagerDuty", webhook.NewPagerDutyTrigger(a.routingKey, dedupKey, webhook.PagerDutyPayload{
+			Summary:       summary,
+			Source:        a.server,
+			Severity:      severity,
+			Component:     "binlog-retention",
+			CustomDetails: map[string]string{"status": name, "message": message},
+		}))
+	}
+}
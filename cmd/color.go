@@ -0,0 +1,94 @@
+package main
+
+import (
+	"io"
+	"os"
+	"regexp"
+)
+
+// ANSI escape sequences for the colors of human-readable output
+const (
+	colorReset  = "\033[0m"
+	colorBold   = "\033[1m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorBlue   = "\033[34m"
+	colorCyan   = "\033[36m"
+	colorGray   = "\033[90m"
+)
+
+// noColor is set by --no-color
+var noColor bool
+
+// colorEnabled reports whether output written to w is colored: only on a terminal, and
+// unless turned off by --no-color, the NO_COLOR environment variable or TERM=dumb
+func colorEnabled(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(f)
+}
+
+// palette colors text when color is enabled and leaves it as is otherwise
+type palette bool
+
+// newPalette returns the palette for output written to w
+func newPalette(w io.Writer) palette {
+	return palette(colorEnabled(w))
+}
+
+// paint wraps s in the given color
+func (p palette) paint(color, s string) string {
+	if !p || s == "" {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// time colors a timestamp, so time ranges stand out from file names and positions
+func (p palette) time(s string) string {
+	return p.paint(colorCyan, s)
+}
+
+// match colors text describing a result by its match quality: green for an exact match
+// and yellow for any other
+func (p palette) match(exact bool, s string) string {
+	if exact {
+		return p.paint(colorGreen, s)
+	}
+	return p.paint(colorYellow, s)
+}
+
+var (
+	logLevelPattern = regexp.MustCompile(`level=(DEBUG|INFO|WARN|ERROR)\S*`)
+	logMsgPattern   = regexp.MustCompile(`msg=("(?:[^"\\]|\\.)*"|\S+)`)
+	logTimePattern  = regexp.MustCompile(`\b(start|end|target)=("(?:[^"\\]|\\.)*"|\S+)`)
+	logLevelColors  = map[string]string{"DEBUG": colorGray, "INFO": colorBlue, "WARN": colorYellow, "ERROR": colorRed}
+)
+
+// logColorizer colors the level, message and timestamps of the lines written by the text
+// log handler, which writes each record with a single Write
+type logColorizer struct {
+	w io.Writer
+}
+
+func (c logColorizer) Write(b []byte) (int, error) {
+	p := palette(true)
+	line := logLevelPattern.ReplaceAllStringFunc(string(b), func(s string) string {
+		level := logLevelPattern.FindStringSubmatch(s)[1]
+		return "level=" + p.paint(logLevelColors[level], s[len("level="):])
+	})
+	line = logMsgPattern.ReplaceAllStringFunc(line, func(s string) string {
+		return "msg=" + p.paint(colorBold, s[len("msg="):])
+	})
+	line = logTimePattern.ReplaceAllStringFunc(line, func(s string) string {
+		m := logTimePattern.FindStringSubmatch(s)
+		return m[1] + "=" + p.time(m[2])
+	})
+	if _, err := io.WriteString(c.w, line); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
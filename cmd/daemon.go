@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/minuteman3/binlog-find-time/internal/systemd"
+)
+
+// superviseDaemon runs a daemon command once it is listening: it tells systemd the
+// service is ready, with a status line for systemctl status, and pings its watchdog.
+// SIGHUP calls reload, which is reported to systemd so that systemctl reload waits for
+// it, and SIGINT or SIGTERM calls stop, which should make the command return.
+func superviseDaemon(status string, reload func() error, stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go systemd.Watchdog(ctx)
+	systemd.Ready(status)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	for sig := range signals {
+		if sig != syscall.SIGHUP {
+			slog.Info("Shutting down", "signal", sig.String())
+			systemd.Stopping()
+			stop()
+			return
+		}
+		slog.Info("Reloading the configuration")
+		systemd.Reloading()
+		if err := reload(); err != nil {
+			// A broken configuration leaves the running one in place
+			slog.Error("Failed to reload the configuration, keeping the previous one", "error", err)
+		}
+		systemd.Ready(status)
+	}
+}
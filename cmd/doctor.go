@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// runDoctor implements the doctor command, checking connectivity and privileges before a search
+func runDoctor(args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	common := registerCommonFlags(fs)
+	output := fs.String("output", "text", "Output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		fatalf("Error parsing flags: %v", err)
+	}
+
+	switch *output {
+	case "text", "json":
+	default:
+		fatalf("Unknown output format %q", *output)
+	}
+
+	cfg, err := common.load()
+	if err != nil {
+		fatalf("Error loading config: %v", err)
+	}
+
+	checks := diagnose(cfg)
+
+	if *output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(checks); err != nil {
+			fatalf("Failed to write output: %v", err)
+		}
+	} else {
+		for _, c := range checks {
+			fmt.Printf("[%s] %s: %s\n", strings.ToUpper(c.Status), c.Name, c.Detail)
+			if c.Remediation != "" {
+				fmt.Printf("    -> %s\n", c.Remediation)
+			}
+		}
+	}
+
+	for _, c := range checks {
+		if c.Status == binlog.CheckFail {
+			os.Exit(1)
+		}
+	}
+}
+
+// diagnose runs the checks against the server, first looking up the primary of a
+// --cluster, whose failure is reported as a check of its own
+func diagnose(cfg *config) []binlog.Check {
+	if cfg.Cluster == "" {
+		return cfg.server().Diagnose()
+	}
+	if err := cfg.resolveCluster(); err != nil {
+		return []binlog.Check{{
+			Name:        "Orchestrator",
+			Status:      binlog.CheckFail,
+			Detail:      err.Error(),
+			Remediation: "Check --orchestrator-api and that Orchestrator knows the cluster " + strconv.Quote(cfg.Cluster),
+		}}
+	}
+	found := binlog.Check{
+		Name:   "Orchestrator",
+		Status: binlog.CheckOK,
+		Detail: fmt.Sprintf("primary of cluster %s is %s", cfg.Cluster, net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))),
+	}
+	return append([]binlog.Check{found}, cfg.server().Diagnose()...)
+}
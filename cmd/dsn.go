@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// applyDSN sets the connection settings from a go-sql-driver/mysql DSN such as
+// "user:pass@tcp(host:3306)/?tls=true". Its timeout, readTimeout and tls parameters set
+// the connect and read timeouts and TLS of both the SQL and replication connections; the
+// database name and other parameters are ignored.
+func (c *config) applyDSN(dsn string) error {
+	parsed, err := mysqldriver.ParseDSN(dsn)
+	if err != nil {
+		return fmt.Errorf("invalid DSN: %w", err)
+	}
+	if parsed.Net != "tcp" {
+		return fmt.Errorf("invalid DSN: network %q is not supported, replication needs tcp", parsed.Net)
+	}
+	host, port, err := net.SplitHostPort(parsed.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid DSN address %q: %w", parsed.Addr, err)
+	}
+	if c.Port, err = strconv.Atoi(port); err != nil {
+		return fmt.Errorf("invalid DSN port %q: %w", port, err)
+	}
+	c.Host = host
+	if parsed.User != "" {
+		c.User = parsed.User
+	}
+	if parsed.Passwd != "" {
+		c.Password = parsed.Passwd
+	}
+	if parsed.Timeout > 0 {
+		c.ConnectTimeout = parsed.Timeout
+	}
+	if parsed.ReadTimeout > 0 {
+		c.ReadTimeout = parsed.ReadTimeout
+	}
+	c.TLS = parsed.TLS
+	return nil
+}
+
+// withHost returns the config for another server sharing its credentials, such as a
+// replica or a fleet member. Certificates are verified against the new host's name.
+func (c config) withHost(host string, port int) config {
+	c.Host, c.Port = host, port
+	if c.TLS != nil && c.TLS.ServerName != "" {
+		c.TLS = c.TLS.Clone()
+		c.TLS.ServerName = host
+	}
+	return c
+}
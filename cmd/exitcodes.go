@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// Exit codes returned by the find command; 1 is reserved for usage and config errors
+const (
+	exitExact       = 0
+	exitApproximate = 2
+	exitNotFound    = 3
+	exitConnection  = 4
+	exitAuth        = 5
+	// exitBeforeRetention and exitInFuture are returned with or without --strict
+	exitBeforeRetention = 6
+	exitInFuture        = 7
+	// exitInterrupted follows the shell convention for a command killed by SIGINT
+	exitInterrupted = 130
+)
+
+// errorExitCode classifies a server error as an authentication or connection failure,
+// or as the result of an interruption
+func errorExitCode(err error) int {
+	if interrupted() {
+		return exitInterrupted
+	}
+	if errors.Is(err, binlog.ErrPermissionDenied) {
+		return exitAuth
+	}
+	return exitConnection
+}
+
+// fatalServerError logs a failure talking to MySQL and exits with the matching code
+func fatalServerError(err error, format string, v ...any) {
+	slog.Error(fmt.Sprintf(format, v...))
+	os.Exit(errorExitCode(err))
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/minuteman3/binlog-find-time/internal/exporter"
+)
+
+// runExporter implements the exporter command, publishing binlog coverage metrics for Prometheus
+func runExporter(args []string) {
+	fs := flag.NewFlagSet("exporter", flag.ExitOnError)
+	common := registerCommonFlags(fs)
+	listen := fs.String("listen", ":9105", "Address for the metrics endpoint to listen on")
+	interval := fs.Duration("interval", time.Minute, "How often to refresh binlog coverage")
+	var targets stringList
+	fs.Var(&targets, "target", "Server to monitor as HOST:PORT, may be repeated (default: the configured host)")
+	minRetention := fs.Duration("min-retention", 0, "Retention below which --webhook-url is notified, e.g. 72h")
+	hook := registerWebhookFlags(fs, "when the retention of a server falls below --min-retention, and when it recovers")
+	if err := fs.Parse(args); err != nil {
+		fatalf("Error parsing flags: %v", err)
+	}
+
+	if hook.given() && *minRetention <= 0 {
+		fatalf("--webhook-url and --slack-webhook-url need --min-retention")
+	}
+
+	cfg, err := common.load()
+	if err != nil {
+		fatalf("Error loading config: %v", err)
+	}
+	if err := cfg.resolveCluster(); err != nil {
+		fatalf("Error looking up the cluster: %v", err)
+	}
+
+	servers, err := cfg.targetServers(targets)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	notifier, err := hook.notifiers(cfg)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	exp := exporter.New(servers)
+	if notifier != nil && *minRetention > 0 {
+		exp.MinRetention = *minRetention
+		exp.OnRetention = func(a exporter.RetentionAlert) {
+			event := "retention_ok"
+			text := fmt.Sprintf(":white_check_mark: Binlog retention of %s recovered to %s (minimum %s)", a.Server, a.Retention.Round(time.Minute), a.MinRetention)
+			if a.Low {
+				event = "retention_low"
+				text = fmt.Sprintf(":warning: Binlog retention of %s fell to %s, below the minimum of %s", a.Server, a.Retention.Round(time.Minute), a.MinRetention)
+			}
+			// Retries must not hold up the refresh of the other servers
+			go notifier.notify(event, a.Server, text, retentionAlert{
+				RetentionSeconds:    a.Retention.Seconds(),
+				MinRetentionSeconds: a.MinRetention.Seconds(),
+				Oldest:              a.Coverage.Oldest.UTC(),
+				Newest:              a.Coverage.Newest.UTC(),
+				Files:               a.Coverage.Files,
+				Bytes:               a.Coverage.Bytes,
+			})
+		}
+	}
+
+	registry := prometheus.NewRegistry()
+	if err := exp.Register(registry); err != nil {
+		fatalf("Failed to register metrics: %v", err)
+	}
+
+	go exp.Run(context.Background(), *interval)
+
+	lis, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fatalf("Failed to listen on %s: %v", *listen, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	// A reload picks up new MySQL settings and servers from the config file
+	reload := func() error {
+		cfg, err := common.load()
+		if err != nil {
+			return err
+		}
+		if err := cfg.resolveCluster(); err != nil {
+			return err
+		}
+		servers, err := cfg.targetServers(targets)
+		if err != nil {
+			return err
+		}
+		exp.SetTargets(servers)
+		return nil
+	}
+	stop := func() { _ = server.Shutdown(context.Background()) }
+
+	slog.Info("Exporter listening", "address", lis.Addr().String())
+	go superviseDaemon("Exporting metrics on "+lis.Addr().String(), reload, stop)
+	if err := server.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fatalf("Exporter failed: %v", err)
+	}
+}
+
+// retentionAlert is the data of a retention_low or retention_ok webhook notification
+type retentionAlert struct {
+	RetentionSeconds    float64   `json:"retention_seconds"`
+	MinRetentionSeconds float64   `json:"min_retention_seconds"`
+	Oldest              time.Time `json:"oldest"`
+	Newest              time.Time `json:"newest"`
+	Files               int       `json:"files"`
+	Bytes               int64     `json:"bytes"`
+}
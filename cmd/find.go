@@ -0,0 +1,1109 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+	"github.com/minuteman3/binlog-find-time/internal/rangecache"
+)
+
+// findResult is the outcome of a search, rendered according to the output flags.
+// Its exported fields are what --format templates can refer to.
+type findResult struct {
+	Target time.Time
+	// Host is the HOST:PORT of the server that was scanned
+	Host string
+	File string
+	// Position is zero unless the position was located (--position or --watch)
+	Position uint32
+	Gtid     string
+	// ServerID is the server_id of the server the event at Position originated on
+	ServerID uint32
+	// Until is set when Position is the stop position of --until: the end of the last
+	// event at or before the target time, which is the start of the first one after it
+	Until bool
+	// SafeStop is set when Position is the stop position of --safe-stop: the end of the
+	// last transaction committed before the target time
+	SafeStop bool
+	// Time is the time of the event at Position, with microseconds for commit timestamp sources
+	Time time.Time
+	// Delta is how far Time is after Target, negative for a transaction that started
+	// before it; zero unless both are set
+	Delta time.Duration
+	Exact bool
+	// Match is the quality of the match: exact, gap, closest, before-oldest or after-newest
+	Match string
+	// Reached is set when --watch waited for the server to write the target time
+	Reached bool
+	// Active is set when File is the binlog the server is still writing
+	Active bool
+	// Gap is set when the target time falls between File and the next file, where no events exist
+	Gap *binlog.Gap
+	// Oldest is the first event of the oldest binlog when the target time is before it
+	Oldest time.Time
+	// Newest is the last event of the newest binlog when the target time is after it
+	Newest time.Time
+	// Truncated names the limit that stopped the probe of File before its end, if any
+	Truncated string
+	// Corrupt lists the probed files with an event failing its checksum
+	Corrupt []binlog.Corruption
+	// Skipped lists the files left out of the search as corrupt (--skip-corrupt)
+	Skipped []string
+	// Estimate is a rough position interpolated from the file size, set for exact
+	// matches in fully probed files when the position was not located
+	Estimate uint32
+	Align    string
+	// Stats is the load the search, and the position and event lookups, put on the server
+	Stats binlog.StatsSnapshot
+	// CatchUp estimates the time to replay from Position to the head (--apply-rate)
+	CatchUp *binlog.CatchUp
+	// Event is the first event of the --event-type at or after the target time, nil when
+	// none follows it
+	Event *binlog.EventSummary
+	// Before and After are the last event at or before the target time and the first at
+	// or after it, set when the position was located and nil when the scan read none
+	Before, After *binlog.EventSummary
+	// bracket prints Before and After (--bracket)
+	bracket bool
+	// replica is set when Host is a replica scanned instead of the given host
+	// (--prefer-replica)
+	replica bool
+}
+
+// Match qualities of a result
+const (
+	matchExact        = "exact"
+	matchGap          = "gap"
+	matchClosest      = "closest"
+	matchBeforeOldest = "before-oldest"
+	matchAfterNewest  = "after-newest"
+	// matchNotFound is only reported by --output=json, for timestamps without a result
+	matchNotFound = "not-found"
+)
+
+// jsonResult is the result for one timestamp in --output=json, which maps every input
+// timestamp to its result
+type jsonResult struct {
+	File     string `json:"file,omitempty"`
+	Position uint32 `json:"position,omitempty"`
+	Gtid     string `json:"gtid,omitempty"`
+	ServerID uint32 `json:"server_id,omitempty"`
+	Time     string `json:"time,omitempty"`
+	// DeltaSeconds is how far time is after the target, set with time
+	DeltaSeconds *float64 `json:"delta_seconds,omitempty"`
+	Match        string   `json:"match"`
+	// Until is set when Position is a stop position (--until)
+	Until bool `json:"until,omitempty"`
+	// SafeStop is set when Position is a transaction-safe stop position (--safe-stop)
+	SafeStop bool `json:"safe_stop,omitempty"`
+	// Oldest and Newest are set for before-oldest and after-newest matches
+	Oldest string `json:"oldest,omitempty"`
+	Newest string `json:"newest,omitempty"`
+	// CatchUp is set with --apply-rate
+	CatchUp *binlog.CatchUp `json:"catch_up,omitempty"`
+	// Event is set with --event-type when an event of the type follows the target time
+	Event *jsonEvent `json:"event,omitempty"`
+	// Before and After are set with --bracket
+	Before *jsonEvent `json:"before,omitempty"`
+	After  *jsonEvent `json:"after,omitempty"`
+}
+
+// jsonEvent is the event found with --event-type in --output=json
+type jsonEvent struct {
+	File     string `json:"file"`
+	Position uint32 `json:"position"`
+	// End is the position after the event
+	End      uint32 `json:"end"`
+	Type     string `json:"type"`
+	Time     string `json:"time"`
+	ServerID uint32 `json:"server_id"`
+	Info     string `json:"info,omitempty"`
+	// InPayload is set for an event inside a compressed transaction payload, which
+	// shares the payload's position
+	InPayload bool `json:"in_payload,omitempty"`
+}
+
+// setPosition records a located position in the result
+func (r *findResult) setPosition(pos binlog.Position) {
+	r.File = pos.File
+	r.Position = pos.Pos
+	r.Gtid = pos.GTID
+	r.ServerID = pos.ServerID
+	r.Time = pos.Timestamp
+	if !r.Target.IsZero() && !r.Time.IsZero() {
+		r.Delta = r.Time.Sub(r.Target).Round(time.Microsecond)
+	}
+	if pos.Bracket != nil {
+		r.Before, r.After = pos.Bracket.Before, pos.Bracket.After
+	}
+}
+
+// newJSONEvent returns the --output=json form of an event, nil for none
+func newJSONEvent(ev *binlog.EventSummary) *jsonEvent {
+	if ev == nil {
+		return nil
+	}
+	return &jsonEvent{File: ev.File, Position: ev.Pos, End: ev.Pos + ev.Size, Type: ev.Type, Time: ev.Timestamp.Format("2006-01-02 15:04:05.999999"), ServerID: ev.ServerID, Info: ev.Info, InPayload: ev.InPayload}
+}
+
+// runFind implements the default command, searching for the binlog containing a timestamp
+func runFind(args []string) {
+	// Define command line flags
+	fs := flag.NewFlagSet("find", flag.ExitOnError)
+	help := fs.Bool("help", false, "Display help message")
+	common := registerCommonFlags(fs)
+	var timestamps stringList
+	fs.Var(&timestamps, "timestamp", "Timestamp to search for (format: YYYY-MM-DD HH:MM:SS), may be repeated")
+	timestampsFile := fs.String("timestamps-file", "", "File of timestamps to search for, one per line; - reads stdin, as do timestamps piped in without --timestamp")
+	position := fs.Bool("position", false, "Also locate the position of the timestamp within the binlog file")
+	until := fs.Bool("until", false, "Locate the stop position for the timestamp instead: the end of the last event at or before it, for mysqlbinlog --stop-position; implies --position")
+	bracket := fs.Bool("bracket", false, "Also report the last event at or before the timestamp and the first at or after it; implies --position")
+	safeStop := fs.Bool("safe-stop", false, "Locate the stop position after the last transaction committed before the timestamp instead, so replay never ends inside a transaction; implies --position")
+	sequential := fs.Bool("sequential", false, "Locate the position by reading the file from its start instead of bisecting it")
+	align := fs.String("align", "", "Boundary to snap positions to: transaction, event or none (default: transaction)")
+	watch := fs.Bool("watch", false, "If the timestamp is beyond the newest binlog event, wait for the server to reach it")
+	watchTimeout := fs.Duration("watch-timeout", 0, "Maximum time to wait in --watch mode (default: no limit)")
+	skipGapConfirm := fs.Bool("skip-gap-confirm", false, "Report a gap between files only when the probe of the file before it read it to its end, instead of reading the rest of a file the scan limits cut short")
+	strict := fs.Bool("strict", false, "Treat an approximate match as no match: print no file and exit 3 instead of 2")
+	var quiet bool
+	fs.BoolVar(&quiet, "quiet", false, "Print only the file name (file:pos with --position) on stdout")
+	fs.BoolVar(&quiet, "q", false, "Shorthand for --quiet")
+	format := fs.String("format", "", "Go template used to render the result, e.g. '{{.File}} {{.Position}} {{.Gtid}}'")
+	output := fs.String("output", "text", "Output format: text, json mapping each timestamp to its result, or env for eval in shell scripts")
+	emitMode := fs.String("emit", "", "Print the position as tool arguments instead: pt for Percona Toolkit DSNs and mysqlbinlog positions; implies --position")
+	progressMode := fs.String("progress", "", "Emit structured progress on stderr while searching: ndjson")
+	timestampSource := fs.String("timestamp-source", "", "Event timestamps to compare: header, immediate-commit or original-commit (default: header)")
+	precise := fs.Bool("precise", false, "Shorthand for --timestamp-source=original-commit")
+	slack := fs.Duration("slack", 0, "How far out of order event timestamps may be when locating a position, e.g. 5s")
+	prefer := fs.String("prefer", "first", "File to pick when the target second spans a rotation: first or last")
+	preferReplica := fs.Bool("prefer-replica", false, "Scan a healthy replica of the given host instead of the host itself")
+	includeActive := fs.Bool("include-active", false, "Search the binlog currently being written (default)")
+	excludeActive := fs.Bool("exclude-active", false, "Skip the binlog currently being written")
+	cacheDir := fs.String("cache-dir", rangecache.DefaultDir(), "Directory holding the binlog range cache written by warm-cache")
+	noCache := fs.Bool("no-cache", false, "Probe every file even if its time range is cached")
+	src := registerSourceFlags(fs)
+	outFlags := registerOutputFlags(fs)
+	var dryRun bool
+	fs.BoolVar(&dryRun, "dry-run", false, "Show the files the search would probe, in order, and the bytes it would read, without reading any binlog")
+	fs.BoolVar(&dryRun, "explain", false, "Same as --dry-run")
+	var applyRate byteRate
+	fs.Var(&applyRate, "apply-rate", "Estimate how long a replica applying this many bytes per second, e.g. 20MB/s, takes to catch up from the position to the head; implies --position")
+	gtid := fs.String("gtid", "", "Locate the transaction with this GTID instead of a timestamp: domain-server-sequence on MariaDB, UUID:N on MySQL")
+	eventType := fs.String("event-type", "", "Also report the first event of this type at or after the timestamp: WRITE_ROWS, UPDATE_ROWS, DELETE_ROWS, TABLE_MAP, GTID, XID, QUERY or ROTATE")
+	skipCorrupt := fs.Bool("skip-corrupt", false, "Leave files that fail a checksum or cannot be parsed out of the search instead of stopping it")
+	hook := registerWebhookFlags(fs, "once --watch sees the server reach the timestamp")
+	if err := fs.Parse(args); err != nil {
+		fatalf("Error parsing flags: %v", err)
+	}
+
+	// Check if help flag is set
+	if *help {
+		printHelp()
+		os.Exit(0)
+	}
+
+	// Load config from file and command line flags
+	cfg, err := common.load()
+	if err != nil {
+		fatalf("Error loading config: %v", err)
+	}
+	if *align != "" {
+		cfg.Align = *align
+	}
+	if cfg.Align == binlog.AlignNone.String() && (*until || *bracket) {
+		fatalf("--align=none reports an offset before the timestamp and cannot be combined with --until or --bracket")
+	}
+	if *safeStop {
+		switch {
+		case *until:
+			fatalf("--safe-stop and --until are mutually exclusive")
+		case *align != "" && *align != "transaction":
+			fatalf("--safe-stop stops between transactions and cannot be combined with --align=%s", *align)
+		}
+		// A config file alignment would cut transactions in half
+		cfg.Align = binlog.AlignTransaction.String()
+	}
+	switch {
+	case *timestampSource != "":
+		cfg.TimestampSource = *timestampSource
+	case *precise:
+		cfg.TimestampSource = binlog.TimestampOriginalCommit.String()
+	}
+
+	// Gather the timestamps from the flags, a file or stdin, falling back to the config file
+	inputs := []string(timestamps)
+	switch {
+	case *timestampsFile == "-" || (*timestampsFile == "" && len(inputs) == 0 && cfg.Timestamp == "" && !isTerminal(os.Stdin)):
+		listed, err := readTimestamps(os.Stdin)
+		if err != nil {
+			fatalf("Failed to read timestamps from stdin: %v", err)
+		}
+		inputs = append(inputs, listed...)
+	case *timestampsFile != "":
+		f, err := os.Open(*timestampsFile)
+		if err != nil {
+			fatalf("Failed to open --timestamps-file: %v", err)
+		}
+		listed, err := readTimestamps(f)
+		f.Close()
+		if err != nil {
+			fatalf("Failed to read --timestamps-file: %v", err)
+		}
+		inputs = append(inputs, listed...)
+	}
+	if applyRate > 0 {
+		switch {
+		case src.archived():
+			fatalf("--apply-rate estimates the catch-up to the server's head and cannot be used with --source or --binlog-glob")
+		case *until || *safeStop || *watch:
+			fatalf("--apply-rate cannot be combined with --until, --safe-stop or --watch")
+		}
+		*position = true
+	}
+	if *gtid != "" {
+		switch {
+		case *bracket:
+			fatalf("--bracket brackets a timestamp and cannot be used with --gtid")
+		case len(inputs) > 0:
+			fatalf("--gtid cannot be combined with timestamps")
+		case src.archived():
+			fatalf("--gtid reads the GTIDs the server records and cannot be used with --source or --binlog-glob")
+		case *watch || *until || *safeStop || dryRun:
+			fatalf("--gtid cannot be combined with --watch, --until, --safe-stop or --dry-run")
+		}
+		if _, err := binlog.ParseGTID(*gtid); err != nil {
+			fatalf("Invalid --gtid: %v", err)
+		}
+	}
+	var eventKind binlog.EventKind
+	if *eventType != "" {
+		if *gtid != "" || *until || *safeStop || *watch || dryRun {
+			fatalf("--event-type cannot be combined with --gtid, --until, --safe-stop, --watch or --dry-run")
+		}
+		if eventKind, err = binlog.ParseEventKind(*eventType); err != nil {
+			fatalf("Invalid --event-type: %v", err)
+		}
+	}
+	if len(inputs) == 0 && cfg.Timestamp != "" && *gtid == "" {
+		inputs = []string{cfg.Timestamp}
+	}
+	if len(inputs) == 0 && *gtid == "" {
+		fatalf("Timestamp is required. Use --timestamp flag or set in config file.")
+	}
+
+	// Parse the timestamps
+	targets := make([]time.Time, len(inputs))
+	for i, input := range inputs {
+		if targets[i], err = parseTimestamp(input); err != nil {
+			fatalf("Invalid timestamp format: %v", err)
+		}
+	}
+	if *watch && len(targets) > 1 {
+		fatalf("--watch waits for a single timestamp")
+	}
+	if hook.given() && !*watch {
+		fatalf("--webhook-url and --slack-webhook-url need --watch")
+	}
+	var notifier *notifiers
+	if *watch {
+		if notifier, err = hook.notifiers(cfg); err != nil {
+			fatalf("%v", err)
+		}
+	}
+	if *until {
+		*position = true
+	}
+	// The start of the transaction holding the first event at or after the target is the
+	// end of the last one committed before it
+	if *safeStop || *bracket || *emitMode != "" {
+		*position = true
+	}
+
+	alignment, err := binlog.ParseAlignment(cfg.Align)
+	if err != nil {
+		fatalf("Invalid alignment: %v", err)
+	}
+
+	source, err := binlog.ParseTimestampSource(cfg.TimestampSource)
+	if err != nil {
+		fatalf("Invalid timestamp source: %v", err)
+	}
+	// The end of the last event at or before an --until target is the start of the first
+	// event after it, which is searched for at the next time the source can record
+	searchTime := func(target time.Time) time.Time {
+		if *until {
+			return source.Next(target)
+		}
+		return target
+	}
+	if source == binlog.TimestampHeader && slices.ContainsFunc(inputs, func(input string) bool { return strings.ContainsAny(input, ".,") }) {
+		slog.Warn("Event header timestamps have whole seconds, so the events of a fractional target's second count as before it; use --precise to compare microsecond commit timestamps")
+	}
+
+	preference, err := binlog.ParsePreference(*prefer)
+	if err != nil {
+		fatalf("Invalid --prefer: %v", err)
+	}
+
+	if *includeActive && *excludeActive {
+		fatalf("--include-active and --exclude-active are mutually exclusive")
+	}
+	if src.archived() && (*watch || *preferReplica) {
+		fatalf("--watch and --prefer-replica need a server and cannot be used with --source or --binlog-glob")
+	}
+
+	progress := newProgressWriter(*progressMode, os.Stderr)
+
+	switch {
+	case *output != "text" && *output != "json" && *output != "env":
+		fatalf("Unknown output format %q", *output)
+	case *output != "text" && (*format != "" || quiet):
+		fatalf("--output=%s cannot be combined with --format or --quiet", *output)
+	case *emitMode != "" && *emitMode != "pt":
+		fatalf("Unknown --emit format %q (expected pt)", *emitMode)
+	case *emitMode != "" && (*output != "text" || *format != "" || quiet || dryRun):
+		fatalf("--emit cannot be combined with --output, --format, --quiet or --dry-run")
+	case *output == "env" && len(targets) > 1:
+		fatalf("--output=env sets the variables of a single timestamp")
+	case dryRun && (*output == "env" || *format != "" || quiet):
+		fatalf("--dry-run prints a plan as text or --output=json")
+	case dryRun && *watch:
+		fatalf("--watch reads the newest binlog and cannot be used with --dry-run")
+	}
+	out, save := outFlags.open()
+
+	// Parse the output template up front so a typo doesn't waste a search
+	var tmpl *template.Template
+	if *format != "" {
+		if tmpl, err = template.New("format").Parse(*format); err != nil {
+			fatalf("Invalid --format template: %v", err)
+		}
+	}
+	emit := func(res findResult) {
+		switch {
+		case *emitMode == "pt":
+			var dsn string
+			if !src.archived() {
+				if dsn, err = ptDSN(cfg); err != nil {
+					fatalf("Cannot emit --emit=pt: %v", err)
+				}
+			}
+			printFindPT(out, res, dsn)
+		case *output == "env":
+			printFindEnv(out, res)
+		case tmpl != nil:
+			printFindTemplate(out, res, tmpl)
+		default:
+			printFindResult(out, res, quiet)
+		}
+	}
+
+	// Archived binlogs are listed and read from the source instead of the server
+	archived, name := src.open()
+	var replica bool
+	// Configure MySQL connection
+	if archived == nil {
+		if err := cfg.resolveCluster(); err != nil {
+			fatalf("Error looking up the cluster: %v", err)
+		}
+		if *preferReplica {
+			// The server itself is returned when no replica is healthy
+			picked := pickReplica(cfg)
+			replica, cfg = picked != cfg, picked
+		}
+	}
+	server := cfg.server()
+	host := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	var lister binlog.BinlogLister = server
+	if archived != nil {
+		lister, host = archived, name
+	}
+	binlogFiles, sizes := listBinlogs(lister)
+
+	if len(binlogFiles) == 0 {
+		slog.Error("No binlog files found")
+		os.Exit(exitNotFound)
+	}
+
+	// The server writes to the newest listed file, but ask in case it rotated since.
+	// Archived files are no longer written.
+	var active string
+	var head *binlog.Status
+	if archived == nil {
+		active = binlogFiles[len(binlogFiles)-1]
+		if status, err := server.BinlogStatus(); err != nil {
+			slog.Warn("Could not get binlog status, assuming the newest file is active", "file", active, "error", err)
+		} else {
+			active, head = status.File, status
+		}
+	}
+
+	if *gtid != "" {
+		res, code := lookupGTID(cfg.finder(), binlogFiles, *gtid)
+		res.Host, res.replica = host, replica
+		if code == exitExact && applyRate > 0 {
+			res.CatchUp = estimateCatchUp(res, binlogFiles, sizes, head, int64(applyRate))
+		}
+		switch {
+		case *output == "json":
+			outFlags.writeJSON(out, map[string]jsonResult{*gtid: newJSONResult(res, code)})
+		case res.File == "":
+		case *output == "text" && tmpl == nil && !quiet && *emitMode == "":
+			printGTIDResult(out, res)
+		default:
+			emit(res)
+		}
+		save()
+		os.Exit(code)
+	}
+
+	// The catch-up estimate runs to the head, which may be in the active file
+	listed := slices.Clone(binlogFiles)
+
+	if *watch {
+		newest := binlogFiles[len(binlogFiles)-1]
+		watcher := cfg.finder()
+		watcher.Source = binlog.TimestampHeader
+		start, err := watcher.StartTime(newest)
+		if err != nil {
+			fatalServerError(err, "Failed to get the start time of %s: %v", newest, err)
+		}
+		if !targets[0].Before(start) {
+			watcher.Source = source
+			res := waitForTarget(watcher, newest, searchTime(targets[0]), alignment, *watchTimeout)
+			res.Target = targets[0]
+			res.Until, res.SafeStop = *until || *safeStop, *safeStop
+			res.bracket, res.replica = *bracket, replica
+			emit(res)
+			save()
+			if notifier != nil {
+				notifyReached(notifier, res, exitExact)
+			}
+			os.Exit(exitExact)
+		}
+	}
+
+	if *excludeActive {
+		binlogFiles = withoutActive(binlogFiles, active)
+		if len(binlogFiles) == 0 {
+			slog.Error("No binlog files found besides the active one", "active", active)
+			os.Exit(exitNotFound)
+		}
+	}
+
+	// Every timestamp is searched with the same Finder, whose cache keeps the files
+	// probed for one from being probed again for the next
+	var gap *binlog.Gap
+	var corrupt []binlog.Corruption
+	var skipped []string
+	var bar *progressBar
+	probes := make(map[string]binlog.Probe)
+	finder := &binlog.Finder{Config: server.Config, Settings: server.Settings, Context: server.Context, Prefer: preference, Source: source, Sizes: sizes, SkipCorrupt: *skipCorrupt, SkipGapConfirm: *skipGapConfirm,
+		OnGap: func(g binlog.Gap) { gap = &g }, OnCorrupt: func(c binlog.Corruption) { corrupt = append(corrupt, c) }}
+	finder.OnProbe = func(p binlog.Probe) {
+		if p.Err == nil {
+			probes[p.File] = p
+		}
+		if p.Decision == binlog.DecisionSkipped {
+			skipped = append(skipped, p.File)
+		}
+		if progress != nil {
+			progress.probed(p)
+		} else {
+			bar.probed(p)
+		}
+	}
+	if progress == nil {
+		// Only draw the bar for interactive runs, and not on top of structured progress
+		finder.OnEvent = func(file string, events int) { bar.event(file, events) }
+	}
+	if archived != nil {
+		finder.Streamer, finder.Lister = archived, archived
+	} else if !*noCache {
+		finder.Known = cachedRanges(server, *cacheDir, host, source, cfg.originFilter(), sizes, active)
+	}
+
+	if dryRun {
+		// The newest file on the server is still being written, so its range ends now
+		var now time.Time
+		if archived == nil && binlogFiles[len(binlogFiles)-1] == active {
+			now = time.Now()
+		}
+		plans := make(map[string]jsonPlan, len(targets))
+		for i, targetTime := range targets {
+			plan := finder.Plan(binlogFiles, searchTime(targetTime), now)
+			if *output == "json" {
+				plans[inputs[i]] = newJSONPlan(plan)
+				continue
+			}
+			if i > 0 {
+				fmt.Fprintln(out)
+			}
+			printPlan(out, plan, targetTime, host, binlogFiles, len(finder.Known))
+		}
+		if *output == "json" {
+			outFlags.writeJSON(out, plans)
+		}
+		save()
+		os.Exit(exitExact)
+	}
+
+	// find searches for one timestamp, returning its result and the exit code it calls for
+	find := func(targetTime time.Time) (findResult, int) {
+		gap, corrupt, skipped = nil, nil, nil
+		stats := &binlog.Stats{}
+		finder.Stats = stats
+		if progress == nil {
+			bar = newProgressBar(len(binlogFiles))
+			// The position and event lookups after the search draw the bar again
+			defer bar.clear()
+		}
+
+		// Binary search for the binlog file
+		progress.started(targetTime, len(binlogFiles))
+		var binlogFile string
+		var exactMatch bool
+		var searchErr error
+		// The load reported includes the position scan and the event lookup after the search
+		defer func() { progress.finished(binlogFile, exactMatch, stats.Snapshot(), searchErr) }()
+		binlogFile, exactMatch, searchErr = finder.Search(binlogFiles, searchTime(targetTime))
+		load := stats.Snapshot()
+		slog.Info("Search finished", "files", len(binlogFiles), "probed", load.FilesProbed, "cached", load.FilesCached, "events", load.Events, "bytes", load.Bytes)
+		bar.clear()
+		if interrupted() {
+			return findResult{}, exitInterrupted
+		}
+		if searchErr != nil {
+			slog.Warn("Target time is not within the binlogs", "error", searchErr)
+		}
+
+		if binlogFile == "" {
+			slog.Error("No binlog containing the target timestamp was found", "target", targetTime.Format("2006-01-02 15:04:05"))
+			return findResult{}, exitNotFound
+		}
+
+		res := findResult{Target: targetTime, Host: host, File: binlogFile, Exact: exactMatch, Until: *until || *safeStop, SafeStop: *safeStop, bracket: *bracket, replica: replica, Active: binlogFile == active, Gap: gap, Truncated: probes[binlogFile].Truncated, Corrupt: corrupt, Skipped: skipped, Align: alignment.String()}
+		var outside *binlog.RangeError
+		switch {
+		case exactMatch:
+			res.Match = matchExact
+		case gap != nil:
+			res.Match = matchGap
+		case errors.As(searchErr, &outside) && errors.Is(outside, binlog.ErrTimestampBeforeRetention):
+			res.Match, res.Oldest = matchBeforeOldest, outside.Bound
+		case errors.As(searchErr, &outside):
+			res.Match, res.Newest = matchAfterNewest, outside.Bound
+		default:
+			res.Match = matchClosest
+		}
+
+		if *position {
+			pos, err := locate(finder, archived != nil, *sequential, binlogFile, searchTime(targetTime), alignment, *slack)
+			switch {
+			case err == nil:
+				res.setPosition(pos)
+				if applyRate > 0 && res.Match != matchAfterNewest {
+					res.CatchUp = estimateCatchUp(res, listed, sizes, head, int64(applyRate))
+				}
+			case len(targets) == 1:
+				fatalServerError(err, "Failed to locate position: %v", err)
+			default:
+				// One timestamp's failure leaves the others to be looked up
+				slog.Error("Failed to locate position", "target", targetTime.Format("2006-01-02 15:04:05"), "error", err)
+			}
+		} else if p, ok := probes[binlogFile]; ok && exactMatch && p.Truncated == "" {
+			res.Estimate = binlog.EstimatePosition(p.Start, p.End, sizes[binlogFile], searchTime(targetTime))
+		}
+
+		// Nothing follows a target after the newest event. The event may be in the active
+		// file even when it was left out of the search.
+		if eventKind != "" && res.Match != matchAfterNewest {
+			ev, err := finder.NextEvent(listed, binlog.Position{File: res.File, Pos: res.Position}, targetTime, eventKind)
+			switch {
+			case err == nil:
+				res.Event = &ev
+			case errors.Is(err, binlog.ErrEventNotFound):
+				slog.Warn("No event of the type after the target time", "type", eventKind, "target", targetTime.Format("2006-01-02 15:04:05"))
+			case len(targets) == 1:
+				fatalServerError(err, "Failed to find the next %s event: %v", eventKind, err)
+			default:
+				slog.Error("Failed to find the next event", "type", eventKind, "target", targetTime.Format("2006-01-02 15:04:05"), "error", err)
+			}
+		}
+
+		// A position or event left out by the interruption would pass for a failed lookup
+		if interrupted() {
+			return findResult{}, exitInterrupted
+		}
+		res.Stats = stats.Snapshot()
+
+		// The closest file is no answer for a timestamp outside the binlogs, so these fail
+		// even without --strict
+		switch {
+		case res.Match == matchBeforeOldest:
+			return res, exitBeforeRetention
+		case res.Match == matchAfterNewest:
+			return res, exitInFuture
+		case !exactMatch && *strict:
+			slog.Error("No binlog contains the target timestamp", "target", targetTime.Format("2006-01-02 15:04:05"), "match", res.Match, "closest", binlogFile)
+			return res, exitNotFound
+		case !exactMatch:
+			return res, exitApproximate
+		}
+		return res, exitExact
+	}
+
+	// Results are printed in the order of the timestamps, or mapped from each timestamp as
+	// given with --output=json, and the exit code is that of the first one not found exactly
+	status := exitExact
+	printed := false
+	var reached *findResult
+	var reachedCode int
+	results := make(map[string]jsonResult, len(targets))
+	for i, targetTime := range targets {
+		res, code := find(targetTime)
+		// The timestamps searched before the interruption are still written
+		if code == exitInterrupted {
+			status = exitInterrupted
+			break
+		}
+		if status == exitExact {
+			status = code
+		}
+		// With --watch, a target before the newest binlog was reached before the search
+		if notifier != nil && code != exitNotFound {
+			reached, reachedCode = &res, code
+		}
+		if *output == "json" {
+			results[inputs[i]] = newJSONResult(res, code)
+			continue
+		}
+		if code == exitNotFound {
+			continue
+		}
+		if printed && tmpl == nil && !quiet {
+			fmt.Fprintln(out)
+		}
+		emit(res)
+		printed = true
+	}
+	if *output == "json" {
+		outFlags.writeJSON(out, results)
+	}
+	save()
+	if reached != nil {
+		notifyReached(notifier, *reached, reachedCode)
+	}
+	os.Exit(status)
+}
+
+// notifyReached notifies the webhooks of a target --watch saw the server reach, with the
+// result as --output=json reports it
+func notifyReached(n *notifiers, res findResult, code int) {
+	at := res.File
+	if res.Position != 0 {
+		at = fmt.Sprintf("%s:%d", res.File, res.Position)
+	}
+	text := fmt.Sprintf(":white_check_mark: The binlog of %s has reached %s, in %s", res.Host, res.Target.Format("2006-01-02 15:04:05"), at)
+	n.notify("target_reached", res.Host, text, newJSONResult(res, code))
+}
+
+// newJSONResult returns the --output=json form of a result and the exit code found for it
+func newJSONResult(res findResult, code int) jsonResult {
+	if code == exitNotFound {
+		return jsonResult{Match: matchNotFound}
+	}
+	out := jsonResult{File: res.File, Position: res.Position, Gtid: res.Gtid, ServerID: res.ServerID, Match: res.Match, Until: res.Until, SafeStop: res.SafeStop, CatchUp: res.CatchUp}
+	if !res.Time.IsZero() {
+		out.Time = res.Time.Format("2006-01-02 15:04:05.999999")
+		if !res.Target.IsZero() {
+			delta := res.Delta.Seconds()
+			out.DeltaSeconds = &delta
+		}
+	}
+	if !res.Oldest.IsZero() {
+		out.Oldest = res.Oldest.Format("2006-01-02 15:04:05.999999")
+	}
+	if !res.Newest.IsZero() {
+		out.Newest = res.Newest.Format("2006-01-02 15:04:05.999999")
+	}
+	out.Event = newJSONEvent(res.Event)
+	if res.bracket {
+		out.Before, out.After = newJSONEvent(res.Before), newJSONEvent(res.After)
+	}
+	return out
+}
+
+// readTimestamps reads timestamps one per line, skipping blank lines and # comments
+func readTimestamps(r io.Reader) ([]string, error) {
+	var timestamps []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			timestamps = append(timestamps, line)
+		}
+	}
+	return timestamps, scanner.Err()
+}
+
+// listBinlogs lists the binlog files with their sizes
+func listBinlogs(lister binlog.BinlogLister) ([]string, map[string]int64) {
+	files, err := lister.ListBinlogs()
+	if err != nil {
+		fatalServerError(err, "Failed to get binlog files: %v", err)
+	}
+	binlogFiles := make([]string, 0, len(files))
+	sizes := make(map[string]int64, len(files))
+	for _, f := range files {
+		binlogFiles = append(binlogFiles, f.Name)
+		sizes[f.Name] = f.Size
+	}
+	return binlogFiles, sizes
+}
+
+// locate finds the position of targetTime in binlogFile with the finder's settings. Server
+// files are bisected unless sequential is set; archived files are always read in order,
+// as bisecting a file relies on SHOW BINLOG EVENTS.
+func locate(finder *binlog.Finder, archived, sequential bool, binlogFile string, targetTime time.Time, align binlog.Alignment, slack time.Duration) (binlog.Position, error) {
+	if archived || sequential {
+		return finder.Locate(binlogFile, targetTime, align, slack)
+	}
+	return finder.Seek(binlogFile, targetTime, align, slack)
+}
+
+// waitForTarget keeps the finder's replication stream open until the server's binlog
+// reaches the target time
+func waitForTarget(finder *binlog.Finder, binlogFile string, targetTime time.Time, align binlog.Alignment, timeout time.Duration) findResult {
+	ctx := interruptCtx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	slog.Info("Waiting for the binlog to reach the target time", "target", targetTime.Format("2006-01-02 15:04:05"), "file", binlogFile)
+	pos, err := finder.WaitForPosition(ctx, binlogFile, targetTime, align)
+	if err != nil {
+		fatalServerError(err, "Stopped waiting for target time: %v", err)
+	}
+
+	res := findResult{Target: targetTime, Host: net.JoinHostPort(finder.Config.Host, strconv.Itoa(int(finder.Config.Port))), Exact: true, Match: matchExact, Reached: true, Align: align.String()}
+	res.setPosition(pos)
+	return res
+}
+
+// printFindResult writes the result; in quiet mode only the file (or file:pos) is printed,
+// or the file:pos of the --event-type event
+func printFindResult(w io.Writer, res findResult, quiet bool) {
+	if quiet {
+		if res.Event != nil {
+			fmt.Fprintf(w, "%s:%d\n", res.Event.File, res.Event.Pos)
+		} else if res.Position != 0 {
+			fmt.Fprintf(w, "%s:%d\n", res.File, res.Position)
+		} else {
+			fmt.Fprintln(w, res.File)
+		}
+		return
+	}
+
+	// Colors mark the match quality, warnings and timestamps on a terminal
+	p := newPalette(w)
+	fmt.Fprintf(w, "Target time: %s\n", p.time(res.Target.Format("2006-01-02 15:04:05.999999")))
+	if res.replica {
+		fmt.Fprintf(w, "Scanned host: %s\n", res.Host)
+	}
+
+	switch {
+	case res.Reached:
+		fmt.Fprintf(w, "%s %s\n", p.match(true, "Binlog reached the target time in file:"), p.paint(colorBold, res.File))
+	case res.Exact:
+		fmt.Fprintf(w, "%s %s\n", p.match(true, "Found exact match in binlog file:"), p.paint(colorBold, res.File))
+	case res.Gap != nil:
+		fmt.Fprintf(w, "%s %s (last event %s) and %s (first event %s)\n", p.match(false, "No events at the target time: it falls in a gap between"),
+			res.Gap.Before, p.time(res.Gap.Start.Format("2006-01-02 15:04:05.999999")), res.Gap.After, p.time(res.Gap.End.Format("2006-01-02 15:04:05.999999")))
+	case res.Match == matchBeforeOldest:
+		fmt.Fprintf(w, "%s %s, %s after the target, in %s\n", p.match(false, "The target time is before the oldest binlog; the oldest available event is at"),
+			p.time(res.Oldest.Format("2006-01-02 15:04:05.999999")), res.Oldest.Sub(res.Target).Round(time.Second), p.paint(colorBold, res.File))
+	case res.Match == matchAfterNewest:
+		fmt.Fprintf(w, "%s %s, %s before the target, in %s\n", p.match(false, "The target time is after the newest binlog event, written at"),
+			p.time(res.Newest.Format("2006-01-02 15:04:05.999999")), res.Target.Sub(res.Newest).Round(time.Second), p.paint(colorBold, res.File))
+		if ahead := time.Until(res.Target); ahead > 0 {
+			fmt.Fprintf(w, "%s the target time is %s in the future\n", p.paint(colorYellow, "Note:"), ahead.Round(time.Second))
+		}
+	default:
+		fmt.Fprintf(w, "%s %s\n", p.match(false, "Closest binlog file containing or preceding the timestamp:"), p.paint(colorBold, res.File))
+	}
+
+	if res.Active {
+		fmt.Fprintf(w, "%s this is the active binlog and is still growing; its end time keeps moving\n", p.paint(colorYellow, "Note:"))
+	}
+	if res.Truncated != "" && !res.Exact {
+		fmt.Fprintf(w, "%s the probe of %s stopped at %s before the end of the file; the target may be in its unread part\n", p.paint(colorYellow, "Note:"), res.File, truncationFlag(res.Truncated))
+	}
+
+	for _, c := range res.Corrupt {
+		fmt.Fprintf(w, "%s checksum mismatch in %s at position %d; the file is corrupt from there on\n", p.paint(colorRed, "Warning:"), c.File, c.Pos)
+	}
+	if len(res.Skipped) > 0 {
+		fmt.Fprintf(w, "%s %s\n", p.paint(colorRed, "Skipped corrupt files:"), strings.Join(res.Skipped, ", "))
+	}
+
+	if res.Estimate != 0 {
+		fmt.Fprintf(w, "Estimated position: ~%d (assuming a steady write rate; use --position for the exact one)\n", res.Estimate)
+	}
+
+	switch {
+	case res.Position != 0 && res.SafeStop:
+		fmt.Fprintf(w, "Safe stop position: %s (for mysqlbinlog --stop-position; replays every transaction committed before the target time, never part of one)\n", p.paint(colorBold, fmt.Sprintf("%s:%d", res.File, res.Position)))
+		if res.Gtid != "" {
+			fmt.Fprintf(w, "First GTID not replayed: %s\n", res.Gtid)
+		}
+		if !res.Time.IsZero() {
+			fmt.Fprintf(w, "Time of the first event not replayed: %s (%s)\n", p.time(res.Time.Format("2006-01-02 15:04:05.999999")), formatDelta(res.Delta))
+		}
+	case res.Position != 0 && res.Until:
+		fmt.Fprintf(w, "Stop position (%s-aligned): %s (for mysqlbinlog --stop-position; replays every event at or before the target time)\n", res.Align, p.paint(colorBold, fmt.Sprintf("%s:%d", res.File, res.Position)))
+		if res.Gtid != "" {
+			fmt.Fprintf(w, "First GTID not replayed: %s\n", res.Gtid)
+		}
+		if !res.Time.IsZero() {
+			fmt.Fprintf(w, "Time of the first event not replayed: %s (%s)\n", p.time(res.Time.Format("2006-01-02 15:04:05.999999")), formatDelta(res.Delta))
+		}
+	case res.Position != 0:
+		fmt.Fprintf(w, "Position (%s-aligned): %s\n", res.Align, p.paint(colorBold, fmt.Sprintf("%s:%d", res.File, res.Position)))
+		if res.Gtid != "" {
+			fmt.Fprintf(w, "GTID: %s\n", res.Gtid)
+		}
+		if !res.Time.IsZero() {
+			fmt.Fprintf(w, "Event time: %s (%s)\n", p.time(res.Time.Format("2006-01-02 15:04:05.999999")), formatDelta(res.Delta))
+		}
+		if res.ServerID != 0 {
+			fmt.Fprintf(w, "Origin server ID: %d\n", res.ServerID)
+		}
+	}
+	if res.bracket && res.Position != 0 {
+		printBracketEvent(w, p, "Last event at or before the target", res.Before, res.File)
+		printBracketEvent(w, p, "First event at or after the target", res.After, res.File)
+	}
+	if ev := res.Event; ev != nil {
+		fmt.Fprintf(w, "Next %s: %s at %s", ev.Type, p.paint(colorBold, fmt.Sprintf("%s:%d", ev.File, ev.Pos)), p.time(ev.Timestamp.Format("2006-01-02 15:04:05.999999")))
+		if ev.Info != "" {
+			fmt.Fprintf(w, " (%s)", ev.Info)
+		}
+		if ev.InPayload {
+			fmt.Fprint(w, ", in a compressed transaction payload")
+		}
+		fmt.Fprintln(w)
+	}
+	printCatchUp(w, res.CatchUp)
+}
+
+// formatDelta describes how far the event at the position is from the target time
+func formatDelta(d time.Duration) string {
+	switch {
+	case d > 0:
+		return d.String() + " after the target"
+	case d < 0:
+		return (-d).String() + " before the target"
+	}
+	return "at the target"
+}
+
+// printBracketEvent writes one side of the --bracket pair, which may be missing from the
+// scanned file
+func printBracketEvent(w io.Writer, p palette, label string, ev *binlog.EventSummary, file string) {
+	if ev == nil {
+		fmt.Fprintf(w, "%s: none in %s\n", label, file)
+		return
+	}
+	fmt.Fprintf(w, "%s: %s %s at %s (ends at %d)\n", label, p.paint(colorBold, fmt.Sprintf("%s:%d", ev.File, ev.Pos)), ev.Type, p.time(ev.Timestamp.Format("2006-01-02 15:04:05.999999")), ev.Pos+ev.Size)
+}
+
+// withoutActive returns the binlogs to search with --exclude-active. It leaves files as
+// they were, as the catch-up estimate and NextEvent read on to the head in the active file.
+func withoutActive(files []string, active string) []string {
+	return slices.DeleteFunc(slices.Clone(files), func(f string) bool { return f == active })
+}
+
+// cachedRanges loads the time ranges cached by warm-cache for the server, keeping only
+// entries for the server instance now behind target whose file sizes are unchanged and
+// leaving out the active file, whose end keeps moving. A missing or unusable cache just means probing.
+func cachedRanges(target binlog.Server, dir, server string, source binlog.TimestampSource, origin binlog.OriginFilter, sizes map[string]int64, active string) map[string]binlog.TimeRange {
+	path := rangecache.Path(dir, server)
+	index, err := rangecache.Load(path)
+	if err != nil {
+		if !rangecache.IsNotExist(err) {
+			slog.Warn("Could not load range cache", "path", path, "error", err)
+		}
+		return nil
+	}
+	if index.Source != source.String() {
+		slog.Info("Ignoring range cache built for another timestamp source", "path", path, "source", index.Source)
+		return nil
+	}
+	if index.Origin != origin.String() {
+		slog.Info("Ignoring range cache built with another origin filter", "path", path, "origin", index.Origin)
+		return nil
+	}
+	uuid, err := target.ServerUUID()
+	if err != nil {
+		slog.Warn("Could not identify the server, ignoring range cache", "error", err)
+		return nil
+	}
+	ranges := index.Ranges(uuid, sizes)
+	if ranges == nil {
+		slog.Warn("Ignoring range cache built for another server instance", "path", path, "server_uuid", index.ServerUUID)
+		return nil
+	}
+	delete(ranges, active)
+	slog.Info("Loaded range cache", "path", path, "files", len(ranges), "updated", index.Updated)
+	return ranges
+}
+
+// truncationFlag returns the command line flag setting the limit that truncated a probe,
+// or a description of what else stopped it
+func truncationFlag(limit string) string {
+	switch limit {
+	case binlog.TruncatedEvents:
+		return "--max-events-per-file"
+	case binlog.TruncatedBytes:
+		return "--max-bytes-per-file"
+	case binlog.TruncatedTimeout:
+		return "--probe-timeout"
+	case binlog.TruncatedError:
+		return "a read error"
+	case binlog.TruncatedCorrupt:
+		return "a corrupt event"
+	default:
+		return limit
+	}
+}
+
+// printFindEnv writes the result as shell variable assignments, for eval in scripts. Every
+// variable is set, empty when the result has no value for it.
+func printFindEnv(w io.Writer, res findResult) {
+	var pos, serverID, delta string
+	if res.Position != 0 {
+		pos = strconv.FormatUint(uint64(res.Position), 10)
+	}
+	if !res.Time.IsZero() {
+		delta = strconv.FormatFloat(res.Delta.Seconds(), 'f', -1, 64)
+	}
+	if res.ServerID != 0 {
+		serverID = strconv.FormatUint(uint64(res.ServerID), 10)
+	}
+	vars := []struct{ name, value string }{
+		{"BINLOG_FILE", res.File},
+		{"BINLOG_POS", pos},
+		{"BINLOG_GTID", res.Gtid},
+		{"BINLOG_SERVER_ID", serverID},
+		{"BINLOG_DELTA_SECONDS", delta},
+		{"BINLOG_MATCH", res.Match},
+	}
+	if res.CatchUp != nil {
+		vars = append(vars, struct{ name, value string }{"BINLOG_CATCH_UP_SECONDS", strconv.FormatFloat(res.CatchUp.Seconds, 'f', -1, 64)})
+	}
+	if res.bracket {
+		var before, after [3]string
+		if ev := res.Before; ev != nil {
+			before = [3]string{ev.File, strconv.FormatUint(uint64(ev.Pos), 10), strconv.FormatUint(uint64(ev.Pos+ev.Size), 10)}
+		}
+		if ev := res.After; ev != nil {
+			after = [3]string{ev.File, strconv.FormatUint(uint64(ev.Pos), 10), strconv.FormatUint(uint64(ev.Pos+ev.Size), 10)}
+		}
+		vars = append(vars, []struct{ name, value string }{
+			{"BINLOG_BEFORE_FILE", before[0]},
+			{"BINLOG_BEFORE_POS", before[1]},
+			{"BINLOG_BEFORE_END", before[2]},
+			{"BINLOG_AFTER_FILE", after[0]},
+			{"BINLOG_AFTER_POS", after[1]},
+		}...)
+	}
+	if ev := res.Event; ev != nil {
+		vars = append(vars, []struct{ name, value string }{
+			{"BINLOG_EVENT_FILE", ev.File},
+			{"BINLOG_EVENT_POS", strconv.FormatUint(uint64(ev.Pos), 10)},
+			{"BINLOG_EVENT_TYPE", ev.Type},
+		}...)
+	}
+	for _, v := range vars {
+		fmt.Fprintf(w, "%s=%s\n", v.name, shellQuote(v.value))
+	}
+}
+
+// shellQuote quotes s as a single word for POSIX shells
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// printFindTemplate renders the result through a user-provided Go template
+func printFindTemplate(w io.Writer, res findResult, tmpl *template.Template) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, res); err != nil {
+		fatalf("Failed to render --format template: %v", err)
+	}
+	if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		fatalf("Failed to write output: %v", err)
+	}
+}
+
+// pickReplica returns the config of the first healthy replica of the configured server,
+// or of the replica Orchestrator picks for a --cluster, falling back to the server itself
+// when none is found. Replicas share its credentials.
+func pickReplica(cfg *config) *config {
+	// Orchestrator knows which replicas' binlogs are complete and up to date
+	if cfg.Cluster != "" {
+		r, err := lookupCluster(cfg, true)
+		if err != nil {
+			slog.Warn("Could not pick a replica in Orchestrator, scanning the primary", "error", err)
+			return cfg
+		}
+		replicaCfg := cfg.withHost(r.Key.Hostname, r.Key.Port)
+		if err := replicaCfg.server().CheckReplicaHealth(); err != nil {
+			slog.Warn("Replica picked in Orchestrator is unhealthy, scanning the primary", "host", r.Key.Hostname, "port", r.Key.Port, "error", err)
+			return cfg
+		}
+		return &replicaCfg
+	}
+
+	replicas, err := cfg.server().DiscoverReplicas()
+	if err != nil {
+		slog.Warn("Could not discover replicas, scanning the primary", "error", err)
+		return cfg
+	}
+
+	for _, r := range replicas {
+		if r.Host == "" {
+			slog.Info("Skipping replica without report_host", "server_id", r.ServerID)
+			continue
+		}
+		replicaCfg := cfg.withHost(r.Host, r.Port)
+		if err := replicaCfg.server().CheckReplicaHealth(); err != nil {
+			slog.Info("Skipping unhealthy replica", "host", r.Host, "port", r.Port, "error", err)
+			continue
+		}
+		slog.Info("Scanning replica", "host", r.Host, "port", r.Port, "server_id", r.ServerID)
+		return &replicaCfg
+	}
+
+	slog.Warn("No healthy replica found, scanning the primary", "replicas", len(replicas))
+	return cfg
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithoutActiveKeepsCatchUpFiles(t *testing.T) {
+	binlogFiles := []string{"binlog.000001", "binlog.000002", "binlog.000003"}
+	sizes := map[string]int64{"binlog.000001": 1000, "binlog.000002": 1000, "binlog.000003": 600}
+	head := &binlog.Status{File: "binlog.000003", Position: 500}
+
+	listed := binlogFiles
+	search := withoutActive(binlogFiles, head.File)
+	assert.Equal(t, []string{"binlog.000001", "binlog.000002"}, search)
+	assert.Equal(t, []string{"binlog.000001", "binlog.000002", "binlog.000003"}, listed, "the listed files were changed")
+
+	// A position found in the files searched, with the head in the active file left out of them
+	res := findResult{File: "binlog.000002", Position: 400, Target: time.Now().Add(-time.Minute)}
+	c := estimateCatchUp(res, listed, sizes, head, 100)
+	require.NotNil(t, c)
+	// The rest of binlog.000002, then binlog.000003 up to the head
+	assert.Equal(t, int64(600+500), c.Backlog)
+}
+
+func TestPrintFindResultScannedHost(t *testing.T) {
+	res := findResult{Target: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), Host: "db1:3306", File: "binlog.000002", Exact: true, Match: matchExact}
+
+	var out bytes.Buffer
+	printFindResult(&out, res, false)
+	assert.NotContains(t, out.String(), "Scanned host", "the given host was scanned")
+
+	res.Host, res.replica = "replica1:3306", true
+	out.Reset()
+	printFindResult(&out, res, false)
+	assert.Contains(t, out.String(), "Scanned host: replica1:3306\n")
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// stringList is a flag that may be repeated, collecting every value
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// hideFlags leaves the named flags out of the usage fs prints for -h and parse errors,
+// while still parsing them
+func hideFlags(fs *flag.FlagSet, names ...string) {
+	fs.Usage = func() {
+		shown := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+		fs.VisitAll(func(f *flag.Flag) {
+			if !slices.Contains(names, f.Name) {
+				shown.Var(f.Value, f.Name, f.Usage)
+				shown.Lookup(f.Name).DefValue = f.DefValue
+			}
+		})
+		fmt.Fprintf(fs.Output(), "Usage of %s:\n", fs.Name())
+		shown.SetOutput(fs.Output())
+		shown.PrintDefaults()
+	}
+}
+
+// byteSize is a flag holding a number of bytes, written with an optional unit such as
+// 64MB or 1GiB. Decimal units are powers of 1000 and binary units powers of 1024.
+type byteSize int64
+
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+func (b *byteSize) String() string {
+	return strconv.FormatInt(int64(*b), 10)
+}
+
+func (b *byteSize) Set(value string) error {
+	n, err := parseByteSize(value)
+	if err != nil {
+		return err
+	}
+	*b = byteSize(n)
+	return nil
+}
+
+// parseByteSize parses a number of bytes with an optional unit
+func parseByteSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	unit := int64(1)
+	for _, u := range byteUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s, unit = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (expected e.g. 512KB, 64MB or 1GiB)", value)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// byteRate is a flag holding a rate in bytes per second, written like a byteSize with an
+// optional /s suffix, e.g. 10MB/s
+type byteRate int64
+
+func (r *byteRate) String() string {
+	return strconv.FormatInt(int64(*r), 10)
+}
+
+func (r *byteRate) Set(value string) error {
+	n, err := parseByteSize(strings.TrimSuffix(strings.TrimSpace(value), "/s"))
+	if err != nil {
+		return fmt.Errorf("invalid rate %q (expected e.g. 10MB/s)", value)
+	}
+	*r = byteRate(n)
+	return nil
+}
+
+// parseTimestamp parses a target time, YYYY-MM-DD HH:MM:SS with up to six fractional
+// digits. Finer digits would compare against microsecond commit timestamps as if they
+// meant something, so they are refused.
+func parseTimestamp(s string) (time.Time, error) {
+	t, err := time.Parse("2006-01-02 15:04:05", s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if t.Nanosecond()%int(time.Microsecond) != 0 {
+		return time.Time{}, fmt.Errorf("%q has more than six fractional digits; binlog timestamps have at most microseconds", s)
+	}
+	return t, nil
+}
+
+// parseServerIDs parses a comma-separated list of server_ids
+func parseServerIDs(s string) ([]uint32, error) {
+	var ids []uint32
+	for _, field := range strings.Split(s, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(field), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid server_id %q", field)
+		}
+		ids = append(ids, uint32(id))
+	}
+	return ids, nil
+}
+
+// formatByteSize formats a number of bytes with the largest binary unit it fills, as
+// parseByteSize reads it back
+func formatByteSize(n int64) string {
+	units := []struct {
+		suffix string
+		size   int64
+	}{{"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10}}
+	for _, u := range units {
+		if n >= u.size {
+			return strconv.FormatFloat(float64(n)/float64(u.size), 'f', 1, 64) + u.suffix
+		}
+	}
+	return strconv.FormatInt(n, 10) + "B"
+}
+
+// parseCompressionAlgorithms parses a comma-separated list of protocol compression
+// algorithms as the mysql client's --compression-algorithms takes it, returning whether
+// connections are compressed. The SQL driver only speaks zlib, so a list allowing only
+// zstd is refused rather than silently left uncompressed.
+func parseCompressionAlgorithms(list string) (bool, error) {
+	if list == "" {
+		return false, nil
+	}
+	var zlib, zstd bool
+	for _, name := range strings.Split(list, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "zlib":
+			zlib = true
+		case "zstd":
+			zstd = true
+		case "uncompressed":
+		default:
+			return false, fmt.Errorf("invalid compression algorithm %q (expected zlib, zstd or uncompressed)", name)
+		}
+	}
+	if zstd && !zlib {
+		return false, fmt.Errorf("zstd compression is not supported by the MySQL driver; allow zlib as well")
+	}
+	return zlib, nil
+}
+
+// checkAuthPlugin checks an --auth-plugin value. mysql_clear_password sends the password
+// as is, so it is refused on connections without TLS.
+func checkAuthPlugin(plugin string, tls bool) error {
+	switch plugin {
+	case "", binlog.AuthNativePassword, binlog.AuthCachingSHA2Password, binlog.AuthSHA256Password:
+		return nil
+	case binlog.AuthClearPassword:
+		if !tls {
+			return fmt.Errorf("auth plugin %s sends the password in cleartext and needs TLS, e.g. --dsn with tls=true", plugin)
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid auth plugin %q (expected %s, %s, %s or %s)", plugin,
+		binlog.AuthNativePassword, binlog.AuthCachingSHA2Password, binlog.AuthSHA256Password, binlog.AuthClearPassword)
+}
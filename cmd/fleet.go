@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// fleetHost is a server listed in a --hosts-file. User and password default to the
+// configured credentials.
+type fleetHost struct {
+	Name     string `yaml:"name"`
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+}
+
+// fleetResult is the outcome of the search on a single server
+type fleetResult struct {
+	Name     string `json:"name"`
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	File     string `json:"file,omitempty"`
+	Position uint32 `json:"position,omitempty"`
+	Gtid     string `json:"gtid,omitempty"`
+	Exact    bool   `json:"exact"`
+	Error    string `json:"error,omitempty"`
+}
+
+// fleetReport is the JSON document produced by the fleet command
+type fleetReport struct {
+	Target string        `json:"target"`
+	Hosts  []fleetResult `json:"hosts"`
+}
+
+// runFleet implements the fleet command, searching for the same timestamp on many servers at once
+func runFleet(args []string) {
+	fs := flag.NewFlagSet("fleet", flag.ExitOnError)
+	common := registerCommonFlags(fs)
+	hostsFile := fs.String("hosts-file", "", "YAML file listing the servers to search")
+	timestamp := fs.String("timestamp", "", "Timestamp to search for (format: YYYY-MM-DD HH:MM:SS)")
+	position := fs.Bool("position", false, "Also locate the position of the timestamp on every server")
+	align := fs.String("align", "", "Boundary to snap positions to: transaction, event or none (default: transaction)")
+	timestampSource := fs.String("timestamp-source", "", "Event timestamps to compare: header, immediate-commit or original-commit (default: header)")
+	concurrency := fs.Int("concurrency", 8, "Maximum number of servers searched at the same time")
+	output := fs.String("output", "text", "Output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		fatalf("Error parsing flags: %v", err)
+	}
+
+	switch *output {
+	case "text", "json":
+	default:
+		fatalf("Unknown output format %q", *output)
+	}
+	if *concurrency < 1 {
+		fatalf("--concurrency must be at least 1")
+	}
+
+	cfg, err := common.load()
+	if err != nil {
+		fatalf("Error loading config: %v", err)
+	}
+	if err := cfg.resolveCluster(); err != nil {
+		fatalf("Error looking up the cluster: %v", err)
+	}
+	if *timestamp != "" {
+		cfg.Timestamp = *timestamp
+	}
+	if *align != "" {
+		cfg.Align = *align
+	}
+	if *timestampSource != "" {
+		cfg.TimestampSource = *timestampSource
+	}
+
+	if cfg.Timestamp == "" {
+		fatalf("Timestamp is required. Use --timestamp flag or set in config file.")
+	}
+	targetTime, err := parseTimestamp(cfg.Timestamp)
+	if err != nil {
+		fatalf("Invalid timestamp format: %v", err)
+	}
+	alignment, err := binlog.ParseAlignment(cfg.Align)
+	if err != nil {
+		fatalf("Invalid alignment: %v", err)
+	}
+	source, err := binlog.ParseTimestampSource(cfg.TimestampSource)
+	if err != nil {
+		fatalf("Invalid timestamp source: %v", err)
+	}
+
+	var hosts []fleetHost
+	if *hostsFile != "" {
+		if hosts, err = loadHostsFile(*hostsFile); err != nil {
+			fatalf("Error loading hosts file: %v", err)
+		}
+	}
+	for _, h := range *common.hosts {
+		hosts = append(hosts, fleetHost{Host: h})
+	}
+	if len(hosts) == 0 {
+		fatalf("No servers given. Use --hosts-file or repeat --host.")
+	}
+
+	results := make([]fleetResult, len(hosts))
+	sem := make(chan struct{}, *concurrency)
+	var wg sync.WaitGroup
+	for i, h := range hosts {
+		hostCfg, err := h.config(cfg)
+		if err != nil {
+			fatalf("Invalid host %q: %v", h.Host, err)
+		}
+		results[i] = fleetResult{Name: h.Name, Host: hostCfg.Host, Port: hostCfg.Port}
+		if results[i].Name == "" {
+			results[i].Name = net.JoinHostPort(hostCfg.Host, strconv.Itoa(hostCfg.Port))
+		}
+
+		wg.Add(1)
+		finder := hostCfg.finder()
+		finder.Source = source
+		go func(res *fleetResult, finder *binlog.Finder) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			searchHost(res, finder, targetTime, *position, alignment)
+		}(&results[i], finder)
+	}
+	wg.Wait()
+
+	if *output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(fleetReport{Target: targetTime.Format("2006-01-02 15:04:05.999999"), Hosts: results}); err != nil {
+			fatalf("Failed to write output: %v", err)
+		}
+	} else {
+		printFleetText(results)
+	}
+
+	exitIfInterrupted()
+	for _, res := range results {
+		if res.Error != "" {
+			os.Exit(exitConnection)
+		}
+	}
+}
+
+// loadHostsFile reads the list of servers from a YAML file of the form
+//
+//	hosts:
+//	  - name: primary
+//	    host: db1.example.com
+//	    port: 3306
+func loadHostsFile(path string) ([]fleetHost, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Hosts []fleetHost `yaml:"hosts"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return file.Hosts, nil
+}
+
+// config returns the connection config for the host, based on the shared config.
+// The host may include a port as HOST:PORT.
+func (h fleetHost) config(base *config) (*config, error) {
+	if h.Host == "" {
+		return nil, fmt.Errorf("host is required")
+	}
+	host, port := h.Host, base.Port
+	if splitHost, splitPort, err := net.SplitHostPort(h.Host); err == nil {
+		host = splitHost
+		if port, err = strconv.Atoi(splitPort); err != nil {
+			return nil, fmt.Errorf("invalid port: %w", err)
+		}
+	}
+	if h.Port != 0 {
+		port = h.Port
+	}
+	cfg := base.withHost(host, port)
+	if h.User != "" {
+		cfg.User = h.User
+	}
+	if h.Password != "" {
+		cfg.Password = h.Password
+	}
+	return &cfg, nil
+}
+
+// searchHost runs the search against a single server, recording the outcome or error in res
+func searchHost(res *fleetResult, finder *binlog.Finder, targetTime time.Time, position bool, align binlog.Alignment) {
+	files, err := finder.Binlogs()
+	if err != nil {
+		res.Error = err.Error()
+		return
+	}
+	if len(files) == 0 {
+		res.Error = "no binlog files found"
+		return
+	}
+	binlogFiles := make([]string, len(files))
+	for i, f := range files {
+		binlogFiles[i] = f.Name
+	}
+
+	found := finder.Find(binlogFiles, targetTime)
+	res.File, res.Exact = found.File, found.Exact()
+	if res.File == "" {
+		res.Error = "no binlog containing the target timestamp was found"
+		return
+	}
+
+	if position {
+		pos, err := finder.Locate(res.File, targetTime, align, 0)
+		if err != nil {
+			res.Error = fmt.Sprintf("failed to locate position: %v", err)
+			return
+		}
+		res.File, res.Position, res.Gtid = pos.File, pos.Pos, pos.GTID
+	}
+}
+
+// printFleetText writes the per-server results as an aligned table
+func printFleetText(results []fleetResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tFILE\tPOSITION\tEXACT\tERROR")
+	for _, res := range results {
+		pos := ""
+		if res.Position != 0 {
+			pos = strconv.FormatUint(uint64(res.Position), 10)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\n", res.Name, res.File, pos, res.Exact, res.Error)
+	}
+	if err := w.Flush(); err != nil {
+		fatalf("Failed to write output: %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// lookupGTID locates the transaction with the given GTID for find --gtid, returning the
+// result and the exit code it calls for. The result has no file when the GTID was not found.
+func lookupGTID(finder *binlog.Finder, binlogFiles []string, gtid string) (findResult, int) {
+	pos, err := finder.LocateGTID(binlogFiles, gtid)
+	switch {
+	case errors.Is(err, binlog.ErrGTIDPurged):
+		slog.Error("The GTID was written before the oldest binlog", "gtid", gtid, "oldest", binlogFiles[0])
+		return findResult{Match: matchBeforeOldest}, exitBeforeRetention
+	case errors.Is(err, binlog.ErrGTIDNotFound):
+		slog.Error("GTID not found in the binlogs", "gtid", gtid, "error", err)
+		return findResult{}, exitNotFound
+	case err != nil:
+		fatalServerError(err, "Failed to locate GTID %s: %v", gtid, err)
+	}
+
+	res := findResult{Exact: true, Match: matchExact, Align: binlog.AlignTransaction.String()}
+	res.setPosition(pos)
+	return res, exitExact
+}
+
+// printGTIDResult prints the position of a transaction found by its GTID
+func printGTIDResult(w io.Writer, res findResult) {
+	p := newPalette(w)
+	fmt.Fprintf(w, "GTID: %s\n", res.Gtid)
+	if res.replica {
+		fmt.Fprintf(w, "Scanned host: %s\n", res.Host)
+	}
+	fmt.Fprintf(w, "%s %s\n", p.match(true, "Found the transaction in binlog file:"), p.paint(colorBold, res.File))
+	fmt.Fprintf(w, "Position: %s\n", p.paint(colorBold, fmt.Sprintf("%s:%d", res.File, res.Position)))
+	fmt.Fprintf(w, "Event time: %s\n", p.time(res.Time.Format("2006-01-02 15:04:05")))
+	if res.ServerID != 0 {
+		fmt.Fprintf(w, "Origin server ID: %d\n", res.ServerID)
+	}
+	printCatchUp(w, res.CatchUp)
+}
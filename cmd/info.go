@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// runInfo implements the info command, reporting the time range of a single binlog file
+// read from stdin or a path, and the position of a timestamp in it
+func runInfo(args []string) {
+	fs := flag.NewFlagSet("info", flag.ExitOnError)
+	common := registerCommonFlags(fs)
+	stdin := fs.Bool("stdin", false, "Read the binlog from stdin, e.g. piped from aws s3 cp s3://bucket/mysql-bin.000012 -")
+	name := fs.String("name", "", "File name reported for the binlog read from stdin (default: stdin)")
+	timestamp := fs.String("timestamp", "", "Also locate this timestamp in the file (format: YYYY-MM-DD HH:MM:SS)")
+	align := fs.String("align", "", "Boundary to snap the position to: transaction, event or none (default: transaction)")
+	timestampSource := fs.String("timestamp-source", "", "Event timestamps to compare: header, immediate-commit or original-commit (default: header)")
+	if err := fs.Parse(args); err != nil {
+		fatalf("Error parsing flags: %v", err)
+	}
+
+	cfg, err := common.load()
+	if err != nil {
+		fatalf("Error loading config: %v", err)
+	}
+	if err := cfg.resolveCluster(); err != nil {
+		fatalf("Error looking up the cluster: %v", err)
+	}
+	if *timestamp != "" {
+		cfg.Timestamp = *timestamp
+	}
+	if *align != "" {
+		cfg.Align = *align
+	}
+	if *timestampSource != "" {
+		cfg.TimestampSource = *timestampSource
+	}
+
+	var targetTime time.Time
+	if cfg.Timestamp != "" {
+		if targetTime, err = parseTimestamp(cfg.Timestamp); err != nil {
+			fatalf("Invalid timestamp format: %v", err)
+		}
+	}
+	alignment, err := binlog.ParseAlignment(cfg.Align)
+	if err != nil {
+		fatalf("Invalid alignment: %v", err)
+	}
+	source, err := binlog.ParseTimestampSource(cfg.TimestampSource)
+	if err != nil {
+		fatalf("Invalid timestamp source: %v", err)
+	}
+
+	var file *os.File
+	switch {
+	case *stdin && fs.NArg() == 0:
+		file = os.Stdin
+		if *name == "" {
+			*name = "stdin"
+		}
+	case !*stdin && fs.NArg() == 1:
+		if file, err = os.Open(fs.Arg(0)); err != nil {
+			fatalf("Failed to open binlog: %v", err)
+		}
+		defer file.Close()
+		if *name == "" {
+			*name = filepath.Base(fs.Arg(0))
+		}
+	default:
+		fatalf("info reads a single binlog: pass --stdin or a file path")
+	}
+
+	summary, err := binlog.SummarizeFile(bufio.NewReaderSize(file, 1<<20), *name, targetTime, alignment, source, cfg.originFilter())
+	if err != nil {
+		fatalf("Failed to read binlog: %v", err)
+	}
+
+	p := newPalette(os.Stdout)
+	fmt.Printf("File: %s\n", *name)
+	fmt.Printf("Time range: %s to %s\n", p.time(summary.Start.Format("2006-01-02 15:04:05.999999")), p.time(summary.End.Format("2006-01-02 15:04:05.999999")))
+	fmt.Printf("Events: %d (%d bytes)\n", summary.Events, summary.Bytes)
+	if targetTime.IsZero() {
+		return
+	}
+
+	fmt.Printf("Target time: %s\n", targetTime.Format("2006-01-02 15:04:05"))
+	if summary.Position == nil {
+		fmt.Println(p.match(false, "The file does not contain the target time"))
+		os.Exit(exitNotFound)
+	}
+	pos := summary.Position
+	fmt.Printf("Position (%s-aligned): %s\n", alignment, p.paint(colorBold, fmt.Sprintf("%s:%d", pos.File, pos.Pos)))
+	if pos.GTID != "" {
+		fmt.Printf("GTID: %s\n", pos.GTID)
+	}
+	if !pos.Timestamp.IsZero() {
+		fmt.Printf("Event time: %s\n", p.time(pos.Timestamp.Format("2006-01-02 15:04:05.999999")))
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// interruptCtx is canceled by the first SIGINT or SIGTERM
+var interruptCtx, interrupt = context.WithCancel(context.Background())
+
+// trapInterrupts makes SIGINT and SIGTERM stop the reads from the server in progress,
+// which run under interruptCtx, instead of killing the program, so that their
+// replication connections are closed and the dumps end on the server, and the results
+// found so far are still written.
+// A second signal exits at once.
+func trapInterrupts() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		slog.Warn("Interrupted, closing connections to the server; interrupt again to exit at once", "signal", sig.String())
+		interrupt()
+		<-signals
+		os.Exit(exitInterrupted)
+	}()
+}
+
+// interrupted reports whether SIGINT or SIGTERM was received
+func interrupted() bool {
+	return interruptCtx.Err() != nil
+}
+
+// exitIfInterrupted exits with exitInterrupted if SIGINT or SIGTERM was received, for
+// commands with nothing to write after a search was cut short
+func exitIfInterrupted() {
+	if interrupted() {
+		os.Exit(exitInterrupted)
+	}
+}
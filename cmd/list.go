@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// listEntry is a binlog file with its time range, when probed
+type listEntry struct {
+	binlog.FileInfo
+	Start *time.Time `json:"start,omitempty"`
+	End   *time.Time `json:"end,omitempty"`
+	// Truncated names the limit that stopped the probe before the end of the file, when
+	// End is only a lower bound
+	Truncated string `json:"truncated,omitempty"`
+}
+
+// listOutput is the JSON document produced by the list command
+type listOutput struct {
+	Files       []listEntry               `json:"files"`
+	Status      *binlog.Status            `json:"status,omitempty"`
+	Compression []binlog.CompressionStats `json:"compression,omitempty"`
+}
+
+// runList implements the list command, printing every binlog file with its metadata
+func runList(args []string) {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	common := registerCommonFlags(fs)
+	output := fs.String("output", "text", "Output format: text, json, csv or tsv")
+	ranges := fs.Bool("ranges", false, "Probe every file for its first and last event timestamps")
+	outFlags := registerOutputFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		fatalf("Error parsing flags: %v", err)
+	}
+
+	switch *output {
+	case "text", "json", "csv", "tsv":
+	default:
+		fatalf("Unknown output format %q", *output)
+	}
+	out, save := outFlags.open()
+	// A header is only written at the top of the file
+	header := !outFlags.appending()
+
+	cfg, err := common.load()
+	if err != nil {
+		fatalf("Error loading config: %v", err)
+	}
+	if err := cfg.resolveCluster(); err != nil {
+		fatalf("Error looking up the cluster: %v", err)
+	}
+	server := cfg.server()
+
+	files, err := server.ListBinlogs()
+	if err != nil {
+		fatalf("Failed to get binlog files: %v", err)
+	}
+	// Probes stop at the listed sizes, so the active file is read without waiting
+	finder := cfg.finder()
+	finder.Sizes = make(map[string]int64, len(files))
+	for _, f := range files {
+		finder.Sizes[f.Name] = f.Size
+	}
+
+	entries := make([]listEntry, 0, len(files))
+	for _, f := range files {
+		entry := listEntry{FileInfo: f}
+		if *ranges {
+			r, err := finder.TimeRange(f.Name)
+			if err != nil {
+				slog.Warn("Could not get time range", "file", f.Name, "error", err)
+			} else {
+				entry.Start, entry.End, entry.Truncated = &r.Start, &r.End, r.Truncated
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	// Status and compression stats are informational only, so don't fail the listing over them
+	status, err := server.BinlogStatus()
+	if err != nil {
+		slog.Warn("Could not get binlog status", "error", err)
+	}
+	stats, err := server.CompressionStats()
+	if err != nil {
+		slog.Warn("Could not get compression stats", "error", err)
+	}
+
+	switch *output {
+	case "json":
+		outFlags.writeJSON(out, listOutput{Files: entries, Status: status, Compression: stats})
+	case "csv":
+		printListDelimited(out, entries, ',', header)
+	case "tsv":
+		printListDelimited(out, entries, '\t', header)
+	default:
+		printListText(out, entries, status, stats, *ranges)
+	}
+	save()
+}
+
+// encryptedLabel formats the optional encryption flag for tabular output
+func encryptedLabel(f binlog.FileInfo, unknown string) string {
+	if f.Encrypted == nil {
+		return unknown
+	}
+	if *f.Encrypted {
+		return "Yes"
+	}
+	return "No"
+}
+
+// formatOptionalTime formats a probed timestamp, or returns an empty string if it wasn't probed
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// printListText writes the binlog list as an aligned table
+func printListText(w io.Writer, entries []listEntry, status *binlog.Status, stats []binlog.CompressionStats, ranges bool) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if ranges {
+		fmt.Fprintln(tw, "FILE\tSIZE\tENCRYPTED\tSTART\tEND")
+	} else {
+		fmt.Fprintln(tw, "FILE\tSIZE\tENCRYPTED")
+	}
+	truncated := false
+	for _, e := range entries {
+		if ranges {
+			end := formatOptionalTime(e.End)
+			if e.Truncated != "" {
+				end += "+"
+				truncated = true
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", e.Name, e.Size, encryptedLabel(e.FileInfo, "-"),
+				formatOptionalTime(e.Start), end)
+		} else {
+			fmt.Fprintf(tw, "%s\t%d\t%s\n", e.Name, e.Size, encryptedLabel(e.FileInfo, "-"))
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		fatalf("Failed to write output: %v", err)
+	}
+	if truncated {
+		fmt.Fprintln(w, "+ the probe stopped before the end of the file, so its last event is at or after END")
+	}
+
+	if status != nil {
+		fmt.Fprintf(w, "\nCurrent position: %s\n", status)
+		if status.ExecutedGTIDSet != "" {
+			fmt.Fprintf(w, "Executed GTID set: %s\n", status.ExecutedGTIDSet)
+		}
+	}
+
+	for _, s := range stats {
+		fmt.Fprintf(w, "\nCompression (%s): %d transactions, %d bytes compressed from %d (%.0f%%)\n",
+			s.CompressionType, s.Transactions, s.CompressedBytes, s.UncompressedBytes, s.CompressionPct)
+	}
+}
+
+// printListDelimited writes the binlog list as CSV or TSV, after a header row if asked
+func printListDelimited(w io.Writer, entries []listEntry, comma rune, header bool) {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	var records [][]string
+	if header {
+		records = append(records, []string{"file", "start", "end", "size", "encrypted", "truncated"})
+	}
+	for _, e := range entries {
+		records = append(records, []string{
+			e.Name,
+			formatOptionalTime(e.Start),
+			formatOptionalTime(e.End),
+			strconv.FormatInt(e.Size, 10),
+			encryptedLabel(e.FileInfo, ""),
+			e.Truncated,
+		})
+	}
+
+	if err := cw.WriteAll(records); err != nil {
+		fatalf("Failed to write output: %v", err)
+	}
+}
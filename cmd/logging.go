@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// setupLogging installs the default slog logger according to the --log-format and --log-level flags.
+// Diagnostics always go to stderr so stdout only carries results.
+func setupLogging(format, level string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q (expected debug, info, warn or error)", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	switch format {
+	case "text":
+		var w io.Writer = os.Stderr
+		if colorEnabled(os.Stderr) {
+			w = logColorizer{w: os.Stderr}
+		}
+		handler = slog.NewTextHandler(w, opts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("invalid log format %q (expected text or json)", format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// fatalf logs an error and exits with the generic failure code
+func fatalf(format string, v ...any) {
+	slog.Error(fmt.Sprintf(format, v...))
+	os.Exit(1)
+}
@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/go-ini/ini"
+	"github.com/go-mysql-org/go-mysql/mysql"
 	"github.com/go-mysql-org/go-mysql/replication"
 
 	"github.com/minuteman3/binlog-find-time/internal/binlog"
@@ -17,11 +22,66 @@ import (
 const defaultConfigFile = ".binlog-find-time.ini"
 
 type config struct {
-	Host      string
-	Port      int
-	User      string
-	Password  string
+	Host     string
+	Port     int
+	User     string
+	Password string
+	// LoginPath, if set, names login credentials stored with mysql_config_editor
+	LoginPath string
+	// DSN, if set, is a go-sql-driver/mysql DSN overriding the settings above
+	DSN string
+	// OrchestratorAPI and Cluster, if set, pick the host to search from the cluster's
+	// topology in Orchestrator, following failovers
+	OrchestratorAPI string
+	Cluster         string
+	// AWSSecretID names a Secrets Manager secret or, with the ssm: prefix, an SSM
+	// parameter holding the password or RDS-formatted JSON credentials
+	AWSSecretID string
+	// TLS secures the SQL and replication connections, from the DSN's tls parameter
+	TLS *tls.Config
+	// AuthPlugin is the account's authentication plugin, if it must be allowed explicitly
+	AuthPlugin string
+	// Flavor is the server's replication flavor, mysql or mariadb, or empty to detect it
+	// from the server's version
+	Flavor string
+	// CompressionAlgorithms lists the protocol compression algorithms allowed, e.g. zlib
+	CompressionAlgorithms string
+	// ConnectTimeout and ReadTimeout bound dialing and each read from the server
+	ConnectTimeout time.Duration
+	ReadTimeout    time.Duration
+	// HeartbeatPeriod is how often an idle replication stream gets a heartbeat, 0 for
+	// binlog.DefaultHeartbeatPeriod or half of ReadTimeout if shorter
+	HeartbeatPeriod time.Duration
+	// RecvBufferSize sets the socket receive buffer of each connection, 0 for the OS default
+	RecvBufferSize int64
+	// Throttle limits each replication stream to this many bytes per second, 0 for no limit
+	Throttle  int64
 	Timestamp string
+	Align     string
+	// TimestampSource selects the event timestamps compared against the target
+	TimestampSource string
+	// OriginServerID and IgnoreServerIDs restrict searches to the events that originated
+	// on some servers of a replication chain
+	OriginServerID  uint32
+	IgnoreServerIDs []uint32
+	// ServeTLSCert, ServeTLSKey and ServeClientCA secure the listeners of serve, the
+	// client CA requiring client certificates
+	ServeTLSCert  string
+	ServeTLSKey   string
+	ServeClientCA string
+	// ServeTokenFile and ServeUsersFile hold the bearer tokens and basic authentication
+	// users serve requires
+	ServeTokenFile string
+	ServeUsersFile string
+	// SlackWebhookURL and PagerDutyRoutingKey, from the [notify] section, are where
+	// notifications go without --slack-webhook-url and --pagerduty-routing-key
+	SlackWebhookURL     string
+	PagerDutyRoutingKey string
+	LogFormat           string
+	LogLevel            string
+	// settings read the binlogs of each server searched: the probe limits, origin filter,
+	// connection options, retries and throttle
+	settings binlog.Settings
 }
 
 func printHelp() {
@@ -30,25 +90,253 @@ Binlog Find Time - Find MySQL binlog file containing a specific timestamp
 
 Usage:
   binlog-find-time [flags]
+  binlog-find-time <command> [flags]
+
+Commands:
+  find                  Find the binlog containing a timestamp (default command)
+  list                  List binlog files with size and encryption metadata
+  serve                 Run a gRPC server answering lookups (--grpc-listen=:50051), with a
+                        REST API (--http-listen=:8080) and, with --refresh=1m, a time index
+                        of the binlogs of each --target kept up to date in the background
+                        (secure it with --tls-cert, --tls-key, --tls-client-ca and
+                        --auth-token-file or --basic-auth-file); gRPC health checks and
+                        reflection are served too
+  exporter              Publish binlog retention metrics for Prometheus (--listen=:9105),
+                        notifying --webhook-url or --slack-webhook-url when retention
+                        falls below --min-retention
+                        (serve and exporter support systemd Type=notify services with a
+                        watchdog, and reload the config file on SIGHUP)
+  check                 Nagios/Sensu retention check (--min-retention=72h [--warn-retention=96h]),
+                        optionally sent to --slack-webhook-url or --pagerduty-routing-key
+  tui                   Browse binlogs and preview their events interactively
+  doctor                Check connectivity, privileges and binlog settings before searching
+  fleet                 Search many servers at once (--hosts-file=hosts.yaml or repeated --host)
+  warm-cache            Probe every binlog once and cache the time index for find
+  info                  Report the time range of one binlog read from stdin or a file
+                        (info --stdin [--timestamp=TS] or info [flags] FILE)
+  range                 Report the start and stop positions and GTIDs of the events
+                        between two timestamps, for replay (--start=TS --end=TS, or
+                        --until=TS to include events at the end time)
+  version               Print the version, commit, build date and go-mysql version
+                        (also --version; --output=json for bug reports and tooling)
 
 Flags:
   --host=HOST           MySQL host (default: localhost)
   --port=PORT           MySQL port (default: 3306)
   --user=USER           MySQL user (default: root)
   --password=PASSWORD   MySQL password
-  --timestamp=TIME      Timestamp to search for (format: YYYY-MM-DD HH:MM:SS)
+  --password-file=FILE  Read the MySQL password from FILE, e.g. a mounted Kubernetes
+                        Secret, without its trailing newline
+  --secrets-dir=DIR     Read [mysql] settings from DIR, one file per key named like it
+                        (host, port, user, password, dsn, ...), as Kubernetes mounts a
+                        Secret; they override the config file and flags override them
+  --login-path=NAME     Read the host, port, user and password stored under NAME in
+                        ~/.mylogin.cnf by mysql_config_editor, as the mysql client
+                        does; the mysql client need not be installed
+  --dsn=DSN             MySQL DSN instead of the flags above, e.g.
+                        'user:pass@tcp(host:3306)/?tls=true'; its timeout, readTimeout
+                        and tls parameters apply, and the flags above override its parts
+  --aws-secret-id=ID    Read the credentials from an AWS Secrets Manager secret (name or
+                        ARN) or, as ssm:NAME or by ARN, an SSM parameter; JSON secrets
+                        in the RDS format also set the host and port. The flags above
+                        override its parts
+  --orchestrator-api=URL
+                        Orchestrator API to look up --cluster in, with any credentials
+                        as basic auth in the URL, e.g. http://orchestrator:3000/api
+  --cluster=ALIAS       Search the primary of this Orchestrator cluster instead of
+                        --host, looked up on every run to follow failovers
+  --auth-plugin=NAME    Authentication plugin of the MySQL user: mysql_native_password,
+                        caching_sha2_password, sha256_password or mysql_clear_password;
+                        only mysql_clear_password (PAM, LDAP) must be given, over TLS
+  --flavor=NAME         Replication flavor of the server: mysql or mariadb (default:
+                        detected from the server version); MariaDB only sends its
+                        GTIDs to replicas registered as MariaDB
+  --compress            Compress the SQL connections (SHOW BINLOG EVENTS when
+                        bisecting) with zlib; replication streams stay uncompressed
+  --compression-algorithms=LIST
+                        Compression algorithms allowed: zlib, zstd or uncompressed,
+                        comma-separated; only zlib is supported by the SQL driver
+  --timestamp=TIME      Timestamp to search for (format: YYYY-MM-DD HH:MM:SS[.ffffff]);
+                        may be repeated to look up several in one run. Positions snap to
+                        the first event timestamped at or after it
+  --timestamps-file=FILE
+                        File of timestamps to look up, one per line; - reads stdin,
+                        as do timestamps piped in without --timestamp
+  --position            Also locate the position of the timestamp within the binlog file
+  --gtid=GTID           Locate the transaction with this GTID instead of a timestamp:
+                        domain-server-sequence on MariaDB (e.g. 0-1-12345, found with
+                        the GTID_LIST events) or UUID:N on MySQL
+  --apply-rate=RATE     Estimate how long a replica applying RATE, e.g. 20MB/s, takes to
+                        catch up from the position to the server's head, given the
+                        rate the server wrote at since; implies --position
+  --event-type=TYPE     Also report the first event of TYPE at or after the timestamp:
+                        WRITE_ROWS, UPDATE_ROWS, DELETE_ROWS, TABLE_MAP, GTID, XID,
+                        QUERY or ROTATE
+  --until               Locate the stop position instead: the end of the last event at
+                        or before the timestamp, for mysqlbinlog --stop-position
+  --bracket             Also report the last event at or before the timestamp and the
+                        first at or after it, with their positions; implies --position
+  --safe-stop           Locate the stop position after the last transaction committed
+                        before the timestamp, so replay never ends inside a transaction
+  --sequential          Locate the position by reading the file from its start rather
+                        than bisecting it with SHOW BINLOG EVENTS
+  --align=MODE          Boundary to snap positions to: transaction, event or none
+                        (default: transaction)
+  --watch               If the timestamp is beyond the newest binlog event, wait for
+                        the server to reach it and report the file and position
+  --watch-timeout=DUR   Maximum time to wait in --watch mode (default: no limit)
+  --webhook-url=URL     With --watch, POST a JSON notification to the URL once the
+                        server reaches the timestamp
+  --slack-webhook-url=URL
+                        With --watch, post a message to the Slack incoming webhook
+                        once the server reaches the timestamp
+  --webhook-retries=N   Times to retry a failed webhook delivery (default: 3)
+  --skip-gap-confirm    Report a gap between files only when the probe of the file before
+                        it read it to its end; by default a file the scan limits cut
+                        short is read to its end to confirm the gap
+  --strict              Treat an approximate match (the closest preceding file, or a gap
+                        between files) as no match: print no file and exit 3 instead
+                        of 2
+  -q, --quiet           Print only the file name (file:pos with --position) on stdout;
+                        all diagnostics go to stderr
+  --format=TEMPLATE     Render the result with a Go template, e.g.
+                        '{{.File}} {{.Position}} {{.Gtid}}'
+  --output=FORMAT       Output format: text, json mapping each timestamp to its
+                        file, position and match quality, or env printing
+                        BINLOG_FILE=... lines for eval (default: text)
+  --emit=pt             Print the position as the arguments Percona Toolkit and
+                        mysqlbinlog take: the h=,P=,u= DSN, --start-position (or
+                        --stop-position) with the file, and --until-master=FILE,POS
+  --output-file=FILE    Write the results to FILE instead of stdout, replacing it
+                        atomically (also for list and range)
+  --append              Append the results to --output-file instead, one JSON
+                        document per line with --output=json
+  --progress=ndjson     Emit one JSON line per probed file on stderr while searching
+  --timestamp-source=S  Event timestamps to compare: header, immediate-commit or
+                        original-commit (default: header). Commit timestamps come from
+                        GTID events (MySQL 8.0+) and have microsecond precision
+  --precise             Shorthand for --timestamp-source=original-commit
+  --slack=DUR           How far out of order event timestamps may be (e.g. from a
+                        multi-threaded replica) when locating a position (default: 0)
+  --prefer=first|last   File to pick when the target second spans a rotation (default: first)
+  --prefer-replica      Scan a healthy replica of the host (from SHOW REPLICAS, or the
+                        least-lagging one of --cluster) to avoid loading the primary
+  --include-active      Search the binlog currently being written (default)
+  --exclude-active      Skip the binlog currently being written
+  --cache-dir=DIR       Directory of the range cache written by warm-cache
+                        (default: the user cache directory)
+  --no-cache            Probe every file even if its time range is cached
+  --source=URL          Search archived binlogs instead of the server: a directory,
+                        s3://bucket/prefix, gcs://bucket/prefix,
+                        azblob://container/prefix, sftp://user@host/path or
+                        the https:// URL of a file index (see README)
+  --binlog-glob=PATTERN Search archived binlog files matching a glob pattern, where
+                        ** matches any number of directories, e.g.
+                        'backups/**/mysql-bin.*'
+  --index-file=FILE     Binlog index file listing the binlogs of a directory --source
+                        in order (default: the directory's index file); implies
+                        --source=its directory
+  --dry-run, --explain  Show the files the search would probe, in order, and the bytes
+                        it would read, from cached and estimated ranges, without
+                        reading any binlog
+  --skip-corrupt        Leave files that fail a checksum or cannot be parsed out of
+                        the search instead of stopping it
+  --keyring=FILE        Keyring file, or file of decrypted keys, for archived binlogs
+                        written with binlog_encryption=ON
   --config=FILE         Path to configuration file (default: .binlog-find-time.ini)
+  --connect-timeout=DUR Maximum time to establish a connection (default: 10s)
+  --read-timeout=DUR    Maximum time to wait for each read from the server; heartbeats
+                        keep idle replication streams alive (default: no limit)
+  --heartbeat-period=DUR
+                        Interval of the server's heartbeats on idle replication
+                        streams, which end probes of the newest binlog; must be shorter
+                        than --read-timeout (default: 500ms, or half of --read-timeout)
+  --recv-buffer-size=SIZE
+                        Socket receive buffer of each connection, e.g. 4MB, for
+                        servers far away (default: set by the OS)
+  --probe-timeout=DUR   Maximum time to read the start of a binlog file when probing
+                        its time range (default: 5s, 0 for no limit)
+  --max-events-per-file=N
+                        Stop probing a binlog file after N events; the file's end time is
+                        then a lower bound (default: 1000, 0 for no limit)
+  --max-bytes-per-file=SIZE
+                        Stop probing a binlog file after SIZE bytes of events, e.g. 64MB
+                        (default: no limit)
+  --throttle=RATE       Maximum rate to read events from each replication stream, e.g.
+                        10MB/s, to limit I/O and network load on the server (default: no limit)
+  --origin-server-id=N  Only consider the events that originated on the server with this
+                        server_id, in a replication chain
+  --ignore-server-ids=LIST
+                        Skip the events that originated on these server_ids, e.g. 10,11
+  --retries=N           Times to retry connecting after a transient error (default: 3)
+  --retry-backoff=DUR   Delay before the first retry, doubled for each retry (default: 1s)
+  --log-format=FORMAT   Log format on stderr: text or json (default: text)
+  --log-level=LEVEL     Minimum log level: debug, info, warn or error (default: warn)
+  -v                    Verbose: log every probe and its time range (info level)
+  -vv, --debug          Trace every event header read and every search decision
+  --no-color            Disable colored results and logs on terminals; the NO_COLOR
+                        environment variable does the same
   --help                Display this help message
 
+Connections:
+  SQL connections carry the connection attributes program_name=binlog-find-time,
+  version and purpose=timestamp-probe. Replication connections cannot be given any:
+  they carry go-mysql's _client_name=go-mysql and _client_role=binary_log_listener,
+  and register as a replica with server ID 100.
+
+Exit codes:
+  0  Exact match
+  1  Usage or configuration error
+  2  Approximate match, closest preceding file or a gap (3 with --strict)
+  3  No binlog found for the timestamp, or only an approximate match with --strict
+  4  Connection error
+  5  Authentication or privilege error
+  6  Timestamp is before the oldest binlog
+  7  Timestamp is after the newest binlog event
+  130 Interrupted by SIGINT or SIGTERM; the results found so far are still written
+
 Configuration file format (.ini):
   [mysql]
   host = localhost
   port = 3306
   user = root
   password = secret
+  # or: password_file = /var/run/secrets/mysql/password
+  # or: login_path = prod
+  # or: dsn = user:secret@tcp(localhost:3306)/?tls=true
+  # or: aws_secret_id = prod/db1/binlog
+  flavor = mariadb
+  compress = false
+  connect_timeout = 10s
+  read_timeout = 30s
+  heartbeat_period = 1s
+  recv_buffer_size = 4MB
 
   [search]
   timestamp = 2023-04-01 12:30:45
+  align = transaction
+  timestamp_source = header
+  origin_server_id = 1
+  ignore_server_ids = 10,11
+
+  [orchestrator]
+  api = http://orchestrator:3000/api
+  cluster = main
+
+  [serve]
+  tls_cert = /etc/tls/tls.crt
+  tls_key = /etc/tls/tls.key
+  tls_client_ca = /etc/tls/ca.crt
+  auth_token_file = /etc/binlog-find-time/tokens
+  basic_auth_file = /etc/binlog-find-time/users
+
+  [notify]
+  slack_webhook_url = https://hooks.slack.com/services/T000/B000/XXXX
+  pagerduty_routing_key = R0UT1NGK3Y
+
+  [log]
+  format = text
+  level = warn
 
 Example:
   binlog-find-time --timestamp="2023-04-01 12:30:45"
@@ -60,126 +348,516 @@ Example:
 
 func loadConfig(filepath string) (*config, error) {
 	cfg := &config{
-		Host: "localhost",
-		Port: 3306,
-		User: "root",
+		Host:            "localhost",
+		Port:            3306,
+		User:            "root",
+		ConnectTimeout:  10 * time.Second,
+		Align:           "transaction",
+		TimestampSource: "header",
+		LogFormat:       "text",
+		LogLevel:        "warn",
 	}
 
 	// Check if config file exists
 	if _, err := os.Stat(filepath); err == nil {
 		iniFile, err := ini.Load(filepath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to load config file: %v", err)
+			return nil, fmt.Errorf("failed to load config file: %w", err)
 		}
 
 		// MySQL section
 		mysqlSection := iniFile.Section("mysql")
 		if mysqlSection != nil {
-			cfg.Host = mysqlSection.Key("host").MustString(cfg.Host)
-			cfg.Port = mysqlSection.Key("port").MustInt(cfg.Port)
-			cfg.User = mysqlSection.Key("user").MustString(cfg.User)
-			cfg.Password = mysqlSection.Key("password").MustString(cfg.Password)
+			if err := cfg.applyMySQLSection(mysqlSection); err != nil {
+				return nil, err
+			}
 		}
 
 		// Search section
 		searchSection := iniFile.Section("search")
 		if searchSection != nil {
 			cfg.Timestamp = searchSection.Key("timestamp").String()
+			cfg.Align = searchSection.Key("align").MustString(cfg.Align)
+			cfg.TimestampSource = searchSection.Key("timestamp_source").MustString(cfg.TimestampSource)
+			cfg.OriginServerID = uint32(searchSection.Key("origin_server_id").MustUint(0))
+			if ids := searchSection.Key("ignore_server_ids").String(); ids != "" {
+				if cfg.IgnoreServerIDs, err = parseServerIDs(ids); err != nil {
+					return nil, fmt.Errorf("invalid ignore_server_ids: %w", err)
+				}
+			}
+		}
+
+		// Orchestrator section
+		orchestratorSection := iniFile.Section("orchestrator")
+		if orchestratorSection != nil {
+			cfg.OrchestratorAPI = orchestratorSection.Key("api").String()
+			cfg.Cluster = orchestratorSection.Key("cluster").String()
+		}
+
+		// Serve section
+		serveSection := iniFile.Section("serve")
+		if serveSection != nil {
+			cfg.ServeTLSCert = serveSection.Key("tls_cert").String()
+			cfg.ServeTLSKey = serveSection.Key("tls_key").String()
+			cfg.ServeClientCA = serveSection.Key("tls_client_ca").String()
+			cfg.ServeTokenFile = serveSection.Key("auth_token_file").String()
+			cfg.ServeUsersFile = serveSection.Key("basic_auth_file").String()
+		}
+
+		// Notify section
+		notifySection := iniFile.Section("notify")
+		if notifySection != nil {
+			cfg.SlackWebhookURL = notifySection.Key("slack_webhook_url").String()
+			cfg.PagerDutyRoutingKey = notifySection.Key("pagerduty_routing_key").String()
+		}
+
+		// Log section
+		logSection := iniFile.Section("log")
+		if logSection != nil {
+			cfg.LogFormat = logSection.Key("format").MustString(cfg.LogFormat)
+			cfg.LogLevel = logSection.Key("level").MustString(cfg.LogLevel)
 		}
 	}
 
 	return cfg, nil
 }
 
-func main() {
-	// Define command line flags
-	configFile := flag.String("config", getDefaultConfigPath(), "Path to configuration file")
-	help := flag.Bool("help", false, "Display help message")
-	mysqlHost := flag.String("host", "", "MySQL host")
-	mysqlPort := flag.Int("port", 0, "MySQL port")
-	mysqlUser := flag.String("user", "", "MySQL user")
-	mysqlPass := flag.String("password", "", "MySQL password")
-	timestamp := flag.String("timestamp", "", "Timestamp to search for (format: YYYY-MM-DD HH:MM:SS)")
-	flag.Parse()
-
-	// Check if help flag is set or no arguments provided
-	if *help || len(os.Args) == 1 {
-		printHelp()
-		os.Exit(0)
+// mysqlKeys are the keys of the [mysql] section of the config file
+var mysqlKeys = []string{"host", "port", "user", "password", "password_file", "login_path", "dsn", "aws_secret_id", "auth_plugin",
+	"flavor", "compress", "compression_algorithms", "connect_timeout", "read_timeout", "heartbeat_period", "recv_buffer_size"}
+
+// applyMySQLSection sets the connection settings given in the [mysql] section of the
+// config file, or read from a secrets directory, keeping the others
+func (c *config) applyMySQLSection(section *ini.Section) error {
+	c.Host = section.Key("host").MustString(c.Host)
+	c.Port = section.Key("port").MustInt(c.Port)
+	c.User = section.Key("user").MustString(c.User)
+	c.Password = section.Key("password").MustString(c.Password)
+	if path := section.Key("password_file").String(); path != "" {
+		password, err := readSecretFile(path)
+		if err != nil {
+			return err
+		}
+		c.Password = password
+	}
+	c.LoginPath = section.Key("login_path").MustString(c.LoginPath)
+	c.DSN = section.Key("dsn").MustString(c.DSN)
+	c.AWSSecretID = section.Key("aws_secret_id").MustString(c.AWSSecretID)
+	c.AuthPlugin = section.Key("auth_plugin").MustString(c.AuthPlugin)
+	c.Flavor = section.Key("flavor").MustString(c.Flavor)
+	c.CompressionAlgorithms = section.Key("compression_algorithms").MustString(c.CompressionAlgorithms)
+	if section.Key("compress").MustBool(false) {
+		c.CompressionAlgorithms = "zlib"
+	}
+	c.ConnectTimeout = section.Key("connect_timeout").MustDuration(c.ConnectTimeout)
+	c.ReadTimeout = section.Key("read_timeout").MustDuration(c.ReadTimeout)
+	c.HeartbeatPeriod = section.Key("heartbeat_period").MustDuration(c.HeartbeatPeriod)
+	if size := section.Key("recv_buffer_size").String(); size != "" {
+		n, err := parseByteSize(size)
+		if err != nil {
+			return fmt.Errorf("invalid recv_buffer_size: %w", err)
+		}
+		c.RecvBufferSize = n
 	}
+	return nil
+}
+
+// commonFlags holds the connection and logging flags shared by all commands
+type commonFlags struct {
+	configFile     *string
+	hosts          *stringList
+	port           *int
+	user           *string
+	password       *string
+	passwordFile   *string
+	secretsDir     *string
+	loginPath      *string
+	dsn            *string
+	awsSecretID    *string
+	authPlugin     *string
+	flavor         *string
+	compress       *bool
+	compression    *string
+	logFormat      *string
+	logLevel       *string
+	verbose        *bool
+	veryVerbose    *bool
+	debug          *bool
+	noColor        *bool
+	retries        *int
+	retryBackoff   *time.Duration
+	connectTimeout *time.Duration
+	readTimeout    *time.Duration
+	heartbeat      *time.Duration
+	recvBuffer     *byteSize
+	probeTimeout   *time.Duration
+	maxEvents      *int
+	maxBytes       *byteSize
+	throttle       *byteRate
+	originServerID *uint
+	ignoreServers  *string
+	orchestrator   *string
+	cluster        *string
+}
+
+// registerCommonFlags defines the shared flags on the given flag set
+func registerCommonFlags(fs *flag.FlagSet) *commonFlags {
+	var hosts stringList
+	fs.Var(&hosts, "host", "MySQL host (may be repeated for the fleet command)")
+	var maxBytes byteSize
+	fs.Var(&maxBytes, "max-bytes-per-file", "Stop probing a binlog file after this many bytes of events, e.g. 64MB (default: no limit)")
+	var throttle byteRate
+	var recvBuffer byteSize
+	fs.Var(&recvBuffer, "recv-buffer-size", "Socket receive buffer of each connection, e.g. 4MB, for high-latency links (default: set by the OS)")
+	fs.Var(&throttle, "throttle", "Maximum rate to read events from each replication stream, e.g. 10MB/s (default: no limit)")
+	return &commonFlags{
+		configFile:     fs.String("config", getDefaultConfigPath(), "Path to configuration file"),
+		hosts:          &hosts,
+		port:           fs.Int("port", 0, "MySQL port"),
+		user:           fs.String("user", "", "MySQL user"),
+		password:       fs.String("password", "", "MySQL password"),
+		passwordFile:   fs.String("password-file", "", "Read the MySQL password from this file, e.g. a mounted Kubernetes Secret"),
+		secretsDir:     fs.String("secrets-dir", "", "Read settings from a directory with one file per [mysql] config key, e.g. a mounted Kubernetes Secret"),
+		loginPath:      fs.String("login-path", "", "Read the connection settings stored under this name in ~/.mylogin.cnf by mysql_config_editor"),
+		dsn:            fs.String("dsn", "", "MySQL DSN instead of the connection flags, e.g. 'user:pass@tcp(host:3306)/?tls=true'"),
+		awsSecretID:    fs.String("aws-secret-id", "", "AWS Secrets Manager secret, or ssm:NAME for an SSM parameter, holding the password or RDS-formatted JSON credentials"),
+		authPlugin:     fs.String("auth-plugin", "", "Authentication plugin of the MySQL user: mysql_native_password, caching_sha2_password, sha256_password or mysql_clear_password (needs TLS)"),
+		flavor:         fs.String("flavor", "", "Replication flavor of the server: mysql or mariadb (default: detected from the server version)"),
+		compress:       fs.Bool("compress", false, "Compress the SQL connections with zlib; same as --compression-algorithms=zlib"),
+		compression:    fs.String("compression-algorithms", "", "Protocol compression algorithms allowed for the SQL connections: zlib, zstd or uncompressed, comma-separated"),
+		logFormat:      fs.String("log-format", "", "Log format: text or json (default: text)"),
+		logLevel:       fs.String("log-level", "", "Minimum log level: debug, info, warn or error (default: warn)"),
+		verbose:        fs.Bool("v", false, "Verbose: log every probe and its time range"),
+		veryVerbose:    fs.Bool("vv", false, "Very verbose: same as --debug"),
+		debug:          fs.Bool("debug", false, "Trace every event header read and every search decision"),
+		noColor:        fs.Bool("no-color", false, "Disable colored output and logs on terminals (also set by NO_COLOR)"),
+		retries:        fs.Int("retries", 3, "Times to retry connecting after a transient error such as too many connections"),
+		retryBackoff:   fs.Duration("retry-backoff", time.Second, "Delay before the first retry, doubled for every further retry"),
+		connectTimeout: fs.Duration("connect-timeout", 0, "Maximum time to establish a connection (default: 10s)"),
+		readTimeout:    fs.Duration("read-timeout", 0, "Maximum time to wait for each read from the server (default: no limit)"),
+		heartbeat:      fs.Duration("heartbeat-period", 0, "Interval of the heartbeats the server sends on idle replication streams, which end probes of the newest binlog (default: 500ms, or half of --read-timeout if shorter)"),
+		recvBuffer:     &recvBuffer,
+		probeTimeout:   fs.Duration("probe-timeout", binlog.DefaultProbeTimeout, "Maximum time to read the start of a binlog file when probing its time range (0 for no limit)"),
+		maxEvents:      fs.Int("max-events-per-file", binlog.DefaultScanLimits.MaxEvents, "Stop probing a binlog file after this many events, 0 for no limit"),
+		maxBytes:       &maxBytes,
+		throttle:       &throttle,
+		originServerID: fs.Uint("origin-server-id", 0, "Only consider the events that originated on the server with this server_id, in a replication chain"),
+		ignoreServers:  fs.String("ignore-server-ids", "", "Skip the events that originated on these server_ids, comma-separated"),
+		orchestrator:   fs.String("orchestrator-api", "", "URL of the Orchestrator API to look up the --cluster in, e.g. http://orchestrator:3000/api"),
+		cluster:        fs.String("cluster", "", "Search the primary of this Orchestrator cluster alias instead of --host"),
+	}
+}
 
-	// Load config from file
-	cfg, err := loadConfig(*configFile)
+// load reads the config file, overrides it with command line flags if provided,
+// and sets up logging accordingly
+func (f *commonFlags) load() (*config, error) {
+	cfg, err := loadConfig(*f.configFile)
 	if err != nil {
-		log.Fatalf("Error loading config: %v", err)
+		return nil, err
+	}
+
+	if *f.logFormat != "" {
+		cfg.LogFormat = *f.logFormat
+	}
+	switch {
+	case *f.debug || *f.veryVerbose:
+		cfg.LogLevel = "debug"
+	case *f.verbose:
+		cfg.LogLevel = "info"
+	}
+	if *f.logLevel != "" {
+		cfg.LogLevel = *f.logLevel
+	}
+	noColor = *f.noColor
+	if err := setupLogging(cfg.LogFormat, cfg.LogLevel); err != nil {
+		return nil, err
+	}
+	cfg.settings = binlog.Settings{
+		ProbeTimeout: *f.probeTimeout,
+		ScanLimits:   binlog.ScanLimits{MaxEvents: *f.maxEvents, MaxBytes: int64(*f.maxBytes)},
+		Retry:        binlog.RetryPolicy{Retries: *f.retries, Backoff: *f.retryBackoff},
+		Throttle:     int64(*f.throttle),
+	}
+	if *f.originServerID != 0 {
+		cfg.OriginServerID = uint32(*f.originServerID)
+	}
+	if *f.ignoreServers != "" {
+		if cfg.IgnoreServerIDs, err = parseServerIDs(*f.ignoreServers); err != nil {
+			return nil, fmt.Errorf("invalid --ignore-server-ids: %w", err)
+		}
+	}
+	cfg.settings.Origin = cfg.originFilter()
+
+	// Settings mounted from a secret, one file per key, override the config file
+	if *f.secretsDir != "" {
+		if err := cfg.applySecretsDir(*f.secretsDir); err != nil {
+			return nil, err
+		}
 	}
 
-	// Override config with command line flags if provided
-	if *mysqlHost != "" {
-		cfg.Host = *mysqlHost
+	// A login path replaces the connection settings of the config file, as does a DSN
+	// after it, and the connection flags override their parts
+	if *f.loginPath != "" {
+		cfg.LoginPath = *f.loginPath
 	}
-	if *mysqlPort != 0 {
-		cfg.Port = *mysqlPort
+	if cfg.LoginPath != "" {
+		if err := cfg.applyLoginPath(cfg.LoginPath); err != nil {
+			return nil, err
+		}
+	}
+	if *f.dsn != "" {
+		cfg.DSN = *f.dsn
 	}
-	if *mysqlUser != "" {
-		cfg.User = *mysqlUser
+	if cfg.DSN != "" {
+		if err := cfg.applyDSN(cfg.DSN); err != nil {
+			return nil, err
+		}
 	}
-	if *mysqlPass != "" {
-		cfg.Password = *mysqlPass
+	// So do credentials kept in AWS, which are fetched on every run to follow rotation
+	if *f.awsSecretID != "" {
+		cfg.AWSSecretID = *f.awsSecretID
 	}
-	if *timestamp != "" {
-		cfg.Timestamp = *timestamp
+	if cfg.AWSSecretID != "" {
+		value, err := fetchAWSSecret(cfg.AWSSecretID)
+		if err != nil {
+			return nil, err
+		}
+		if err := cfg.applySecret(value); err != nil {
+			return nil, fmt.Errorf("%s: %w", cfg.AWSSecretID, err)
+		}
 	}
 
-	// Validate timestamp
-	if cfg.Timestamp == "" {
-		log.Fatal("Timestamp is required. Use --timestamp flag or set in config file.")
+	// Commands searching a single server use the last --host given, like any other flag
+	if len(*f.hosts) > 0 {
+		cfg.Host = (*f.hosts)[len(*f.hosts)-1]
+	}
+	if *f.port != 0 {
+		cfg.Port = *f.port
+	}
+	if *f.user != "" {
+		cfg.User = *f.user
+	}
+	switch {
+	case *f.password != "" && *f.passwordFile != "":
+		return nil, fmt.Errorf("--password and --password-file cannot be used together")
+	case *f.password != "":
+		cfg.Password = *f.password
+	case *f.passwordFile != "":
+		if cfg.Password, err = readSecretFile(*f.passwordFile); err != nil {
+			return nil, err
+		}
+	}
+	if *f.connectTimeout != 0 {
+		cfg.ConnectTimeout = *f.connectTimeout
+	}
+	if *f.readTimeout != 0 {
+		cfg.ReadTimeout = *f.readTimeout
+	}
+	if *f.heartbeat != 0 {
+		cfg.HeartbeatPeriod = *f.heartbeat
+	}
+	if *f.recvBuffer != 0 {
+		cfg.RecvBufferSize = int64(*f.recvBuffer)
+	}
+	switch {
+	case *f.compress:
+		cfg.CompressionAlgorithms = "zlib"
+	case *f.compression != "":
+		cfg.CompressionAlgorithms = *f.compression
+	}
+	// The cluster's primary replaces the host of the config file; see resolveCluster
+	if *f.orchestrator != "" {
+		cfg.OrchestratorAPI = *f.orchestrator
+	}
+	if *f.cluster != "" {
+		cfg.Cluster = *f.cluster
+	}
+	if cfg.Cluster != "" {
+		switch {
+		case cfg.OrchestratorAPI == "":
+			return nil, fmt.Errorf("--cluster needs --orchestrator-api to look it up")
+		case len(*f.hosts) > 0:
+			return nil, fmt.Errorf("--host and --cluster cannot be used together")
+		}
+	}
+	if cfg.ReadTimeout > 0 && cfg.HeartbeatPeriod >= cfg.ReadTimeout {
+		return nil, fmt.Errorf("the heartbeat period (%s) must be shorter than the read timeout (%s), or idle replication streams time out", cfg.HeartbeatPeriod, cfg.ReadTimeout)
+	}
+	cfg.Throttle = int64(*f.throttle)
+	// Certificates are verified against the host connected to, which --host may change
+	if cfg.TLS != nil && cfg.TLS.ServerName != "" {
+		*cfg = cfg.withHost(cfg.Host, cfg.Port)
 	}
 
-	// Parse the timestamp
-	targetTime, err := time.Parse("2006-01-02 15:04:05", cfg.Timestamp)
+	compress, err := parseCompressionAlgorithms(cfg.CompressionAlgorithms)
 	if err != nil {
-		log.Fatalf("Invalid timestamp format: %v", err)
+		return nil, err
+	}
+	if *f.authPlugin != "" {
+		cfg.AuthPlugin = *f.authPlugin
+	}
+	if err := checkAuthPlugin(cfg.AuthPlugin, cfg.TLS != nil); err != nil {
+		return nil, err
+	}
+	if *f.flavor != "" {
+		cfg.Flavor = *f.flavor
+	}
+	if cfg.Flavor != "" && cfg.Flavor != mysql.MySQLFlavor && cfg.Flavor != mysql.MariaDBFlavor {
+		return nil, fmt.Errorf("invalid flavor %q: use mysql or mariadb", cfg.Flavor)
 	}
+	cfg.settings.Connection = binlog.ConnectionOptions{Compress: compress, AuthPlugin: cfg.AuthPlugin, Attributes: connectionAttributes()}
+	return cfg, nil
+}
 
-	// Configure MySQL connection
-	syncerCfg := replication.BinlogSyncerConfig{
-		ServerID: 100,
-		Flavor:   "mysql",
-		Host:     cfg.Host,
-		Port:     uint16(cfg.Port),
-		User:     cfg.User,
-		Password: cfg.Password,
+// targetServers returns the servers given as HOST:PORT, which share the configured
+// credentials and settings, or the configured host when there are none
+func (c *config) targetServers(targets []string) ([]binlog.Server, error) {
+	if len(targets) == 0 {
+		return []binlog.Server{c.server()}, nil
 	}
+	servers := make([]binlog.Server, 0, len(targets))
+	for _, target := range targets {
+		host, port, err := net.SplitHostPort(target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target %q: %w", target, err)
+		}
+		targetCfg := *c
+		targetCfg.Host = host
+		if targetCfg.Port, err = strconv.Atoi(port); err != nil {
+			return nil, fmt.Errorf("invalid port in target %q: %w", target, err)
+		}
+		servers = append(servers, targetCfg.server())
+	}
+	return servers, nil
+}
+
+// originFilter returns the events searches are restricted to
+func (c *config) originFilter() binlog.OriginFilter {
+	return binlog.OriginFilter{ServerID: c.OriginServerID, Ignore: c.IgnoreServerIDs}
+}
 
-	syncer := replication.NewBinlogSyncer(syncerCfg)
-	defer syncer.Close()
+// server returns the configured MySQL server, read with the configured settings until
+// the program is interrupted
+func (c *config) server() binlog.Server {
+	return binlog.Server{Config: c.syncerConfig(), Settings: &c.settings, Context: interruptCtx}
+}
 
-	// Get list of binlog files
-	binlogFiles, err := binlog.GetBinlogFiles(syncerCfg)
-	if err != nil {
-		log.Fatalf("Failed to get binlog files: %v", err)
+// finder returns a Finder reading the binlogs of the configured server like server
+func (c *config) finder() *binlog.Finder {
+	return &binlog.Finder{Config: c.syncerConfig(), Settings: &c.settings, Context: interruptCtx}
+}
+
+// syncerConfig builds the replication config used to connect to MySQL
+func (c *config) syncerConfig() replication.BinlogSyncerConfig {
+	syncerCfg := replication.BinlogSyncerConfig{
+		ServerID:    100,
+		Flavor:      c.Flavor,
+		Host:        c.Host,
+		Port:        uint16(c.Port),
+		User:        c.User,
+		Password:    c.Password,
+		Dialer:      dialer(c.ConnectTimeout, int(c.RecvBufferSize)),
+		ReadTimeout: c.ReadTimeout,
+		TLSConfig:   c.TLS,
+		// Probes close their streams early, so never let the server wait for them to
+		// acknowledge transactions as a semi-synchronous replica
+		SemiSyncEnabled: false,
 	}
+	// A heartbeat tells a scan it has reached the end of the newest binlog, and keeps an
+	// idle stream, e.g. waiting there, from tripping the read timeout
+	switch {
+	case c.HeartbeatPeriod > 0:
+		syncerCfg.HeartbeatPeriod = c.HeartbeatPeriod
+	case c.ReadTimeout > 0:
+		syncerCfg.HeartbeatPeriod = min(binlog.DefaultHeartbeatPeriod, c.ReadTimeout/2)
+	default:
+		syncerCfg.HeartbeatPeriod = binlog.DefaultHeartbeatPeriod
+	}
+	// Events are buffered as fast as the server sends them, so keep the buffer small for
+	// a throttled reader to slow down the network transfer rather than just the parsing
+	if c.Throttle > 0 {
+		syncerCfg.EventCacheCount = 16
+	}
+	return syncerCfg
+}
 
-	if len(binlogFiles) == 0 {
-		log.Fatal("No binlog files found")
+// dialer returns the dial function of the SQL and replication connections. The receive
+// buffer is set here rather than with BinlogSyncerConfig.RecvBufferSize, which the
+// replication client skips on TLS connections and the SQL driver lacks.
+func dialer(timeout time.Duration, recvBufferSize int) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	d := &net.Dialer{Timeout: timeout}
+	if recvBufferSize <= 0 {
+		return d.DialContext
 	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := d.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		if tcp, ok := conn.(*net.TCPConn); ok {
+			if err := tcp.SetReadBuffer(recvBufferSize); err != nil {
+				slog.Warn("Could not set the receive buffer size", "size", recvBufferSize, "error", err)
+			}
+		}
+		return conn, nil
+	}
+}
 
-	// Binary search for the binlog file
-	binlogFile, exactMatch := binlog.BinarySearchBinlogs(syncerCfg, binlogFiles, targetTime)
+func main() {
+	// The daemons stop on signals themselves, letting the requests in flight finish
+	if len(os.Args) < 2 || (os.Args[1] != "serve" && os.Args[1] != "exporter") {
+		trapInterrupts()
+	}
 
-	fmt.Printf("Target time: %s\n", targetTime.Format("2006-01-02 15:04:05"))
+	// Dispatch subcommands; anything else is treated as a timestamp search
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "find":
+			runFind(os.Args[2:])
+			return
+		case "list":
+			runList(os.Args[2:])
+			return
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		case "exporter":
+			runExporter(os.Args[2:])
+			return
+		case "check":
+			runCheck(os.Args[2:])
+			return
+		case "tui":
+			runTUI(os.Args[2:])
+			return
+		case "doctor":
+			runDoctor(os.Args[2:])
+			return
+		case "fleet":
+			runFleet(os.Args[2:])
+			return
+		case "warm-cache":
+			runWarmCache(os.Args[2:])
+			return
+		case "info":
+			runInfo(os.Args[2:])
+			return
+		case "range":
+			runRange(os.Args[2:])
+			return
+		case "version", "--version", "-version":
+			runVersion(os.Args[2:])
+			return
+		}
+	}
 
-	if exactMatch {
-		fmt.Printf("Found exact match in binlog file: %s\n", binlogFile)
-		os.Exit(0)
-	} else if binlogFile != "" {
-		fmt.Printf("Closest binlog file containing or preceding the timestamp: %s\n", binlogFile)
+	// Display help when no arguments are provided
+	if len(os.Args) == 1 {
+		printHelp()
 		os.Exit(0)
-	} else {
-		fmt.Println("No binlog containing the target timestamp was found")
-		os.Exit(1)
 	}
+
+	runFind(os.Args[1:])
 }
 
 // getDefaultConfigPath returns the path to the default config file in the user's home directory
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoadPrecedence checks that flags override the secrets directory, which overrides
+// the config file
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.ini")
+	require.NoError(t, os.WriteFile(configFile, []byte("[mysql]\nhost = db1\nuser = app\ncompression_algorithms = zstd\n"), 0o600))
+	secrets := filepath.Join(dir, "secrets")
+	require.NoError(t, os.Mkdir(secrets, 0o700))
+	for key, value := range map[string]string{"user": "repl", "password": "secret", "compression-algorithms": "zlib,zstd"} {
+		require.NoError(t, os.WriteFile(filepath.Join(secrets, key), []byte(value+"\n"), 0o600))
+	}
+
+	tests := []struct {
+		name        string
+		args        []string
+		user        string
+		compression string
+	}{
+		{"Secrets over the config file", nil, "repl", "zlib,zstd"},
+		{"--compress over the secrets", []string{"--compress"}, "repl", "zlib"},
+		{"--compression-algorithms over the secrets", []string{"--compression-algorithms=uncompressed"}, "repl", "uncompressed"},
+		{"--user over the secrets", []string{"--user=admin"}, "admin", "zlib,zstd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			common := registerCommonFlags(fs)
+			require.NoError(t, fs.Parse(append([]string{"--config=" + configFile, "--secrets-dir=" + secrets}, tt.args...)))
+			cfg, err := common.load()
+			require.NoError(t, err)
+			assert.Equal(t, "db1", cfg.Host)
+			assert.Equal(t, tt.user, cfg.User)
+			assert.Equal(t, "secret", cfg.Password)
+			assert.Equal(t, tt.compression, cfg.CompressionAlgorithms)
+		})
+	}
+}
+
+func TestHideFlags(t *testing.T) {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.String("grpc-listen", ":50051", "Address for the gRPC server to listen on")
+	rate := fs.Float64("chaos", 0, "Soak test")
+	hideFlags(fs, "chaos")
+	var out strings.Builder
+	fs.SetOutput(&out)
+
+	assert.ErrorIs(t, fs.Parse([]string{"-h"}), flag.ErrHelp)
+	assert.Contains(t, out.String(), "-grpc-listen")
+	assert.Contains(t, out.String(), `(default ":50051")`)
+	assert.NotContains(t, out.String(), "chaos")
+
+	require.NoError(t, fs.Parse([]string{"--chaos=0.5"}))
+	assert.Equal(t, 0.5, *rate)
+}
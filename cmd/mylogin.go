@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-ini/ini"
+)
+
+// Layout of the login file written by mysql_config_editor: 4 unused bytes and a 20-byte
+// key, followed by the lines of an option file, each encrypted on its own with AES-128 in
+// ECB mode and prefixed with its length as a little-endian uint32
+const (
+	loginKeyOffset = 4
+	loginKeySize   = 20
+)
+
+// loginFilePath returns the path of the login file, ~/.mylogin.cnf unless
+// MYSQL_TEST_LOGIN_FILE names another, as for the mysql client
+func loginFilePath() (string, error) {
+	if path := os.Getenv("MYSQL_TEST_LOGIN_FILE"); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the login file: %w", err)
+	}
+	return filepath.Join(home, ".mylogin.cnf"), nil
+}
+
+// decryptLoginFile returns the option file obfuscated in a login file
+func decryptLoginFile(data []byte) ([]byte, error) {
+	if len(data) < loginKeyOffset+loginKeySize {
+		return nil, errors.New("file too short for a login file")
+	}
+	// The 20-byte key is folded into an AES-128 key
+	var key [16]byte
+	for i, b := range data[loginKeyOffset : loginKeyOffset+loginKeySize] {
+		key[i%len(key)] ^= b
+	}
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	var plain bytes.Buffer
+	for rest := data[loginKeyOffset+loginKeySize:]; len(rest) > 0; {
+		if len(rest) < 4 {
+			return nil, errors.New("truncated login file")
+		}
+		n := int(binary.LittleEndian.Uint32(rest))
+		rest = rest[4:]
+		if n == 0 || n%aes.BlockSize != 0 || n > len(rest) {
+			return nil, errors.New("corrupt login file: invalid line length")
+		}
+		line := make([]byte, n)
+		for i := 0; i < n; i += aes.BlockSize {
+			block.Decrypt(line[i:i+aes.BlockSize], rest[i:i+aes.BlockSize])
+		}
+		rest = rest[n:]
+		// Lines are padded to the block size as in PKCS#7
+		pad := int(line[n-1])
+		if pad == 0 || pad > aes.BlockSize {
+			return nil, errors.New("corrupt login file: invalid padding")
+		}
+		plain.Write(line[:n-pad])
+	}
+	return plain.Bytes(), nil
+}
+
+// applyLoginPath sets the host, port, user and password stored by mysql_config_editor
+// under a login path, on top of those stored in its [client] group, as the mysql client
+// does with --login-path. The mysql client need not be installed.
+func (c *config) applyLoginPath(name string) error {
+	path, err := loginFilePath()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read login file: %w", err)
+	}
+	plain, err := decryptLoginFile(data)
+	if err != nil {
+		return fmt.Errorf("failed to read login file %s: %w", path, err)
+	}
+	file, err := ini.Load(plain)
+	if err != nil {
+		return fmt.Errorf("failed to parse login file %s: %w", path, err)
+	}
+	if !file.HasSection(name) {
+		return fmt.Errorf("login path %q not found in %s", name, path)
+	}
+
+	for _, group := range []string{"client", name} {
+		section := file.Section(group)
+		c.Host = section.Key("host").MustString(c.Host)
+		c.Port = section.Key("port").MustInt(c.Port)
+		c.User = section.Key("user").MustString(c.User)
+		c.Password = section.Key("password").MustString(c.Password)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loginFile obfuscates option file lines as mysql_config_editor does: 4 unused bytes, a
+// 20-byte key folded into an AES-128 key, then each line padded to the block size,
+// encrypted in ECB mode and prefixed with its length as a little-endian uint32
+func loginFile(t *testing.T, lines ...string) []byte {
+	t.Helper()
+	rawKey := []byte("\x01\x23\x45\x67\x89\xab\xcd\xef\xfe\xdc\xba\x98\x76\x54\x32\x10\x0f\x1e\x2d\x3c")
+	var key [16]byte
+	for i, b := range rawKey {
+		key[i%len(key)] ^= b
+	}
+	block, err := aes.NewCipher(key[:])
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	out.Write(make([]byte, loginKeyOffset))
+	out.Write(rawKey)
+	for _, line := range lines {
+		pad := aes.BlockSize - len(line)%aes.BlockSize
+		plain := append([]byte(line), bytes.Repeat([]byte{byte(pad)}, pad)...)
+		encrypted := make([]byte, len(plain))
+		for i := 0; i < len(plain); i += aes.BlockSize {
+			block.Encrypt(encrypted[i:i+aes.BlockSize], plain[i:i+aes.BlockSize])
+		}
+		require.NoError(t, binary.Write(&out, binary.LittleEndian, uint32(len(encrypted))))
+		out.Write(encrypted)
+	}
+	return out.Bytes()
+}
+
+func TestDecryptLoginFile(t *testing.T) {
+	lines := []string{
+		"[client]\n",
+		// A line of a whole block is followed by a block of padding
+		"user = \"admin1\"\n",
+		"[prod]\n",
+		"host = \"db1.example.com\"\n",
+		"port = 3307\n",
+		"password = \"s3cret with spaces\"\n",
+	}
+	require.Len(t, lines[1], aes.BlockSize)
+	data := loginFile(t, lines...)
+
+	plain, err := decryptLoginFile(data)
+	require.NoError(t, err)
+	var want bytes.Buffer
+	for _, line := range lines {
+		want.WriteString(line)
+	}
+	assert.Equal(t, want.String(), string(plain))
+
+	t.Run("Login path", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".mylogin.cnf")
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+		t.Setenv("MYSQL_TEST_LOGIN_FILE", path)
+
+		cfg := &config{Host: "localhost", Port: 3306, User: "root"}
+		require.NoError(t, cfg.applyLoginPath("prod"))
+		assert.Equal(t, "db1.example.com", cfg.Host)
+		assert.Equal(t, 3307, cfg.Port)
+		assert.Equal(t, "admin1", cfg.User, "taken from [client]")
+		assert.Equal(t, "s3cret with spaces", cfg.Password)
+
+		assert.ErrorContains(t, cfg.applyLoginPath("staging"), "not found")
+	})
+
+	t.Run("Corrupt", func(t *testing.T) {
+		_, err := decryptLoginFile(data[:loginKeyOffset+loginKeySize-1])
+		assert.ErrorContains(t, err, "too short")
+		_, err = decryptLoginFile(data[:loginKeyOffset+loginKeySize+2])
+		assert.ErrorContains(t, err, "truncated")
+		_, err = decryptLoginFile(data[:len(data)-1])
+		assert.ErrorContains(t, err, "invalid line length")
+
+		// A length that is not a whole number of blocks
+		odd := bytes.Clone(data)
+		binary.LittleEndian.PutUint32(odd[loginKeyOffset+loginKeySize:], aes.BlockSize+1)
+		_, err = decryptLoginFile(odd)
+		assert.ErrorContains(t, err, "invalid line length")
+	})
+}
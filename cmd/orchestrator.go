@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/orchestrator"
+)
+
+// orchestratorTimeout bounds requests to the Orchestrator API without a --connect-timeout
+const orchestratorTimeout = 10 * time.Second
+
+// resolveCluster replaces the host with the primary of the --cluster, if any, looked up
+// on every run to follow failovers. Commands call it once they know they connect to the
+// server, so that those reading archived binlogs do not depend on Orchestrator.
+func (c *config) resolveCluster() error {
+	if c.Cluster == "" {
+		return nil
+	}
+	primary, err := lookupCluster(c, false)
+	if err != nil {
+		return err
+	}
+	*c = c.withHost(primary.Key.Hostname, primary.Key.Port)
+	return nil
+}
+
+// lookupCluster asks Orchestrator for the instance of the --cluster to search: its
+// primary, or with replica set its least lagging replica whose binlogs are up to date
+func lookupCluster(cfg *config, replica bool) (orchestrator.Instance, error) {
+	base, err := url.Parse(cfg.OrchestratorAPI)
+	if err != nil {
+		return orchestrator.Instance{}, fmt.Errorf("invalid Orchestrator API URL: %w", err)
+	}
+	timeout := cfg.ConnectTimeout
+	if timeout <= 0 {
+		timeout = orchestratorTimeout
+	}
+	client := orchestrator.New(&http.Client{Timeout: timeout}, base)
+	instances, err := client.Cluster(cfg.Cluster)
+	if err != nil {
+		return orchestrator.Instance{}, err
+	}
+	pick := orchestrator.Primary
+	if replica {
+		pick = orchestrator.BestReplica
+	}
+	instance, err := pick(instances)
+	if err != nil {
+		return orchestrator.Instance{}, fmt.Errorf("cluster %q: %w", cfg.Cluster, err)
+	}
+	slog.Info("Picked instance from Orchestrator", "cluster", cfg.Cluster, "host", instance.Key.Hostname, "port", instance.Key.Port, "replica", replica)
+	return instance, nil
+}
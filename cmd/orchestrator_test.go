@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/minuteman3/binlog-find-time/internal/orchestrator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveCluster(t *testing.T) {
+	var lookups int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups++
+		assert.Equal(t, "/api/cluster/alias/main", r.URL.Path)
+		primary := orchestrator.Instance{Key: orchestrator.InstanceKey{Hostname: "db1", Port: 3307}, LogBinEnabled: true, IsLastCheckValid: true}
+		require.NoError(t, json.NewEncoder(w).Encode([]orchestrator.Instance{primary}))
+	}))
+	defer srv.Close()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	common := registerCommonFlags(fs)
+	require.NoError(t, fs.Parse([]string{"--config=" + t.TempDir() + "/none.ini", "--orchestrator-api=" + srv.URL + "/api", "--cluster=main"}))
+	cfg, err := common.load()
+	require.NoError(t, err)
+	assert.Zero(t, lookups, "loading the config looked up the cluster")
+
+	require.NoError(t, cfg.resolveCluster())
+	assert.Equal(t, 1, lookups)
+	assert.Equal(t, "db1", cfg.Host)
+	assert.Equal(t, 3307, cfg.Port)
+
+	cfg.OrchestratorAPI = "http://127.0.0.1:1/api"
+	assert.Error(t, cfg.resolveCluster())
+}
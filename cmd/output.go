@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// outputFlags holds the flags writing results to a file instead of stdout, keeping them
+// apart from the logs and progress on the terminal
+type outputFlags struct {
+	path   *string
+	append *bool
+}
+
+// registerOutputFlags defines the output file flags on the given flag set
+func registerOutputFlags(fs *flag.FlagSet) *outputFlags {
+	return &outputFlags{
+		path:   fs.String("output-file", "", "Write the results to a file instead of stdout, replacing it atomically"),
+		append: fs.Bool("append", false, "Append the results to --output-file instead of replacing it"),
+	}
+}
+
+// open returns the writer for results and a function saving them. Results for a file are
+// buffered until saved, so a failed run leaves the file as it was.
+func (f *outputFlags) open() (io.Writer, func()) {
+	if *f.path == "" {
+		if *f.append {
+			fatalf("--append needs --output-file")
+		}
+		return os.Stdout, func() {}
+	}
+	var buf bytes.Buffer
+	return &buf, func() {
+		var err error
+		if *f.append {
+			err = appendFile(*f.path, buf.Bytes())
+		} else {
+			err = replaceFile(*f.path, buf.Bytes())
+		}
+		if err != nil {
+			fatalf("Failed to write --output-file: %v", err)
+		}
+	}
+}
+
+// appending reports whether results are appended to a file that already has some, so
+// headers and document framing written once per file can be left out
+func (f *outputFlags) appending() bool {
+	if *f.path == "" || !*f.append {
+		return false
+	}
+	info, err := os.Stat(*f.path)
+	return err == nil && info.Size() > 0
+}
+
+// writeJSON writes v as the JSON result. Appended results take one line each, so a file
+// shared by batch jobs can be read as newline-delimited JSON.
+func (f *outputFlags) writeJSON(w io.Writer, v any) {
+	enc := json.NewEncoder(w)
+	if !*f.append {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		fatalf("Failed to write output: %v", err)
+	}
+}
+
+// appendFile appends data to the file at path in a single write, so results of batch jobs
+// sharing the file are not interleaved
+func appendFile(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// replaceFile writes data to a temporary file beside path and renames it over path, so
+// readers see either the old results or all of the new ones
+func replaceFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	// CreateTemp makes the file private, unlike a file written by a shell redirect
+	if err := tmp.Chmod(0o644); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
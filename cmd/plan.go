@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// jsonPlanStep is a planned probe in find --dry-run --output=json
+type jsonPlanStep struct {
+	File      string `json:"file"`
+	Cached    bool   `json:"cached,omitempty"`
+	Estimated bool   `json:"estimated,omitempty"`
+	Bytes     int64  `json:"bytes"`
+}
+
+// jsonPlan is the plan for one timestamp in find --dry-run --output=json
+type jsonPlan struct {
+	Steps []jsonPlanStep `json:"steps"`
+	// Undecided is the number of probes left once the plan can no longer be worked out
+	Undecided int   `json:"undecided,omitempty"`
+	Bytes     int64 `json:"bytes"`
+}
+
+// newJSONPlan returns the --output=json form of a plan
+func newJSONPlan(plan binlog.Plan) jsonPlan {
+	out := jsonPlan{Steps: make([]jsonPlanStep, len(plan.Steps)), Undecided: plan.Undecided, Bytes: plan.Bytes}
+	for i, step := range plan.Steps {
+		out.Steps[i] = jsonPlanStep{File: step.File, Cached: step.Cached, Estimated: step.Estimated, Bytes: step.Bytes}
+	}
+	return out
+}
+
+// printPlan writes the files a search would probe, in order, and the bytes it would read
+func printPlan(w io.Writer, plan binlog.Plan, targetTime time.Time, host string, binlogFiles []string, cached int) {
+	p := newPalette(w)
+	fmt.Fprintf(w, "Plan for %s on %s: %d binlogs, %d with cached ranges\n", p.time(targetTime.Format("2006-01-02 15:04:05.999999")), host, len(binlogFiles), cached)
+	var probes int
+	for i, step := range plan.Steps {
+		var note string
+		switch {
+		case step.Cached:
+			note = "cached range, not read"
+		case step.Bytes == 0:
+			note = "probe"
+		default:
+			note = "probe, reads up to " + formatByteSize(step.Bytes)
+		}
+		if step.Estimated {
+			note += " (range estimated from the files around it)"
+		}
+		if !step.Cached {
+			probes++
+		}
+		fmt.Fprintf(w, "  %d. %s  %s\n", i+1, p.paint(colorBold, step.File), note)
+	}
+	if plan.Undecided > 0 {
+		fmt.Fprintf(w, "%s the search then needs up to %s, depending on what the last one finds\n", p.paint(colorYellow, "Note:"), countProbes(plan.Undecided, "more probe"))
+	}
+	fmt.Fprintf(w, "Estimated read: up to %s in %s; nothing was read from the binlogs\n", formatByteSize(plan.Bytes), countProbes(probes, "probe"))
+}
+
+// countProbes formats a number of probes, e.g. "1 probe" or "3 probes"
+func countProbes(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// progressEvent is a single line of --progress=ndjson output
+type progressEvent struct {
+	Event    string     `json:"event"`
+	Time     time.Time  `json:"time"`
+	Target   string     `json:"target,omitempty"`
+	Files    int        `json:"files,omitempty"`
+	File     string     `json:"file,omitempty"`
+	Start    *time.Time `json:"start,omitempty"`
+	End      *time.Time `json:"end,omitempty"`
+	Decision string     `json:"decision,omitempty"`
+	Error    string     `json:"error,omitempty"`
+	// Truncated names the limit that stopped the probe before the end of the file
+	Truncated string `json:"truncated,omitempty"`
+	// Cached is set when the file's range came from the range cache
+	Cached bool  `json:"cached,omitempty"`
+	Exact  *bool `json:"exact,omitempty"`
+	// Stats is the load the search put on the server, reported when it is done
+	Stats *binlog.StatsSnapshot `json:"stats,omitempty"`
+}
+
+// progressWriter streams search progress as newline-delimited JSON
+type progressWriter struct {
+	enc *json.Encoder
+}
+
+// newProgressWriter creates a writer for the given --progress mode, or nil when progress is disabled
+func newProgressWriter(mode string, w io.Writer) *progressWriter {
+	switch mode {
+	case "":
+		return nil
+	case "ndjson":
+		return &progressWriter{enc: json.NewEncoder(w)}
+	default:
+		fatalf("Unknown progress format %q", mode)
+		return nil
+	}
+}
+
+func (p *progressWriter) emit(ev progressEvent) {
+	if p == nil {
+		return
+	}
+	ev.Time = time.Now().UTC()
+	if err := p.enc.Encode(ev); err != nil {
+		slog.Warn("Could not write progress", "error", err)
+	}
+}
+
+// started reports the beginning of a search
+func (p *progressWriter) started(target time.Time, files int) {
+	p.emit(progressEvent{Event: "start", Target: target.Format("2006-01-02 15:04:05"), Files: files})
+}
+
+// probed reports a single binlog file probed by the search
+func (p *progressWriter) probed(probe binlog.Probe) {
+	ev := progressEvent{Event: "probe", File: probe.File, Decision: probe.Decision, Truncated: probe.Truncated, Cached: probe.Cached}
+	if probe.Err != nil {
+		ev.Error = probe.Err.Error()
+	}
+	if !probe.Start.IsZero() {
+		ev.Start = &probe.Start
+	}
+	if !probe.End.IsZero() {
+		ev.End = &probe.End
+	}
+	p.emit(ev)
+}
+
+// finished reports the outcome of a search and the load it put on the server, with the
+// reason the target is not within the binlogs, if any
+func (p *progressWriter) finished(file string, exact bool, stats binlog.StatsSnapshot, err error) {
+	ev := progressEvent{Event: "done", File: file, Exact: &exact, Stats: &stats}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	p.emit(ev)
+}
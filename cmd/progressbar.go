@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+const (
+	progressBarWidth = 30
+	// progressBarInterval limits how often event counts redraw the bar
+	progressBarInterval = 100 * time.Millisecond
+)
+
+// progressBar draws a single-line search progress bar on a terminal
+type progressBar struct {
+	mu        sync.Mutex
+	w         io.Writer
+	probes    int
+	remaining int
+	file      string
+	events    int
+	drawn     time.Time
+}
+
+// isTerminal reports whether f is attached to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// newProgressBar returns a progress bar drawn on stderr for a search over the given number
+// of files, or nil unless both stdout and stderr are terminals
+func newProgressBar(files int) *progressBar {
+	if !isTerminal(os.Stdout) || !isTerminal(os.Stderr) {
+		return nil
+	}
+	return &progressBar{w: os.Stderr, remaining: bits.Len(uint(files))}
+}
+
+// probed records a completed probe and redraws the bar
+func (b *progressBar) probed(p binlog.Probe) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probes++
+	b.remaining = p.Remaining
+	b.file, b.events = "", 0
+	b.draw()
+}
+
+// event records the number of events scanned in the file currently being probed
+func (b *progressBar) event(file string, events int) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.file, b.events = file, events
+	if time.Since(b.drawn) >= progressBarInterval {
+		b.draw()
+	}
+}
+
+// clear erases the bar so that the result is printed on a clean line
+func (b *progressBar) clear() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	fmt.Fprint(b.w, "\r\033[K")
+}
+
+func (b *progressBar) draw() {
+	total := b.probes + b.remaining
+	filled := progressBarWidth
+	if total > 0 {
+		filled = progressBarWidth * b.probes / total
+	}
+	line := fmt.Sprintf("[%s%s] %d/~%d probes", strings.Repeat("#", filled), strings.Repeat(".", progressBarWidth-filled), b.probes, total)
+	if b.file != "" {
+		line += fmt.Sprintf(" | %s: %d events", b.file, b.events)
+	}
+	fmt.Fprintf(b.w, "\r\033[K%s", line)
+	b.drawn = time.Now()
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ptDSN returns the DSN of the server in the h=,P=,u= form Percona Toolkit tools take.
+// The password is left out: pass it with --ask-pass or an option file instead.
+func ptDSN(cfg *config) (string, error) {
+	// Commas and equals signs separate DSN parts and cannot be escaped
+	for _, v := range []struct{ name, value string }{{"host", cfg.Host}, {"user", cfg.User}} {
+		if strings.ContainsAny(v.value, ",=") {
+			return "", fmt.Errorf("the %s %q cannot be written in a Percona Toolkit DSN, which has no escape for commas and equals signs", v.name, v.value)
+		}
+	}
+	return fmt.Sprintf("h=%s,P=%d,u=%s", cfg.Host, cfg.Port, cfg.User), nil
+}
+
+// printFindPT writes the result as the arguments of Percona Toolkit and mysqlbinlog
+// (--emit=pt), under comments naming the tool each is for. dsn is empty for archived
+// binlogs, which no tool can connect to.
+func printFindPT(w io.Writer, res findResult, dsn string) {
+	fmt.Fprintf(w, "# %s\n", res.Target.Format("2006-01-02 15:04:05.999999"))
+	if dsn != "" {
+		fmt.Fprintln(w, "# pt-table-sync, pt-slave-restart: DSN of the scanned server")
+		fmt.Fprintln(w, dsn)
+	}
+	if res.Position == 0 {
+		fmt.Fprintf(w, "# no position located, closest file %s\n", res.File)
+		return
+	}
+	if res.Until {
+		fmt.Fprintln(w, "# mysqlbinlog: replay up to the target")
+		fmt.Fprintf(w, "--stop-position=%d %s\n", res.Position, res.File)
+	} else {
+		fmt.Fprintln(w, "# mysqlbinlog: replay from the target")
+		fmt.Fprintf(w, "--start-position=%d %s\n", res.Position, res.File)
+	}
+	fmt.Fprintln(w, "# pt-slave-restart: keep the replica running until it reaches the position")
+	fmt.Fprintf(w, "--until-master=%s,%d\n", res.File, res.Position)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintFindPT(t *testing.T) {
+	target := time.Date(2024, 1, 1, 12, 30, 45, 0, time.UTC)
+	tests := []struct {
+		name string
+		res  findResult
+		dsn  string
+		want string
+	}{
+		{
+			name: "Start position",
+			res:  findResult{Target: target, File: "binlog.000042", Position: 1234},
+			dsn:  "h=db1,P=3306,u=repl",
+			want: `# 2024-01-01 12:30:45
+# pt-table-sync, pt-slave-restart: DSN of the scanned server
+h=db1,P=3306,u=repl
+# mysqlbinlog: replay from the target
+--start-position=1234 binlog.000042
+# pt-slave-restart: keep the replica running until it reaches the position
+--until-master=binlog.000042,1234
+`,
+		},
+		{
+			name: "Stop position of an archived file",
+			res:  findResult{Target: target, File: "binlog.000042", Position: 1234, Until: true},
+			want: `# 2024-01-01 12:30:45
+# mysqlbinlog: replay up to the target
+--stop-position=1234 binlog.000042
+# pt-slave-restart: keep the replica running until it reaches the position
+--until-master=binlog.000042,1234
+`,
+		},
+		{
+			name: "No position",
+			res:  findResult{Target: target, File: "binlog.000042"},
+			dsn:  "h=db1,P=3306,u=repl",
+			want: `# 2024-01-01 12:30:45
+# pt-table-sync, pt-slave-restart: DSN of the scanned server
+h=db1,P=3306,u=repl
+# no position located, closest file binlog.000042
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			printFindPT(&out, tt.res, tt.dsn)
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+}
+
+func TestPTDSN(t *testing.T) {
+	dsn, err := ptDSN(&config{Host: "db1.example.com", Port: 3307, User: "repl"})
+	require.NoError(t, err)
+	assert.Equal(t, "h=db1.example.com,P=3307,u=repl", dsn)
+
+	_, err = ptDSN(&config{Host: "db1,h=db2", Port: 3306, User: "repl"})
+	assert.ErrorContains(t, err, "host")
+	_, err = ptDSN(&config{Host: "db1", Port: 3306, User: "repl=admin"})
+	assert.ErrorContains(t, err, "user")
+}
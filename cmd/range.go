@@ -0,0 +1,272 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// rangeBound is a replay bound in range --output=json
+type rangeBound struct {
+	File     string `json:"file"`
+	Position uint32 `json:"position"`
+	Gtid     string `json:"gtid,omitempty"`
+	ServerID uint32 `json:"server_id,omitempty"`
+	Time     string `json:"time,omitempty"`
+}
+
+// rangeReport is the output of range --output=json. Stop is null when no event follows
+// the window in the last file, so replay runs to its end.
+type rangeReport struct {
+	Start *rangeBound `json:"start"`
+	Stop  *rangeBound `json:"stop"`
+	// Files lists the binlogs to replay, from the start file to the stop file
+	Files []string `json:"files"`
+	// Incomplete is set when the window starts before the oldest binlog
+	Incomplete bool `json:"incomplete,omitempty"`
+	// Bytes is the amount of binlog in the window, from the start position to the stop
+	// position, or 0 if a file size is unknown
+	Bytes int64 `json:"bytes,omitempty"`
+}
+
+// runRange implements the range command, reporting the start and stop coordinates of the
+// events between two timestamps, as mysqlbinlog --start-position and --stop-position take
+// them
+func runRange(args []string) {
+	fs := flag.NewFlagSet("range", flag.ExitOnError)
+	common := registerCommonFlags(fs)
+	src := registerSourceFlags(fs)
+	start := fs.String("start", "", "Start of the window, the first event replayed (format: YYYY-MM-DD HH:MM:SS)")
+	end := fs.String("end", "", "End of the window, where replay stops before the first event at or after it (format: YYYY-MM-DD HH:MM:SS)")
+	until := fs.String("until", "", "Inclusive end of the window instead of --end: replay stops after the last event at or before it")
+	align := fs.String("align", "", "Boundary to snap positions to: transaction or event (default: transaction)")
+	timestampSource := fs.String("timestamp-source", "", "Event timestamps to compare: header, immediate-commit or original-commit (default: header)")
+	slack := fs.Duration("slack", 0, "How far out of order event timestamps may be when locating a position, e.g. 5s")
+	sequential := fs.Bool("sequential", false, "Locate positions by reading files from their start instead of bisecting them")
+	output := fs.String("output", "text", "Output format: text, json, or env for eval in shell scripts")
+	outFlags := registerOutputFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		fatalf("Error parsing flags: %v", err)
+	}
+
+	if *output != "text" && *output != "json" && *output != "env" {
+		fatalf("Unknown output format %q", *output)
+	}
+	out, save := outFlags.open()
+	cfg, err := common.load()
+	if err != nil {
+		fatalf("Error loading config: %v", err)
+	}
+	if *align != "" {
+		cfg.Align = *align
+	}
+	if *timestampSource != "" {
+		cfg.TimestampSource = *timestampSource
+	}
+
+	if *start == "" || (*end == "") == (*until == "") {
+		fatalf("--start and one of --end or --until are required")
+	}
+	startTime, err := parseTimestamp(*start)
+	if err != nil {
+		fatalf("Invalid --start: %v", err)
+	}
+	alignment, err := binlog.ParseAlignment(cfg.Align)
+	if err != nil {
+		fatalf("Invalid alignment: %v", err)
+	}
+	// An offset before --end would stop replay short of the window
+	if alignment == binlog.AlignNone {
+		fatalf("range cannot use --align=none, which reports offsets before the timestamps")
+	}
+	source, err := binlog.ParseTimestampSource(cfg.TimestampSource)
+	if err != nil {
+		fatalf("Invalid timestamp source: %v", err)
+	}
+	var endTime time.Time
+	if *until != "" {
+		untilTime, err := parseTimestamp(*until)
+		if err != nil {
+			fatalf("Invalid --until: %v", err)
+		}
+		// Replay stops at the first event after the inclusive end, as for find --until
+		endTime = source.Next(untilTime)
+	} else if endTime, err = parseTimestamp(*end); err != nil {
+		fatalf("Invalid --end: %v", err)
+	}
+	if !endTime.After(startTime) {
+		fatalf("The end of the window must be after --start")
+	}
+
+	archived, _ := src.open()
+	if archived == nil {
+		if err := cfg.resolveCluster(); err != nil {
+			fatalf("Error looking up the cluster: %v", err)
+		}
+	}
+	server := cfg.server()
+	var lister binlog.BinlogLister = server
+	if archived != nil {
+		lister = archived
+	}
+	binlogFiles, sizes := listBinlogs(lister)
+	if len(binlogFiles) == 0 {
+		slog.Error("No binlog files found")
+		os.Exit(exitNotFound)
+	}
+
+	// Both ends are searched with the same Finder, so files probed for the start are not
+	// probed again for the end
+	finder := cfg.finder()
+	finder.Source, finder.Sizes = source, sizes
+	if archived != nil {
+		finder.Streamer, finder.Lister = archived, archived
+	}
+
+	var report rangeReport
+	startFile, exact, err := finder.Search(binlogFiles, startTime)
+	exitIfInterrupted()
+	switch {
+	case errors.Is(err, binlog.ErrTimestampInFuture):
+		fatalf("--start is after the newest binlog event: %v", err)
+	case errors.Is(err, binlog.ErrTimestampBeforeRetention):
+		// Replay can only start at the oldest binlog left
+		slog.Warn("--start is before the oldest binlog, so the window is incomplete", "error", err)
+		report.Incomplete = true
+	case startFile != "" && !exact:
+		// The start falls after the events of the file found, so replay starts with the next
+		if i := slices.Index(binlogFiles, startFile); i+1 < len(binlogFiles) {
+			startFile = binlogFiles[i+1]
+		}
+	}
+	if startFile == "" {
+		slog.Error("No binlog containing the start time was found", "target", startTime.Format("2006-01-02 15:04:05"), "error", err)
+		os.Exit(exitNotFound)
+	}
+	pos, err := locate(finder, archived != nil, *sequential, startFile, startTime, alignment, *slack)
+	if err != nil {
+		fatalServerError(err, "Failed to locate the start position: %v", err)
+	}
+	report.Start = newRangeBound(pos)
+
+	// Without an event at or after the end in the file found, such as when the end is after
+	// the newest event, replay runs to the end of that file
+	stopFile, exact, _ := finder.Search(binlogFiles, endTime)
+	exitIfInterrupted()
+	if stopFile == "" {
+		slog.Error("No binlog containing the end time was found", "target", endTime.Format("2006-01-02 15:04:05"))
+		os.Exit(exitNotFound)
+	}
+	if exact {
+		pos, err := locate(finder, archived != nil, *sequential, stopFile, endTime, alignment, *slack)
+		if err != nil {
+			fatalServerError(err, "Failed to locate the stop position: %v", err)
+		}
+		report.Stop = newRangeBound(pos)
+	}
+	first, last := slices.Index(binlogFiles, report.Start.File), slices.Index(binlogFiles, stopFile)
+	if first > last {
+		slog.Error("No events between the start and end times: they fall in a gap between binlogs", "before", stopFile, "after", report.Start.File)
+		os.Exit(exitNotFound)
+	}
+	report.Files = binlogFiles[first : last+1]
+	from := binlog.Position{File: report.Start.File, Pos: report.Start.Position}
+	to := binlog.Position{File: stopFile}
+	if report.Stop != nil {
+		to.Pos = report.Stop.Position
+	}
+	if report.Bytes, err = binlog.ReplayBytes(binlogFiles, sizes, from, to); err != nil {
+		slog.Warn("Cannot tell the size of the window", "error", err)
+	}
+
+	switch *output {
+	case "json":
+		outFlags.writeJSON(out, report)
+	case "env":
+		printRangeEnv(out, report)
+	default:
+		printRange(out, report, alignment)
+	}
+	save()
+}
+
+// printRangeEnv writes the replay bounds as shell variable assignments, for eval in
+// scripts. The stop variables are empty when replay runs to the end of the last file.
+func printRangeEnv(w io.Writer, report rangeReport) {
+	stop := report.Stop
+	if stop == nil {
+		stop = &rangeBound{}
+	}
+	var stopPos string
+	if stop.Position != 0 {
+		stopPos = strconv.FormatUint(uint64(stop.Position), 10)
+	}
+	var bytes string
+	if report.Bytes != 0 {
+		bytes = strconv.FormatInt(report.Bytes, 10)
+	}
+	vars := []struct{ name, value string }{
+		{"BINLOG_START_FILE", report.Start.File},
+		{"BINLOG_START_POS", strconv.FormatUint(uint64(report.Start.Position), 10)},
+		{"BINLOG_START_GTID", report.Start.Gtid},
+		{"BINLOG_STOP_FILE", stop.File},
+		{"BINLOG_STOP_POS", stopPos},
+		{"BINLOG_STOP_GTID", stop.Gtid},
+		{"BINLOG_FILES", strings.Join(report.Files, " ")},
+		{"BINLOG_BYTES", bytes},
+	}
+	for _, v := range vars {
+		fmt.Fprintf(w, "%s=%s\n", v.name, shellQuote(v.value))
+	}
+}
+
+// newRangeBound returns the replay bound at a located position
+func newRangeBound(pos binlog.Position) *rangeBound {
+	b := &rangeBound{File: pos.File, Position: pos.Pos, Gtid: pos.GTID, ServerID: pos.ServerID}
+	if !pos.Timestamp.IsZero() {
+		b.Time = pos.Timestamp.Format("2006-01-02 15:04:05.999999")
+	}
+	return b
+}
+
+// printRange writes the replay bounds and the mysqlbinlog command replaying them
+func printRange(w io.Writer, report rangeReport, align binlog.Alignment) {
+	p := newPalette(w)
+	printBound := func(label string, b *rangeBound) {
+		fmt.Fprintf(w, "%s (%s-aligned): %s\n", label, align, p.paint(colorBold, fmt.Sprintf("%s:%d", b.File, b.Position)))
+		if b.Gtid != "" {
+			fmt.Fprintf(w, "%s GTID: %s\n", label, b.Gtid)
+		}
+		if b.Time != "" {
+			fmt.Fprintf(w, "%s event time: %s\n", label, p.time(b.Time))
+		}
+	}
+	printBound("Start", report.Start)
+	if report.Stop != nil {
+		printBound("Stop", report.Stop)
+	} else {
+		fmt.Fprintf(w, "Stop: none, replay to the end of %s\n", report.Files[len(report.Files)-1])
+	}
+	if report.Bytes != 0 {
+		fmt.Fprintf(w, "Window size: %s in %d files, to fetch for replay\n", formatByteSize(report.Bytes), len(report.Files))
+	}
+	if report.Incomplete {
+		fmt.Fprintf(w, "%s the start time is before the oldest binlog; earlier events are no longer available\n", p.paint(colorYellow, "Warning:"))
+	}
+
+	// mysqlbinlog applies --start-position to the first file and --stop-position to the last
+	command := fmt.Sprintf("mysqlbinlog --start-position=%d", report.Start.Position)
+	if report.Stop != nil {
+		command += fmt.Sprintf(" --stop-position=%d", report.Stop.Position)
+	}
+	fmt.Fprintf(w, "Replay: %s\n", p.paint(colorBold, command+" "+strings.Join(report.Files, " ")))
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/go-ini/ini"
+)
+
+// ssmPrefix marks an --aws-secret-id naming an SSM Parameter Store parameter rather than
+// a Secrets Manager secret, e.g. ssm:/prod/db1/binlog
+const ssmPrefix = "ssm:"
+
+// rdsSecret holds the fields of a secret in the JSON format RDS and Secrets Manager
+// rotation use, e.g. {"username": "binlog", "password": "...", "host": "...", "port": 3306}
+type rdsSecret struct {
+	Username string          `json:"username"`
+	Password string          `json:"password"`
+	Host     string          `json:"host"`
+	Port     json.RawMessage `json:"port"`
+}
+
+// fetchAWSSecret reads a Secrets Manager secret, by name or ARN, or an SSM parameter
+// named with the ssm: prefix or by ARN, with the default AWS credential chain. SecureString
+// parameters are decrypted. An ARN's region overrides the configured one.
+func fetchAWSSecret(id string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	service := "secretsmanager"
+	if parsed, err := arn.Parse(id); err == nil {
+		service, cfg.Region = parsed.Service, parsed.Region
+	} else if name, ok := strings.CutPrefix(id, ssmPrefix); ok {
+		service, id = "ssm", name
+	}
+
+	switch service {
+	case "secretsmanager":
+		out, err := secretsmanager.NewFromConfig(cfg).GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(id)})
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret %s: %w", id, err)
+		}
+		if out.SecretString == nil {
+			return "", fmt.Errorf("secret %s holds binary data, not a password or JSON credentials", id)
+		}
+		return *out.SecretString, nil
+	case "ssm":
+		out, err := ssm.NewFromConfig(cfg).GetParameter(ctx, &ssm.GetParameterInput{Name: aws.String(id), WithDecryption: aws.Bool(true)})
+		if err != nil {
+			return "", fmt.Errorf("failed to read parameter %s: %w", id, err)
+		}
+		return aws.ToString(out.Parameter.Value), nil
+	}
+	return "", fmt.Errorf("%s is neither a Secrets Manager secret nor an SSM parameter", id)
+}
+
+// applySecret sets the credentials from a secret's value: a JSON object in the RDS format,
+// whose username, password, host and port are used when present, or else the password
+// alone
+func (c *config) applySecret(value string) error {
+	trimmed := strings.TrimSpace(value)
+	if !strings.HasPrefix(trimmed, "{") {
+		c.Password = value
+		return nil
+	}
+	var secret rdsSecret
+	if err := json.Unmarshal([]byte(trimmed), &secret); err != nil {
+		return fmt.Errorf("invalid JSON credentials in secret: %w", err)
+	}
+	if secret.Username != "" {
+		c.User = secret.Username
+	}
+	if secret.Password != "" {
+		c.Password = secret.Password
+	}
+	if secret.Host != "" {
+		c.Host = secret.Host
+	}
+	// RDS writes the port as a number, but hand-written secrets often quote it
+	if port := strings.Trim(string(secret.Port), `"`); port != "" && port != "null" {
+		n, err := strconv.Atoi(port)
+		if err != nil {
+			return fmt.Errorf("invalid port %s in secret", secret.Port)
+		}
+		c.Port = n
+	}
+	return nil
+}
+
+// readSecretFile reads a secret kept in a file, such as a key of a Kubernetes Secret
+// mounted as a volume, without the trailing newline editors and echo leave
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// applySecretsDir sets the connection settings from a directory holding one file per key
+// of the [mysql] section, named like the key, as Kubernetes mounts a Secret: a file named
+// password holds the password. Hidden entries, such as the ..data link Kubernetes keeps
+// up to date, are skipped, and files named after no setting are ignored with a warning.
+func (c *config) applySecretsDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read secrets directory: %w", err)
+	}
+	section := ini.Empty().Section("mysql")
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") || entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		// Secret keys are often written with dashes, so accept them in place of underscores
+		key := strings.ReplaceAll(entry.Name(), "-", "_")
+		if !slices.Contains(mysqlKeys, key) {
+			slog.Warn("Ignoring file in the secrets directory not named after a setting", "file", path)
+			continue
+		}
+		value, err := readSecretFile(path)
+		if err != nil {
+			return err
+		}
+		section.Key(key).SetValue(value)
+	}
+	return c.applyMySQLSection(section)
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/minuteman3/binlog-find-time/api/binlogfindpb"
+	"github.com/minuteman3/binlog-find-time/internal/chaos"
+	"github.com/minuteman3/binlog-find-time/internal/grpcserver"
+	"github.com/minuteman3/binlog-find-time/internal/timeindex"
+)
+
+// chaosRefresh is the refresh interval of --chaos without --refresh
+const chaosRefresh = 10 * time.Second
+
+// runServe implements the serve command, answering lookups over gRPC and, optionally, REST
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	common := registerCommonFlags(fs)
+	grpcListen := fs.String("grpc-listen", ":50051", "Address for the gRPC server to listen on")
+	httpListen := fs.String("http-listen", "", "Address for the REST API to listen on (default: none)")
+	refresh := fs.Duration("refresh", 0, "Keep a time index of the binlogs, refreshed at this interval, and answer lookups from it (default: probe on every lookup)")
+	var targets stringList
+	fs.Var(&targets, "target", "Server to index as HOST:PORT, may be repeated; needs --refresh (default: the configured host)")
+	tlsCert := fs.String("tls-cert", "", "Serve TLS with the certificate in this PEM file")
+	tlsKey := fs.String("tls-key", "", "Private key of --tls-cert, in a PEM file")
+	clientCA := fs.String("tls-client-ca", "", "Require client certificates signed by the CAs in this PEM file (mutual TLS)")
+	tokenFile := fs.String("auth-token-file", "", "Require one of the bearer tokens in this file, one per line")
+	usersFile := fs.String("basic-auth-file", "", "Require basic authentication as one of the USER:PASSWORD lines in this file")
+	// Left out of the help: a soak test of the daemon, not something to run in production
+	chaosRate := fs.Float64("chaos", 0, "Serve generated binlogs whose listings and streams fail, stall and are purged with this probability, for soak testing")
+	hideFlags(fs, "chaos")
+	if err := fs.Parse(args); err != nil {
+		fatalf("Error parsing flags: %v", err)
+	}
+	if *chaosRate < 0 || *chaosRate > 1 {
+		fatalf("--chaos must be between 0 and 1")
+	}
+
+	// load reads the config file, which the flags override, again on every reload
+	load := func() (*config, error) {
+		cfg, err := common.load()
+		if err != nil {
+			return nil, err
+		}
+		if err := cfg.resolveCluster(); err != nil {
+			return nil, err
+		}
+		// The flags override the [serve] section of the config file
+		if *tlsCert != "" {
+			cfg.ServeTLSCert = *tlsCert
+		}
+		if *tlsKey != "" {
+			cfg.ServeTLSKey = *tlsKey
+		}
+		if *clientCA != "" {
+			cfg.ServeClientCA = *clientCA
+		}
+		if *tokenFile != "" {
+			cfg.ServeTokenFile = *tokenFile
+		}
+		if *usersFile != "" {
+			cfg.ServeUsersFile = *usersFile
+		}
+		return cfg, nil
+	}
+	cfg, err := load()
+	if err != nil {
+		fatalf("Error loading config: %v", err)
+	}
+	tlsConfig, creds, err := serveSecurity(cfg)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	// The generated binlogs rotate with every refresh, so that each one has something to do
+	var source *chaos.Source
+	if *chaosRate > 0 {
+		if len(targets) > 0 {
+			fatalf("--chaos serves generated binlogs and cannot be combined with --target")
+		}
+		if *refresh == 0 {
+			*refresh = chaosRefresh
+		}
+		seed := time.Now().UnixNano()
+		source = chaos.New(*chaosRate, seed)
+		go source.Run(context.Background(), *refresh)
+		slog.Warn("Serving generated binlogs failing at random instead of the server", "rate", *chaosRate, "seed", seed, "server", chaosTarget(source).Name())
+	}
+	// serveTargets returns the servers to index, read from the config on every reload
+	serveTargets := func(cfg *config) ([]timeindex.Target, error) {
+		if source != nil {
+			target := chaosTarget(source)
+			target.Settings = &cfg.settings
+			return []timeindex.Target{target}, nil
+		}
+		return cfg.indexTargets(targets)
+	}
+
+	var service *grpcserver.Server
+	var index *timeindex.Index
+	if *refresh > 0 {
+		indexTargets, err := serveTargets(cfg)
+		if err != nil {
+			fatalf("%v", err)
+		}
+		index = timeindex.New(indexTargets)
+		go index.Run(context.Background(), *refresh)
+		service = grpcserver.NewIndexed(index)
+	} else {
+		if len(targets) > 0 {
+			fatalf("--target needs --refresh: without a time index only the configured host is served")
+		}
+		service = grpcserver.New(cfg.server())
+	}
+
+	lis, err := net.Listen("tcp", *grpcListen)
+	if err != nil {
+		fatalf("Failed to listen on %s: %v", *grpcListen, err)
+	}
+
+	var httpServer *http.Server
+	if *httpListen != "" {
+		httpLis, err := net.Listen("tcp", *httpListen)
+		if err != nil {
+			fatalf("Failed to listen on %s: %v", *httpListen, err)
+		}
+		handler := service.Handler()
+		if creds != nil {
+			handler = creds.Middleware(handler)
+		}
+		if tlsConfig != nil {
+			httpLis = tls.NewListener(httpLis, tlsConfig.Config())
+		}
+		httpServer = &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+		slog.Info("REST API listening", "address", httpLis.Addr().String(), "tls", tlsConfig != nil, "auth", creds != nil)
+		go func() {
+			if err := httpServer.Serve(httpLis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fatalf("REST API failed: %v", err)
+			}
+		}()
+	}
+
+	var opts []grpc.ServerOption
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig.Config("h2"))))
+	}
+	if creds != nil {
+		opts = append(opts, grpc.UnaryInterceptor(creds.UnaryInterceptor()), grpc.StreamInterceptor(creds.StreamInterceptor()))
+	}
+	server := grpc.NewServer(opts...)
+	binlogfindpb.RegisterBinlogFindServer(server, service)
+	// Health checks serve Kubernetes probes, and reflection lets grpcurl list the methods
+	healthpb.RegisterHealthServer(server, service.Health(context.Background()))
+	reflection.Register(server)
+
+	// A reload picks up new credentials, certificates, client CAs, MySQL settings and
+	// servers to index; the listeners and whether they use TLS or authentication stay as
+	// they started
+	reload := func() error {
+		cfg, err := load()
+		if err != nil {
+			return err
+		}
+		newTLSConfig, newCreds, err := serveSecurity(cfg)
+		if err != nil {
+			return err
+		}
+		if (newTLSConfig == nil) != (tlsConfig == nil) || (newCreds == nil) != (creds == nil) {
+			return fmt.Errorf("turning TLS or authentication on or off needs a restart")
+		}
+		var indexTargets []timeindex.Target
+		if index != nil {
+			if indexTargets, err = serveTargets(cfg); err != nil {
+				return err
+			}
+		}
+
+		if tlsConfig != nil {
+			tlsConfig.Replace(newTLSConfig)
+		}
+		if creds != nil {
+			creds.Replace(newCreds)
+		}
+		if index != nil {
+			index.SetTargets(indexTargets)
+		} else {
+			service.SetTarget(cfg.server())
+		}
+		return nil
+	}
+	stop := func() {
+		if httpServer != nil {
+			_ = httpServer.Shutdown(context.Background())
+		}
+		server.GracefulStop()
+	}
+
+	slog.Info("gRPC server listening", "address", lis.Addr().String(), "tls", tlsConfig != nil, "auth", creds != nil)
+	go superviseDaemon("Serving on "+lis.Addr().String(), reload, stop)
+	if err := server.Serve(lis); err != nil {
+		fatalf("gRPC server failed: %v", err)
+	}
+}
+
+// indexTargets returns the time index targets of the servers, or of the configured host
+func (c *config) indexTargets(targets []string) ([]timeindex.Target, error) {
+	servers, err := c.targetServers(targets)
+	if err != nil {
+		return nil, err
+	}
+	indexTargets := make([]timeindex.Target, len(servers))
+	for i, server := range servers {
+		indexTargets[i] = timeindex.Target{Config: server.Config, Settings: server.Settings, Context: server.Context}
+	}
+	return indexTargets, nil
+}
+
+// chaosTarget is the time index target reading the binlogs of a chaos source
+func chaosTarget(source *chaos.Source) timeindex.Target {
+	return timeindex.Target{Config: replication.BinlogSyncerConfig{Host: "chaos", Port: 3306}, Streamer: source, Lister: source}
+}
+
+// serveSecurity builds the TLS config and credentials of the listeners from the config,
+// either of which is nil when not configured. Binlog metadata is worth protecting, so
+// serving without credentials, or sending them without TLS, is warned about.
+func serveSecurity(cfg *config) (*grpcserver.TLS, *grpcserver.Credentials, error) {
+	var tlsConfig *grpcserver.TLS
+	switch {
+	case cfg.ServeTLSCert != "" && cfg.ServeTLSKey != "":
+		var err error
+		if tlsConfig, err = grpcserver.ServerTLS(cfg.ServeTLSCert, cfg.ServeTLSKey, cfg.ServeClientCA); err != nil {
+			return nil, nil, err
+		}
+	case cfg.ServeTLSCert != "" || cfg.ServeTLSKey != "":
+		return nil, nil, fmt.Errorf("--tls-cert and --tls-key must be given together")
+	case cfg.ServeClientCA != "":
+		return nil, nil, fmt.Errorf("--tls-client-ca needs --tls-cert and --tls-key")
+	}
+
+	var creds *grpcserver.Credentials
+	if cfg.ServeTokenFile != "" || cfg.ServeUsersFile != "" {
+		var err error
+		if creds, err = grpcserver.ReadCredentials(cfg.ServeTokenFile, cfg.ServeUsersFile); err != nil {
+			return nil, nil, err
+		}
+		if tlsConfig == nil {
+			slog.Warn("Credentials are sent in clear text without --tls-cert")
+		}
+	} else if cfg.ServeClientCA == "" {
+		slog.Warn("Serving without authentication: anyone reaching the port can read binlog metadata; set --auth-token-file, --basic-auth-file or --tls-client-ca")
+	}
+	return tlsConfig, creds, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"path/filepath"
+
+	"github.com/minuteman3/binlog-find-time/internal/archive"
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// sourceFlags holds the flags choosing archived binlogs to search instead of the server
+type sourceFlags struct {
+	url       *string
+	glob      *string
+	indexFile *string
+	keyring   *string
+}
+
+// registerSourceFlags defines the archive flags on the given flag set
+func registerSourceFlags(fs *flag.FlagSet) *sourceFlags {
+	return &sourceFlags{
+		url:       fs.String("source", "", "Search archived binlogs instead of the server: a directory, s3://bucket/prefix, gcs://bucket/prefix, azblob://container/prefix, sftp://user@host/path or the https:// URL of a file index"),
+		glob:      fs.String("binlog-glob", "", "Search archived binlog files matching a glob pattern, where ** matches any number of directories, e.g. 'backups/**/mysql-bin.*'"),
+		indexFile: fs.String("index-file", "", "Binlog index file listing the binlogs of a directory --source in order (default: the directory's index file); implies --source=its directory"),
+		keyring:   fs.String("keyring", "", "Keyring file, or file of decrypted keys, for archived binlogs written with binlog_encryption=ON"),
+	}
+}
+
+// archived reports whether an archive was chosen instead of the server
+func (f *sourceFlags) archived() bool {
+	return *f.url != "" || *f.glob != "" || *f.indexFile != ""
+}
+
+// open returns the chosen archive and its name, reported in place of the server's, or
+// nil when binlogs are read from the server
+func (f *sourceFlags) open() (archive.Archive, string) {
+	if *f.glob != "" && (*f.url != "" || *f.indexFile != "") {
+		fatalf("--binlog-glob cannot be combined with --source or --index-file")
+	}
+	if *f.indexFile != "" && *f.url == "" {
+		*f.url = filepath.Dir(*f.indexFile)
+	}
+	if *f.keyring != "" && !f.archived() {
+		fatalf("--keyring decrypts archived binlogs and needs --source or --binlog-glob; the server decrypts binlogs it streams")
+	}
+
+	var archived archive.Archive
+	var name string
+	switch {
+	case *f.glob != "":
+		archived, name = archive.NewGlob(*f.glob), *f.glob
+	case *f.url != "":
+		var err error
+		if archived, err = archive.Open(interruptCtx, *f.url); err != nil {
+			fatalf("Failed to open source: %v", err)
+		}
+		if *f.indexFile != "" {
+			dir, ok := archived.(*archive.Dir)
+			if !ok {
+				fatalf("--index-file needs a directory --source")
+			}
+			dir.IndexFile = *f.indexFile
+		}
+		name = *f.url
+	default:
+		return nil, ""
+	}
+
+	if *f.keyring != "" {
+		keys, err := binlog.LoadKeyring(*f.keyring)
+		if err != nil {
+			fatalf("Failed to load keyring: %v", err)
+		}
+		archived = archive.Decrypt(archived, keys)
+	}
+	return archived, name
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+const tuiHelp = "[yellow]Enter[-] events  [yellow]c[-] copy coordinates  [yellow]Esc[-] back  [yellow]q[-] quit"
+
+// runTUI implements the tui command, an interactive browser for binlog files and their events
+func runTUI(args []string) {
+	fs := flag.NewFlagSet("tui", flag.ExitOnError)
+	common := registerCommonFlags(fs)
+	previewEvents := fs.Int("preview-events", 200, "Maximum number of events shown when drilling into a file")
+	if err := fs.Parse(args); err != nil {
+		fatalf("Error parsing flags: %v", err)
+	}
+
+	cfg, err := common.load()
+	if err != nil {
+		fatalf("Error loading config: %v", err)
+	}
+	if err := cfg.resolveCluster(); err != nil {
+		fatalf("Error looking up the cluster: %v", err)
+	}
+	finder := cfg.finder()
+	files, err := finder.Binlogs()
+	if err != nil {
+		fatalServerError(err, "Failed to get binlog files: %v", err)
+	}
+	// Probes stop at the listed sizes, so the active file is read without waiting
+	finder.Sizes = make(map[string]int64, len(files))
+	for _, f := range files {
+		finder.Sizes[f.Name] = f.Size
+	}
+
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		fatalf("Failed to open terminal: %v", err)
+	}
+
+	// Logs written to stderr would corrupt the screen, so errors are shown in the status line instead
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	b := &browser{
+		app:    tview.NewApplication().SetScreen(screen),
+		screen: screen,
+		finder: finder,
+		files:  files,
+		limit:  *previewEvents,
+	}
+	if err := b.run(); err != nil {
+		fatalf("TUI failed: %v", err)
+	}
+}
+
+// browser holds the state of the tui command
+type browser struct {
+	app    *tview.Application
+	screen tcell.Screen
+	finder *binlog.Finder
+	files  []binlog.FileInfo
+	limit  int
+
+	pages  *tview.Pages
+	list   *tview.List
+	events *tview.Table
+	status *tview.TextView
+
+	// preview holds the events currently shown in the events table
+	preview []binlog.EventSummary
+}
+
+func (b *browser) run() error {
+	b.list = tview.NewList().SetSelectedFunc(func(i int, _, _ string, _ rune) { b.openFile(i) })
+	b.list.SetBorder(true).SetTitle(" Binlogs ")
+	for _, f := range b.files {
+		label := fmt.Sprintf("%s  %s", f.Name, formatSize(f.Size))
+		if f.Encrypted != nil && *f.Encrypted {
+			label += "  (encrypted)"
+		}
+		b.list.AddItem(tview.Escape(label), "probing time range...", 0, nil)
+	}
+
+	b.events = tview.NewTable().SetSelectable(true, false).SetFixed(1, 0)
+	b.events.SetBorder(true)
+
+	b.status = tview.NewTextView().SetDynamicColors(true).SetText(tuiHelp)
+
+	b.pages = tview.NewPages().
+		AddPage("files", b.list, true, true).
+		AddPage("events", b.events, true, false)
+
+	root := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(b.pages, 0, 1, true).
+		AddItem(b.status, 1, 0, false)
+
+	b.app.SetRoot(root, true).SetInputCapture(b.handleKey)
+
+	// Probing every file takes a while on large servers, so fill in time ranges as they arrive
+	go b.probeRanges()
+
+	return b.app.Run()
+}
+
+// handleKey implements the key bindings shared by both pages
+func (b *browser) handleKey(ev *tcell.EventKey) *tcell.EventKey {
+	switch {
+	case ev.Key() == tcell.KeyEscape:
+		if name, _ := b.pages.GetFrontPage(); name == "events" {
+			b.pages.SwitchToPage("files")
+			b.app.SetFocus(b.list)
+			return nil
+		}
+	case ev.Rune() == 'q':
+		b.app.Stop()
+		return nil
+	case ev.Rune() == 'c':
+		b.copyCoordinates()
+		return nil
+	}
+	return ev
+}
+
+// probeRanges fetches the time range of every file and updates the list as each completes
+func (b *browser) probeRanges() {
+	for i, f := range b.files {
+		secondary := "time range unavailable"
+		r, err := b.finder.TimeRange(f.Name)
+		if err == nil {
+			secondary = fmt.Sprintf("%s - %s", r.Start.Format("2006-01-02 15:04:05"), r.End.Format("2006-01-02 15:04:05"))
+			if r.Truncated != "" {
+				secondary += fmt.Sprintf(" or later (probe stopped at %s)", truncationFlag(r.Truncated))
+			}
+		}
+		b.app.QueueUpdateDraw(func() {
+			label, _ := b.list.GetItemText(i)
+			b.list.SetItemText(i, label, secondary)
+		})
+	}
+}
+
+// openFile loads an event preview for the file and switches to the events page
+func (b *browser) openFile(i int) {
+	name := b.files[i].Name
+	b.status.SetText(fmt.Sprintf("Reading events from %s...", name))
+	go func() {
+		events, err := b.finder.PreviewEvents(name, b.limit)
+		b.app.QueueUpdateDraw(func() {
+			if err != nil && len(events) == 0 {
+				b.status.SetText(fmt.Sprintf("[red]Failed to read %s: %v[-]", name, err))
+				return
+			}
+			b.showEvents(name, events)
+			b.status.SetText(tuiHelp)
+		})
+	}()
+}
+
+// showEvents fills the events table and brings it to the front
+func (b *browser) showEvents(name string, events []binlog.EventSummary) {
+	b.preview = events
+	b.events.Clear()
+	b.events.SetTitle(fmt.Sprintf(" %s (first %d events) ", name, len(events)))
+	for col, header := range []string{"Pos", "Time", "Type", "Server ID", "Size", "Info"} {
+		b.events.SetCell(0, col, tview.NewTableCell(header).SetTextColor(tcell.ColorYellow).SetSelectable(false))
+	}
+	for row, ev := range events {
+		typ := ev.Type
+		if ev.InPayload {
+			// Events decoded from a compressed payload are shown under it
+			typ = "  " + typ
+		}
+		cells := []string{
+			fmt.Sprint(ev.Pos),
+			ev.Timestamp.Format("2006-01-02 15:04:05"),
+			typ,
+			fmt.Sprint(ev.ServerID),
+			fmt.Sprint(ev.Size),
+			ev.Info,
+		}
+		for col, text := range cells {
+			b.events.SetCell(row+1, col, tview.NewTableCell(tview.Escape(text)))
+		}
+	}
+	b.events.Select(1, 0).ScrollToBeginning()
+	b.pages.SwitchToPage("events")
+	b.app.SetFocus(b.events)
+}
+
+// copyCoordinates copies the selected file, or file:pos on the events page, to the clipboard
+func (b *browser) copyCoordinates() {
+	var coords string
+	if name, _ := b.pages.GetFrontPage(); name == "events" {
+		row, _ := b.events.GetSelection()
+		if row < 1 || row > len(b.preview) {
+			return
+		}
+		ev := b.preview[row-1]
+		coords = fmt.Sprintf("%s:%d", ev.File, ev.Pos)
+	} else {
+		if len(b.files) == 0 {
+			return
+		}
+		coords = b.files[b.list.GetCurrentItem()].Name
+	}
+
+	// Uses the terminal's OSC 52 support, which also works over SSH
+	b.screen.SetClipboard([]byte(coords))
+	b.status.SetText(fmt.Sprintf("Copied %s", coords))
+}
+
+// formatSize renders a byte count with a binary unit suffix
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
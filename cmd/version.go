@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata, injected at build time with
+// -ldflags "-X main.version=v1.2.3 -X main.commit=abc1234 -X main.date=2024-01-01T00:00:00Z".
+// Builds without them fall back to what the Go toolchain records in the binary.
+var (
+	version = ""
+	commit  = ""
+	date    = ""
+)
+
+// goMySQLModule is the replication library whose version is reported, as its behavior
+// varies the most between servers
+const goMySQLModule = "github.com/go-mysql-org/go-mysql"
+
+// versionInfo is the build metadata printed by the version command
+type versionInfo struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit,omitempty"`
+	Date    string `json:"date,omitempty"`
+	GoMySQL string `json:"go_mysql,omitempty"`
+	Go      string `json:"go"`
+	// Platform is the GOOS/GOARCH the binary was built for
+	Platform string `json:"platform"`
+}
+
+// connectionAttributes identifies the connections the tool opens, in
+// performance_schema.session_connect_attrs
+func connectionAttributes() map[string]string {
+	return map[string]string{"program_name": "binlog-find-time", "version": buildVersion().Version, "purpose": "timestamp-probe"}
+}
+
+// buildVersion returns the metadata of the running binary, preferring values injected
+// with -ldflags over those recorded by the toolchain
+func buildVersion() versionInfo {
+	info := versionInfo{Version: version, Commit: commit, Date: date, Go: runtime.Version(), Platform: runtime.GOOS + "/" + runtime.GOARCH}
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		if info.Version == "" {
+			info.Version = "dev"
+		}
+		return info
+	}
+	if info.Version == "" {
+		// go install module@version records the module version; go build records (devel)
+		info.Version = "dev"
+		if v := build.Main.Version; v != "" && v != "(devel)" {
+			info.Version = v
+		}
+	}
+	if info.Commit == "" {
+		var dirty bool
+		for _, s := range build.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				info.Commit = s.Value
+			case "vcs.modified":
+				dirty = s.Value == "true"
+			}
+		}
+		if dirty && info.Commit != "" {
+			info.Commit += "-dirty"
+		}
+	}
+	if info.Date == "" {
+		// The commit time is the closest the toolchain records to a build date
+		for _, s := range build.Settings {
+			if s.Key == "vcs.time" {
+				info.Date = s.Value
+			}
+		}
+	}
+	for _, dep := range build.Deps {
+		if dep.Path == goMySQLModule {
+			info.GoMySQL = dep.Version
+			if dep.Replace != nil {
+				info.GoMySQL = dep.Replace.Path + " " + dep.Replace.Version
+			}
+		}
+	}
+	return info
+}
+
+// runVersion implements the version command and the --version flag
+func runVersion(args []string) {
+	fs := flag.NewFlagSet("version", flag.ExitOnError)
+	output := fs.String("output", "text", "Output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		fatalf("Error parsing flags: %v", err)
+	}
+
+	info := buildVersion()
+	switch *output {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(info); err != nil {
+			fatalf("Failed to write output: %v", err)
+		}
+	case "text":
+		fmt.Printf("binlog-find-time %s\n", info.Version)
+		if info.Commit != "" {
+			fmt.Printf("Commit: %s\n", info.Commit)
+		}
+		if info.Date != "" {
+			fmt.Printf("Built: %s\n", info.Date)
+		}
+		if info.GoMySQL != "" {
+			fmt.Printf("go-mysql: %s\n", info.GoMySQL)
+		}
+		fmt.Printf("Go: %s %s\n", info.Go, info.Platform)
+	default:
+		fatalf("Unknown output format %q", *output)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+	"github.com/minuteman3/binlog-find-time/internal/rangecache"
+)
+
+// runWarmCache implements the warm-cache command, probing every binlog once and writing
+// the time index to the range cache used by find
+func runWarmCache(args []string) {
+	fs := flag.NewFlagSet("warm-cache", flag.ExitOnError)
+	common := registerCommonFlags(fs)
+	cacheDir := fs.String("cache-dir", rangecache.DefaultDir(), "Directory holding the binlog range cache")
+	timestampSource := fs.String("timestamp-source", "", "Event timestamps to index: header, immediate-commit or original-commit (default: header)")
+	if err := fs.Parse(args); err != nil {
+		fatalf("Error parsing flags: %v", err)
+	}
+
+	cfg, err := common.load()
+	if err != nil {
+		fatalf("Error loading config: %v", err)
+	}
+	if err := cfg.resolveCluster(); err != nil {
+		fatalf("Error looking up the cluster: %v", err)
+	}
+	if *timestampSource != "" {
+		cfg.TimestampSource = *timestampSource
+	}
+	source, err := binlog.ParseTimestampSource(cfg.TimestampSource)
+	if err != nil {
+		fatalf("Invalid timestamp source: %v", err)
+	}
+	target := cfg.server()
+	server := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+
+	listed, err := target.ListBinlogs()
+	if err != nil {
+		fatalServerError(err, "Failed to get binlog files: %v", err)
+	}
+	files := make([]string, 0, len(listed))
+	sizes := make(map[string]int64, len(listed))
+	for _, f := range listed {
+		files = append(files, f.Name)
+		sizes[f.Name] = f.Size
+	}
+
+	// Without a server UUID a rebuilt server could not be told apart from this one
+	uuid, err := target.ServerUUID()
+	if err != nil {
+		fatalServerError(err, "The range cache needs the server UUID (not available on MariaDB): %v", err)
+	}
+
+	// Files already cached for this server instance with an unchanged size keep their ranges
+	path := rangecache.Path(*cacheDir, server)
+	var cached map[string]binlog.TimeRange
+	origin := cfg.originFilter().String()
+	if previous, err := rangecache.Load(path); err == nil && previous.Source == source.String() && previous.Origin == origin {
+		cached = previous.Ranges(uuid, sizes)
+	} else if err != nil && !rangecache.IsNotExist(err) {
+		slog.Warn("Could not load range cache, rebuilding it", "path", path, "error", err)
+	}
+
+	// Only the first event of each file is read: a file ends where its successor in the
+	// same epoch starts. The last file of each epoch has no successor, and the newest one
+	// is still being written, so those are left for find to probe.
+	finder := cfg.finder()
+	finder.Source = source
+	starts := make(map[string]time.Time, len(files))
+	var probed, failed int
+	for _, file := range files {
+		if r, ok := cached[file]; ok {
+			starts[file] = r.Start
+			continue
+		}
+		// The files probed before an interruption are still saved
+		if interrupted() {
+			break
+		}
+		start, err := finder.StartTime(file)
+		if err != nil {
+			if interrupted() {
+				break
+			}
+			slog.Warn("Could not get start time", "file", file, "error", err)
+			failed++
+			continue
+		}
+		starts[file] = start
+		probed++
+		slog.Info("Probed binlog start time", "file", file, "start", start.Format("2006-01-02 15:04:05"))
+	}
+
+	index := &rangecache.Index{Server: server, ServerUUID: uuid, Source: source.String(), Origin: origin, Updated: time.Now().UTC()}
+	for _, epoch := range binlog.SplitEpochs(files, starts) {
+		for i := 0; i+1 < len(epoch); i++ {
+			start, ok := starts[epoch[i]]
+			end, next := starts[epoch[i+1]]
+			if ok && next {
+				index.Files = append(index.Files, rangecache.Entry{File: epoch[i], Size: sizes[epoch[i]], Start: start, End: end})
+			}
+		}
+	}
+
+	if err := index.Save(path); err != nil {
+		fatalf("Failed to save cache: %v", err)
+	}
+	fmt.Printf("Cached time ranges of %d of %d binlog files in %s (%d files probed)\n", len(index.Files), len(files), path, probed)
+	exitIfInterrupted()
+	if failed > 0 {
+		os.Exit(exitConnection)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/webhook"
+)
+
+// webhookFlags holds the flags posting notifications to a webhook or Slack, so that
+// automation and people waiting on an event don't have to poll for it
+type webhookFlags struct {
+	url      *string
+	slackURL *string
+	retries  *int
+}
+
+// registerWebhookFlags defines the webhook flags on the given flag set; when describes
+// what is notified
+func registerWebhookFlags(fs *flag.FlagSet, when string) *webhookFlags {
+	return &webhookFlags{
+		url:      fs.String("webhook-url", "", "POST a JSON notification to this URL "+when),
+		slackURL: fs.String("slack-webhook-url", "", "Post a message to this Slack incoming webhook "+when),
+		retries:  fs.Int("webhook-retries", 3, "Times to retry a failed webhook delivery, waiting 1s and doubling"),
+	}
+}
+
+// given reports whether a webhook was given on the command line
+func (f *webhookFlags) given() bool {
+	return *f.url != "" || *f.slackURL != ""
+}
+
+// notifiers returns the notifiers of the flags, with the Slack webhook of the config file
+// unless given, or nil when there is none
+func (f *webhookFlags) notifiers(cfg *config) (*notifiers, error) {
+	slackURL := *f.slackURL
+	if slackURL == "" {
+		slackURL = cfg.SlackWebhookURL
+	}
+	if *f.url == "" && slackURL == "" {
+		return nil, nil
+	}
+	if *f.retries < 0 {
+		return nil, fmt.Errorf("--webhook-retries must not be negative")
+	}
+	n := &notifiers{}
+	var err error
+	if *f.url != "" {
+		if n.webhook, err = f.notifier("--webhook-url", *f.url); err != nil {
+			return nil, err
+		}
+	}
+	if slackURL != "" {
+		if n.slack, err = f.notifier("--slack-webhook-url", slackURL); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// notifier returns the notifier of a webhook URL given with the flag
+func (f *webhookFlags) notifier(flagName, rawURL string) (*webhook.Notifier, error) {
+	if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		// Slack webhook URLs are secrets, so the URL itself is left out
+		return nil, fmt.Errorf("invalid %s: expected an http or https URL", flagName)
+	}
+	return &webhook.Notifier{URL: rawURL, Retries: *f.retries, Backoff: time.Second}, nil
+}
+
+// notifiers are the webhooks an event is posted to, either of which may be nil
+type notifiers struct {
+	webhook *webhook.Notifier
+	slack   *webhook.Notifier
+}
+
+// notify posts the event with its data to the webhook, and its text to Slack, logging
+// deliveries that failed for good
+func (n *notifiers) notify(event, server, text string, data any) {
+	if n.webhook != nil {
+		deliver(n.webhook, event, "webhook", webhook.Notification{Event: event, Server: server, Time: time.Now().UTC(), Data: data})
+	}
+	if n.slack != nil {
+		deliver(n.slack, event, "Slack", webhook.SlackMessage{Text: text})
+	}
+}
+
+// deliver sends one notification of an event to a destination, logging the outcome
+func deliver(n *webhook.Notifier, event, destination string, payload any) {
+	if err := n.Send(context.Background(), payload); err != nil {
+		slog.Error("Failed to deliver notification", "event", event, "to", destination, "error", err)
+		return
+	}
+	slog.Info("Delivered notification", "event", event, "to", destination)
+}
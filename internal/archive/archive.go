@@ -0,0 +1,64 @@
+// Package archive lists and reads binlog files archived outside the server, so that
+// timestamps older than the server's retention can still be searched.
+//
+// Archived files are read through binlog.FileStreamer, with ranged reads: probing a file
+// for its time range only downloads its head. Files compressed with gzip or zstd, named
+// like mysql-bin.000012.gz or mysql-bin.000012.zst, are decompressed as they are read, and
+// Decrypt decrypts files written with binlog_encryption=ON. A binlog stored both
+// uncompressed and compressed is read from the uncompressed copy, with a warning.
+package archive
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// Archive lists, reads and streams archived binlog files
+type Archive interface {
+	binlog.BinlogLister
+	binlog.EventStreamer
+	binlog.FileReader
+}
+
+// Open returns the archive at location, a directory or a URL such as s3://bucket/prefix,
+// gcs://bucket/prefix, azblob://container/prefix, sftp://user@host/path or the https://
+// URL of an index listing the files
+func Open(ctx context.Context, location string) (Archive, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source %q: %w", location, err)
+	}
+	switch u.Scheme {
+	case "", "file":
+		return NewDir(u.Path), nil
+	case "s3":
+		return openS3(ctx, u)
+	case "gcs", "gs":
+		return openGCS(ctx, u)
+	case "azblob":
+		return openAzureBlob(ctx, u)
+	case "sftp":
+		return openSFTP(u)
+	case "http", "https":
+		return NewHTTP(ctx, httpClient, u), nil
+	default:
+		return nil, fmt.Errorf("unsupported source %q (expected a directory, s3://, gcs://, azblob://, sftp:// or https://)", location)
+	}
+}
+
+// Decrypt returns a with files written with binlog_encryption=ON decrypted with keys.
+// Unencrypted files are read as before.
+func Decrypt(a Archive, keys binlog.Keyring) Archive {
+	reader := &binlog.DecryptingReader{Reader: a, Keys: keys}
+	return decrypted{BinlogLister: a, FileReader: reader, FileStreamer: binlog.FileStreamer{Reader: reader}}
+}
+
+// decrypted lists the files of an archive and reads them through a DecryptingReader
+type decrypted struct {
+	binlog.BinlogLister
+	binlog.FileReader
+	binlog.FileStreamer
+}
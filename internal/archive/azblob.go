@@ -0,0 +1,131 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// AzureBlob reads binlogs archived under a prefix of an Azure Blob Storage container.
+// Only blobs directly under the prefix whose names end in a sequence number, optionally
+// followed by .gz or .zst, are listed.
+type AzureBlob struct {
+	binlog.FileStreamer
+	ctx    context.Context
+	client *container.Client
+	// prefix is empty or ends with a slash
+	prefix string
+	index  objectIndex
+}
+
+// NewAzureBlob returns the archive under prefix in the container accessed through client,
+// with requests made under ctx
+func NewAzureBlob(ctx context.Context, client *container.Client, prefix string) *AzureBlob {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	a := &AzureBlob{ctx: ctx, client: client, prefix: prefix}
+	a.FileStreamer = binlog.FileStreamer{Reader: a}
+	return a
+}
+
+// openAzureBlob opens an azblob://container/prefix URL. The storage account is taken from
+// the account query parameter or AZURE_STORAGE_ACCOUNT, and the endpoint parameter
+// overrides https://<account>.blob.core.windows.net, e.g. for Azurite. A SAS token in
+// AZURE_STORAGE_SAS_TOKEN is used if set; otherwise the default Azure credential chain
+// authenticates, which includes the environment, workload identity and managed identity.
+func openAzureBlob(ctx context.Context, u *url.URL) (*AzureBlob, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing container in %s", u.Redacted())
+	}
+	query := u.Query()
+	endpoint := query.Get("endpoint")
+	if endpoint == "" {
+		account := query.Get("account")
+		if account == "" {
+			account = os.Getenv("AZURE_STORAGE_ACCOUNT")
+		}
+		if account == "" {
+			return nil, fmt.Errorf("missing storage account: add ?account=NAME to %s or set AZURE_STORAGE_ACCOUNT", u.Redacted())
+		}
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", account)
+	}
+	containerURL := strings.TrimSuffix(endpoint, "/") + "/" + u.Host
+	prefix := strings.TrimPrefix(u.Path, "/")
+
+	if sas := os.Getenv("AZURE_STORAGE_SAS_TOKEN"); sas != "" {
+		client, err := container.NewClientWithNoCredential(containerURL+"?"+strings.TrimPrefix(sas, "?"), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure Blob client: %w", err)
+		}
+		return NewAzureBlob(ctx, client, prefix), nil
+	}
+
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Azure credentials: %w", err)
+	}
+	client, err := container.NewClient(containerURL, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure Blob client: %w", err)
+	}
+	return NewAzureBlob(ctx, client, prefix), nil
+}
+
+// ListBinlogs lists the binlogs under the prefix, ordered by name and sequence number
+func (a *AzureBlob) ListBinlogs() ([]binlog.FileInfo, error) {
+	var listed []object
+	pager := a.client.NewListBlobsHierarchyPager("/", &container.ListBlobsHierarchyOptions{Prefix: to.Ptr(a.prefix)})
+	for pager.More() {
+		page, err := pager.NextPage(a.ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s/%s: %w", a.client.URL(), a.prefix, err)
+		}
+		for _, item := range page.Segment.BlobItems {
+			var size int64
+			if item.Properties != nil && item.Properties.ContentLength != nil {
+				size = *item.Properties.ContentLength
+			}
+			listed = append(listed, object{key: path.Base(*item.Name), size: size})
+		}
+	}
+	return a.index.add(listed), nil
+}
+
+// OpenAt reads binlogFile from offset in growing ranged downloads
+func (a *AzureBlob) OpenAt(binlogFile string, offset int64) (io.ReadCloser, error) {
+	return a.index.open(a, binlogFile, offset, a.stat)
+}
+
+// stat asks for the size of a blob
+func (a *AzureBlob) stat(key string) (int64, error) {
+	props, err := a.client.NewBlobClient(a.prefix+key).GetProperties(a.ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat %s%s: %w", a.prefix, key, err)
+	}
+	if props.ContentLength == nil {
+		return 0, fmt.Errorf("no size reported for %s%s", a.prefix, key)
+	}
+	return *props.ContentLength, nil
+}
+
+func (a *AzureBlob) fetchRange(key string, offset, length int64) (io.ReadCloser, error) {
+	resp, err := a.client.NewBlobClient(a.prefix+key).DownloadStream(a.ctx, &blob.DownloadStreamOptions{
+		Range: blob.HTTPRange{Offset: offset, Count: length},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,134 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+)
+
+// fakeAzureBlob serves the List Blobs, Get Blob Properties and Get Blob calls of a
+// single container, rejecting requests without the expected SAS signature and counting
+// the blob bytes sent
+type fakeAzureBlob struct {
+	container string
+	sig       string
+	blobs     map[string][]byte
+	sent      atomic.Int64
+}
+
+type enumerationResults struct {
+	XMLName xml.Name `xml:"EnumerationResults"`
+	Blobs   struct {
+		Blob []azureBlobItem
+	}
+}
+
+type azureBlobItem struct {
+	Name       string
+	Properties struct {
+		ContentLength int `xml:"Content-Length"`
+		BlobType      string
+	}
+}
+
+func (s *fakeAzureBlob) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("sig") != s.sig {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	name, ok := strings.CutPrefix(r.URL.Path, "/"+s.container)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	name = strings.TrimPrefix(name, "/")
+
+	if name == "" && r.URL.Query().Get("comp") == "list" {
+		var res enumerationResults
+		prefix := r.URL.Query().Get("prefix")
+		for k, data := range s.blobs {
+			if rest, ok := strings.CutPrefix(k, prefix); ok && !strings.Contains(rest, "/") {
+				item := azureBlobItem{Name: k}
+				item.Properties.ContentLength = len(data)
+				item.Properties.BlobType = "BlockBlob"
+				res.Blobs.Blob = append(res.Blobs.Blob, item)
+			}
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		_ = xml.NewEncoder(w).Encode(res)
+		return
+	}
+
+	data, ok := s.blobs[name]
+	if !ok {
+		w.Header().Set("x-ms-error-code", "BlobNotFound")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if rng := r.Header.Get("x-ms-range"); rng != "" {
+		r.Header.Set("Range", rng)
+	}
+	http.ServeContent(countingWriter{w, &s.sent}, r, name, time.Time{}, bytes.NewReader(data))
+}
+
+func TestAzureBlobSearch(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 8, FileSize: 1 << 20, Rate: 50, Seed: 1})
+	server := &fakeAzureBlob{container: "backups", sig: "secret", blobs: map[string][]byte{"db1/other/binlog.000001": files[0].Data}}
+	var total int64
+	for _, f := range files {
+		server.blobs["db1/"+f.Name] = f.Data
+		total += f.Size()
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	t.Setenv("AZURE_STORAGE_SAS_TOKEN", "?sv=2022-11-02&sig=secret")
+	a, err := Open(context.Background(), "azblob://backups/db1?endpoint="+ts.URL)
+	require.NoError(t, err)
+
+	listed, err := a.ListBinlogs()
+	require.NoError(t, err)
+	require.Len(t, listed, len(files))
+	names := make([]string, len(listed))
+	sizes := make(map[string]int64, len(listed))
+	for i, f := range listed {
+		names[i] = f.Name
+		sizes[f.Name] = f.Size
+		assert.Equal(t, files[i].Name, f.Name)
+		assert.Equal(t, files[i].Size(), f.Size)
+	}
+
+	target := files[6].Start.Add(files[6].End.Sub(files[6].Start) / 2).Truncate(time.Second).Add(500 * time.Millisecond)
+	finder := &binlog.Finder{Streamer: a, Lister: a, Sizes: sizes}
+	file, _, err := finder.Search(names, target)
+	require.NoError(t, err)
+	assert.Equal(t, files[6].Name, file)
+	assert.Less(t, server.sent.Load(), total/10, "only file heads are downloaded")
+
+	// The size of a file that was not listed is asked for
+	unlisted, err := Open(context.Background(), "azblob://backups/db1?endpoint="+ts.URL)
+	require.NoError(t, err)
+	stream, err := unlisted.StreamFrom(files[0].Name, 4)
+	require.NoError(t, err)
+	stream.Close()
+	_, err = unlisted.StreamFrom("binlog.000099", 4)
+	assert.Error(t, err)
+}
+
+func TestAzureBlobMissingAccount(t *testing.T) {
+	t.Setenv("AZURE_STORAGE_ACCOUNT", "")
+	_, err := Open(context.Background(), "azblob://backups/db1")
+	assert.ErrorContains(t, err, "missing storage account")
+}
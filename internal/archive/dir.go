@@ -0,0 +1,128 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// Dir reads binlogs archived in a local directory, such as a mounted backup volume, or a
+// server's data directory. Only files directly in the directory whose names end in a
+// sequence number, optionally followed by .gz or .zst, are listed.
+//
+// When the directory holds the server's index file, like mysql-bin.index, the binlogs it
+// lists are listed in its order; files missing from it, e.g. left behind by a basename
+// change or removed from the index on purpose, are left out.
+type Dir struct {
+	binlog.FileStreamer
+	// IndexFile, if set, is the index file listing the binlogs. Otherwise the index file
+	// in the directory is used if there is exactly one.
+	IndexFile string
+
+	path  string
+	index objectIndex
+}
+
+// NewDir returns the archive in the directory at path
+func NewDir(path string) *Dir {
+	a := &Dir{path: path}
+	a.FileStreamer = binlog.FileStreamer{Reader: a}
+	return a
+}
+
+// ListBinlogs lists the binlogs in the directory, in the order of the index file if there
+// is one and ordered by name and sequence number otherwise
+func (a *Dir) ListBinlogs() ([]binlog.FileInfo, error) {
+	entries, err := os.ReadDir(a.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", a.path, err)
+	}
+	var listed []object
+	var indexes []string
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if filepath.Ext(entry.Name()) == ".index" {
+			indexes = append(indexes, filepath.Join(a.path, entry.Name()))
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", entry.Name(), err)
+		}
+		listed = append(listed, object{key: entry.Name(), size: info.Size()})
+	}
+	files := a.index.add(listed)
+
+	indexFile := a.IndexFile
+	if indexFile == "" && len(indexes) == 1 {
+		indexFile = indexes[0]
+	}
+	if indexFile == "" {
+		return files, nil
+	}
+	names, err := readIndex(indexFile)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]binlog.FileInfo, len(files))
+	for _, f := range files {
+		found[f.Name] = f
+	}
+	// Files listed in the index but no longer in the directory are left out
+	indexed := make([]binlog.FileInfo, 0, len(names))
+	for _, name := range names {
+		if f, ok := found[name]; ok {
+			indexed = append(indexed, f)
+		}
+	}
+	return indexed, nil
+}
+
+// readIndex returns the names of the binlogs listed in an index file, in order. The
+// server writes their paths, relative to its data directory unless log_bin is absolute.
+func readIndex(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index file: %w", err)
+	}
+	var names []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			names = append(names, filepath.Base(line))
+		}
+	}
+	return names, nil
+}
+
+// OpenAt reads binlogFile from offset
+func (a *Dir) OpenAt(binlogFile string, offset int64) (io.ReadCloser, error) {
+	return a.index.open(a, binlogFile, offset, a.stat)
+}
+
+// stat returns the size of a file
+func (a *Dir) stat(key string) (int64, error) {
+	info, err := os.Stat(filepath.Join(a.path, key))
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (a *Dir) fetchRange(key string, offset, length int64) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(a.path, key))
+	if err != nil {
+		return nil, err
+	}
+	return sectionReader{io.NewSectionReader(f, offset, length), f}, nil
+}
+
+// sectionReader reads a section of a file and closes the file
+type sectionReader struct {
+	*io.SectionReader
+	io.Closer
+}
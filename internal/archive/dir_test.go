@@ -0,0 +1,54 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+)
+
+func listNames(t *testing.T, a binlog.BinlogLister) []string {
+	files, err := a.ListBinlogs()
+	require.NoError(t, err)
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.Name
+	}
+	return names
+}
+
+func TestDirIndex(t *testing.T) {
+	dir := t.TempDir()
+	old := binlogtest.Generate(binlogtest.Options{Prefix: "mysql-bin", Files: 2, FileSize: 4 << 10})
+	renamed := binlogtest.Generate(binlogtest.Options{Prefix: "binlog", Files: 2, FileSize: 4 << 10, Start: old[1].End})
+	for _, f := range append(old, renamed...) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, f.Name), f.Data, 0o600))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "binlog.000003.gz"), compress(t, renamed[1].Data, ".gz"), 0o600))
+
+	// Without an index, files are ordered by name
+	assert.Equal(t, []string{"binlog.000001", "binlog.000002", "binlog.000003", "mysql-bin.000001", "mysql-bin.000002"}, listNames(t, NewDir(dir)))
+
+	// The index orders the files across the basename change and leaves out those it does
+	// not list. Entries are paths, relative to the data directory or absolute.
+	index := "./mysql-bin.000002\n/var/lib/mysql/binlog.000001\n./binlog.000003\n./binlog.000004\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "binlog.index"), []byte(index), 0o600))
+	assert.Equal(t, []string{"mysql-bin.000002", "binlog.000001", "binlog.000003"}, listNames(t, NewDir(dir)))
+
+	// Only a single index file is picked up on its own
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "mysql-bin.index"), []byte("./mysql-bin.000001\n"), 0o600))
+	assert.Len(t, listNames(t, NewDir(dir)), 5)
+
+	a := NewDir(dir)
+	a.IndexFile = filepath.Join(dir, "mysql-bin.index")
+	assert.Equal(t, []string{"mysql-bin.000001"}, listNames(t, a))
+
+	a.IndexFile = filepath.Join(dir, "missing.index")
+	_, err := a.ListBinlogs()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,91 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// GCS reads binlogs archived under a prefix of a Google Cloud Storage bucket. Only
+// objects directly under the prefix whose names end in a sequence number, optionally
+// followed by .gz or .zst, are listed.
+type GCS struct {
+	binlog.FileStreamer
+	ctx    context.Context
+	bucket *storage.BucketHandle
+	name   string
+	// prefix is empty or ends with a slash
+	prefix string
+	index  objectIndex
+}
+
+// NewGCS returns the archive under prefix in bucket, accessed through client
+func NewGCS(ctx context.Context, client *storage.Client, bucket, prefix string) *GCS {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	a := &GCS{ctx: ctx, bucket: client.Bucket(bucket), name: bucket, prefix: prefix}
+	a.FileStreamer = binlog.FileStreamer{Reader: a}
+	return a
+}
+
+// openGCS opens a gcs://bucket/prefix URL with Application Default Credentials. Like
+// other GCS clients, it talks to an emulator instead when STORAGE_EMULATOR_HOST is set.
+func openGCS(ctx context.Context, u *url.URL) (*GCS, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing bucket in %s", u.Redacted())
+	}
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+	return NewGCS(ctx, client, u.Host, strings.TrimPrefix(u.Path, "/")), nil
+}
+
+// ListBinlogs lists the binlogs under the prefix, ordered by name and sequence number
+func (a *GCS) ListBinlogs() ([]binlog.FileInfo, error) {
+	var listed []object
+	it := a.bucket.Objects(a.ctx, &storage.Query{Prefix: a.prefix, Delimiter: "/"})
+	for {
+		obj, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list gcs://%s/%s: %w", a.name, a.prefix, err)
+		}
+		// Subdirectories are listed as prefixes without a name
+		if obj.Name == "" {
+			continue
+		}
+		listed = append(listed, object{key: path.Base(obj.Name), size: obj.Size})
+	}
+	return a.index.add(listed), nil
+}
+
+// OpenAt reads binlogFile from offset in growing ranged reads
+func (a *GCS) OpenAt(binlogFile string, offset int64) (io.ReadCloser, error) {
+	return a.index.open(a, binlogFile, offset, a.stat)
+}
+
+// stat asks for the size of an object
+func (a *GCS) stat(key string) (int64, error) {
+	attrs, err := a.bucket.Object(a.prefix + key).Attrs(a.ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat gcs://%s/%s%s: %w", a.name, a.prefix, key, err)
+	}
+	return attrs.Size, nil
+}
+
+func (a *GCS) fetchRange(key string, offset, length int64) (io.ReadCloser, error) {
+	return a.bucket.Object(a.prefix+key).NewRangeReader(a.ctx, offset, length)
+}
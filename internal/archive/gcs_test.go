@@ -0,0 +1,116 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+)
+
+// fakeGCS serves the object listing, metadata (JSON API) and media (XML API) requests
+// of a single bucket, as an emulator would, counting the object bytes sent
+type fakeGCS struct {
+	bucket  string
+	objects map[string][]byte
+	sent    atomic.Int64
+}
+
+type gcsObject struct {
+	Name string `json:"name"`
+	Size string `json:"size"`
+}
+
+func (s *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if rest, ok := strings.CutPrefix(r.URL.EscapedPath(), "/storage/v1/b/"+s.bucket+"/o"); ok {
+		s.serveMetadata(w, r, strings.TrimPrefix(rest, "/"))
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+s.bucket+"/")
+	data, found := s.objects[key]
+	if !ok || !found {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeContent(countingWriter{w, &s.sent}, r, key, time.Time{}, bytes.NewReader(data))
+}
+
+func (s *fakeGCS) serveMetadata(w http.ResponseWriter, r *http.Request, escapedKey string) {
+	w.Header().Set("Content-Type", "application/json")
+	if escapedKey != "" {
+		key, _ := url.PathUnescape(escapedKey)
+		data, ok := s.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":404,"message":"No such object"}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(gcsObject{Name: key, Size: strconv.Itoa(len(data))})
+		return
+	}
+
+	var items []gcsObject
+	prefix := r.URL.Query().Get("prefix")
+	for k, data := range s.objects {
+		if rest, ok := strings.CutPrefix(k, prefix); ok && !strings.Contains(rest, "/") {
+			items = append(items, gcsObject{Name: k, Size: strconv.Itoa(len(data))})
+		}
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"kind": "storage#objects", "items": items})
+}
+
+func TestGCSSearch(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 8, FileSize: 1 << 20, Rate: 50, Seed: 1})
+	server := &fakeGCS{bucket: "backups", objects: map[string][]byte{"mysql/mysql-bin.index": []byte("./mysql-bin.000001\n")}}
+	var total int64
+	for _, f := range files {
+		server.objects["mysql/"+f.Name] = f.Data
+		total += f.Size()
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	t.Setenv("STORAGE_EMULATOR_HOST", strings.TrimPrefix(ts.URL, "http://"))
+	a, err := Open(context.Background(), "gcs://backups/mysql")
+	require.NoError(t, err)
+
+	listed, err := a.ListBinlogs()
+	require.NoError(t, err)
+	require.Len(t, listed, len(files))
+	names := make([]string, len(listed))
+	sizes := make(map[string]int64, len(listed))
+	for i, f := range listed {
+		names[i] = f.Name
+		sizes[f.Name] = f.Size
+		assert.Equal(t, files[i].Name, f.Name)
+		assert.Equal(t, files[i].Size(), f.Size)
+	}
+
+	target := files[2].Start.Add(files[2].End.Sub(files[2].Start) / 2).Truncate(time.Second).Add(500 * time.Millisecond)
+	finder := &binlog.Finder{Streamer: a, Lister: a, Sizes: sizes}
+	file, _, err := finder.Search(names, target)
+	require.NoError(t, err)
+	assert.Equal(t, files[2].Name, file)
+	assert.Less(t, server.sent.Load(), total/10, "only file heads are downloaded")
+
+	// The size of a file that was not listed is asked for
+	unlisted, err := Open(context.Background(), "gcs://backups/mysql/")
+	require.NoError(t, err)
+	stream, err := unlisted.StreamFrom(files[0].Name, 4)
+	require.NoError(t, err)
+	stream.Close()
+	_, err = unlisted.StreamFrom("mysql-bin.000099", 4)
+	assert.Error(t, err)
+}
@@ -0,0 +1,163 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// Glob reads binlogs found by a glob pattern, such as archives organized into dated
+// subdirectories. Patterns use path.Match syntax in each path element, and a "**" element
+// matches any number of directories, so backups/**/mysql-bin.* finds binlogs at any
+// depth under backups. The walk starts at the leading elements without wildcards and
+// skips directories no match can be under. Matching files are ordered by name and
+// sequence number, wherever they are; when the same binlog is found more than once, as
+// when the file being written was copied by successive archive runs, the copy found last
+// in path order is used.
+type Glob struct {
+	binlog.FileStreamer
+	pattern string
+	// root is the directory named by the leading elements of the pattern without
+	// wildcards, which is walked for matches
+	root  string
+	index objectIndex
+}
+
+// NewGlob returns the archive of the files matching pattern
+func NewGlob(pattern string) *Glob {
+	pattern = filepath.ToSlash(filepath.Clean(pattern))
+	var root []string
+	elems := strings.Split(pattern, "/")
+	for len(elems) > 1 && !hasMeta(elems[0]) {
+		root, elems = append(root, elems[0]), elems[1:]
+	}
+	a := &Glob{pattern: strings.Join(elems, "/"), root: strings.Join(root, "/")}
+	switch {
+	case a.root == "" && strings.HasPrefix(pattern, "/"):
+		a.root = "/"
+	case a.root == "":
+		a.root = "."
+	}
+	a.FileStreamer = binlog.FileStreamer{Reader: a}
+	return a
+}
+
+// hasMeta reports whether a path element holds wildcards
+func hasMeta(elem string) bool {
+	return strings.ContainsAny(elem, `*?[\`)
+}
+
+// ListBinlogs lists the binlogs matching the pattern, ordered by name and sequence number
+func (a *Glob) ListBinlogs() ([]binlog.FileInfo, error) {
+	found := make(map[string]object)
+	err := filepath.WalkDir(filepath.FromSlash(a.root), func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && !entry.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(filepath.FromSlash(a.root), p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		// Directories no match can be under are not walked
+		if entry.IsDir() {
+			if rel != "." && !matchGlobDir(a.pattern, rel) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !matchGlob(a.pattern, rel) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", p, err)
+		}
+		// Later copies replace earlier ones, as the walk is in lexical order
+		name, _ := binlogName(rel)
+		found[name] = object{key: rel, size: info.Size()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", a.root, err)
+	}
+
+	listed := make([]object, 0, len(found))
+	for _, obj := range found {
+		listed = append(listed, obj)
+	}
+	return a.index.add(listed), nil
+}
+
+// matchGlob reports whether the slash-separated name matches pattern, where a "**"
+// element matches zero or more elements
+func matchGlob(pattern, name string) bool {
+	return matchElems(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// matchGlobDir reports whether names under the slash-separated directory dir may match
+// pattern
+func matchGlobDir(pattern, dir string) bool {
+	elems, names := strings.Split(pattern, "/"), strings.Split(dir, "/")
+	for ; len(names) > 0; elems, names = elems[1:], names[1:] {
+		// The last element is matched by the names of files
+		if len(elems) < 2 {
+			return false
+		}
+		if elems[0] == "**" {
+			return true
+		}
+		if ok, err := path.Match(elems[0], names[0]); err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func matchElems(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchElems(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// OpenAt reads binlogFile from offset
+func (a *Glob) OpenAt(binlogFile string, offset int64) (io.ReadCloser, error) {
+	return a.index.open(a, binlogFile, offset, a.stat)
+}
+
+// stat fails for binlogs that were not listed, as their directory is unknown
+func (a *Glob) stat(key string) (int64, error) {
+	return 0, fmt.Errorf("%s does not match %s: %w", key, a.pattern, os.ErrNotExist)
+}
+
+func (a *Glob) fetchRange(key string, offset, length int64) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(filepath.FromSlash(a.root), filepath.FromSlash(key)))
+	if err != nil {
+		return nil, err
+	}
+	return sectionReader{io.NewSectionReader(f, offset, length), f}, nil
+}
@@ -0,0 +1,97 @@
+package archive
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"mysql-bin.*", "mysql-bin.000001", true},
+		{"mysql-bin.*", "2024-01-01/mysql-bin.000001", false},
+		{"*/mysql-bin.*", "2024-01-01/mysql-bin.000001", true},
+		{"**/mysql-bin.*", "mysql-bin.000001", true},
+		{"**/mysql-bin.*", "db1/2024/01/mysql-bin.000001.gz", true},
+		{"db1/**/mysql-bin.*", "db2/2024/mysql-bin.000001", false},
+		{"**/2024-*/**", "host/2024-01-01/sub/binlog.000003", true},
+		{"**/mysql-bin.*", "2024-01-01/relay-bin.000001", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.match, matchGlob(tt.pattern, tt.name))
+		})
+	}
+}
+
+func TestMatchGlobDir(t *testing.T) {
+	tests := []struct {
+		pattern string
+		dir     string
+		match   bool
+	}{
+		{"mysql-bin.*", "2024-01-01", false},
+		{"2024-*/mysql-bin.*", "2024-01-01", true},
+		{"2024-*/mysql-bin.*", "2023-12-31", false},
+		{"2024-*/mysql-bin.*", "2024-01-01/old", false},
+		{"*/*/mysql-bin.*", "db1/2024", true},
+		{"db1/**/mysql-bin.*", "db1/2024/01", true},
+		{"db1/**/mysql-bin.*", "db2", false},
+		{"**/mysql-bin.*", "any/depth", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.dir, func(t *testing.T) {
+			assert.Equal(t, tt.match, matchGlobDir(tt.pattern, tt.dir))
+		})
+	}
+}
+
+func TestGlob(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Prefix: "mysql-bin", Files: 12, FileSize: 8 << 10, Rate: 0.5, Seed: 1})
+	dir := t.TempDir()
+	write := func(rel string, data []byte) {
+		p := filepath.Join(dir, filepath.FromSlash(rel))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o700))
+		require.NoError(t, os.WriteFile(p, data, 0o600))
+	}
+	// Four files a day, and each day's run also copied part of the file then being written
+	for i, f := range files {
+		day := fmt.Sprintf("2024-01-%02d", i/4+1)
+		write(day+"/"+f.Name+".gz", compress(t, f.Data, ".gz"))
+		if i%4 == 3 && i+1 < len(files) {
+			write(day+"/"+files[i+1].Name, files[i+1].Data[:len(files[i+1].Data)/2])
+		}
+	}
+	write("2024-01-01/relay-bin.000001", files[0].Data)
+
+	a := NewGlob(filepath.Join(dir, "**", "mysql-bin.*"))
+	names := listNames(t, a)
+	want := make([]string, len(files))
+	for i, f := range files {
+		want[i] = f.Name
+	}
+	assert.Equal(t, want, names)
+
+	finder := &binlog.Finder{Streamer: a, Lister: a}
+	for _, f := range files {
+		target := f.Start.Add(f.End.Sub(f.Start) / 2).Truncate(time.Second).Add(500 * time.Millisecond)
+		file, exact, err := finder.Search(names, target)
+		require.NoError(t, err)
+		assert.Equal(t, f.Name, file)
+		assert.True(t, exact)
+	}
+}
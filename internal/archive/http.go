@@ -0,0 +1,241 @@
+package archive
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// HTTP reads binlogs served by a web server that honors Range requests. The files are
+// listed from an index at the source URL, which may be:
+//   - an HTML directory listing, such as the autoindex pages of nginx or Apache, whose
+//     links name the files
+//   - the JSON listing of nginx (autoindex_format json), which also gives their sizes
+//   - a plain text list of file URLs, one per line, relative to the index or absolute
+//
+// Sizes missing from the index are asked for with a HEAD request when a file is read.
+type HTTP struct {
+	binlog.FileStreamer
+	ctx    context.Context
+	client *http.Client
+	// location is the URL of the index
+	location *url.URL
+	index    objectIndex
+
+	mu sync.Mutex
+	// base is the URL of the index as last served, which unlisted files are relative to
+	base *url.URL
+	// urls maps listed object keys, the names of the files, to their URLs
+	urls map[string]*url.URL
+}
+
+// httpClient reads archives over HTTP for Open. Its timeouts bound connecting and waiting
+// for each response, but not reading a body, as a server ignoring Range requests sends
+// whole files that may take long to read through.
+var httpClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		IdleConnTimeout:       90 * time.Second,
+		ForceAttemptHTTP2:     true,
+	},
+}
+
+// NewHTTP returns the archive listed by the index at u, accessed through client.
+// Credentials in u are sent as basic authentication with every request.
+func NewHTTP(ctx context.Context, client *http.Client, u *url.URL) *HTTP {
+	a := &HTTP{ctx: ctx, client: client, location: u, base: u}
+	a.FileStreamer = binlog.FileStreamer{Reader: a}
+	return a
+}
+
+// ListBinlogs lists the binlogs in the index, ordered by name and sequence number
+func (a *HTTP) ListBinlogs() ([]binlog.FileInfo, error) {
+	resp, err := a.do(http.MethodGet, a.location, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the index %s: %w", a.location.Redacted(), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch the index %s: %s", a.location.Redacted(), resp.Status)
+	}
+	// Links are relative to the index as finally served, e.g. after a redirect adding a
+	// trailing slash
+	base := resp.Request.URL
+
+	var entries []indexEntry
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		entries, err = parseJSONIndex(resp.Body)
+	case "text/plain":
+		entries, err = parseTextIndex(resp.Body)
+	default:
+		entries, err = parseHTMLIndex(resp.Body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the index %s: %w", a.location.Redacted(), err)
+	}
+
+	urls := make(map[string]*url.URL, len(entries))
+	listed := make([]object, 0, len(entries))
+	for _, e := range entries {
+		ref, err := url.Parse(e.href)
+		if err != nil {
+			continue
+		}
+		u := base.ResolveReference(ref)
+		key := path.Base(u.Path)
+		if strings.HasSuffix(u.Path, "/") || key == "." || key == "/" {
+			continue
+		}
+		urls[key] = u
+		listed = append(listed, object{key: key, size: e.size})
+	}
+	a.mu.Lock()
+	a.base, a.urls = base, urls
+	a.mu.Unlock()
+	return a.index.add(listed), nil
+}
+
+// indexEntry is a file named by an index, with its size or -1
+type indexEntry struct {
+	href string
+	size int64
+}
+
+// hrefPattern finds the links of an HTML directory listing
+var hrefPattern = regexp.MustCompile(`(?i)href\s*=\s*["']([^"'#?]+)["']`)
+
+func parseHTMLIndex(r io.Reader) ([]indexEntry, error) {
+	page, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var entries []indexEntry
+	for _, m := range hrefPattern.FindAllSubmatch(page, -1) {
+		entries = append(entries, indexEntry{href: string(m[1]), size: -1})
+	}
+	return entries, nil
+}
+
+func parseJSONIndex(r io.Reader) ([]indexEntry, error) {
+	var listing []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+		Size *int64 `json:"size"`
+	}
+	if err := json.NewDecoder(r).Decode(&listing); err != nil {
+		return nil, err
+	}
+	var entries []indexEntry
+	for _, f := range listing {
+		if f.Type != "" && f.Type != "file" {
+			continue
+		}
+		e := indexEntry{href: url.PathEscape(f.Name), size: -1}
+		if f.Size != nil {
+			e.size = *f.Size
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func parseTextIndex(r io.Reader) ([]indexEntry, error) {
+	var entries []indexEntry
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			entries = append(entries, indexEntry{href: line, size: -1})
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// OpenAt reads binlogFile from offset in growing Range requests
+func (a *HTTP) OpenAt(binlogFile string, offset int64) (io.ReadCloser, error) {
+	return a.index.open(a, binlogFile, offset, a.stat)
+}
+
+// url returns the URL of a listed file, or of a file next to the index
+func (a *HTTP) url(key string) *url.URL {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if u, ok := a.urls[key]; ok {
+		return u
+	}
+	return a.base.ResolveReference(&url.URL{Path: key})
+}
+
+// stat asks for the size of a file with a HEAD request
+func (a *HTTP) stat(key string) (int64, error) {
+	u := a.url(key)
+	resp, err := a.do(http.MethodHead, u, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat %s: %w", u.Redacted(), err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to stat %s: %s", u.Redacted(), resp.Status)
+	}
+	if resp.ContentLength < 0 {
+		return 0, fmt.Errorf("failed to stat %s: the server did not send its size", u.Redacted())
+	}
+	return resp.ContentLength, nil
+}
+
+func (a *HTTP) fetchRange(key string, offset, length int64) (io.ReadCloser, error) {
+	u := a.url(key)
+	resp, err := a.do(http.MethodGet, u, http.Header{"Range": {fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)}})
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		return resp.Body, nil
+	case http.StatusOK:
+		// The server ignored the range and sends the whole file, which is read on to its
+		// end rather than fetched again for every chunk
+		if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		return wholeBody{resp.Body}, nil
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", u.Redacted(), resp.Status)
+	}
+}
+
+// do sends a request, with the credentials of the index URL if it has any
+func (a *HTTP) do(method string, u *url.URL, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(a.ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if user := a.location.User; user != nil {
+		password, _ := user.Password()
+		req.SetBasicAuth(user.Username(), password)
+	}
+	return a.client.Do(req)
+}
@@ -0,0 +1,191 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+)
+
+// fakeHTTP serves binlogs under /binlogs/ with HTML and JSON directory listings and a
+// plain text index,
+// counting the file bytes sent
+type fakeHTTP struct {
+	files []*binlogtest.File
+	// noRanges makes the server ignore Range requests, sending whole files
+	noRanges bool
+	sent     atomic.Int64
+}
+
+func (s *fakeHTTP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/binlogs":
+		http.Redirect(w, r, "/binlogs/", http.StatusMovedPermanently)
+		return
+	case "/binlogs/":
+		if r.URL.Query().Get("format") == "json" {
+			listing := []map[string]any{{"name": "old", "type": "directory"}}
+			for _, f := range s.files {
+				listing = append(listing, map[string]any{"name": f.Name, "type": "file", "size": f.Size()})
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(listing)
+			return
+		}
+		var page strings.Builder
+		page.WriteString(`<html><body><a href="../">../</a> <a href="?C=N;O=D">Name</a>` + "\n")
+		for _, f := range s.files {
+			fmt.Fprintf(&page, "<a href=\"%s\">%s</a> %d\n", f.Name, f.Name, f.Size())
+		}
+		page.WriteString(`<a HREF='notes.txt'>notes.txt</a> <a href="old/">old/</a></body></html>`)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(page.String()))
+		return
+	case "/binlogs.txt":
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprintln(w, "# archived binlogs")
+		for _, f := range s.files {
+			fmt.Fprintf(w, "binlogs/%s\n", f.Name)
+		}
+		return
+	}
+
+	name, ok := strings.CutPrefix(r.URL.Path, "/binlogs/")
+	for _, f := range s.files {
+		if ok && f.Name == name {
+			if s.noRanges {
+				r.Header.Del("Range")
+			}
+			http.ServeContent(countingWriter{w, &s.sent}, r, name, time.Time{}, bytes.NewReader(f.Data))
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+func TestHTTPSearch(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 8, FileSize: 1 << 20, Rate: 50, Seed: 1})
+	var total int64
+	for _, f := range files {
+		total += f.Size()
+	}
+	want := make([]string, len(files))
+	for i, f := range files {
+		want[i] = f.Name
+	}
+	target := files[5].Start.Add(files[5].End.Sub(files[5].Start) / 2).Truncate(time.Second).Add(500 * time.Millisecond)
+
+	tests := []struct {
+		name  string
+		index string
+		sizes bool
+	}{
+		{"html", "/binlogs", false},
+		{"json", "/binlogs/?format=json", true},
+		{"text", "/binlogs.txt", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &fakeHTTP{files: files}
+			ts := httptest.NewServer(server)
+			defer ts.Close()
+
+			a, err := Open(context.Background(), ts.URL+tt.index)
+			require.NoError(t, err)
+			listed, err := a.ListBinlogs()
+			require.NoError(t, err)
+			names := make([]string, len(listed))
+			for i, f := range listed {
+				names[i] = f.Name
+				if tt.sizes {
+					assert.Equal(t, files[i].Size(), f.Size)
+				} else {
+					assert.Zero(t, f.Size)
+				}
+			}
+			assert.Equal(t, want, names)
+
+			finder := &binlog.Finder{Streamer: a, Lister: a}
+			file, _, err := finder.Search(names, target)
+			require.NoError(t, err)
+			assert.Equal(t, files[5].Name, file)
+			assert.Less(t, server.sent.Load(), total/10, "only file heads are downloaded")
+		})
+	}
+}
+
+func TestHTTPIgnoredRanges(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 3, Seed: 1})
+	server := &fakeHTTP{files: files, noRanges: true}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	a, err := Open(context.Background(), ts.URL+"/binlogs/")
+	require.NoError(t, err)
+	_, err = a.ListBinlogs()
+	require.NoError(t, err)
+
+	// A server sending whole files is read from the requested offset all the same
+	f := files[1]
+	r, err := a.OpenAt(f.Name, int64(f.Events[1].Pos))
+	require.NoError(t, err)
+	defer r.Close()
+	head := make([]byte, 64)
+	_, err = io.ReadFull(r, head)
+	require.NoError(t, err)
+	assert.Equal(t, f.Data[f.Events[1].Pos:int(f.Events[1].Pos)+len(head)], head)
+
+	// Reading a whole file takes a single download, not one per chunk
+	server.sent.Store(0)
+	whole, err := a.OpenAt(f.Name, 0)
+	require.NoError(t, err)
+	defer whole.Close()
+	data, err := io.ReadAll(whole)
+	require.NoError(t, err)
+	assert.Equal(t, f.Data, data)
+	assert.Equal(t, f.Size(), server.sent.Load())
+
+	_, err = a.OpenAt("binlog.000099", 4)
+	assert.ErrorContains(t, err, "404")
+}
+
+func TestHTTPInterrupted(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 1, Seed: 1})
+	server := &fakeHTTP{files: files}
+	// Files hang until the request is abandoned
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/binlogs/binlog.") {
+			<-r.Context().Done()
+			return
+		}
+		server.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := Open(ctx, ts.URL+"/binlogs/")
+	require.NoError(t, err)
+	_, err = a.ListBinlogs()
+	require.NoError(t, err)
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err = a.OpenAt(files[0].Name, 4)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
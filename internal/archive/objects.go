@@ -0,0 +1,164 @@
+package archive
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// object is a stored binlog file
+type object struct {
+	// key is the name of the object, relative to the archive's prefix
+	key string
+	// size is -1 when the listing does not tell it
+	size int64
+	// compression is the extension of a compressed object, or empty
+	compression string
+}
+
+// Extensions of compressed binlogs, which are decompressed as they are read
+var compressions = []string{".gz", ".zst"}
+
+// objectIndex maps binlog names to the stored objects holding them, as ranged reads need
+// to know where an object ends and which objects are compressed
+type objectIndex struct {
+	mu      sync.Mutex
+	objects map[string]object
+}
+
+// binlogName returns the name of the binlog held by the object at key, and the extension
+// of its compression if any
+func binlogName(key string) (name, compression string) {
+	name = path.Base(key)
+	for _, ext := range compressions {
+		if base, ok := strings.CutSuffix(name, ext); ok {
+			return base, ext
+		}
+	}
+	return name, ""
+}
+
+// add records listed objects, named relative to the archive's prefix, and returns the
+// binlogs they hold ordered by name and sequence number. The size of a compressed binlog
+// is unknown until it is read, so it is listed as 0, as are sizes the listing lacks.
+// A binlog held by more than one object, such as mysql-bin.000012 and
+// mysql-bin.000012.gz, is listed once, read from the uncompressed object if there is one
+// and from the first listed otherwise, and the others are logged as ignored.
+func (x *objectIndex) add(listed []object) []binlog.FileInfo {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.objects == nil {
+		x.objects = make(map[string]object, len(listed))
+	}
+
+	chosen := make(map[string]object, len(listed))
+	for _, obj := range listed {
+		var name string
+		name, obj.compression = binlogName(obj.key)
+		other, ok := chosen[name]
+		if !ok {
+			chosen[name] = obj
+			continue
+		}
+		// Uncompressed copies are cheaper to read, and their size is known
+		if other.compression != "" && obj.compression == "" {
+			chosen[name], other, obj = obj, obj, other
+		}
+		slog.Warn("Binlog is stored more than once, ignoring a copy", "file", name, "reading", other.key, "ignored", obj.key)
+	}
+
+	files := make([]binlog.FileInfo, 0, len(chosen))
+	for name, obj := range chosen {
+		x.objects[name] = obj
+		info := binlog.FileInfo{Name: name}
+		if obj.compression == "" && obj.size > 0 {
+			info.Size = obj.size
+		}
+		files = append(files, info)
+	}
+	return binlog.SortFiles(files)
+}
+
+// open reads binlogFile from offset through ranged reads, asking stat for the size of a
+// binlog that was not listed, which is then taken to be uncompressed, or was listed
+// without its size
+func (x *objectIndex) open(fetch rangeFetcher, binlogFile string, offset int64, stat func(key string) (int64, error)) (io.ReadCloser, error) {
+	x.mu.Lock()
+	obj, ok := x.objects[binlogFile]
+	x.mu.Unlock()
+	if !ok {
+		size, err := stat(binlogFile)
+		if err != nil {
+			return nil, err
+		}
+		obj = object{key: binlogFile, size: size}
+		x.add([]object{obj})
+	} else if obj.size < 0 {
+		size, err := stat(obj.key)
+		if err != nil {
+			return nil, err
+		}
+		obj.size = size
+		x.mu.Lock()
+		x.objects[binlogFile] = obj
+		x.mu.Unlock()
+	}
+
+	if obj.compression == "" {
+		return newRangedReader(fetch, obj.key, offset, obj.size), nil
+	}
+	return decompress(newRangedReader(fetch, obj.key, 0, obj.size), obj.compression, offset)
+}
+
+// decompress reads a compressed binlog from offset in its decompressed content. Only the
+// compressed bytes up to the point read are fetched.
+func decompress(r io.ReadCloser, compression string, offset int64) (io.ReadCloser, error) {
+	var dr io.ReadCloser
+	switch compression {
+	case ".gz":
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			_ = r.Close()
+			return nil, fmt.Errorf("failed to read gzip header: %w", err)
+		}
+		dr = gz
+	case ".zst":
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			_ = r.Close()
+			return nil, fmt.Errorf("failed to read zstd header: %w", err)
+		}
+		dr = zr.IOReadCloser()
+	default:
+		_ = r.Close()
+		return nil, fmt.Errorf("unsupported compression %s", compression)
+	}
+
+	d := &decompressed{Reader: dr, decoder: dr, source: r}
+	// Compressed streams cannot seek, so the bytes before offset are decompressed and dropped
+	if _, err := io.CopyN(io.Discard, d, offset); err != nil {
+		_ = d.Close()
+		return nil, fmt.Errorf("failed to skip to %d: %w", offset, err)
+	}
+	return d, nil
+}
+
+// decompressed is a decompressing reader that closes its source
+type decompressed struct {
+	io.Reader
+	decoder io.Closer
+	source  io.Closer
+}
+
+func (d *decompressed) Close() error {
+	_ = d.decoder.Close()
+	return d.source.Close()
+}
@@ -0,0 +1,180 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+)
+
+// compress returns data compressed for the given extension
+func compress(t testing.TB, data []byte, ext string) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch ext {
+	case ".gz":
+		w = gzip.NewWriter(&buf)
+	case ".zst":
+		zw, err := zstd.NewWriter(&buf)
+		require.NoError(t, err)
+		w = zw
+	default:
+		return data
+	}
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestObjectIndex(t *testing.T) {
+	var index objectIndex
+	files := index.add([]object{
+		{key: "mysql-bin.000002.zst", size: 10},
+		{key: "mysql-bin.000001.gz", size: 20},
+		{key: "mysql-bin.000003", size: 30},
+		{key: "mysql-bin.index", size: 40},
+		{key: "notes.txt.gz", size: 50},
+	})
+
+	assert.Equal(t, []binlog.FileInfo{
+		{Name: "mysql-bin.000001"},
+		{Name: "mysql-bin.000002"},
+		{Name: "mysql-bin.000003", Size: 30},
+	}, files)
+	assert.Equal(t, object{key: "mysql-bin.000001.gz", size: 20, compression: ".gz"}, index.objects["mysql-bin.000001"])
+}
+
+func TestObjectIndexDuplicates(t *testing.T) {
+	var index objectIndex
+	files := index.add([]object{
+		{key: "mysql-bin.000001.gz", size: 10},
+		{key: "mysql-bin.000001", size: 30},
+		{key: "mysql-bin.000002.zst", size: 10},
+		{key: "mysql-bin.000002.gz", size: 20},
+	})
+
+	// Each binlog is listed once, from the uncompressed copy or else the first listed
+	assert.Equal(t, []binlog.FileInfo{
+		{Name: "mysql-bin.000001", Size: 30},
+		{Name: "mysql-bin.000002"},
+	}, files)
+	assert.Equal(t, "mysql-bin.000001", index.objects["mysql-bin.000001"].key)
+	assert.Equal(t, "mysql-bin.000002.zst", index.objects["mysql-bin.000002"].key)
+}
+
+func TestDirCompressed(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 6, FileSize: 16 << 10, Rate: 0.5, Seed: 1})
+	dir := t.TempDir()
+	// Mixed compression, as when the archiving job changed over time
+	exts := []string{".gz", ".zst", "", ".gz", ".zst", ""}
+	for i, f := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, f.Name+exts[i]), compress(t, f.Data, exts[i]), 0o600))
+	}
+
+	a, err := Open(context.Background(), dir)
+	require.NoError(t, err)
+	listed, err := a.ListBinlogs()
+	require.NoError(t, err)
+	names := make([]string, len(listed))
+	for i, f := range listed {
+		names[i] = f.Name
+	}
+	require.Len(t, names, len(files))
+
+	finder := &binlog.Finder{Streamer: a, Lister: a}
+	for i, f := range files {
+		target := f.Start.Add(f.End.Sub(f.Start) / 2).Truncate(time.Second).Add(500 * time.Millisecond)
+		file, exact, err := finder.Search(names, target)
+		require.NoError(t, err)
+		assert.Equal(t, f.Name, file, "compression %q", exts[i])
+		assert.True(t, exact, "compression %q", exts[i])
+	}
+
+	// Starting inside a compressed file decompresses and skips what precedes the position
+	for _, i := range []int{0, 1} {
+		start := files[i].Events[len(files[i].Events)/2]
+		stream, err := a.StreamFrom(files[i].Name, start.Pos)
+		require.NoError(t, err)
+		_, err = stream.GetEvent(context.Background()) // format description
+		require.NoError(t, err)
+		ev, err := stream.GetEvent(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, start.Pos, ev.Header.LogPos-ev.Header.EventSize, "compression %q", exts[i])
+		stream.Close()
+	}
+}
+
+func TestCompressedHeadOnly(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 1, FileSize: 16 << 20, Rate: 1000, Seed: 1})
+	for _, ext := range []string{".gz", ".zst"} {
+		t.Run(ext, func(t *testing.T) {
+			data := compress(t, files[0].Data, ext)
+			objects := &memoryObjects{objects: map[string][]byte{files[0].Name + ext: data}}
+			var index objectIndex
+			index.add([]object{{key: files[0].Name + ext, size: int64(len(data))}})
+
+			r, err := index.open(objects, files[0].Name, 0, nil)
+			require.NoError(t, err)
+			head := make([]byte, 4<<10)
+			_, err = io.ReadFull(r, head)
+			require.NoError(t, err)
+			require.NoError(t, r.Close())
+
+			assert.Equal(t, files[0].Data[:len(head)], head)
+			assert.Less(t, objects.fetched, int64(len(data)), "only the head is fetched")
+		})
+	}
+}
+
+func TestDirEncrypted(t *testing.T) {
+	const keyID = "MySQLReplicationKey_4b5e2cd5-ea0d-11ee-8c41-0242ac110002_1"
+	key := bytes.Repeat([]byte{0x42}, 32)
+	files := binlogtest.Generate(binlogtest.Options{Files: 4, FileSize: 16 << 10, Rate: 0.5, Seed: 1})
+	dir := t.TempDir()
+	// Encryption enabled after the first file, and archived encrypted files compressed
+	exts := []string{"", "", ".gz", ".zst"}
+	for i, f := range files {
+		data := f.Data
+		if i > 0 {
+			data = binlogtest.Encrypt(data, keyID, key)
+		}
+		require.NoError(t, os.WriteFile(filepath.Join(dir, f.Name+exts[i]), compress(t, data, exts[i]), 0o600))
+	}
+
+	a, err := Open(context.Background(), dir)
+	require.NoError(t, err)
+	a = Decrypt(a, binlog.Keyring{keyID: key})
+	listed, err := a.ListBinlogs()
+	require.NoError(t, err)
+	names := make([]string, len(listed))
+	for i, f := range listed {
+		names[i] = f.Name
+	}
+
+	finder := &binlog.Finder{Streamer: a, Lister: a}
+	for _, f := range files {
+		target := f.Start.Add(f.End.Sub(f.Start) / 2).Truncate(time.Second).Add(500 * time.Millisecond)
+		file, exact, err := finder.Search(names, target)
+		require.NoError(t, err)
+		assert.Equal(t, f.Name, file)
+		assert.True(t, exact)
+
+		start := f.Events[len(f.Events)/2]
+		pos, err := finder.Locate(f.Name, start.Time, binlog.AlignEvent, 0)
+		require.NoError(t, err)
+		assert.Equal(t, f.Name, pos.File)
+		assert.LessOrEqual(t, pos.Pos, start.Pos, f.Name)
+	}
+}
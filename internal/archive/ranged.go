@@ -0,0 +1,84 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+)
+
+// Chunk sizes of ranged reads. The first read covers the head of the file, which is all
+// a probe usually needs; reads double in size as a scan carries on through the file.
+const (
+	minChunk = 64 << 10
+	maxChunk = 8 << 20
+)
+
+// rangeFetcher reads byte ranges of archived objects
+type rangeFetcher interface {
+	// fetchRange returns a reader for length bytes of the object from offset, or a
+	// wholeBody reading on to its end
+	fetchRange(key string, offset, length int64) (io.ReadCloser, error)
+}
+
+// wholeBody is returned by fetchRange when the store sent the rest of the object instead
+// of the range, e.g. a web server ignoring Range requests. The ranged reader then reads on
+// through it rather than downloading the object again for each chunk.
+type wholeBody struct {
+	io.ReadCloser
+}
+
+// rangedReader reads an object of known size sequentially through ranged reads, so that
+// a reader closed early has only downloaded what it read, rounded up to a chunk
+type rangedReader struct {
+	fetch  rangeFetcher
+	key    string
+	offset int64
+	size   int64
+	chunk  int64
+	body   io.ReadCloser
+	// left is the number of bytes of body not yet read
+	left int64
+}
+
+func newRangedReader(fetch rangeFetcher, key string, offset, size int64) *rangedReader {
+	return &rangedReader{fetch: fetch, key: key, offset: offset, size: size, chunk: minChunk}
+}
+
+func (r *rangedReader) Read(p []byte) (int, error) {
+	if r.body == nil {
+		if r.offset >= r.size {
+			return 0, io.EOF
+		}
+		length := min(r.chunk, r.size-r.offset)
+		body, err := r.fetch.fetchRange(r.key, r.offset, length)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read %s at %d: %w", r.key, r.offset, err)
+		}
+		if _, ok := body.(wholeBody); ok {
+			length = r.size - r.offset
+		}
+		r.body, r.left = body, length
+		r.chunk = min(r.chunk*2, maxChunk)
+	}
+
+	n, err := r.body.Read(p[:min(int64(len(p)), r.left)])
+	r.offset += int64(n)
+	r.left -= int64(n)
+	if r.left == 0 {
+		_ = r.body.Close()
+		r.body = nil
+		return n, nil
+	}
+	if err == io.EOF {
+		return n, fmt.Errorf("read of %s ended early at %d: %w", r.key, r.offset, io.ErrUnexpectedEOF)
+	}
+	return n, err
+}
+
+func (r *rangedReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
@@ -0,0 +1,63 @@
+package archive
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryObjects serves byte ranges of in-memory objects, counting the bytes served
+type memoryObjects struct {
+	objects map[string][]byte
+	fetched int64
+	fetches int
+}
+
+func (m *memoryObjects) fetchRange(name string, offset, length int64) (io.ReadCloser, error) {
+	m.fetched += length
+	m.fetches++
+	return io.NopCloser(bytes.NewReader(m.objects[name][offset : offset+length])), nil
+}
+
+func TestRangedReader(t *testing.T) {
+	data := make([]byte, 1<<20)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	tests := []struct {
+		name    string
+		offset  int64
+		fetches int
+	}{
+		// Chunks of 64KiB, 128KiB, 256KiB and 512KiB, the last one cut short
+		{name: "Whole object", offset: 0, fetches: 5},
+		{name: "From offset", offset: 900 << 10, fetches: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := &memoryObjects{objects: map[string][]byte{"binlog.000001": data}}
+			r := newRangedReader(objects, "binlog.000001", tt.offset, int64(len(data)))
+			got, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, data[tt.offset:], got)
+			assert.Equal(t, tt.fetches, objects.fetches)
+			assert.Equal(t, int64(len(data))-tt.offset, objects.fetched)
+			assert.NoError(t, r.Close())
+		})
+	}
+}
+
+func TestRangedReaderClosedEarly(t *testing.T) {
+	objects := &memoryObjects{objects: map[string][]byte{"binlog.000001": make([]byte, 1<<20)}}
+	r := newRangedReader(objects, "binlog.000001", 0, 1<<20)
+
+	_, err := io.ReadFull(r, make([]byte, 100))
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, int64(minChunk), objects.fetched)
+}
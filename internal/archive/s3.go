@@ -0,0 +1,113 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// S3 reads binlogs archived under a prefix of an S3 bucket. Only objects directly under
+// the prefix whose names end in a sequence number, like mysql-bin.000012, optionally
+// compressed as mysql-bin.000012.gz or .zst, are listed.
+type S3 struct {
+	binlog.FileStreamer
+	ctx    context.Context
+	client *s3.Client
+	bucket string
+	// prefix is empty or ends with a slash
+	prefix string
+	index  objectIndex
+}
+
+// NewS3 returns the archive under prefix in bucket, accessed through client with requests
+// made under ctx
+func NewS3(ctx context.Context, client *s3.Client, bucket, prefix string) *S3 {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	a := &S3{ctx: ctx, client: client, bucket: bucket, prefix: prefix}
+	a.FileStreamer = binlog.FileStreamer{Reader: a}
+	return a
+}
+
+// openS3 opens an s3://bucket/prefix URL with the default AWS credential chain. The
+// region and endpoint query parameters override the configured region and endpoint, the
+// latter for S3-compatible stores such as MinIO.
+func openS3(ctx context.Context, u *url.URL) (*S3, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing bucket in %s", u.Redacted())
+	}
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	query := u.Query()
+	if region := query.Get("region"); region != "" {
+		cfg.Region = region
+	}
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint := query.Get("endpoint"); endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return NewS3(ctx, client, u.Host, strings.TrimPrefix(u.Path, "/")), nil
+}
+
+// ListBinlogs lists the binlogs under the prefix, ordered by name and sequence number
+func (a *S3) ListBinlogs() ([]binlog.FileInfo, error) {
+	var listed []object
+	paginator := s3.NewListObjectsV2Paginator(a.client, &s3.ListObjectsV2Input{
+		Bucket:    aws.String(a.bucket),
+		Prefix:    aws.String(a.prefix),
+		Delimiter: aws.String("/"),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(a.ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list s3://%s/%s: %w", a.bucket, a.prefix, err)
+		}
+		for _, obj := range page.Contents {
+			listed = append(listed, object{key: path.Base(aws.ToString(obj.Key)), size: aws.ToInt64(obj.Size)})
+		}
+	}
+	return a.index.add(listed), nil
+}
+
+// OpenAt reads binlogFile from offset in growing ranged GETs
+func (a *S3) OpenAt(binlogFile string, offset int64) (io.ReadCloser, error) {
+	return a.index.open(a, binlogFile, offset, a.stat)
+}
+
+// stat asks for the size of an object
+func (a *S3) stat(key string) (int64, error) {
+	head, err := a.client.HeadObject(a.ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(a.bucket),
+		Key:    aws.String(a.prefix + key),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat s3://%s/%s%s: %w", a.bucket, a.prefix, key, err)
+	}
+	return aws.ToInt64(head.ContentLength), nil
+}
+
+func (a *S3) fetchRange(key string, offset, length int64) (io.ReadCloser, error) {
+	out, err := a.client.GetObject(a.ctx, &s3.GetObjectInput{
+		Bucket: aws.String(a.bucket),
+		Key:    aws.String(a.prefix + key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
@@ -0,0 +1,142 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+)
+
+// fakeS3 serves the ListObjectsV2, HeadObject and GetObject calls of a single bucket,
+// counting the object bytes sent
+type fakeS3 struct {
+	bucket  string
+	objects map[string][]byte
+	sent    atomic.Int64
+}
+
+type listBucketResult struct {
+	XMLName  xml.Name `xml:"ListBucketResult"`
+	Contents []struct {
+		Key  string
+		Size int
+	}
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+s.bucket)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	key = strings.TrimPrefix(key, "/")
+
+	if key == "" && r.URL.Query().Get("list-type") == "2" {
+		var res listBucketResult
+		prefix := r.URL.Query().Get("prefix")
+		for k, data := range s.objects {
+			if rest, ok := strings.CutPrefix(k, prefix); ok && !strings.Contains(rest, "/") {
+				res.Contents = append(res.Contents, struct {
+					Key  string
+					Size int
+				}{k, len(data)})
+			}
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		_ = xml.NewEncoder(w).Encode(res)
+		return
+	}
+
+	data, ok := s.objects[key]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("<Error><Code>NoSuchKey</Code></Error>"))
+		return
+	}
+	http.ServeContent(countingWriter{w, &s.sent}, r, key, time.Time{}, bytes.NewReader(data))
+}
+
+// countingWriter counts the bytes of response bodies
+type countingWriter struct {
+	http.ResponseWriter
+	n *atomic.Int64
+}
+
+func (w countingWriter) Write(p []byte) (int, error) {
+	w.n.Add(int64(len(p)))
+	return w.ResponseWriter.Write(p)
+}
+
+func TestS3Search(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 8, FileSize: 1 << 20, Rate: 50, Seed: 1})
+	server := &fakeS3{bucket: "backups", objects: map[string][]byte{"mysql/notes.txt": []byte("not a binlog")}}
+	var total int64
+	for _, f := range files {
+		server.objects["mysql/"+f.Name] = f.Data
+		total += f.Size()
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_REGION", "us-east-1")
+	a, err := Open(context.Background(), "s3://backups/mysql?endpoint="+ts.URL)
+	require.NoError(t, err)
+
+	listed, err := a.ListBinlogs()
+	require.NoError(t, err)
+	names := make([]string, len(listed))
+	sizes := make(map[string]int64, len(listed))
+	for i, f := range listed {
+		names[i] = f.Name
+		sizes[f.Name] = f.Size
+		assert.Equal(t, files[i].Name, f.Name)
+		assert.Equal(t, files[i].Size(), f.Size)
+	}
+
+	// The default scan limits stop each probe well within the first ranged read
+	target := files[5].Start.Add(files[5].End.Sub(files[5].Start) / 2).Truncate(time.Second).Add(500 * time.Millisecond)
+	finder := &binlog.Finder{Streamer: a, Lister: a, Sizes: sizes}
+	file, _, err := finder.Search(names, target)
+	require.NoError(t, err)
+	assert.Equal(t, files[5].Name, file)
+	assert.Less(t, server.sent.Load(), total/10, "only file heads are downloaded")
+}
+
+func TestS3UnlistedFile(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 1, Seed: 1})
+	server := &fakeS3{bucket: "backups", objects: map[string][]byte{files[0].Name: files[0].Data}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_REGION", "us-east-1")
+	a, err := Open(context.Background(), "s3://backups?endpoint="+ts.URL)
+	require.NoError(t, err)
+
+	// The size of a file that was not listed is asked for
+	stream, err := a.StreamFrom(files[0].Name, 4)
+	require.NoError(t, err)
+	stream.Close()
+
+	_, err = a.StreamFrom("binlog.000099", 4)
+	assert.Error(t, err)
+}
+
+func TestOpenUnsupported(t *testing.T) {
+	_, err := Open(context.Background(), "ftp://backups/mysql")
+	assert.ErrorContains(t, err, "unsupported source")
+}
@@ -0,0 +1,173 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// SFTP reads binlogs in a directory of a host reached over SFTP. Only files directly in
+// the directory whose names end in a sequence number, optionally followed by .gz or .zst,
+// are listed.
+type SFTP struct {
+	binlog.FileStreamer
+	client *sftp.Client
+	dir    string
+	index  objectIndex
+}
+
+// NewSFTP returns the archive in dir, accessed through client. A relative dir is relative
+// to the directory the server starts sessions in, usually the user's home.
+func NewSFTP(client *sftp.Client, dir string) *SFTP {
+	a := &SFTP{client: client, dir: dir}
+	a.FileStreamer = binlog.FileStreamer{Reader: a}
+	return a
+}
+
+// openSFTP opens an sftp://user@host:port/path URL. The user defaults to the local one;
+// a password in the URL, keys held by ssh-agent and the default private keys in ~/.ssh,
+// or the one named by the identity query parameter, are tried in turn. The host key must
+// be in ~/.ssh/known_hosts, or the file named by the known_hosts query parameter. A path
+// starting with /~/ is relative to the user's home.
+func openSFTP(u *url.URL) (*SFTP, error) {
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("missing host in %s", u.Redacted())
+	}
+	home, _ := os.UserHomeDir()
+	query := u.Query()
+
+	username := u.User.Username()
+	if username == "" {
+		current, err := user.Current()
+		if err != nil {
+			return nil, fmt.Errorf("missing user in %s: %w", u.Redacted(), err)
+		}
+		username = current.Username
+	}
+
+	knownHosts := query.Get("known_hosts")
+	if knownHosts == "" {
+		knownHosts = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	hostKeys, err := knownhosts.New(expandHome(knownHosts, home))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read known hosts: %w", err)
+	}
+
+	var auth []ssh.AuthMethod
+	if password, ok := u.User.Password(); ok {
+		auth = append(auth, ssh.Password(password))
+	}
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+	identities := []string{query.Get("identity")}
+	if identities[0] == "" {
+		identities = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+		for i, name := range identities {
+			identities[i] = filepath.Join(home, ".ssh", name)
+		}
+	}
+	var signers []ssh.Signer
+	for _, identity := range identities {
+		key, err := os.ReadFile(expandHome(identity, home))
+		if errors.Is(err, fs.ErrNotExist) && query.Get("identity") == "" {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read identity: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse identity %s: %w", identity, err)
+		}
+		signers = append(signers, signer)
+	}
+	if len(signers) > 0 {
+		auth = append(auth, ssh.PublicKeys(signers...))
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "22")
+	}
+	conn, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{User: username, Auth: auth, HostKeyCallback: hostKeys})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to start SFTP on %s: %w", addr, err)
+	}
+
+	dir := u.Path
+	if rest, ok := strings.CutPrefix(dir, "/~"); ok {
+		dir = strings.TrimPrefix(rest, "/")
+	}
+	if dir == "" {
+		dir = "."
+	}
+	return NewSFTP(client, dir), nil
+}
+
+// expandHome replaces a leading ~/ in p with home
+func expandHome(p, home string) string {
+	if rest, ok := strings.CutPrefix(p, "~/"); ok {
+		return filepath.Join(home, rest)
+	}
+	return p
+}
+
+// ListBinlogs lists the binlogs in the directory, ordered by name and sequence number
+func (a *SFTP) ListBinlogs() ([]binlog.FileInfo, error) {
+	entries, err := a.client.ReadDir(a.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", a.dir, err)
+	}
+	listed := make([]object, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Mode().IsRegular() {
+			listed = append(listed, object{key: entry.Name(), size: entry.Size()})
+		}
+	}
+	return a.index.add(listed), nil
+}
+
+// OpenAt reads binlogFile from offset
+func (a *SFTP) OpenAt(binlogFile string, offset int64) (io.ReadCloser, error) {
+	return a.index.open(a, binlogFile, offset, a.stat)
+}
+
+func (a *SFTP) stat(key string) (int64, error) {
+	info, err := a.client.Stat(path.Join(a.dir, key))
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (a *SFTP) fetchRange(key string, offset, length int64) (io.ReadCloser, error) {
+	f, err := a.client.Open(path.Join(a.dir, key))
+	if err != nil {
+		return nil, err
+	}
+	return sectionReader{io.NewSectionReader(f, offset, length), f}, nil
+}
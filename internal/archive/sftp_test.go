@@ -0,0 +1,71 @@
+package archive
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+)
+
+// sftpClient returns a client of an SFTP server serving the local filesystem in-process
+func sftpClient(t *testing.T) *sftp.Client {
+	serverConn, clientConn := net.Pipe()
+	server, err := sftp.NewServer(serverConn)
+	require.NoError(t, err)
+	go func() { _ = server.Serve() }()
+	client, err := sftp.NewClientPipe(clientConn, clientConn)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client
+}
+
+func TestSFTP(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 6, FileSize: 32 << 10, Rate: 1, Seed: 1})
+	dir := t.TempDir()
+	for i, f := range files {
+		name, data := f.Name, f.Data
+		if i%2 == 1 {
+			name, data = name+".zst", compress(t, data, ".zst")
+		}
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o600))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "binlog.index"), []byte("./binlog.000001\n"), 0o600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "binlog.000099"), 0o700))
+
+	a := NewSFTP(sftpClient(t), dir)
+	names := listNames(t, a)
+	want := make([]string, len(files))
+	for i, f := range files {
+		want[i] = f.Name
+	}
+	assert.Equal(t, want, names)
+
+	finder := &binlog.Finder{Streamer: a, Lister: a}
+	for _, f := range files {
+		target := f.Start.Add(f.End.Sub(f.Start) / 2).Truncate(time.Second).Add(500 * time.Millisecond)
+		file, exact, err := finder.Search(names, target)
+		require.NoError(t, err)
+		assert.Equal(t, f.Name, file)
+		assert.True(t, exact)
+	}
+
+	_, err := a.OpenAt("binlog.000100", 4)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestOpenSFTPMissingHost(t *testing.T) {
+	_, err := Open(context.Background(), "sftp:///var/backups/mysql")
+	assert.ErrorContains(t, err, "missing host")
+}
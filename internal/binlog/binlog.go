@@ -3,282 +3,585 @@ package binlog
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
-	"sort"
+	"io"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/go-mysql-org/go-mysql/mysql"
 	"github.com/go-mysql-org/go-mysql/replication"
-	_ "github.com/go-sql-driver/mysql" // Import MySQL driver
+	mysqldriver "github.com/go-sql-driver/mysql"
 )
 
+// FileInfo describes a single binlog file as reported by SHOW BINARY LOGS
+type FileInfo struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	// Encrypted is only reported by MySQL 8.0.14 and later
+	Encrypted *bool `json:"encrypted,omitempty"`
+}
+
+// CompressionStats holds the server-wide binlog transaction compression counters
+// from performance_schema.binary_log_transaction_compression_stats
+type CompressionStats struct {
+	CompressionType   string  `json:"compression_type"`
+	Transactions      int64   `json:"transactions"`
+	CompressedBytes   int64   `json:"compressed_bytes"`
+	UncompressedBytes int64   `json:"uncompressed_bytes"`
+	CompressionPct    float64 `json:"compression_percentage"`
+}
+
+// openDB opens a SQL connection to the server in Config, with the connection options and
+// retry policy of the Settings
+func (s Server) openDB() (*sql.DB, error) {
+	settings := s.settings()
+	dsn, err := sqlConfig(s.Config, settings.Connection)
+	if err != nil {
+		return nil, err
+	}
+	connector, err := mysqldriver.NewConnector(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MySQL: %w", err)
+	}
+	db := sql.OpenDB(connector)
+
+	// Connect now, so that transient errors are retried before any query runs
+	ctx := s.context()
+	if err := withRetry(ctx, settings.Retry, "connect to MySQL", func() error { return db.PingContext(ctx) }); err != nil {
+		closeDB(db)
+		return nil, fmt.Errorf("failed to connect to MySQL: %w", err)
+	}
+	return db, nil
+}
+
+// closeDB closes the database connection, logging any error
+func closeDB(db *sql.DB) {
+	if cerr := db.Close(); cerr != nil {
+		slog.Warn("Error closing database connection", "error", cerr)
+	}
+}
+
 // GetBinlogFiles fetches a list of all available binlog files from MySQL
 func GetBinlogFiles(cfg replication.BinlogSyncerConfig) ([]string, error) {
-	// Create a connection string
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/", cfg.User, cfg.Password, cfg.Host, cfg.Port)
+	files, err := ListBinlogs(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	// Open a connection to the database
-	db, err := sql.Open("mysql", dsn)
+	binlogFiles := make([]string, 0, len(files))
+	for _, f := range files {
+		binlogFiles = append(binlogFiles, f.Name)
+	}
+	return binlogFiles, nil
+}
+
+// ListBinlogs fetches all available binlog files from MySQL along with their metadata
+func ListBinlogs(cfg replication.BinlogSyncerConfig) ([]FileInfo, error) {
+	return Server{Config: cfg}.ListBinlogs()
+}
+
+// ListBinlogs lists the server's binlogs with SHOW BINARY LOGS
+func (s Server) ListBinlogs() ([]FileInfo, error) {
+	db, err := s.openDB()
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to MySQL: %v", err)
+		return nil, err
 	}
-	defer func() {
-		if cerr := db.Close(); cerr != nil {
-			log.Printf("Error closing database connection: %v", cerr)
-		}
-	}()
+	defer closeDB(db)
 
 	// Execute SHOW BINARY LOGS command
 	rows, err := db.Query("SHOW BINARY LOGS")
+	if errorCode(err) == mysql.ER_NO_BINARY_LOGGING {
+		return nil, binlogDisabledError(db)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute SHOW BINARY LOGS: %v", err)
+		return nil, fmt.Errorf("failed to execute SHOW BINARY LOGS: %w", classify(err))
 	}
 	defer func() {
 		if cerr := rows.Close(); cerr != nil {
-			log.Printf("Error closing rows: %v", cerr)
+			slog.Warn("Error closing rows", "error", cerr)
 		}
 	}()
 
-	var binlogFiles []string
+	// MySQL 8.0.14+ adds an Encrypted column, so scan based on what the server returns
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+
+	var files []FileInfo
 	for rows.Next() {
-		var filename string
-		var size int64
-		if err := rows.Scan(&filename, &size); err != nil {
-			return nil, fmt.Errorf("failed to scan row: %v", err)
+		var info FileInfo
+		var encrypted sql.NullString
+		dest := []any{&info.Name, &info.Size}
+		for range columns[2:] {
+			dest = append(dest, &encrypted)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
-		binlogFiles = append(binlogFiles, filename)
+		if len(columns) > 2 && encrypted.Valid {
+			isEncrypted := strings.EqualFold(encrypted.String, "Yes")
+			info.Encrypted = &isEncrypted
+		}
+		files = append(files, info)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %v", err)
+		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	// Sort binlog files (they should already be sorted by the server, but just to be safe)
-	sort.Strings(binlogFiles)
-	return binlogFiles, nil
+	// Keep the server's index order rather than sorting by name: after RESET MASTER or a
+	// basename change, lexical order would interleave files from different histories
+	return files, nil
 }
 
-// GetTimeRangeForBinlog returns the start and end timestamps for a binlog file
-func GetTimeRangeForBinlog(syncer *replication.BinlogSyncer, binlogFile string) (start, end time.Time, err error) {
-	// Create context with timeout to prevent hanging
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// GetCompressionStats fetches binlog transaction compression statistics (MySQL 8.0.20+).
+// It returns no stats and no error when the server does not provide them.
+func GetCompressionStats(cfg replication.BinlogSyncerConfig) ([]CompressionStats, error) {
+	return Server{Config: cfg}.CompressionStats()
+}
 
-	// Get the first event timestamp
-	streamer, err := syncer.StartSync(mysql.Position{Name: binlogFile, Pos: 4})
+// CompressionStats is GetCompressionStats on the server in Config
+func (s Server) CompressionStats() ([]CompressionStats, error) {
+	db, err := s.openDB()
 	if err != nil {
-		return time.Time{}, time.Time{}, fmt.Errorf("failed to start sync from %s: %v", binlogFile, err)
-	}
-	// Make sure we close the sync after we're done
-	defer syncer.Close()
-
-	// Get first event with timestamp
-	var firstTimestamp uint32
-	var foundTimestamp bool
-
-	// Try to get the first timestamp
-	for i := 0; i < 10; i++ { // Limit attempts to prevent infinite loop
-		select {
-		case <-ctx.Done():
-			return time.Time{}, time.Time{}, fmt.Errorf("timeout getting first event timestamp for %s", binlogFile)
-		default:
-			ev, err := streamer.GetEvent(ctx)
-			if err != nil {
-				return time.Time{}, time.Time{}, fmt.Errorf("failed to get event: %v", err)
-			}
+		return nil, err
+	}
+	defer closeDB(db)
 
-			// Check for rotation event which might indicate we're reading the wrong file
-			if ev.Header.EventType == replication.ROTATE_EVENT {
-				rotateEvent := ev.Event.(*replication.RotateEvent)
-				nextFile := string(rotateEvent.NextLogName)
-				if nextFile != binlogFile {
-					log.Printf("Detected rotation from %s to %s", binlogFile, nextFile)
-					// If this is just the start event pointing to itself, continue
-					if i == 0 && nextFile == binlogFile {
-						continue
-					}
-				}
-			}
+	rows, err := db.Query(`SELECT COMPRESSION_TYPE, TRANSACTION_COUNTER, COMPRESSED_BYTES_COUNTER,
+		UNCOMPRESSED_BYTES_COUNTER, COMPRESSION_PERCENTAGE
+		FROM performance_schema.binary_log_transaction_compression_stats
+		WHERE LOG_TYPE = 'BINARY'`)
+	if err != nil {
+		// ER_NO_SUCH_TABLE: older server or performance_schema disabled
+		var mysqlErr *mysqldriver.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1146 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query compression stats: %w", classify(err))
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			slog.Warn("Error closing rows", "error", cerr)
+		}
+	}()
 
-			// Skip events with no timestamp (like FORMAT_DESCRIPTION)
-			if ev.Header.Timestamp > 0 {
-				firstTimestamp = ev.Header.Timestamp
-				foundTimestamp = true
-				goto found // Use goto instead of break to clearly exit the outer loop
-			}
+	var stats []CompressionStats
+	for rows.Next() {
+		var s CompressionStats
+		if err := rows.Scan(&s.CompressionType, &s.Transactions, &s.CompressedBytes,
+			&s.UncompressedBytes, &s.CompressionPct); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
+		stats = append(stats, s)
 	}
 
-found:
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return stats, nil
+}
 
-	if !foundTimestamp {
-		return time.Time{}, time.Time{}, fmt.Errorf("no events with timestamp found in %s", binlogFile)
+// traceEvent logs the header of every event read when debug logging is enabled
+func traceEvent(binlogFile string, ev *replication.BinlogEvent) {
+	if !slog.Default().Enabled(context.Background(), slog.LevelDebug) {
+		return
 	}
+	slog.Debug("Read event",
+		"file", binlogFile,
+		"type", ev.Header.EventType.String(),
+		"timestamp", ev.Header.Timestamp,
+		"server_id", ev.Header.ServerID,
+		"log_pos", ev.Header.LogPos,
+		"size", ev.Header.EventSize)
+}
 
-	// For the last event, we need to seek to the end
-	// This requires reading all events, which could be optimized with more knowledge of the binlog format
-	var lastTimestamp uint32 = firstTimestamp
+// DefaultProbeTimeout is how long reading the start of a binlog file may take by default
+const DefaultProbeTimeout = 5 * time.Second
+
+// DefaultHeartbeatPeriod is how often the server is asked for a heartbeat on an idle
+// replication stream when no period is configured. A heartbeat tells a scan it has read
+// every event written so far, so probes of the active file end shortly after its last
+// event instead of waiting for the probe timeout.
+const DefaultHeartbeatPeriod = 500 * time.Millisecond
+
+// Limits that can stop a probe before the end of a binlog file, reported as Probe.Truncated
+const (
+	TruncatedEvents  = "max-events"
+	TruncatedBytes   = "max-bytes"
+	TruncatedTimeout = "timeout"
+	// TruncatedError means the stream failed after some timestamps had been read
+	TruncatedError = "error"
+	// TruncatedCorrupt means an event failed its checksum after some timestamps had been
+	// read; see Corruption
+	TruncatedCorrupt = "corrupt"
+)
 
-	// Create a channel to signal completion
-	done := make(chan struct{})
+// Corruption locates the first event of a binlog file whose CRC32 checksum does not match
+// its contents. Nothing after it can be trusted, so probes stop there.
+type Corruption struct {
+	File string
+	// Pos is the position of the corrupt event
+	Pos uint32
+}
 
-	// Use a goroutine to read events with timeout
-	go func() {
-		defer close(done)
+func (c *Corruption) Error() string {
+	return fmt.Sprintf("checksum mismatch in %s at position %d", c.File, c.Pos)
+}
 
-		// Limit the number of events to read
-		for i := 0; i < 1000; i++ {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				ev, err := streamer.GetEvent(ctx)
-				if err != nil {
-					// End of file or other error
-					return
-				}
+// Unwrap returns replication.ErrChecksumMismatch
+func (c *Corruption) Unwrap() error {
+	return replication.ErrChecksumMismatch
+}
 
-				if ev.Header.Timestamp > 0 {
-					lastTimestamp = ev.Header.Timestamp
-				}
-			}
-		}
-	}()
+// ScanLimits caps how much of a binlog file is read when probing its time range. The end
+// time of a file that is not read to its end is only a lower bound.
+type ScanLimits struct {
+	// MaxEvents is the number of events read from the start of the file, 0 for no limit
+	MaxEvents int
+	// MaxBytes is the number of event bytes read from the start of the file, 0 for no limit
+	MaxBytes int64
+}
 
-	// Wait for completion or timeout
-	select {
-	case <-done:
-		// Reading completed normally
-	case <-ctx.Done():
-		log.Printf("Timeout reading events from %s, using available timestamps", binlogFile)
+// DefaultScanLimits samples the first 1000 events of each file
+var DefaultScanLimits = ScanLimits{MaxEvents: 1000}
+
+// reached returns the limit stopping a scan that has read the given events and bytes, if any
+func (l ScanLimits) reached(events int, bytes int64) string {
+	switch {
+	case l.MaxEvents > 0 && events >= l.MaxEvents:
+		return TruncatedEvents
+	case l.MaxBytes > 0 && bytes >= l.MaxBytes:
+		return TruncatedBytes
 	}
+	return ""
+}
 
-	// Convert Unix timestamps to time.Time
-	startTime := time.Unix(int64(firstTimestamp), 0)
-	endTime := time.Unix(int64(lastTimestamp), 0)
+// probeContext returns the context of a probe bounded by timeout, with 0 for no limit
+func probeContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return withReadTimeout(parent, timeout)
+}
 
-	return startTime, endTime, nil
+// GetTimeRangeForBinlog returns the start and end timestamps for a binlog file, read
+// within the limits of DefaultSettings. When one of them stopped the scan, Truncated
+// names it and End is only a lower bound.
+func GetTimeRangeForBinlog(syncer *replication.BinlogSyncer, binlogFile string) (TimeRange, error) {
+	r, err := getTimeRange(context.Background(), newSyncerStreamer(syncer), binlogFile, 0, DefaultSettings.ProbeTimeout, DefaultScanLimits, TimestampHeader, nil)
+	return TimeRange{Start: r.start, End: r.end, Truncated: r.truncated}, err
 }
 
-// BinarySearchBinlogs performs a binary search on binlog files to find which contains the target timestamp
-func BinarySearchBinlogs(syncerConfig replication.BinlogSyncerConfig, binlogFiles []string, targetTime time.Time) (string, bool) {
-	if len(binlogFiles) == 0 {
-		log.Printf("Warning: No binlog files provided")
-		return "", false
-	}
+// GetEndTime returns the time of the last event in a binlog file, reading it to its end
+// whatever the scan limits. A size of 0 means the file size is unknown; see endOfFile.
+func GetEndTime(syncer *replication.BinlogSyncer, binlogFile string, size int64) (time.Time, error) {
+	return lastEventTime(context.Background(), newSyncerStreamer(syncer), binlogFile, size, TimestampHeader, nil)
+}
 
-	log.Printf("Searching through %d binlog files for timestamp %s", len(binlogFiles), targetTime.Format("2006-01-02 15:04:05"))
+// GetStartTime returns the time of the first event in a binlog file, reading only as far
+// as that event. Commit timestamp sources fall back to header timestamps when the first
+// few events carry none.
+func GetStartTime(syncer *replication.BinlogSyncer, binlogFile string, source TimestampSource) (time.Time, error) {
+	return startTime(context.Background(), newSyncerStreamer(syncer), binlogFile, DefaultSettings.ProbeTimeout, source)
+}
 
-	// Track files that we've checked successfully
-	validFiles := make(map[string]struct{})
-	timeRanges := make(map[string]struct{ start, end time.Time })
+// StartTime is GetStartTime reading through the Finder's streamer, with its timestamp source
+func (f *Finder) StartTime(binlogFile string) (time.Time, error) {
+	return startTime(f.readContext(), f.streamer(), binlogFile, f.probeTimeout(), f.Source)
+}
 
-	// If only one file, check if it contains the target time
-	if len(binlogFiles) == 1 {
-		syncer := replication.NewBinlogSyncer(syncerConfig)
-		start, end, err := GetTimeRangeForBinlog(syncer, binlogFiles[0])
-		if err != nil {
-			log.Printf("Warning: Could not get time range for %s: %v", binlogFiles[0], err)
-			return binlogFiles[0], false
-		}
+// EndTime is GetEndTime reading through the Finder's streamer, with its timestamp source
+// and the file size taken from Sizes. What it reads is added to Stats.
+func (f *Finder) EndTime(binlogFile string) (time.Time, error) {
+	onEvent, done := f.reader(binlogFile)
+	defer done(false)
+	return lastEventTime(f.readContext(), f.streamer(), binlogFile, f.Sizes[binlogFile], f.Source, onEvent)
+}
 
-		log.Printf("Binlog %s has time range: %s to %s",
-			binlogFiles[0],
-			start.Format("2006-01-02 15:04:05"),
-			end.Format("2006-01-02 15:04:05"))
+// TimeRange returns the time range of a binlog file from its first to its last event, as
+// a search probes it, unless the range is known or cached
+func (f *Finder) TimeRange(binlogFile string) (TimeRange, error) {
+	r, err := (&searchRun{Finder: f}).timeRange(binlogFile)
+	return TimeRange{Start: r.start, End: r.end, Truncated: r.truncated}, err
+}
+
+// FullTimeRange is TimeRange, but when the probe stops before the end of the file it reads
+// the file to its end for the time of its last event, so the range is never truncated.
+// Files that cannot be read to their end, e.g. past a corrupt event, return an error.
+func (f *Finder) FullTimeRange(binlogFile string) (TimeRange, error) {
+	r, err := f.TimeRange(binlogFile)
+	if err != nil || r.Truncated == "" {
+		return r, err
+	}
+	if r.End, err = f.EndTime(binlogFile); err != nil {
+		return TimeRange{}, fmt.Errorf("probe stopped at %s: %w", r.Truncated, err)
+	}
+	r.Truncated = ""
+	return r, nil
+}
 
-		validFiles[binlogFiles[0]] = struct{}{}
-		timeRanges[binlogFiles[0]] = struct{ start, end time.Time }{start, end}
+// startTime implements GetStartTime, bounded by timeout
+func startTime(ctx context.Context, streamer EventStreamer, binlogFile string, timeout time.Duration, source TimestampSource) (time.Time, error) {
+	ctx, cancel := probeContext(ctx, timeout)
+	defer cancel()
 
-		if !targetTime.Before(start) && !targetTime.After(end) {
-			return binlogFiles[0], true
+	stream, err := streamer.StreamFrom(binlogFile, 4)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to start sync from %s: %w", binlogFile, err)
+	}
+	defer stream.Close()
+
+	var firstHeader time.Time
+	// The first transaction follows the format description and previous GTIDs events
+	for i := 0; i < 10; i++ {
+		ev, err := stream.GetEvent(ctx)
+		if err != nil {
+			if !firstHeader.IsZero() && !interrupted(err) {
+				break
+			}
+			return time.Time{}, fmt.Errorf("failed to get event: %w", err)
 		}
+		if isHeartbeat(ev) {
+			break
+		}
+		traceEvent(binlogFile, ev)
 
-		return binlogFiles[0], false
+		if t, ok := eventTime(ev, source); ok {
+			return t, nil
+		}
+		if firstHeader.IsZero() && ev.Header.Timestamp > 0 {
+			firstHeader = time.Unix(int64(ev.Header.Timestamp), 0)
+		}
+		if endOfFile(ev, binlogFile, 0) {
+			break
+		}
 	}
+	if firstHeader.IsZero() {
+		return time.Time{}, fmt.Errorf("no events with timestamp found in %s", binlogFile)
+	}
+	return firstHeader, nil
+}
 
-	// Binary search
-	left, right := 0, len(binlogFiles)-1
-	var errorCount int
+// span is the earliest and latest of a set of timestamps
+type span struct {
+	min, max time.Time
+}
 
-	for left <= right {
-		mid := left + (right-left)/2
+func (s *span) add(t time.Time) {
+	if s.min.IsZero() || t.Before(s.min) {
+		s.min = t
+	}
+	if t.After(s.max) {
+		s.max = t
+	}
+}
 
-		// Check if we already processed this file
-		if _, exists := timeRanges[binlogFiles[mid]]; !exists {
-			// Create new syncer for each file to avoid "Sync is running" errors
-			syncer := replication.NewBinlogSyncer(syncerConfig)
-			start, end, err := GetTimeRangeForBinlog(syncer, binlogFiles[mid])
-			if err != nil {
-				log.Printf("Warning: Could not get time range for %s: %v", binlogFiles[mid], err)
-				errorCount++
-				// If we've had too many errors, return what we have
-				if errorCount > 3 {
-					log.Printf("Too many errors encountered. Stopping search.")
-					break
-				}
+// getTimeRange implements GetTimeRangeForBinlog for the given timestamp source, calling
+// onEvent (if set) with the running count of events and bytes read. The range also
+// holds the limit that stopped the scan before the end of the file, if any, and the
+// first event failing its checksum. Checksum failures before any timestamp are returned
+// as a *Corruption error. A size of 0 means the file size is unknown; see endOfFile. A
+// timeout of 0 leaves the probe unbounded.
+func getTimeRange(ctx context.Context, streamer EventStreamer, binlogFile string, size int64, timeout time.Duration, limits ScanLimits, source TimestampSource, onEvent func(events int, bytes int64)) (fileRange, error) {
+	// Create context with timeout to prevent hanging
+	ctx, cancel := probeContext(ctx, timeout)
+	defer cancel()
 
-				// Try to continue with the search
-				if mid > 0 {
-					right = mid - 1
-				} else {
-					left = mid + 1
-				}
-				continue
+	stream, err := streamer.StreamFrom(binlogFile, 4)
+	if err != nil {
+		return fileRange{}, fmt.Errorf("failed to start sync from %s: %w", binlogFile, err)
+	}
+	// Make sure we close the stream after we're done
+	defer stream.Close()
+
+	// Multi-threaded replica appliers can write slightly out-of-order timestamps, so track
+	// the minimum and maximum rather than trusting the first and last events. Header
+	// timestamps are kept as well, since commit timestamps are missing before MySQL 8.0.1
+	// and in files without transactions.
+	var found, header span
+	var events int
+	var bytes int64
+	var truncated string
+	var corrupt *Corruption
+	// next is the position of the event to be read
+	next := uint32(4)
+	for {
+		// Keep reading until the first timestamp, however small the limits
+		if !header.min.IsZero() {
+			if truncated = limits.reached(events, bytes); truncated != "" {
+				break
 			}
+		}
 
-			log.Printf("Binlog %s has time range: %s to %s",
-				binlogFiles[mid],
-				start.Format("2006-01-02 15:04:05"),
-				end.Format("2006-01-02 15:04:05"))
-
-			validFiles[binlogFiles[mid]] = struct{}{}
-			timeRanges[binlogFiles[mid]] = struct{ start, end time.Time }{start, end}
+		ev, err := stream.GetEvent(ctx)
+		if errors.Is(err, io.EOF) && !header.min.IsZero() {
+			break
+		}
+		if errors.Is(err, replication.ErrChecksumMismatch) {
+			corrupt = &Corruption{File: binlogFile, Pos: next}
+			if header.min.IsZero() {
+				return fileRange{}, corrupt
+			}
+			truncated = TruncatedCorrupt
+			break
+		}
+		if errors.Is(err, ErrEventEncryption) {
+			// Only the format description could be read, which says nothing of the events
+			return fileRange{}, err
+		}
+		if interrupted(err) {
+			return fileRange{}, err
+		}
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				if header.min.IsZero() {
+					return fileRange{}, fmt.Errorf("timeout getting first event timestamp for %s", binlogFile)
+				}
+				truncated = TruncatedTimeout
+				break
+			}
+			if header.min.IsZero() {
+				return fileRange{}, fmt.Errorf("failed to get event: %w", err)
+			}
+			slog.Warn("Error reading events, using available timestamps", "file", binlogFile, "error", err)
+			truncated = TruncatedError
+			break
+		}
+		if isHeartbeat(ev) {
+			break
+		}
+		traceEvent(binlogFile, ev)
+		// Events made up by the server, like the rotate event starting a stream, have none
+		if ev.Header.LogPos > 0 {
+			next = ev.Header.LogPos
+		}
+		events++
+		bytes += int64(ev.Header.EventSize)
+		if onEvent != nil {
+			onEvent(events, bytes)
 		}
 
-		timeRange := timeRanges[binlogFiles[mid]]
-		start, end := timeRange.start, timeRange.end
+		if t, ok := eventTime(ev, source); ok {
+			found.add(t)
+		}
+		if ev.Header.Timestamp > 0 {
+			header.add(time.Unix(int64(ev.Header.Timestamp), 0))
+		}
 
-		// Target time is within this binlog's range
-		if !targetTime.Before(start) && !targetTime.After(end) {
-			return binlogFiles[mid], true
+		if endOfFile(ev, binlogFile, size) {
+			break
 		}
+	}
 
-		// Target time is before this binlog
-		if targetTime.Before(start) {
-			right = mid - 1
-		} else {
-			// Target time is after this binlog
-			left = mid + 1
+	if found.min.IsZero() {
+		if header.min.IsZero() {
+			return fileRange{}, fmt.Errorf("no events with timestamp found in %s", binlogFile)
+		}
+		if source != TimestampHeader {
+			slog.Debug("No commit timestamps found, using header timestamps", "file", binlogFile, "source", source.String())
 		}
+		found = header
 	}
 
-	// If we didn't find an exact match, return the closest binlog that's before the target time
-	if len(validFiles) > 0 {
-		// Find the closest valid file that's before the target time
-		var closestFile string
-		var closestEnd time.Time
+	if truncated != "" {
+		slog.Info("Probe stopped before the end of the binlog, its end time is a lower bound",
+			"file", binlogFile, "limit", truncated, "events", events, "bytes", bytes)
+	}
+	return fileRange{start: found.min, end: found.max, truncated: truncated, corrupt: corrupt}, nil
+}
 
-		for file := range validFiles {
-			timeRange := timeRanges[file]
-			if !targetTime.Before(timeRange.end) {
-				if closestFile == "" || timeRange.end.After(closestEnd) {
-					closestFile = file
-					closestEnd = timeRange.end
-				}
-			}
+// endOfFile reports whether ev is the last event of binlogFile: the rotate event closing
+// it or, when the size listed by SHOW BINARY LOGS is known, the event ending at that size.
+// The active file has no rotate event yet, so without its size a scan reads on until a
+// heartbeat (see isHeartbeat), or until it times out on a stream without heartbeats.
+func endOfFile(ev *replication.BinlogEvent, binlogFile string, size int64) bool {
+	// The rotate event sent at the start of the stream has no timestamp
+	if rotate, ok := ev.Event.(*replication.RotateEvent); ok && ev.Header.Timestamp > 0 && string(rotate.NextLogName) != binlogFile {
+		return true
+	}
+	return size > 0 && int64(ev.Header.LogPos) >= size
+}
+
+// isHeartbeat reports whether ev is a heartbeat, which the server sends on a replication
+// stream that has been idle for the heartbeat period: every event written so far has
+// been sent, so a scan reading to the end of the active file can stop
+func isHeartbeat(ev *replication.BinlogEvent) bool {
+	return ev.Header.EventType == replication.HEARTBEAT_EVENT || ev.Header.EventType == replication.HEARTBEAT_LOG_EVENT_V2
+}
+
+// EstimatePosition guesses where in a file of the given size the target time falls,
+// assuming events were written at a steady rate between start and end. Without a usable
+// range it returns the start of the file.
+func EstimatePosition(start, end time.Time, size int64, target time.Time) uint32 {
+	const headerSize = 4
+	if size <= headerSize || !end.After(start) || !target.After(start) {
+		return headerSize
+	}
+	if !target.Before(end) {
+		return uint32(size)
+	}
+	fraction := float64(target.Sub(start)) / float64(end.Sub(start))
+	return uint32(headerSize + fraction*float64(size-headerSize))
+}
+
+// lastEventTime reads a binlog file to its end and returns the latest timestamp in it.
+// Unlike the sampled end time from getTimeRange it is exact, but it reads the whole file.
+// A size of 0 means the file size is unknown; see endOfFile.
+func lastEventTime(ctx context.Context, streamer EventStreamer, binlogFile string, size int64, source TimestampSource, onEvent func(events int, bytes int64)) (time.Time, error) {
+	// Reading a whole file is sequential, so allow as long as locating a position
+	ctx, cancel := withReadTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	stream, err := streamer.StreamFrom(binlogFile, 4)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to start sync from %s: %w", binlogFile, err)
+	}
+	defer stream.Close()
+
+	var last, lastHeader time.Time
+	var bytes int64
+	for events := 1; ; events++ {
+		ev, err := stream.GetEvent(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return time.Time{}, fmt.Errorf("end of %s not reached: %w", binlogFile, err)
+		}
+		if isHeartbeat(ev) {
+			break
+		}
+		traceEvent(binlogFile, ev)
+		bytes += int64(ev.Header.EventSize)
+		if onEvent != nil {
+			onEvent(events, bytes)
 		}
 
-		if closestFile != "" {
-			return closestFile, false
+		// Timestamps can be slightly out of order, so keep the latest rather than the last
+		if t, ok := eventTime(ev, source); ok && t.After(last) {
+			last = t
+		}
+		if t := time.Unix(int64(ev.Header.Timestamp), 0); ev.Header.Timestamp > 0 && t.After(lastHeader) {
+			lastHeader = t
+		}
+		if endOfFile(ev, binlogFile, size) {
+			break
 		}
 	}
-
-	// If no match found and we have files, return the first file
-	if len(binlogFiles) > 0 {
-		return binlogFiles[0], false
+	// Commit timestamps are missing before MySQL 8.0.1 and in files without transactions
+	if last.IsZero() {
+		return lastHeader, nil
 	}
+	return last, nil
+}
 
-	return "", false
+// BinarySearchBinlogs performs a binary search on binlog files to find which contains the target timestamp
+func BinarySearchBinlogs(syncerConfig replication.BinlogSyncerConfig, binlogFiles []string, targetTime time.Time) (string, bool) {
+	finder := &Finder{Config: syncerConfig}
+	res := finder.Find(binlogFiles, targetTime)
+	return res.File, res.Exact()
 }
@@ -1,12 +1,14 @@
 package binlog
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/go-mysql-org/go-mysql/replication"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockBinlogSyncer is a mock for the BinlogSyncer
@@ -76,8 +78,151 @@ func TestBinarySearchBinlogs(t *testing.T) {
 	}
 }
 
+func TestScanLimitsReached(t *testing.T) {
+	tests := []struct {
+		name     string
+		limits   ScanLimits
+		events   int
+		bytes    int64
+		expected string
+	}{
+		{name: "No limits", limits: ScanLimits{}, events: 1e6, bytes: 1e9, expected: ""},
+		{name: "Below both", limits: ScanLimits{MaxEvents: 10, MaxBytes: 1000}, events: 9, bytes: 999, expected: ""},
+		{name: "Events reached", limits: ScanLimits{MaxEvents: 10}, events: 10, bytes: 1e9, expected: TruncatedEvents},
+		{name: "Bytes reached", limits: ScanLimits{MaxEvents: 10, MaxBytes: 1000}, events: 3, bytes: 1000, expected: TruncatedBytes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.limits.reached(tt.events, tt.bytes))
+		})
+	}
+}
+
+func TestProbeContext(t *testing.T) {
+	ctx, cancel := probeContext(context.Background(), time.Minute)
+	deadline, ok := ctx.Deadline()
+	cancel()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	// No limit at all
+	ctx, cancel = probeContext(context.Background(), 0)
+	defer cancel()
+	_, ok = ctx.Deadline()
+	assert.False(t, ok)
+	assert.NoError(t, ctx.Err())
+}
+
+func TestFinderProbeTimeout(t *testing.T) {
+	assert.Equal(t, DefaultProbeTimeout, (&Finder{}).probeTimeout())
+	assert.Zero(t, (&Finder{Settings: &Settings{}}).probeTimeout(), "no limit")
+	// A timeout of its own overrides the settings
+	assert.Equal(t, time.Minute, (&Finder{Settings: &Settings{ProbeTimeout: time.Second}, ProbeTimeout: time.Minute}).probeTimeout())
+}
+
+func TestEndOfFile(t *testing.T) {
+	rotate := func(timestamp uint32, next string) *replication.BinlogEvent {
+		return &replication.BinlogEvent{
+			Header: &replication.EventHeader{Timestamp: timestamp, EventType: replication.ROTATE_EVENT},
+			Event:  &replication.RotateEvent{NextLogName: []byte(next), Position: 4},
+		}
+	}
+	query := func(logPos uint32) *replication.BinlogEvent {
+		return &replication.BinlogEvent{
+			Header: &replication.EventHeader{Timestamp: 1700000000, LogPos: logPos, EventType: replication.QUERY_EVENT},
+			Event:  &replication.QueryEvent{},
+		}
+	}
+
+	assert.False(t, endOfFile(rotate(0, "binlog.000001"), "binlog.000001", 0), "rotate at the start of the stream")
+	assert.True(t, endOfFile(rotate(1700000000, "binlog.000002"), "binlog.000001", 0), "rotate closing the file")
+	assert.False(t, endOfFile(query(500), "binlog.000001", 0), "size unknown")
+	assert.False(t, endOfFile(query(500), "binlog.000001", 1000), "before the listed size")
+	assert.True(t, endOfFile(query(1000), "binlog.000001", 1000), "at the listed size")
+}
+
+func TestGetTimeRangeStopsAtHeartbeat(t *testing.T) {
+	// The active file has no closing rotate event, and its size is not known
+	events := append(transaction(100, 1700000000), transaction(200, 1700000010)...)
+	events = append(events, &replication.BinlogEvent{
+		Header: &replication.EventHeader{EventType: replication.HEARTBEAT_LOG_EVENT_V2, LogPos: 280},
+		Event:  &replication.GenericEvent{},
+	})
+	streamer := &fakeStreamer{names: []string{"binlog.000001"}, files: map[string][]*replication.BinlogEvent{"binlog.000001": events}}
+
+	start := time.Now()
+	r, err := getTimeRange(context.Background(), streamer, "binlog.000001", 0, 10*time.Second, DefaultScanLimits, TimestampHeader, nil)
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), time.Second, "waited for the probe timeout")
+	assert.Equal(t, time.Unix(1700000000, 0), r.start)
+	assert.Equal(t, time.Unix(1700000010, 0), r.end)
+	assert.Empty(t, r.truncated)
+
+	last, err := lastEventTime(context.Background(), streamer, "binlog.000001", 0, TimestampHeader, nil)
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1700000010, 0), last)
+}
+
+func TestEstimatePosition(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(100 * time.Second)
+
+	tests := []struct {
+		name     string
+		start    time.Time
+		end      time.Time
+		size     int64
+		target   time.Time
+		expected uint32
+	}{
+		{name: "Middle", start: start, end: end, size: 1004, target: start.Add(50 * time.Second), expected: 504},
+		{name: "Before start", start: start, end: end, size: 1004, target: start.Add(-time.Second), expected: 4},
+		{name: "After end", start: start, end: end, size: 1004, target: end.Add(time.Second), expected: 1004},
+		{name: "Single second", start: start, end: start, size: 1004, target: start, expected: 4},
+		{name: "Unknown size", start: start, end: end, size: 0, target: start.Add(50 * time.Second), expected: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, EstimatePosition(tt.start, tt.end, tt.size, tt.target))
+		})
+	}
+}
+
 // TestGetBinlogFiles would test the GetBinlogFiles function
 // TestGetTimeRangeForBinlog would test the GetTimeRangeForBinlog function
 
 // In a real test suite, you would set up a test MySQL server
 // or use mocks to simulate MySQL responses
+
+func TestFinderStartTime(t *testing.T) {
+	events := append(transaction(100, 1700000000), transaction(200, 1700000010)...)
+	streamer := &fakeStreamer{names: []string{"binlog.000001"}, files: map[string][]*replication.BinlogEvent{"binlog.000001": events}}
+	f := &Finder{Streamer: streamer}
+
+	start, err := f.StartTime("binlog.000001")
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1700000000, 0), start)
+}
+
+func TestFinderFullTimeRange(t *testing.T) {
+	var events []*replication.BinlogEvent
+	for i := range uint32(3) {
+		events = append(events, transaction(100+100*i, 1700000000+10*i)...)
+	}
+	streamer := &fakeStreamer{names: []string{"binlog.000001"}, files: map[string][]*replication.BinlogEvent{"binlog.000001": events}}
+	// The file ends with the last event, rather than streaming on
+	sizes := map[string]int64{"binlog.000001": 380}
+	settings := &Settings{ScanLimits: ScanLimits{MaxEvents: 2}}
+
+	r, err := (&Finder{Streamer: streamer, Sizes: sizes, Settings: settings}).TimeRange("binlog.000001")
+	require.NoError(t, err)
+	assert.Equal(t, TruncatedEvents, r.Truncated)
+
+	r, err = (&Finder{Streamer: streamer, Sizes: sizes, Settings: settings}).FullTimeRange("binlog.000001")
+	require.NoError(t, err)
+	assert.Empty(t, r.Truncated)
+	assert.Equal(t, time.Unix(1700000000, 0), r.Start)
+	assert.Equal(t, time.Unix(1700000020, 0), r.End, "the file was read to its end")
+}
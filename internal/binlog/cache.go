@@ -0,0 +1,54 @@
+package binlog
+
+import "sync"
+
+// CacheKey identifies a probed binlog file. Size is the file size listed by
+// SHOW BINARY LOGS: ranges are only cached for files of known size, as the active file
+// keeps growing under the same name.
+type CacheKey struct {
+	// Server is the HOST:PORT of the server the file was probed on
+	Server string
+	// ServerUUID is the server's @@server_uuid, if known, so that a server rebuilt behind
+	// the same address does not get the ranges of the old one
+	ServerUUID string
+	// Source is the timestamp source the range was read with
+	Source TimestampSource
+	// Origin is the origin filter the range was read through, as OriginFilter.String
+	Origin string
+	File   string
+	Size   int64
+}
+
+// Cache stores the time ranges of probed binlog files for reuse by later searches.
+// Implementations must be safe for concurrent use, and may be backed by shared storage
+// such as Redis or bolt.
+type Cache interface {
+	Get(key CacheKey) (TimeRange, bool)
+	Put(key CacheKey, r TimeRange)
+}
+
+// MemoryCache is a Cache held in process memory
+type MemoryCache struct {
+	mu     sync.RWMutex
+	ranges map[CacheKey]TimeRange
+}
+
+// NewMemoryCache creates an empty in-memory cache
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{ranges: make(map[CacheKey]TimeRange)}
+}
+
+// Get returns the cached range for key, if any
+func (c *MemoryCache) Get(key CacheKey) (TimeRange, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	r, ok := c.ranges[key]
+	return r, ok
+}
+
+// Put caches the range for key
+func (c *MemoryCache) Put(key CacheKey, r TimeRange) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ranges[key] = r
+}
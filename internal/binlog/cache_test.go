@@ -0,0 +1,79 @@
+package binlog
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCacheConcurrent(t *testing.T) {
+	cache := NewMemoryCache()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := CacheKey{Server: "db:3306", File: fmt.Sprintf("binlog.%06d", j), Size: 1024}
+				cache.Put(key, TimeRange{Start: start, End: start.Add(time.Duration(j) * time.Second)})
+				_, _ = cache.Get(key)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	r, ok := cache.Get(CacheKey{Server: "db:3306", File: "binlog.000042", Size: 1024})
+	require.True(t, ok)
+	assert.Equal(t, start.Add(42*time.Second), r.End)
+
+	_, ok = cache.Get(CacheKey{Server: "db:3306", File: "binlog.000042", Size: 2048})
+	assert.False(t, ok, "a resized file must miss the cache")
+}
+
+func TestFinderUsesCache(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewMemoryCache()
+	key := CacheKey{Server: "db:3306", ServerUUID: "3e11fa47-71ca-11e1-9e33-c80aa9429562", Source: TimestampHeader, File: "binlog.000001", Size: 1024}
+	cache.Put(key, TimeRange{Start: start, End: start.Add(time.Hour)})
+
+	tests := []struct {
+		name   string
+		uuid   string
+		source TimestampSource
+		origin OriginFilter
+		sizes  map[string]int64
+		cached bool
+	}{
+		{"Same server and source", key.ServerUUID, TimestampHeader, OriginFilter{}, map[string]int64{"binlog.000001": 1024}, true},
+		{"Rebuilt server", "5c0f7ba4-2b1e-11ef-8d3f-0242ac120002", TimestampHeader, OriginFilter{}, map[string]int64{"binlog.000001": 1024}, false},
+		{"Commit timestamps", key.ServerUUID, TimestampImmediateCommit, OriginFilter{}, map[string]int64{"binlog.000001": 1024}, false},
+		{"Origin filter", key.ServerUUID, TimestampHeader, OriginFilter{ServerID: 2}, map[string]int64{"binlog.000001": 1024}, false},
+		{"Unknown size", key.ServerUUID, TimestampHeader, OriginFilter{}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := DefaultSettings
+			settings.Origin = tt.origin
+			// The config points nowhere, so any probe would fail
+			f := &Finder{Config: replication.BinlogSyncerConfig{ServerID: 100, Host: "db", Port: 3306}, Settings: &settings, Cache: cache,
+				ServerUUID: tt.uuid, Source: tt.source, Sizes: tt.sizes}
+			var probes []Probe
+			f.OnProbe = func(p Probe) { probes = append(probes, p) }
+
+			res := f.Find([]string{"binlog.000001"}, start.Add(30*time.Minute))
+			require.Len(t, probes, 1)
+			assert.Equal(t, tt.cached, probes[0].Cached)
+			if tt.cached {
+				assert.Equal(t, "binlog.000001", res.File)
+				assert.True(t, res.Exact())
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package binlog
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// CatchUp estimates how long a replica started at a position takes to apply every event up
+// to the head of the binlogs, which keeps moving while the server writes
+type CatchUp struct {
+	// Backlog is the number of bytes of binlog from the position to the head
+	Backlog int64 `json:"backlog_bytes"`
+	// WriteRate is the rate the server wrote binlogs at since the position, in bytes per
+	// second, taken as the rate it keeps writing at
+	WriteRate float64 `json:"write_rate"`
+	// ApplyRate is the rate the replica applies binlogs at, in bytes per second
+	ApplyRate float64 `json:"apply_rate"`
+	// Seconds is the estimated time to catch up, or -1 if the replica never does, as the
+	// server writes at least as fast as it applies
+	Seconds float64 `json:"seconds"`
+}
+
+// Duration returns the estimated time to catch up, and false if the replica never does
+func (c CatchUp) Duration() (time.Duration, bool) {
+	if c.Seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(c.Seconds * float64(time.Second)), true
+}
+
+// EstimateCatchUp estimates the catch-up of a replica applying applyRate bytes per second
+// from pos, written at the given time, to the head the server reports at now. The backlog is
+// summed from the sizes of the files in between, and the write rate is that backlog spread
+// over the time since: at apply rate a and write rate w, a backlog of b takes b / (a - w).
+func EstimateCatchUp(pos Position, written time.Time, files []string, sizes map[string]int64, head Status, now time.Time, applyRate int64) (CatchUp, error) {
+	if applyRate <= 0 {
+		return CatchUp{}, fmt.Errorf("invalid apply rate %d", applyRate)
+	}
+	backlog, err := ReplayBytes(files, sizes, pos, Position{File: head.File, Pos: head.Position})
+	if err != nil {
+		return CatchUp{}, err
+	}
+
+	c := CatchUp{Backlog: backlog, ApplyRate: float64(applyRate)}
+	if elapsed := now.Sub(written).Seconds(); elapsed > 0 {
+		c.WriteRate = float64(backlog) / elapsed
+	}
+	c.Seconds = -1
+	if c.ApplyRate > c.WriteRate {
+		c.Seconds = math.Round(float64(backlog)/(c.ApplyRate-c.WriteRate)*10) / 10
+	}
+	return c, nil
+}
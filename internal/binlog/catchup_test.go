@@ -0,0 +1,45 @@
+package binlog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateCatchUp(t *testing.T) {
+	files := []string{"mysql-bin.000001", "mysql-bin.000002", "mysql-bin.000003"}
+	sizes := map[string]int64{"mysql-bin.000001": 1000, "mysql-bin.000002": 2000, "mysql-bin.000003": 500}
+	now := time.Unix(1000, 0)
+
+	tests := []struct {
+		name      string
+		pos       Position
+		written   time.Time
+		applyRate int64
+		backlog   int64
+		writeRate float64
+		seconds   float64
+	}{
+		{"Across files", Position{File: "mysql-bin.000001", Pos: 400}, now.Add(-100 * time.Second), 100, 3000, 30, 42.9},
+		{"Same file", Position{File: "mysql-bin.000003", Pos: 100}, now.Add(-10 * time.Second), 100, 300, 30, 4.3},
+		{"Never catches up", Position{File: "mysql-bin.000002", Pos: 4}, now.Add(-10 * time.Second), 100, 2396, 239.6, -1},
+		{"At the head", Position{File: "mysql-bin.000003", Pos: 400}, now, 100, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := EstimateCatchUp(tt.pos, tt.written, files, sizes, Status{File: "mysql-bin.000003", Position: 400}, now, tt.applyRate)
+			require.NoError(t, err)
+			assert.Equal(t, tt.backlog, c.Backlog)
+			assert.InDelta(t, tt.writeRate, c.WriteRate, 0.01)
+			assert.InDelta(t, tt.seconds, c.Seconds, 0.01)
+		})
+	}
+
+	_, err := EstimateCatchUp(Position{File: "mysql-bin.000003", Pos: 450}, now, files, sizes, Status{File: "mysql-bin.000003", Position: 400}, now, 100)
+	assert.ErrorContains(t, err, "is past")
+	_, err = EstimateCatchUp(Position{File: "mysql-bin.000009", Pos: 4}, now, files, sizes, Status{File: "mysql-bin.000003", Position: 400}, now, 100)
+	assert.Error(t, err)
+}
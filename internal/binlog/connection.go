@@ -0,0 +1,96 @@
+package binlog
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// ConnectionOptions tunes the connections opened to the server beyond what a
+// BinlogSyncerConfig describes
+type ConnectionOptions struct {
+	// Compress enables zlib protocol compression on SQL connections, which carry SHOW
+	// BINLOG EVENTS when bisecting a file. Replication streams are not compressed, as
+	// the replication client does not negotiate compression.
+	Compress bool
+	// AuthPlugin is the authentication plugin of the account, if it has to be allowed
+	// explicitly. mysql_clear_password lets SQL connections send the password as is, as
+	// PAM, LDAP and IAM accounts need, so it should only be used with TLS. The other
+	// plugins are negotiated with the server, and caching_sha2_password and
+	// sha256_password fetch the server's RSA public key for connections without TLS.
+	AuthPlugin string
+	// Attributes are sent as connection attributes on SQL connections, for
+	// performance_schema.session_connect_attrs to tell which program opened them.
+	// Replication connections carry the replication client's own attributes instead
+	// (_client_name=go-mysql, _client_role=binary_log_listener), as it cannot add any.
+	// Keys and values must not contain commas.
+	Attributes map[string]string
+}
+
+// Authentication plugins accepted for ConnectionOptions.AuthPlugin
+const (
+	AuthNativePassword      = "mysql_native_password"
+	AuthCachingSHA2Password = "caching_sha2_password"
+	AuthSHA256Password      = "sha256_password"
+	AuthClearPassword       = "mysql_clear_password"
+)
+
+// sqlConfig returns the driver config of a SQL connection to the server in cfg, sharing
+// the replication connection's dialer, read timeout and TLS settings
+func sqlConfig(cfg replication.BinlogSyncerConfig, opts ConnectionOptions) (*mysqldriver.Config, error) {
+	dsn := mysqldriver.NewConfig()
+	dsn.User = cfg.User
+	dsn.Passwd = cfg.Password
+	dsn.Net = "tcp"
+	dsn.Addr = net.JoinHostPort(cfg.Host, strconv.Itoa(int(cfg.Port)))
+	dsn.DialFunc = cfg.Dialer
+	dsn.ReadTimeout = cfg.ReadTimeout
+	dsn.TLS = cfg.TLSConfig
+	dsn.AllowCleartextPasswords = opts.AuthPlugin == AuthClearPassword
+	dsn.ConnectionAttributes = formatAttributes(opts.Attributes)
+	if err := dsn.Apply(mysqldriver.EnableCompression(opts.Compress)); err != nil {
+		return nil, fmt.Errorf("invalid connection options: %w", err)
+	}
+	return dsn, nil
+}
+
+// formatAttributes formats connection attributes as the SQL driver takes them, e.g.
+// "program_name:binlog-find-time,purpose:timestamp-probe"
+func formatAttributes(attrs map[string]string) string {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + ":" + attrs[k]
+	}
+	return strings.Join(pairs, ",")
+}
+
+// startSyncer starts a syncer at pos, turning a panic of the replication client while
+// it authenticates into an error. The client does not check the RSA public key it asks
+// for to send the password over a connection without TLS, so a server that sends none,
+// e.g. because it has no RSA key pair, would otherwise crash the program.
+func startSyncer(syncer *replication.BinlogSyncer, pos mysql.Position) (streamer *replication.BinlogStreamer, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			// Closing the syncer connects again to kill the failed connection, which
+			// panics the same way; once closed, closing it again does nothing
+			func() {
+				defer func() { _ = recover() }()
+				syncer.Close()
+			}()
+			err = fmt.Errorf("%w: the server did not send a usable RSA public key to authenticate without TLS (%v); "+
+				"connect with TLS or check the server's RSA key pair (caching_sha2_password_public_key_path)", ErrPermissionDenied, r)
+		}
+	}()
+	return syncer.StartSync(pos)
+}
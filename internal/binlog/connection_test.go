@@ -0,0 +1,82 @@
+package binlog
+
+import (
+	"crypto/tls"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/go-mysql-org/go-mysql/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLConfig(t *testing.T) {
+	tlsConfig := &tls.Config{ServerName: "db.example.com"}
+	cfg := replication.BinlogSyncerConfig{Host: "db.example.com", Port: 3307, User: "binlog", Password: "secret", TLSConfig: tlsConfig}
+
+	dsn, err := sqlConfig(cfg, ConnectionOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "db.example.com:3307", dsn.Addr)
+	assert.Same(t, tlsConfig, dsn.TLS)
+	assert.NotContains(t, dsn.FormatDSN(), "compress")
+
+	dsn, err = sqlConfig(cfg, ConnectionOptions{Compress: true})
+	require.NoError(t, err)
+	assert.Contains(t, dsn.FormatDSN(), "compress=true")
+
+	dsn, err = sqlConfig(cfg, ConnectionOptions{AuthPlugin: AuthClearPassword})
+	require.NoError(t, err)
+	assert.True(t, dsn.AllowCleartextPasswords)
+
+	dsn, err = sqlConfig(cfg, ConnectionOptions{Attributes: map[string]string{"program_name": "binlog-find-time", "purpose": "timestamp-probe"}})
+	require.NoError(t, err)
+	assert.Equal(t, "program_name:binlog-find-time,purpose:timestamp-probe", dsn.ConnectionAttributes)
+}
+
+func TestStartSyncerWithoutPublicKey(t *testing.T) {
+	// A caching_sha2_password server without TLS or an RSA key pair sends an empty key
+	// when asked for one for a full authentication
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	srv := server.NewServer("8.0.36", mysql.DEFAULT_COLLATION_ID, mysql.AUTH_CACHING_SHA2_PASSWORD, nil, nil)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = srv.NewCustomizedConn(conn, uncachedCredentials{}, server.EmptyHandler{})
+			}()
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	syncer := replication.NewBinlogSyncer(replication.BinlogSyncerConfig{
+		ServerID: 100, Flavor: "mysql", Host: "127.0.0.1", Port: uint16(addr.Port), User: "binlog", Password: "secret",
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	defer syncer.Close()
+
+	_, err = startSyncer(syncer, mysql.Position{Name: "binlog.000001", Pos: 4})
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	assert.ErrorContains(t, err, "RSA public key")
+}
+
+// uncachedCredentials is the account of a fake server that has not cached its
+// caching_sha2_password hash, so every login needs a full authentication
+type uncachedCredentials struct{}
+
+func (uncachedCredentials) CheckUsername(user string) (bool, error) {
+	return user == "binlog", nil
+}
+
+func (uncachedCredentials) GetCredential(user string) (string, bool, error) {
+	return "secret", user == "binlog", nil
+}
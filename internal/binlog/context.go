@@ -0,0 +1,21 @@
+package binlog
+
+import (
+	"context"
+	"errors"
+)
+
+// readContext returns the context the Finder's reads run under: its Context, or one that
+// is never canceled
+func (f *Finder) readContext() context.Context {
+	if f.Context != nil {
+		return f.Context
+	}
+	return context.Background()
+}
+
+// interrupted reports whether err comes from the context of a read being canceled,
+// rather than from its timeout
+func interrupted(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
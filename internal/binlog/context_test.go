@@ -0,0 +1,63 @@
+package binlog
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanceledContextInterruptsProbe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// The active file has no closing rotate event, so the probe waits for more events
+	events := append(transaction(100, 1700000000), transaction(200, 1700000010)...)
+	streamer := &fakeStreamer{names: []string{"binlog.000001"}, files: map[string][]*replication.BinlogEvent{"binlog.000001": events}}
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := getTimeRange(ctx, streamer, "binlog.000001", 0, 10*time.Second, DefaultScanLimits, TimestampHeader, nil)
+	assert.ErrorIs(t, err, context.Canceled, "the timestamps read so far must not be taken for the range")
+	assert.Less(t, time.Since(start), time.Second, "waited for the probe timeout")
+}
+
+func TestFinderContextStopsSearch(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 8, FileSize: 4 << 10, Rate: 0.5, Seed: 1})
+	streamer := newFakeStreamer(t, files)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var probes []Probe
+	finder := &Finder{Streamer: streamer, Context: ctx, OnProbe: func(p Probe) { probes = append(probes, p) }}
+	file, exact, err := finder.Search(streamer.names, files[5].Start)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, file)
+	assert.False(t, exact)
+	require.Len(t, probes, 1, "the search must stop at the first interrupted probe")
+	assert.Equal(t, DecisionError, probes[0].Decision)
+
+	// Another Finder in the process is not stopped
+	file, exact, err = (&Finder{Streamer: streamer}).Search(streamer.names, files[5].Start)
+	require.NoError(t, err)
+	assert.Equal(t, files[5].Name, file)
+	assert.True(t, exact)
+}
+
+func TestCanceledContextStopsRetries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var calls int
+	time.AfterFunc(50*time.Millisecond, cancel)
+	err := withRetry(ctx, RetryPolicy{Retries: 5, Backoff: time.Minute}, "test", func() error {
+		calls++
+		return io.EOF
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, calls, "retried after the context was canceled")
+}
@@ -3,4 +3,10 @@
 // It includes functionality to search through binlog files to find which file
 // contains events for a specific timestamp. The package uses binary search
 // for efficient searching through multiple binlog files.
+//
+// The search reads binlogs through the BinlogLister and EventStreamer interfaces.
+// Server implements both over replication connections to a MySQL server; other
+// implementations can serve events from elsewhere, such as test fixtures. FileStreamer
+// parses stored binlog files, such as archived copies, read through a FileReader;
+// DecryptingReader decrypts those written with binlog_encryption=ON.
 package binlog
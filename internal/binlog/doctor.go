@@ -0,0 +1,311 @@
+package binlog
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// Outcomes of a preflight check
+const (
+	CheckOK      = "ok"
+	CheckWarn    = "warn"
+	CheckFail    = "fail"
+	CheckSkipped = "skipped"
+)
+
+// Check is the outcome of a single preflight check run by Diagnose
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	// Remediation suggests how to fix a failed or warning check
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// requiredPrivileges are the global privileges needed to list and stream binlogs,
+// with the alternative names used by newer MySQL and MariaDB versions
+var requiredPrivileges = []struct {
+	name    string
+	aliases []string
+}{
+	{name: "REPLICATION SLAVE", aliases: []string{"REPLICATION REPLICA"}},
+	{name: "REPLICATION CLIENT", aliases: []string{"BINLOG MONITOR"}},
+}
+
+// Diagnose checks that the server described by cfg can be searched: that it is reachable,
+// accepts the credentials, has binary logging enabled, writes binlogs that archived copies
+// can be searched in and grants the required privileges.
+// On Amazon RDS and Aurora it also reports the binlog retention hours. Checks that depend
+// on an earlier failure are reported as skipped.
+func Diagnose(cfg replication.BinlogSyncerConfig) []Check {
+	return Server{Config: cfg}.Diagnose()
+}
+
+// Diagnose is Diagnose on the server in Config, connecting with the Settings
+func (s Server) Diagnose() []Check {
+	cfg := s.Config
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(int(cfg.Port)))
+	checks := []Check{checkReachable(s.context(), cfg, addr)}
+	if checks[0].Status == CheckFail {
+		return append(checks, skipped("Authentication", "Server version", "Binary logging", "Binlog format", "Binlog encryption", "Privileges")...)
+	}
+
+	db, err := s.openDB()
+	if err != nil {
+		return append(checks, authFailed(err, cfg.User))
+	}
+	defer closeDB(db)
+
+	auth := checkAuthentication(db, cfg.User)
+	checks = append(checks, auth)
+	if auth.Status == CheckFail {
+		return append(checks, skipped("Server version", "Binary logging", "Binlog format", "Binlog encryption", "Privileges")...)
+	}
+
+	platform := detectPlatform(db)
+	logBin := checkLogBin(db, platform)
+	checks = append(checks, checkVersion(db, platform), logBin)
+	// Managed platforms purge binlogs on their own schedule rather than binlog_expire_logs_seconds
+	if platform != "" && logBin.Status == CheckOK {
+		checks = append(checks, checkRDSRetention(db))
+	}
+	return append(checks,
+		checkBinlogFormat(db),
+		checkBinlogEncryption(db),
+		checkPrivileges(db, cfg.User),
+	)
+}
+
+func skipped(names ...string) []Check {
+	checks := make([]Check, 0, len(names))
+	for _, name := range names {
+		checks = append(checks, Check{Name: name, Status: CheckSkipped, Detail: "depends on a failed check"})
+	}
+	return checks
+}
+
+func checkReachable(ctx context.Context, cfg replication.BinlogSyncerConfig, addr string) Check {
+	dial := cfg.Dialer
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 5 * time.Second}).DialContext
+	}
+	conn, err := dial(ctx, "tcp", addr)
+	if err != nil {
+		return Check{
+			Name:        "Reachability",
+			Status:      CheckFail,
+			Detail:      err.Error(),
+			Remediation: fmt.Sprintf("Check --host and --port, that mysqld is running and listening on %s (bind_address), and that no firewall or security group blocks the connection", addr),
+		}
+	}
+	_ = conn.Close()
+	return Check{Name: "Reachability", Status: CheckOK, Detail: addr + " accepts TCP connections"}
+}
+
+func checkAuthentication(db *sql.DB, user string) Check {
+	if err := db.Ping(); err != nil {
+		return authFailed(err, user)
+	}
+	return Check{Name: "Authentication", Status: CheckOK, Detail: fmt.Sprintf("logged in as %q", user)}
+}
+
+// authFailed reports a failure to log in, with the likely fix for it
+func authFailed(err error, user string) Check {
+	check := Check{Name: "Authentication", Status: CheckFail, Detail: err.Error()}
+	var mysqlErr *mysqldriver.MySQLError
+	switch {
+	case errors.As(err, &mysqlErr) && mysqlErr.Number == 1045:
+		check.Remediation = fmt.Sprintf("Check --user and --password, and that %q is allowed to connect from this host (SELECT user, host FROM mysql.user)", user)
+	case errors.Is(err, mysqldriver.ErrCleartextPassword):
+		check.Remediation = fmt.Sprintf("%q authenticates with mysql_clear_password, e.g. through PAM or LDAP; allow it with --auth-plugin=mysql_clear_password over TLS", user)
+	default:
+		check.Remediation = "Check that the server speaks the MySQL protocol on this port and that its TLS and authentication plugin settings are supported"
+	}
+	return check
+}
+
+func checkVersion(db *sql.DB, platform string) Check {
+	var version string
+	if err := db.QueryRow("SELECT VERSION()").Scan(&version); err != nil {
+		return Check{Name: "Server version", Status: CheckWarn, Detail: err.Error()}
+	}
+	if platform != "" {
+		version += " on " + platform
+	}
+	return Check{Name: "Server version", Status: CheckOK, Detail: version}
+}
+
+func checkLogBin(db *sql.DB, platform string) Check {
+	var logBin int
+	if err := db.QueryRow("SELECT @@GLOBAL.log_bin").Scan(&logBin); err != nil {
+		return Check{Name: "Binary logging", Status: CheckFail, Detail: err.Error()}
+	}
+	if logBin == 0 {
+		return Check{
+			Name:        "Binary logging",
+			Status:      CheckFail,
+			Detail:      "log_bin is OFF",
+			Remediation: enableBinlogRemediation(platform),
+		}
+	}
+	return Check{Name: "Binary logging", Status: CheckOK, Detail: "log_bin is ON"}
+}
+
+func checkRDSRetention(db *sql.DB) Check {
+	hours, ok, err := rdsRetentionHours(db)
+	if err != nil {
+		return Check{Name: "Binlog retention", Status: CheckWarn, Detail: fmt.Sprintf("could not read binlog retention hours: %v", err)}
+	}
+	if !ok {
+		return Check{
+			Name:        "Binlog retention",
+			Status:      CheckWarn,
+			Detail:      "binlog retention hours is NULL, so binlogs are purged as soon as possible",
+			Remediation: "Keep binlogs long enough to search, e.g. CALL mysql.rds_set_configuration('binlog retention hours', 24)",
+		}
+	}
+	return Check{Name: "Binlog retention", Status: CheckOK, Detail: fmt.Sprintf("binlog retention hours is %d", hours)}
+}
+
+func checkBinlogFormat(db *sql.DB) Check {
+	var format string
+	if err := db.QueryRow("SELECT @@GLOBAL.binlog_format").Scan(&format); err != nil {
+		return Check{Name: "Binlog format", Status: CheckWarn, Detail: err.Error()}
+	}
+	if !strings.EqualFold(format, "ROW") {
+		return Check{
+			Name:        "Binlog format",
+			Status:      CheckWarn,
+			Detail:      "binlog_format is " + format,
+			Remediation: "Timestamp search works with any format, but replaying statement-based binlogs can diverge for non-deterministic statements; consider binlog_format=ROW",
+		}
+	}
+	return Check{Name: "Binlog format", Status: CheckOK, Detail: "binlog_format is ROW"}
+}
+
+func checkBinlogEncryption(db *sql.DB) Check {
+	// binlog_encryption is MySQL's and Percona Server 8.0's, encrypt_binlog Percona Server
+	// 5.7's and MariaDB's
+	rows, err := queryRows(db, "SHOW GLOBAL VARIABLES WHERE Variable_name IN ('binlog_encryption', 'encrypt_binlog')")
+	if err != nil {
+		return Check{Name: "Binlog encryption", Status: CheckWarn, Detail: fmt.Sprintf("could not read encryption settings: %v", err)}
+	}
+	vars := make(map[string]string, len(rows))
+	for _, row := range rows {
+		vars[strings.ToLower(row["variable_name"])] = row["value"]
+	}
+	// Keyring components don't show up as plugins, so none may be found
+	var keyrings []string
+	if rows, err := queryRows(db, "SELECT PLUGIN_NAME FROM information_schema.PLUGINS WHERE PLUGIN_NAME LIKE 'keyring%' AND PLUGIN_STATUS = 'ACTIVE'"); err == nil {
+		for _, row := range rows {
+			keyrings = append(keyrings, row["plugin_name"])
+		}
+	}
+	return encryptionCheck(vars, keyrings)
+}
+
+// encryptionCheck reports whether binlogs copied off the server, with the given
+// encryption variables and active keyring plugins, can be searched with --source
+func encryptionCheck(vars map[string]string, keyrings []string) Check {
+	if strings.EqualFold(vars["encrypt_binlog"], "ON") {
+		return Check{
+			Name:        "Binlog encryption",
+			Status:      CheckWarn,
+			Detail:      "encrypt_binlog is ON, so each event is encrypted",
+			Remediation: "The server decrypts the binlogs it streams, so searching it works, but archived copies of its binlogs cannot be searched with --source",
+		}
+	}
+	if !strings.EqualFold(vars["binlog_encryption"], "ON") {
+		return Check{Name: "Binlog encryption", Status: CheckOK, Detail: "binlogs are not encrypted"}
+	}
+	detail := "binlog_encryption is ON"
+	if len(keyrings) > 0 {
+		detail += " with " + strings.Join(keyrings, ", ")
+	}
+	for _, keyring := range keyrings {
+		if keyring != "keyring_file" {
+			return Check{
+				Name:        "Binlog encryption",
+				Status:      CheckWarn,
+				Detail:      detail,
+				Remediation: "The server decrypts the binlogs it streams; to search archived copies with --source, export the binlog keys from " + keyring + " to a --keyring file of <key ID> <hex key> lines",
+			}
+		}
+	}
+	return Check{Name: "Binlog encryption", Status: CheckOK, Detail: detail + "; search archived copies with --keyring"}
+}
+
+func checkPrivileges(db *sql.DB, user string) Check {
+	rows, err := db.Query("SHOW GRANTS FOR CURRENT_USER()")
+	if err != nil {
+		return Check{Name: "Privileges", Status: CheckWarn, Detail: fmt.Sprintf("could not read grants: %v", err)}
+	}
+	defer func() { _ = rows.Close() }()
+
+	var grants []string
+	for rows.Next() {
+		var grant string
+		if err := rows.Scan(&grant); err != nil {
+			return Check{Name: "Privileges", Status: CheckWarn, Detail: fmt.Sprintf("could not read grants: %v", err)}
+		}
+		grants = append(grants, grant)
+	}
+
+	missing := missingPrivileges(grants)
+	if len(missing) > 0 {
+		return Check{
+			Name:        "Privileges",
+			Status:      CheckFail,
+			Detail:      "missing " + strings.Join(missing, ", "),
+			Remediation: fmt.Sprintf("GRANT %s ON *.* TO '%s'@'<host>'", strings.Join(missing, ", "), user),
+		}
+	}
+	return Check{Name: "Privileges", Status: CheckOK, Detail: "REPLICATION SLAVE and REPLICATION CLIENT granted"}
+}
+
+// missingPrivileges returns the required privileges not granted globally by any of the
+// SHOW GRANTS statements. Privileges inherited through roles are not detected.
+func missingPrivileges(grants []string) []string {
+	var global []string
+	for _, grant := range grants {
+		upper := strings.ToUpper(grant)
+		if !strings.HasPrefix(upper, "GRANT ") {
+			continue
+		}
+		on := strings.Index(upper, " ON *.* ")
+		if on < 0 {
+			continue
+		}
+		for _, priv := range strings.Split(upper[len("GRANT "):on], ",") {
+			global = append(global, strings.TrimSpace(priv))
+		}
+	}
+
+	has := func(names ...string) bool {
+		for _, priv := range global {
+			for _, name := range names {
+				if priv == name || priv == "ALL" || priv == "ALL PRIVILEGES" {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	var missing []string
+	for _, req := range requiredPrivileges {
+		if !has(append([]string{req.name}, req.aliases...)...) {
+			missing = append(missing, req.name)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,98 @@
+package binlog
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMissingPrivileges(t *testing.T) {
+	tests := []struct {
+		name     string
+		grants   []string
+		expected []string
+	}{
+		{
+			name:     "No grants",
+			grants:   []string{"GRANT USAGE ON *.* TO `binlog`@`%`"},
+			expected: []string{"REPLICATION SLAVE", "REPLICATION CLIENT"},
+		},
+		{
+			name:     "Both granted",
+			grants:   []string{"GRANT SELECT, RELOAD, REPLICATION SLAVE, REPLICATION CLIENT ON *.* TO `binlog`@`%`"},
+			expected: nil,
+		},
+		{
+			name:     "All privileges",
+			grants:   []string{"GRANT ALL PRIVILEGES ON *.* TO 'root'@'localhost' WITH GRANT OPTION"},
+			expected: nil,
+		},
+		{
+			name:     "MariaDB names",
+			grants:   []string{"GRANT REPLICATION REPLICA, BINLOG MONITOR ON *.* TO `binlog`@`%`"},
+			expected: nil,
+		},
+		{
+			name: "Schema level grants do not count",
+			grants: []string{
+				"GRANT REPLICATION CLIENT ON *.* TO `binlog`@`%`",
+				"GRANT ALL PRIVILEGES ON `app`.* TO `binlog`@`%`",
+			},
+			expected: []string{"REPLICATION SLAVE"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, missingPrivileges(tt.grants))
+		})
+	}
+}
+
+func TestAuthFailed(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		advice string
+	}{
+		{"Wrong password", fmt.Errorf("failed to connect to MySQL: %w", &mysqldriver.MySQLError{Number: 1045}), "--user and --password"},
+		{"Cleartext plugin", fmt.Errorf("failed to connect to MySQL: %w", mysqldriver.ErrCleartextPassword), "--auth-plugin=mysql_clear_password"},
+		{"Other error", errors.New("malformed packet"), "authentication plugin settings"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := authFailed(tt.err, "binlog")
+			assert.Equal(t, CheckFail, check.Status)
+			assert.Contains(t, check.Remediation, tt.advice)
+		})
+	}
+}
+
+func TestEncryptionCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		vars     map[string]string
+		keyrings []string
+		status   string
+		advice   string
+	}{
+		{"Not encrypted", map[string]string{"binlog_encryption": "OFF"}, nil, CheckOK, ""},
+		{"No variables", map[string]string{}, nil, CheckOK, ""},
+		{"Keyring file", map[string]string{"binlog_encryption": "ON"}, []string{"keyring_file"}, CheckOK, ""},
+		{"Keyring component", map[string]string{"binlog_encryption": "ON"}, nil, CheckOK, ""},
+		{"Percona vault", map[string]string{"binlog_encryption": "ON"}, []string{"keyring_vault"}, CheckWarn, "export the binlog keys from keyring_vault"},
+		{"Percona 5.7", map[string]string{"encrypt_binlog": "ON"}, []string{"keyring_vault"}, CheckWarn, "cannot be searched with --source"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := encryptionCheck(tt.vars, tt.keyrings)
+			assert.Equal(t, tt.status, check.Status)
+			assert.Contains(t, check.Remediation, tt.advice)
+		})
+	}
+}
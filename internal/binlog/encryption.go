@@ -0,0 +1,329 @@
+package binlog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Layout of the header MySQL writes at the start of binlog files when binlog_encryption
+// is ON. The header is followed by the plain binlog, magic number included, encrypted
+// with AES-256-CTR; binlog positions count from the end of the header.
+const (
+	encryptionHeaderSize = 512
+	encryptionVersion    = 1
+
+	// Fields of the header, each a type byte followed by its value
+	encryptionFieldEnd      = 0
+	encryptionFieldKeyID    = 1 // length byte, then the keyring key ID
+	encryptionFieldPassword = 2 // file password encrypted with the keyring key
+	encryptionFieldIV       = 3 // IV of the file password encryption
+
+	filePasswordSize = 32
+)
+
+// encryptedMagic starts binlog files written with binlog_encryption=ON
+var encryptedMagic = []byte{0xfd, 'b', 'i', 'n'}
+
+// Keyring maps keyring key IDs, like MySQLReplicationKey_<server_uuid>_1, to the keys
+// that encrypt binlog file passwords
+type Keyring map[string][]byte
+
+// LoadKeyring reads the keys needed to decrypt encrypted binlogs from path, which may
+// hold any of:
+//   - the data file of the component_keyring_file component (JSON)
+//   - the data file of the keyring_file plugin
+//   - decrypted keys, one "<key ID> <hex key>" pair per line
+func LoadKeyring(path string) (Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+	keys, err := ParseKeyring(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse keyring %s: %w", path, err)
+	}
+	return keys, nil
+}
+
+// ParseKeyring parses keys in any of the formats accepted by LoadKeyring
+func ParseKeyring(data []byte) (Keyring, error) {
+	switch {
+	case bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")):
+		return parseComponentKeyring(data)
+	case bytes.HasPrefix(data, []byte(pluginKeyringHeader)):
+		return parsePluginKeyring(data)
+	default:
+		return parseKeyList(data)
+	}
+}
+
+// parseComponentKeyring parses the JSON file of component_keyring_file, which stores
+// keys hex encoded
+func parseComponentKeyring(data []byte) (Keyring, error) {
+	var file struct {
+		Elements []struct {
+			DataID string `json:"data_id"`
+			Data   string `json:"data"`
+		} `json:"elements"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	keys := Keyring{}
+	for _, e := range file.Elements {
+		key, err := hex.DecodeString(e.Data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %s: %w", e.DataID, err)
+		}
+		keys[e.DataID] = key
+	}
+	return keys, nil
+}
+
+// The keyring_file plugin stores a version header, then each key as five native
+// size_t fields (the record size and the lengths of the key ID, key type, user and key),
+// the four values and padding to a multiple of eight bytes, and finally "EOF" and a
+// digest. Key values are obfuscated by XOR with a fixed string.
+const (
+	pluginKeyringHeader    = "Keyring file version:"
+	pluginKeyringObfuscate = "*305=Ljt0*!@$Hnm(*-9-w;:"
+	pluginKeyringEOF       = "EOF"
+)
+
+func parsePluginKeyring(data []byte) (Keyring, error) {
+	const sizeT = 8
+	rest := data[len(pluginKeyringHeader)+len("2.0"):]
+	keys := Keyring{}
+	for !bytes.HasPrefix(rest, []byte(pluginKeyringEOF)) {
+		if len(rest) < 5*sizeT {
+			return nil, errors.New("truncated key record")
+		}
+		var fields [5]uint64
+		for i := range fields {
+			fields[i] = binary.LittleEndian.Uint64(rest[i*sizeT:])
+		}
+		size, idLen, typeLen, userLen, keyLen := fields[0], fields[1], fields[2], fields[3], fields[4]
+		if size > uint64(len(rest)) || 5*sizeT+idLen+typeLen+userLen+keyLen > size {
+			return nil, errors.New("truncated key record")
+		}
+		value := rest[5*sizeT : size]
+		id := string(value[:idLen])
+		obfuscated := value[idLen+typeLen+userLen : idLen+typeLen+userLen+keyLen]
+		key := make([]byte, len(obfuscated))
+		for i, b := range obfuscated {
+			key[i] = b ^ pluginKeyringObfuscate[i%len(pluginKeyringObfuscate)]
+		}
+		keys[id] = key
+		rest = rest[size:]
+	}
+	return keys, nil
+}
+
+// parseKeyList parses lines of "<key ID> <hex key>", skipping blank lines and comments
+func parseKeyList(data []byte) (Keyring, error) {
+	keys := Keyring{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a key ID and a hex key", line)
+		}
+		key, err := hex.DecodeString(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid key: %w", line, err)
+		}
+		keys[fields[0]] = key
+	}
+	return keys, scanner.Err()
+}
+
+// DecryptingReader reads binlog files through Reader, decrypting those written with
+// binlog_encryption=ON with keys from Keys. Unencrypted files are read unchanged, and
+// offsets of encrypted files are binlog positions, as in the decrypted file.
+type DecryptingReader struct {
+	Reader FileReader
+	Keys   Keyring
+
+	mu sync.Mutex
+	// ciphers caches the file key and IV of each file, so that reopening a file at a
+	// later position does not read its header again. Unencrypted files map to nil.
+	ciphers map[string]*fileCipher
+}
+
+// fileCipher holds the AES-256-CTR key and initial counter of an encrypted file
+type fileCipher struct {
+	block cipher.Block
+	iv    []byte
+}
+
+// OpenAt returns a reader for the decrypted bytes of binlogFile from offset
+func (d *DecryptingReader) OpenAt(binlogFile string, offset int64) (io.ReadCloser, error) {
+	d.mu.Lock()
+	c, cached := d.ciphers[binlogFile]
+	d.mu.Unlock()
+	if !cached {
+		r, err := d.Reader.OpenAt(binlogFile, 0)
+		if err != nil {
+			return nil, err
+		}
+		var head []byte
+		if c, head, err = d.readHeader(binlogFile, r); err != nil {
+			_ = r.Close()
+			return nil, err
+		}
+		d.mu.Lock()
+		if d.ciphers == nil {
+			d.ciphers = map[string]*fileCipher{}
+		}
+		d.ciphers[binlogFile] = c
+		d.mu.Unlock()
+
+		// Carry on with the open file when reading from its start, as probes do
+		if offset == 0 {
+			if c == nil {
+				return readCloser{io.MultiReader(bytes.NewReader(head), r), r}, nil
+			}
+			return readCloser{cipher.StreamReader{S: c.stream(0), R: r}, r}, nil
+		}
+		_ = r.Close()
+	}
+
+	if c == nil {
+		return d.Reader.OpenAt(binlogFile, offset)
+	}
+	r, err := d.Reader.OpenAt(binlogFile, offset+encryptionHeaderSize)
+	if err != nil {
+		return nil, err
+	}
+	return readCloser{cipher.StreamReader{S: c.stream(offset), R: r}, r}, nil
+}
+
+// readHeader reads the encryption header at the start of r. Files without one return a
+// nil cipher and the bytes read, which are the start of the plain file.
+func (d *DecryptingReader) readHeader(binlogFile string, r io.Reader) (*fileCipher, []byte, error) {
+	head := make([]byte, len(encryptedMagic))
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, nil, fmt.Errorf("failed to read the header of %s: %w", binlogFile, err)
+	}
+	if !bytes.Equal(head[:n], encryptedMagic) {
+		return nil, head[:n], nil
+	}
+
+	header := make([]byte, encryptionHeaderSize)
+	copy(header, head)
+	if _, err := io.ReadFull(r, header[len(head):]); err != nil {
+		return nil, nil, fmt.Errorf("failed to read the encryption header of %s: %w", binlogFile, err)
+	}
+	if header[len(encryptedMagic)] != encryptionVersion {
+		return nil, nil, fmt.Errorf("%s uses unsupported encryption version %d", binlogFile, header[len(encryptedMagic)])
+	}
+
+	var keyID string
+	var password, iv []byte
+	fields := header[len(encryptedMagic)+1:]
+	for len(fields) > 0 && fields[0] != encryptionFieldEnd {
+		typ := fields[0]
+		fields = fields[1:]
+		var size int
+		switch typ {
+		case encryptionFieldKeyID:
+			if len(fields) > 0 {
+				size = int(fields[0])
+				fields = fields[1:]
+			}
+		case encryptionFieldPassword:
+			size = filePasswordSize
+		case encryptionFieldIV:
+			size = aes.BlockSize
+		default:
+			return nil, nil, fmt.Errorf("invalid encryption header in %s: unknown field %d", binlogFile, typ)
+		}
+		if size > len(fields) {
+			return nil, nil, fmt.Errorf("invalid encryption header in %s: truncated field %d", binlogFile, typ)
+		}
+		switch typ {
+		case encryptionFieldKeyID:
+			keyID = string(fields[:size])
+		case encryptionFieldPassword:
+			password = fields[:size]
+		case encryptionFieldIV:
+			iv = fields[:size]
+		}
+		fields = fields[size:]
+	}
+	if keyID == "" || password == nil || iv == nil {
+		return nil, nil, fmt.Errorf("invalid encryption header in %s: missing fields", binlogFile)
+	}
+
+	key, ok := d.Keys[keyID]
+	if !ok {
+		return nil, nil, fmt.Errorf("%s is encrypted with key %s, which is not in the keyring", binlogFile, keyID)
+	}
+	c, err := newFileCipher(key, password, iv)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt %s with key %s: %w", binlogFile, keyID, err)
+	}
+	return c, nil, nil
+}
+
+// newFileCipher decrypts the file password with the keyring key (AES-256-CBC) and
+// derives the file key and IV from it as OpenSSL's EVP_BytesToKey does with SHA-512
+func newFileCipher(key, encryptedPassword, iv []byte) (*fileCipher, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key is %d bytes, expected 32", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	password := make([]byte, filePasswordSize)
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(password, encryptedPassword)
+
+	derived := sha512.Sum512(password)
+	if block, err = aes.NewCipher(derived[:32]); err != nil {
+		return nil, err
+	}
+	return &fileCipher{block: block, iv: derived[32 : 32+aes.BlockSize]}, nil
+}
+
+// stream returns the keystream of the file from offset: the counter starts at the IV
+// and counts blocks, so it is advanced by whole blocks and the rest is discarded
+func (c *fileCipher) stream(offset int64) cipher.Stream {
+	counter := make([]byte, aes.BlockSize)
+	copy(counter, c.iv)
+	carry := uint64(offset / aes.BlockSize)
+	for i := aes.BlockSize - 1; i >= 0 && carry > 0; i-- {
+		sum := uint64(counter[i]) + carry&0xff
+		counter[i] = byte(sum)
+		carry = carry>>8 + sum>>8
+	}
+	s := cipher.NewCTR(c.block, counter)
+	if skip := offset % aes.BlockSize; skip > 0 {
+		discard := make([]byte, skip)
+		s.XORKeyStream(discard, discard)
+	}
+	return s
+}
+
+// readCloser reads from a reader wrapping the one it closes
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
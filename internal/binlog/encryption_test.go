@@ -0,0 +1,108 @@
+package binlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKeyID = "MySQLReplicationKey_4b5e2cd5-ea0d-11ee-8c41-0242ac110002_1"
+
+var testKey = bytes.Repeat([]byte{0x5a, 0x17}, 16)
+
+// pluginKeyring returns a keyring_file plugin data file holding keys
+func pluginKeyring(keys Keyring) []byte {
+	data := []byte(pluginKeyringHeader + "2.0")
+	for id, key := range keys {
+		obfuscated := make([]byte, len(key))
+		for i, b := range key {
+			obfuscated[i] = b ^ pluginKeyringObfuscate[i%len(pluginKeyringObfuscate)]
+		}
+		values := append(append([]byte(id), "AES"...), obfuscated...)
+		size := 5*8 + len(values)
+		size += (8 - size%8) % 8
+		for _, n := range []int{size, len(id), len("AES"), 0, len(key)} {
+			data = binary.LittleEndian.AppendUint64(data, uint64(n))
+		}
+		data = append(data, values...)
+		data = append(data, make([]byte, size-5*8-len(values))...)
+	}
+	data = append(data, pluginKeyringEOF...)
+	return append(data, make([]byte, 32)...)
+}
+
+func TestParseKeyring(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "component_keyring_file",
+			data: []byte(`{"version":"1.0","elements":[{"user":"","data_id":"` + testKeyID +
+				`","data_type":"AES","data":"5A175A175A175A175A175A175A175A175A175A175A175A175A175A175A175A17","extension":[]}]}`),
+		},
+		{
+			name: "keyring_file plugin",
+			data: pluginKeyring(Keyring{testKeyID: testKey}),
+		},
+		{
+			name: "Decrypted keys",
+			data: []byte("# exported keys\n\n" + testKeyID + " 5a175a175a175a175a175a175a175a175a175a175a175a175a175a175a175a17\n"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ParseKeyring(tt.data)
+			require.NoError(t, err)
+			assert.Equal(t, Keyring{testKeyID: testKey}, keys)
+		})
+	}
+
+	_, err := ParseKeyring([]byte(testKeyID + " not-hex\n"))
+	assert.ErrorContains(t, err, "line 1")
+}
+
+func TestDecryptingReader(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 2, FileSize: 4 << 10, Seed: 1})
+	encrypted, plain := files[0], files[1]
+	reader := &DecryptingReader{
+		Reader: memoryFiles{
+			encrypted.Name: binlogtest.Encrypt(encrypted.Data, testKeyID, testKey),
+			plain.Name:     plain.Data,
+		},
+		Keys: Keyring{testKeyID: testKey},
+	}
+
+	for _, f := range files {
+		for _, offset := range []int64{0, 4, int64(f.Events[5].Pos), int64(f.Events[5].Pos) + 7} {
+			r, err := reader.OpenAt(f.Name, offset)
+			require.NoError(t, err)
+			data, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.NoError(t, r.Close())
+			assert.Equal(t, f.Data[offset:], data, "%s from %d", f.Name, offset)
+		}
+	}
+
+	// Events stream from the middle of an encrypted file as from a plain one
+	start := encrypted.Events[len(encrypted.Events)/2]
+	stream, err := FileStreamer{Reader: reader}.StreamFrom(encrypted.Name, start.Pos)
+	require.NoError(t, err)
+	defer stream.Close()
+	_, err = stream.GetEvent(context.Background()) // format description
+	require.NoError(t, err)
+	ev, err := stream.GetEvent(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, start.Pos, ev.Header.LogPos-ev.Header.EventSize)
+
+	missing := &DecryptingReader{Reader: reader.Reader, Keys: Keyring{}}
+	_, err = missing.OpenAt(encrypted.Name, 0)
+	assert.ErrorContains(t, err, "not in the keyring")
+}
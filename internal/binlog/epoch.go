@@ -0,0 +1,184 @@
+package binlog
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// splitName splits a binlog file name such as mysql-bin.000012 into its basename and sequence number
+func splitName(binlogFile string) (string, int, bool) {
+	dot := strings.LastIndex(binlogFile, ".")
+	if dot < 0 {
+		return binlogFile, 0, false
+	}
+	seq, err := strconv.Atoi(binlogFile[dot+1:])
+	if err != nil {
+		return binlogFile, 0, false
+	}
+	return binlogFile[:dot], seq, true
+}
+
+// SortFiles orders binlog files found outside a server index, such as archived copies, by
+// basename and then sequence number. Files whose names carry no sequence number are dropped.
+func SortFiles(files []FileInfo) []FileInfo {
+	sorted := slices.DeleteFunc(slices.Clone(files), func(f FileInfo) bool {
+		_, _, ok := splitName(f.Name)
+		return !ok
+	})
+	slices.SortFunc(sorted, func(a, b FileInfo) int {
+		baseA, seqA, _ := splitName(a.Name)
+		baseB, seqB, _ := splitName(b.Name)
+		if c := strings.Compare(baseA, baseB); c != 0 {
+			return c
+		}
+		return seqA - seqB
+	})
+	return sorted
+}
+
+// SplitEpochs splits a binlog list, in server index order, wherever the history was restarted.
+// A new epoch begins when the basename changes or the sequence number fails to increase,
+// which is what RESET MASTER or re-initializing the server with a new log-bin name leaves behind.
+// starts holds the first event times known for some of the files, if any: a new epoch
+// also begins at a file starting before a file listed before it, as after a reset that
+// kept the numbering going, such as RESET MASTER TO.
+func SplitEpochs(binlogFiles []string, starts map[string]time.Time) [][]string {
+	var epochs [][]string
+	var prevBase string
+	var prevSeq int
+	// latest is the latest start known in the current epoch
+	var latest time.Time
+
+	for i, file := range binlogFiles {
+		base, seq, ok := splitName(file)
+		start, known := starts[file]
+		if i == 0 || !ok || base != prevBase || seq <= prevSeq || (known && start.Before(latest)) {
+			epochs = append(epochs, nil)
+			latest = time.Time{}
+		}
+		epochs[len(epochs)-1] = append(epochs[len(epochs)-1], file)
+		prevBase, prevSeq = base, seq
+		if known && start.After(latest) {
+			latest = start
+		}
+	}
+
+	return epochs
+}
+
+// knownStarts returns the first event times of the files known so far, from Known and
+// from the files the search probed
+func (f *searchRun) knownStarts() map[string]time.Time {
+	starts := maps.Clone(f.starts)
+	if starts == nil {
+		starts = make(map[string]time.Time, len(f.Known))
+	}
+	for file, r := range f.Known {
+		starts[file] = r.Start
+	}
+	return starts
+}
+
+// restarted reports whether the last file of an epoch starts before the first file the
+// search probed in it, which only a restart of the history within it explains. The start
+// of the first file after the restart is then found by bisecting the start times, and
+// recorded for SplitEpochs. Only the last file is read unless the history did restart.
+func (f *searchRun) restarted(epoch []string) bool {
+	known := f.knownStarts()
+	from := slices.IndexFunc(epoch, func(file string) bool {
+		_, ok := known[file]
+		return ok
+	})
+	if from < 0 || from == len(epoch)-1 {
+		return false
+	}
+	first := known[epoch[from]]
+	if last, ok := f.fileStart(epoch[len(epoch)-1]); !ok || !last.Before(first) {
+		return false
+	}
+	lo, hi := from+1, len(epoch)-1
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, ok := f.fileStart(epoch[mid])
+		if !ok {
+			return false
+		}
+		if start.Before(first) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	f.logger().Warn("Binlog history restarted without a change of file names", "file", epoch[hi])
+	return true
+}
+
+// fileStart returns the first event time of a file, reading only as far as that event
+// unless it is already known
+func (f *searchRun) fileStart(binlogFile string) (time.Time, bool) {
+	if start, ok := f.knownStarts()[binlogFile]; ok {
+		return start, true
+	}
+	start, err := startTime(f.readContext(), f.streamer(), binlogFile, f.probeTimeout(), f.Source)
+	if err != nil || start.IsZero() {
+		f.logger().Debug("Could not get start time", "file", binlogFile, "error", err)
+		return time.Time{}, false
+	}
+	if f.starts == nil {
+		f.starts = make(map[string]time.Time)
+	}
+	f.starts[binlogFile] = start
+	return start, true
+}
+
+// wentBack reports whether the range r probed for binlogFiles[i] starts before a file
+// listed before it, or after one listed after it, which only a restarted history explains
+func wentBack(binlogFiles []string, i int, r fileRange, timeRanges map[string]fileRange) bool {
+	if r.start.IsZero() {
+		return false
+	}
+	for j, file := range binlogFiles {
+		other, ok := timeRanges[file]
+		if !ok || j == i || other.start.IsZero() {
+			continue
+		}
+		if (j < i && r.start.Before(other.start)) || (j > i && other.start.Before(r.start)) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectEpoch picks the newest epoch whose first event is at or before the target time,
+// so the binary search never compares files from different histories
+func (f *searchRun) selectEpoch(epochs [][]string, targetTime time.Time) []string {
+	f.logger().Info("Detected multiple binlog epochs (history was reset or renamed)", "epochs", len(epochs))
+
+	var newerStart time.Time
+	for i := len(epochs) - 1; i >= 0; i-- {
+		head := epochs[i][0]
+		r, err := f.timeRange(head)
+		if err != nil {
+			f.logger().Warn("Could not get time range for epoch head", "file", head, "error", err)
+			f.report(Probe{File: head, Err: err, Decision: DecisionError})
+			continue
+		}
+		f.report(r.probe(head, DecisionEpoch))
+		start := r.start
+
+		if !newerStart.IsZero() && !start.Before(newerStart) {
+			f.logger().Warn("Epoch begins after the newer epoch; server clock may have moved backwards", "file", head)
+		}
+		newerStart = start
+
+		if !targetTime.Before(start) {
+			f.logger().Info("Searching epoch", "first", head, "last", epochs[i][len(epochs[i])-1])
+			return epochs[i]
+		}
+	}
+
+	return epochs[0]
+}
@@ -0,0 +1,119 @@
+package binlog
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitEpochs(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		binlogFiles []string
+		starts      map[string]time.Time
+		expected    [][]string
+	}{
+		{
+			name:        "Empty binlog files",
+			binlogFiles: []string{},
+			expected:    nil,
+		},
+		{
+			name:        "Single continuous history",
+			binlogFiles: []string{"mysql-bin.000001", "mysql-bin.000002", "mysql-bin.000003"},
+			expected:    [][]string{{"mysql-bin.000001", "mysql-bin.000002", "mysql-bin.000003"}},
+		},
+		{
+			name:        "Gaps in numbering stay in one epoch",
+			binlogFiles: []string{"mysql-bin.000007", "mysql-bin.000009"},
+			expected:    [][]string{{"mysql-bin.000007", "mysql-bin.000009"}},
+		},
+		{
+			name:        "Numbering restarted after RESET MASTER",
+			binlogFiles: []string{"mysql-bin.000041", "mysql-bin.000042", "mysql-bin.000001", "mysql-bin.000002"},
+			expected: [][]string{
+				{"mysql-bin.000041", "mysql-bin.000042"},
+				{"mysql-bin.000001", "mysql-bin.000002"},
+			},
+		},
+		{
+			name:        "Basename changed after re-initialization",
+			binlogFiles: []string{"mysql-bin.000041", "binlog.000042", "binlog.000043"},
+			expected: [][]string{
+				{"mysql-bin.000041"},
+				{"binlog.000042", "binlog.000043"},
+			},
+		},
+		{
+			name:        "Start time went back after RESET MASTER TO",
+			binlogFiles: []string{"mysql-bin.000041", "mysql-bin.000042", "mysql-bin.000043", "mysql-bin.000044"},
+			starts: map[string]time.Time{
+				"mysql-bin.000041": day.Add(2 * time.Hour),
+				"mysql-bin.000043": day,
+				"mysql-bin.000044": day.Add(time.Hour),
+			},
+			expected: [][]string{
+				{"mysql-bin.000041", "mysql-bin.000042"},
+				{"mysql-bin.000043", "mysql-bin.000044"},
+			},
+		},
+		{
+			name:        "Start times in order stay in one epoch",
+			binlogFiles: []string{"mysql-bin.000041", "mysql-bin.000042"},
+			starts:      map[string]time.Time{"mysql-bin.000041": day, "mysql-bin.000042": day},
+			expected:    [][]string{{"mysql-bin.000041", "mysql-bin.000042"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SplitEpochs(tt.binlogFiles, tt.starts))
+		})
+	}
+}
+
+func TestSortFiles(t *testing.T) {
+	files := []FileInfo{
+		{Name: "mysql-bin.000010", Size: 10},
+		{Name: "mysql-bin.index"},
+		{Name: "mysql-bin.000009", Size: 9},
+		{Name: "binlog.000002", Size: 2},
+		{Name: "README"},
+		{Name: "mysql-bin.1000000", Size: 1000000},
+	}
+
+	assert.Equal(t, []FileInfo{
+		{Name: "binlog.000002", Size: 2},
+		{Name: "mysql-bin.000009", Size: 9},
+		{Name: "mysql-bin.000010", Size: 10},
+		{Name: "mysql-bin.1000000", Size: 1000000},
+	}, SortFiles(files))
+	assert.Equal(t, "mysql-bin.000010", files[0].Name, "input is left untouched")
+}
+
+func TestFinderRestartedHistory(t *testing.T) {
+	// Files 1 to 4 were written a day after files 5 to 8, which came after a reset that
+	// kept the numbering going: only their start times tell the histories apart
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	older := binlogtest.Generate(binlogtest.Options{Files: 4, FileSize: 4 << 10, Rate: 0.5, Seed: 1, Start: day.Add(24 * time.Hour)})
+	newer := binlogtest.Generate(binlogtest.Options{Files: 4, FileSize: 4 << 10, Rate: 0.5, Seed: 2, Start: day})
+	stored := make(memoryFiles)
+	var names []string
+	for i, f := range append(older, newer...) {
+		name := fmt.Sprintf("binlog.%06d", i+1)
+		stored[name] = f.Data
+		names = append(names, name)
+	}
+
+	finder := &Finder{Streamer: FileStreamer{Reader: stored}}
+	for i, f := range newer {
+		target := f.Start.Add(f.End.Sub(f.Start) / 2).Truncate(time.Second)
+		res := finder.Find(names, target)
+		assert.Equal(t, names[len(older)+i], res.File, "target %s", target)
+		assert.True(t, res.Exact(), "target %s", target)
+	}
+}
@@ -0,0 +1,82 @@
+package binlog
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// Errors callers can test for with errors.Is. They wrap the underlying error, if any.
+var (
+	// ErrNoBinlogs is returned when there are no binlog files to search
+	ErrNoBinlogs = errors.New("no binlog files")
+	// ErrTimestampBeforeRetention is returned when the target time is older than the
+	// oldest binlog still on the server
+	ErrTimestampBeforeRetention = errors.New("timestamp is before the oldest available binlog")
+	// ErrTimestampInFuture is returned when the target time is newer than the last event
+	// the server has written
+	ErrTimestampInFuture = errors.New("timestamp is after the newest binlog event")
+	// ErrPermissionDenied is returned when the server rejects the credentials or the user
+	// lacks a privilege, such as REPLICATION SLAVE or REPLICATION CLIENT
+	ErrPermissionDenied = errors.New("permission denied")
+	// ErrBinlogDisabled is returned when the server has binary logging turned off
+	ErrBinlogDisabled = errors.New("binary logging is disabled")
+	// ErrGTIDPurged is returned when a GTID was written before the oldest available binlog
+	ErrGTIDPurged = errors.New("GTID is before the oldest available binlog")
+	// ErrGTIDNotFound is returned when no binlog holds a transaction with the GTID, such as
+	// one not yet written
+	ErrGTIDNotFound = errors.New("GTID not found")
+)
+
+// RangeError reports a target time outside the binlogs: before the first event of the
+// oldest file or after the last event of the newest. It wraps ErrTimestampBeforeRetention
+// or ErrTimestampInFuture.
+type RangeError struct {
+	// File is the oldest or the newest binlog file
+	File string
+	// Bound is the time of the first event of the oldest file, or of the last event of
+	// the newest
+	Bound time.Time
+	err   error
+}
+
+func (e *RangeError) Error() string {
+	if e.err == ErrTimestampBeforeRetention {
+		return fmt.Sprintf("%v: %s starts at %s", e.err, e.File, e.Bound.Format("2006-01-02 15:04:05"))
+	}
+	return fmt.Sprintf("%v: %s ends at %s", e.err, e.File, e.Bound.Format("2006-01-02 15:04:05"))
+}
+
+// Unwrap returns ErrTimestampBeforeRetention or ErrTimestampInFuture
+func (e *RangeError) Unwrap() error {
+	return e.err
+}
+
+// errorCode returns the MySQL error number of err, or 0 if it is not a server error
+func errorCode(err error) uint16 {
+	var driverErr *mysqldriver.MySQLError
+	var myErr *mysql.MyError
+	switch {
+	case errors.As(err, &driverErr):
+		return driverErr.Number
+	case errors.As(err, &myErr):
+		return myErr.Code
+	}
+	return 0
+}
+
+// classify marks access denied errors from the server with ErrPermissionDenied
+func classify(err error) error {
+	if err == nil || errors.Is(err, ErrPermissionDenied) {
+		return err
+	}
+	switch errorCode(err) {
+	case mysql.ER_DBACCESS_DENIED_ERROR, mysql.ER_ACCESS_DENIED_ERROR, mysql.ER_SPECIFIC_ACCESS_DENIED_ERROR,
+		mysql.ER_ACCESS_DENIED_NO_PASSWORD_ERROR, mysql.ER_TABLEACCESS_DENIED_ERROR:
+		return fmt.Errorf("%w: %w", ErrPermissionDenied, err)
+	}
+	return err
+}
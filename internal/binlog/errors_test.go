@@ -0,0 +1,33 @@
+package binlog
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		denied bool
+	}{
+		{"Wrong password", &mysqldriver.MySQLError{Number: mysql.ER_ACCESS_DENIED_ERROR}, true},
+		{"Missing privilege on a replication connection", &mysql.MyError{Code: mysql.ER_SPECIFIC_ACCESS_DENIED_ERROR}, true},
+		{"Wrapped", fmt.Errorf("failed to connect to MySQL: %w", &mysqldriver.MySQLError{Number: mysql.ER_DBACCESS_DENIED_ERROR}), true},
+		{"Purged binlog", &mysql.MyError{Code: mysql.ER_MASTER_FATAL_ERROR_READING_BINLOG}, false},
+		{"Network", errors.New("connection refused"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classify(tt.err)
+			assert.Equal(t, tt.denied, errors.Is(err, ErrPermissionDenied))
+			assert.ErrorIs(t, err, tt.err, "the server error must stay reachable")
+		})
+	}
+	assert.NoError(t, classify(nil))
+}
@@ -0,0 +1,148 @@
+package binlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// maxEventInfo limits the length of the statement text shown for query events
+const maxEventInfo = 120
+
+// EventSummary describes a single binlog event for display
+type EventSummary struct {
+	File      string
+	Pos       uint32
+	Type      string
+	Timestamp time.Time
+	ServerID  uint32
+	Size      uint32
+	// Info is a short, event-specific description such as the statement or GTID
+	Info string
+	// InPayload is set for events decoded from a compressed transaction payload
+	// (binlog_transaction_compression=ON). They share the payload event's position.
+	InPayload bool
+}
+
+// PreviewEvents reads up to limit events from the start of a binlog file. Reaching the
+// end of the newest file is not an error: the events read so far are returned.
+func PreviewEvents(syncer *replication.BinlogSyncer, binlogFile string, limit int) ([]EventSummary, error) {
+	return previewEvents(context.Background(), newSyncerStreamer(syncer), binlogFile, limit)
+}
+
+// PreviewEvents is PreviewEvents reading through the Finder's streamer
+func (f *Finder) PreviewEvents(binlogFile string, limit int) ([]EventSummary, error) {
+	return previewEvents(f.readContext(), f.streamer(), binlogFile, limit)
+}
+
+// previewEvents implements PreviewEvents
+func previewEvents(ctx context.Context, streamer EventStreamer, binlogFile string, limit int) ([]EventSummary, error) {
+	ctx, cancel := withReadTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	stream, err := streamer.StreamFrom(binlogFile, 4)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start sync from %s: %w", binlogFile, err)
+	}
+	defer stream.Close()
+
+	var events []EventSummary
+	for len(events) < limit {
+		ev, err := stream.GetEvent(ctx)
+		if err != nil {
+			// A stored file ends with io.EOF rather than a heartbeat
+			if errors.Is(err, io.EOF) || errors.Is(err, context.DeadlineExceeded) && len(events) > 0 {
+				break
+			}
+			return events, fmt.Errorf("failed to get event: %w", err)
+		}
+		if isHeartbeat(ev) {
+			break
+		}
+		traceEvent(binlogFile, ev)
+
+		// The fake rotate event sent at the start of the stream has no timestamp
+		if _, ok := ev.Event.(*replication.RotateEvent); ok && ev.Header.Timestamp == 0 {
+			continue
+		}
+
+		pos := ev.Header.LogPos - ev.Header.EventSize
+		events = append(events, summarize(binlogFile, pos, ev))
+		if payload, ok := ev.Event.(*replication.TransactionPayloadEvent); ok {
+			for _, inner := range payload.Events {
+				summary := summarize(binlogFile, pos, inner)
+				summary.InPayload = true
+				events = append(events, summary)
+			}
+		}
+
+		// Stop at the end of the file rather than following the stream into the next one
+		if _, ok := ev.Event.(*replication.RotateEvent); ok {
+			break
+		}
+	}
+	return events, nil
+}
+
+// summarize describes an event starting at pos
+func summarize(binlogFile string, pos uint32, ev *replication.BinlogEvent) EventSummary {
+	return EventSummary{
+		File:      binlogFile,
+		Pos:       pos,
+		Type:      ev.Header.EventType.String(),
+		Timestamp: time.Unix(int64(ev.Header.Timestamp), 0),
+		ServerID:  ev.Header.ServerID,
+		Size:      ev.Header.EventSize,
+		Info:      eventInfo(ev),
+	}
+}
+
+// eventInfo returns a one-line description of the event's payload
+func eventInfo(ev *replication.BinlogEvent) string {
+	switch e := ev.Event.(type) {
+	case *replication.QueryEvent:
+		query := strings.Join(strings.Fields(string(e.Query)), " ")
+		if len(query) > maxEventInfo {
+			query = query[:maxEventInfo] + "..."
+		}
+		if len(e.Schema) > 0 {
+			return fmt.Sprintf("use `%s`; %s", e.Schema, query)
+		}
+		return query
+	case *replication.GTIDEvent:
+		if next, err := e.GTIDNext(); err == nil {
+			return fmt.Sprintf("SET @@SESSION.GTID_NEXT= '%s'", next)
+		}
+	case *replication.MariadbGTIDEvent:
+		return "BEGIN GTID " + e.GTID.String()
+	case *replication.MariadbGTIDListEvent:
+		gtids := make([]string, len(e.GTIDs))
+		for i := range e.GTIDs {
+			gtids[i] = e.GTIDs[i].String()
+		}
+		return "[" + strings.Join(gtids, ",") + "]"
+	case *replication.XIDEvent:
+		return fmt.Sprintf("COMMIT /* xid=%d */", e.XID)
+	case *replication.RotateEvent:
+		return fmt.Sprintf("%s;pos=%d", e.NextLogName, e.Position)
+	case *replication.TableMapEvent:
+		return fmt.Sprintf("table_id: %d (%s.%s)", e.TableID, e.Schema, e.Table)
+	case *replication.RowsEvent:
+		if e.Table != nil {
+			return fmt.Sprintf("table_id: %d (%s.%s) rows: %d", e.TableID, e.Table.Schema, e.Table.Table, len(e.Rows))
+		}
+		return fmt.Sprintf("table_id: %d rows: %d", e.TableID, len(e.Rows))
+	case *replication.TransactionPayloadEvent:
+		compression := "uncompressed"
+		if e.CompressionType == replication.ZSTD {
+			compression = "zstd"
+		}
+		return fmt.Sprintf("%s payload: %d events, %d bytes (%d uncompressed)", compression, len(e.Events), e.Size, e.UncompressedSize)
+	}
+	return ""
+}
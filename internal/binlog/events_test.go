@@ -0,0 +1,74 @@
+package binlog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    replication.Event
+		expected string
+	}{
+		{
+			name:     "Query with schema",
+			event:    &replication.QueryEvent{Schema: []byte("shop"), Query: []byte("UPDATE orders\n  SET state = 'paid'")},
+			expected: "use `shop`; UPDATE orders SET state = 'paid'",
+		},
+		{
+			name:     "Query without schema",
+			event:    &replication.QueryEvent{Query: []byte("BEGIN")},
+			expected: "BEGIN",
+		},
+		{
+			name:     "Commit",
+			event:    &replication.XIDEvent{XID: 42},
+			expected: "COMMIT /* xid=42 */",
+		},
+		{
+			name:     "Rotate",
+			event:    &replication.RotateEvent{NextLogName: []byte("mysql-bin.000002"), Position: 4},
+			expected: "mysql-bin.000002;pos=4",
+		},
+		{
+			name:     "Table map",
+			event:    &replication.TableMapEvent{TableID: 7, Schema: []byte("shop"), Table: []byte("orders")},
+			expected: "table_id: 7 (shop.orders)",
+		},
+		{
+			name: "Compressed transaction",
+			event: &replication.TransactionPayloadEvent{Size: 120, UncompressedSize: 300, CompressionType: replication.ZSTD,
+				Events: make([]*replication.BinlogEvent, 4)},
+			expected: "zstd payload: 4 events, 120 bytes (300 uncompressed)",
+		},
+		{
+			name:     "MariaDB GTID",
+			event:    &replication.MariadbGTIDEvent{GTID: mysql.MariadbGTID{DomainID: 0, ServerID: 1, SequenceNumber: 12345}},
+			expected: "BEGIN GTID 0-1-12345",
+		},
+		{
+			name:     "MariaDB GTID list",
+			event:    &replication.MariadbGTIDListEvent{GTIDs: []mysql.MariadbGTID{{DomainID: 0, ServerID: 1, SequenceNumber: 100}, {DomainID: 1, ServerID: 2, SequenceNumber: 7}}},
+			expected: "[0-1-100,1-2-7]",
+		},
+		{
+			name:     "Other events",
+			event:    &replication.FormatDescriptionEvent{},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, eventInfo(&replication.BinlogEvent{Event: tt.event}))
+		})
+	}
+
+	long := eventInfo(&replication.BinlogEvent{Event: &replication.QueryEvent{Query: []byte(strings.Repeat("x", 200))}})
+	assert.Len(t, long, maxEventInfo+len("..."))
+}
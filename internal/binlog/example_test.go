@@ -0,0 +1,134 @@
+package binlog_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+)
+
+// archive is a binlog.FileReader over binlog files held in memory, standing in for a
+// directory of archived binlogs so that the examples run without a server
+type archive map[string][]byte
+
+func (a archive) OpenAt(binlogFile string, offset int64) (io.ReadCloser, error) {
+	data, ok := a[binlogFile]
+	if !ok {
+		return nil, fmt.Errorf("no binlog %s", binlogFile)
+	}
+	return io.NopCloser(bytes.NewReader(data[offset:])), nil
+}
+
+// exampleFinder returns a Finder reading three generated binlogs, each holding a few
+// minutes of transactions from 2024-01-01 00:00:00 UTC, and their names in order
+func exampleFinder(opts ...binlog.Option) (*binlog.Finder, []string) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 3, FileSize: 16 << 10, Rate: 0.2, Seed: 1})
+	stored := make(archive, len(files))
+	names := make([]string, len(files))
+	for i, f := range files {
+		stored[f.Name] = f.Data
+		names[i] = f.Name
+	}
+	finder := &binlog.Finder{
+		Streamer: binlog.FileStreamer{Reader: stored},
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	for _, opt := range opts {
+		opt(finder)
+	}
+	return finder, names
+}
+
+// Connecting to a server and finding the binlog to start a point-in-time recovery from.
+// The user needs the REPLICATION SLAVE and REPLICATION CLIENT privileges.
+func ExampleNewFinder() {
+	finder, err := binlog.NewFinder("repl:secret@tcp(db.example.com:3306)/?timeout=5s&readTimeout=30s",
+		binlog.WithServerID(4242),
+		binlog.WithTimeout(time.Minute),
+		binlog.WithPosition(binlog.AlignTransaction))
+	if err != nil {
+		log.Fatal(err)
+	}
+	files, err := finder.Binlogs()
+	if err != nil {
+		log.Fatal(err)
+	}
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.Name
+	}
+
+	res := finder.Find(names, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	if !res.Exact() {
+		log.Fatalf("no binlog holds the target time (%s): %v", res.MatchQuality, res.Warnings)
+	}
+	fmt.Printf("mysqlbinlog --start-position=%d %s\n", res.Position.Pos, res.File)
+}
+
+func ExampleFinder_Find() {
+	finder, names := exampleFinder(binlog.WithPosition(binlog.AlignTransaction))
+
+	res := finder.Find(names, time.Date(2024, 1, 1, 0, 2, 0, 0, time.UTC))
+	fmt.Println(res.File, res.MatchQuality)
+	fmt.Println("range:", res.FileRange.Start.UTC().Format(time.TimeOnly), "to", res.FileRange.End.UTC().Format(time.TimeOnly))
+	fmt.Println("first transaction:", res.Position, "at", res.Position.Timestamp.UTC().Format(time.TimeOnly))
+	fmt.Println("probes:", len(res.Probes))
+	// Output:
+	// binlog.000001 exact
+	// range: 00:00:00 to 00:03:25
+	// first transaction: binlog.000001:9478 at 00:02:00
+	// probes: 2
+}
+
+// Targets outside the binlogs still find the nearest file, with the match quality and
+// warnings telling how far to trust it
+func ExampleFinder_Find_approximate() {
+	finder, names := exampleFinder()
+
+	for _, target := range []time.Time{
+		time.Date(2023, 12, 31, 23, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC),
+	} {
+		res := finder.Find(names, target)
+		switch res.MatchQuality {
+		case binlog.MatchBeforeOldest:
+			fmt.Println("events before", res.FileRange.Start.UTC().Format(time.TimeOnly), "were purged; start from", res.File)
+		case binlog.MatchAfterNewest:
+			fmt.Println("no events after", res.FileRange.End.UTC().Format(time.TimeOnly), "yet; the newest binlog is", res.File)
+		default:
+			fmt.Println(res.File, res.MatchQuality)
+		}
+	}
+	// Output:
+	// events before 00:00:00 were purged; start from binlog.000001
+	// no events after 00:10:20 yet; the newest binlog is binlog.000003
+}
+
+// Finding the window of events to replay between two times, as the range command does:
+// the start position is the first transaction at or after the start time and the stop
+// position the first at or after the end time.
+func ExampleFinder_Locate() {
+	finder, names := exampleFinder()
+	start, end := time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC), time.Date(2024, 1, 1, 0, 5, 0, 0, time.UTC)
+
+	var window [2]binlog.Position
+	for i, target := range []time.Time{start, end} {
+		res := finder.Find(names, target)
+		if !res.Exact() {
+			log.Fatalf("%s is not within the binlogs: %v", target, res.Warnings)
+		}
+		pos, err := finder.Locate(res.File, target, binlog.AlignTransaction, 0)
+		if err != nil {
+			log.Fatal(err)
+		}
+		window[i] = pos
+	}
+	fmt.Println("replay from", window[0], "to", window[1])
+	// Output:
+	// replay from binlog.000001:4652 to binlog.000002:7228
+}
@@ -0,0 +1,292 @@
+package binlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// FileReader opens binlog files stored outside a server, such as archived copies in a
+// directory or a bucket
+type FileReader interface {
+	// OpenAt returns a reader for the bytes of binlogFile from offset to its end
+	OpenAt(binlogFile string, offset int64) (io.ReadCloser, error)
+}
+
+// errNotBinlog is returned for stored files that do not start with the binlog magic number
+var errNotBinlog = errors.New("not a binlog file")
+
+// parseError is an error parsing a stored file, as opposed to reading it
+type parseError struct {
+	file string
+	err  error
+}
+
+func (e *parseError) Error() string {
+	return fmt.Sprintf("failed to parse event in %s: %v", e.file, e.err)
+}
+
+func (e *parseError) Unwrap() error {
+	return e.err
+}
+
+// ErrEventEncryption is returned for archived binlogs whose events are encrypted one by
+// one, as encrypt_binlog does on Percona Server 5.7 and MariaDB. Only files written with
+// MySQL's binlog_encryption can be decrypted, with a keyring; servers decrypt either kind
+// of binlog they stream.
+var ErrEventEncryption = errors.New("events are encrypted with encrypt_binlog, which only the server can decrypt")
+
+// damaged reports whether err means a binlog file is corrupt or cannot be parsed, rather
+// than that reading it failed
+func damaged(err error) bool {
+	var parseErr *parseError
+	return errors.As(err, &parseErr) || errors.Is(err, errNotBinlog) || errors.Is(err, replication.ErrChecksumMismatch)
+}
+
+// FileStreamer streams binlog files read through a FileReader. Streams end with io.EOF at
+// the end of the file rather than carrying on into the next one, and with
+// replication.ErrChecksumMismatch at an event failing its checksum.
+//
+// Event positions are offsets in the stored file. They match the positions in the event
+// headers, except in files saved by mysqlbinlog --read-from-remote-server --raw from a
+// position past the start of a binlog, which hold the format description event and then
+// the events from that position, still numbered as in the server's file. Files saved
+// without the magic number, starting with the format description event, are read too.
+type FileStreamer struct {
+	Reader FileReader
+}
+
+// StreamFrom parses binlogFile from the event starting at pos. The format description
+// event at the start of the file is always read first, as it describes the checksums of
+// the events that follow.
+func (s FileStreamer) StreamFrom(binlogFile string, pos uint32) (EventStream, error) {
+	r, err := s.Reader.OpenAt(binlogFile, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", binlogFile, err)
+	}
+
+	parser := replication.NewBinlogParser()
+	parser.SetVerifyChecksum(true)
+	stream := &fileStream{file: binlogFile, r: r, parser: parser}
+
+	// The type of the event at the start of a file without the magic number is read too
+	head := make([]byte, len(replication.BinLogFileHeader)+1)
+	if _, err := io.ReadFull(r, head[:len(replication.BinLogFileHeader)]); err != nil {
+		_ = r.Close()
+		return nil, fmt.Errorf("failed to read the header of %s: %w", binlogFile, err)
+	}
+	switch {
+	case bytes.Equal(head[:len(replication.BinLogFileHeader)], replication.BinLogFileHeader):
+		stream.offset = int64(len(replication.BinLogFileHeader))
+	case readFull(r, head[len(replication.BinLogFileHeader):]) && replication.EventType(head[4]) == replication.FORMAT_DESCRIPTION_EVENT:
+		stream.r = prefixed{io.MultiReader(bytes.NewReader(head), r), r}
+	default:
+		_ = r.Close()
+		return nil, fmt.Errorf("%s is %w", binlogFile, errNotBinlog)
+	}
+
+	fde, err := stream.next()
+	if err != nil {
+		stream.Close()
+		return nil, fmt.Errorf("failed to read the format description of %s: %w", binlogFile, err)
+	}
+	// Like a replication stream, the format description comes first wherever it starts
+	stream.pending = fde
+	if int64(pos) <= stream.offset {
+		return stream, nil
+	}
+
+	// Only the format description was needed from the start of the file
+	stream.Close()
+	if stream.r, err = s.Reader.OpenAt(binlogFile, int64(pos)); err != nil {
+		return nil, fmt.Errorf("failed to open %s at %d: %w", binlogFile, pos, err)
+	}
+	stream.offset = int64(pos)
+	return stream, nil
+}
+
+// readFull reports whether p could be filled from r
+func readFull(r io.Reader, p []byte) bool {
+	_, err := io.ReadFull(r, p)
+	return err == nil
+}
+
+// prefixed reads bytes already taken from a file followed by the rest of it
+type prefixed struct {
+	io.Reader
+	io.Closer
+}
+
+// fileStream parses the events of a single binlog file
+type fileStream struct {
+	file   string
+	r      io.ReadCloser
+	parser *replication.BinlogParser
+	// pending is returned by the next call to GetEvent, before reading any further
+	pending *replication.BinlogEvent
+	// readErr is the first error reading r, other than io.EOF
+	readErr error
+	// offset is the offset in the stored file of the next byte read from r
+	offset int64
+}
+
+func (s *fileStream) GetEvent(ctx context.Context) (*replication.BinlogEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if ev := s.pending; ev != nil {
+		s.pending = nil
+		return ev, nil
+	}
+	return s.next()
+}
+
+// next parses the next event, returning io.EOF at the end of the file
+func (s *fileStream) next() (*replication.BinlogEvent, error) {
+	for {
+		var ev *replication.BinlogEvent
+		done, err := s.parser.ParseSingleEvent(s, func(e *replication.BinlogEvent) error {
+			ev = e
+			return nil
+		})
+		// The parser reports read errors as text, so they are told apart by readErr. A file
+		// ending early is truncated rather than unreadable.
+		if err != nil && s.readErr != nil && !errors.Is(s.readErr, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("failed to read %s: %w", s.file, s.readErr)
+		}
+		if err != nil {
+			return nil, &parseError{file: s.file, err: err}
+		}
+		if done {
+			return nil, io.EOF
+		}
+		// Rows events read without their table map event, when starting inside a
+		// transaction, are skipped by the parser
+		if ev == nil {
+			continue
+		}
+		// The events after it could only be parsed as garbage
+		if ev.Header.EventType == replication.MARIADB_START_ENCRYPTION_EVENT {
+			return nil, fmt.Errorf("%s: %w", s.file, ErrEventEncryption)
+		}
+		// Events made up by the server, like the format description of a raw dump
+		// starting past the start of a binlog, keep their position of 0
+		if ev.Header.LogPos > 0 {
+			ev.Header.LogPos = uint32(s.offset)
+		}
+		return ev, nil
+	}
+}
+
+// Read reads the file for the parser, keeping the first error
+func (s *fileStream) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	s.offset += int64(n)
+	if err != nil && err != io.EOF && s.readErr == nil {
+		s.readErr = err
+	}
+	return n, err
+}
+
+func (s *fileStream) Close() {
+	_ = s.r.Close()
+}
+
+// FileSummary describes a binlog file read in a single pass by SummarizeFile
+type FileSummary struct {
+	// Start and End are the earliest and latest event times in the file
+	Start, End time.Time
+	Events     int
+	Bytes      int64
+	// Position locates the target time, set when the file contains it
+	Position *Position
+}
+
+// SummarizeFile reads a whole binlog file from r, which need not be seekable, such as a
+// file piped to stdin, and returns its time range. If targetTime is not zero and within
+// the range, the position of the target time is located in the same pass, snapped
+// according to align. binlogFile names the file in the position. Only the events origin
+// passes are read.
+func SummarizeFile(r io.Reader, binlogFile string, targetTime time.Time, align Alignment, source TimestampSource, origin OriginFilter) (FileSummary, error) {
+	streamer := Settings{Origin: origin}.streamer(FileStreamer{Reader: onceReader{r}})
+	stream, err := streamer.StreamFrom(binlogFile, 4)
+	if err != nil {
+		return FileSummary{}, err
+	}
+	defer stream.Close()
+	counted := &summaryStream{EventStream: stream, source: source}
+
+	ctx := context.Background()
+	var located *Position
+	if !targetTime.IsZero() {
+		// Reaching the end without a match is not an error here, as the range tells why
+		if pos, err := scanToTime(ctx, counted, binlogFile, targetTime, align, source, 0, false); err == nil && pos.File == binlogFile {
+			located = &pos
+		}
+	}
+	// Read the rest of the file for its end time
+	for counted.err == nil {
+		_, _ = counted.GetEvent(ctx)
+	}
+	if !errors.Is(counted.err, io.EOF) {
+		return FileSummary{}, counted.err
+	}
+
+	found := counted.found
+	if found.min.IsZero() {
+		found = counted.header
+	}
+	if found.min.IsZero() {
+		return FileSummary{}, fmt.Errorf("no events with timestamp found in %s", binlogFile)
+	}
+	summary := FileSummary{Start: found.min, End: found.max, Events: counted.events, Bytes: counted.bytes}
+	if located != nil && !targetTime.Before(found.min) && !targetTime.After(found.max) {
+		summary.Position = located
+	}
+	return summary, nil
+}
+
+// onceReader serves a reader that can only be read once as a file read from its start
+type onceReader struct {
+	r io.Reader
+}
+
+func (o onceReader) OpenAt(binlogFile string, offset int64) (io.ReadCloser, error) {
+	if offset != 0 {
+		return nil, fmt.Errorf("cannot seek in %s", binlogFile)
+	}
+	return io.NopCloser(o.r), nil
+}
+
+// summaryStream passes events on while keeping the time range and counts of those read,
+// and the error that ended the stream
+type summaryStream struct {
+	EventStream
+	source        TimestampSource
+	found, header span
+	events        int
+	bytes         int64
+	err           error
+}
+
+func (s *summaryStream) GetEvent(ctx context.Context) (*replication.BinlogEvent, error) {
+	ev, err := s.EventStream.GetEvent(ctx)
+	if err != nil {
+		s.err = err
+		return nil, err
+	}
+	s.events++
+	s.bytes += int64(ev.Header.EventSize)
+	if t, ok := eventTime(ev, s.source); ok {
+		s.found.add(t)
+	}
+	if ev.Header.Timestamp > 0 {
+		s.header.add(time.Unix(int64(ev.Header.Timestamp), 0))
+	}
+	return ev, nil
+}
@@ -0,0 +1,283 @@
+package binlog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryFiles serves generated binlogs as stored files
+type memoryFiles map[string][]byte
+
+func (m memoryFiles) OpenAt(binlogFile string, offset int64) (io.ReadCloser, error) {
+	data, ok := m[binlogFile]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return io.NopCloser(bytes.NewReader(data[offset:])), nil
+}
+
+func TestFileStreamer(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 1, FileSize: 4 << 10, Seed: 1})
+	f := files[0]
+	streamer := FileStreamer{Reader: memoryFiles{f.Name: f.Data}}
+
+	tests := []struct {
+		name string
+		pos  uint32
+		// first is the index in f.Events of the first event after the format description
+		first int
+	}{
+		{name: "Start of file", pos: 4, first: 1},
+		{name: "Middle of file", pos: f.Events[5].Pos, first: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := streamer.StreamFrom(f.Name, tt.pos)
+			require.NoError(t, err)
+			defer stream.Close()
+
+			ev, err := stream.GetEvent(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, replication.FORMAT_DESCRIPTION_EVENT, ev.Header.EventType)
+
+			var positions []uint32
+			for {
+				ev, err := stream.GetEvent(context.Background())
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				positions = append(positions, ev.Header.LogPos-ev.Header.EventSize)
+			}
+			var want []uint32
+			for _, e := range f.Events[tt.first:] {
+				want = append(want, e.Pos)
+			}
+			assert.Equal(t, want, positions)
+		})
+	}
+}
+
+func TestFileStreamerErrors(t *testing.T) {
+	streamer := FileStreamer{Reader: memoryFiles{"notes.000001": []byte("not a binlog")}}
+
+	_, err := streamer.StreamFrom("notes.000001", 4)
+	assert.ErrorContains(t, err, "not a binlog file")
+	_, err = streamer.StreamFrom("binlog.000001", 4)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestFileStreamerEventEncryption(t *testing.T) {
+	f := binlogtest.Generate(binlogtest.Options{FileSize: 4 << 10, Seed: 1})[0]
+	streamer := FileStreamer{Reader: memoryFiles{f.Name: binlogtest.EncryptEvents(f)}}
+
+	_, err := getTimeRange(context.Background(), streamer, f.Name, 0, time.Second, DefaultScanLimits, TimestampHeader, nil)
+	assert.ErrorIs(t, err, ErrEventEncryption)
+	assert.False(t, damaged(err), "encrypted files are not corrupt")
+}
+
+func TestFileStreamerRawDump(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 1, FileSize: 8 << 10, Rate: 0.5, Seed: 1})
+	f := files[0]
+	from := len(f.Events) / 2
+	for f.Events[from].Type != replication.GTID_EVENT {
+		from++
+	}
+	skipped := f.Events[from].Pos - f.Events[1].Pos
+
+	tests := []struct {
+		name string
+		data []byte
+		// shift is how far before its position in f each event after the format
+		// description is stored
+		shift uint32
+		first int
+	}{
+		{name: "Raw dump from a position", data: binlogtest.RawDump(f, from), shift: skipped, first: from},
+		{name: "No magic number", data: f.Data[4:], shift: 4, first: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streamer := FileStreamer{Reader: memoryFiles{f.Name: tt.data}}
+			var want []uint32
+			for _, e := range f.Events[tt.first:] {
+				want = append(want, e.Pos-tt.shift)
+			}
+
+			// Positions are offsets in the stored file, from its start or any event
+			for _, start := range []uint32{4, want[len(want)/2]} {
+				stream, err := streamer.StreamFrom(f.Name, start)
+				require.NoError(t, err)
+				ev, err := stream.GetEvent(context.Background())
+				require.NoError(t, err)
+				assert.Equal(t, replication.FORMAT_DESCRIPTION_EVENT, ev.Header.EventType)
+				var positions []uint32
+				for {
+					ev, err := stream.GetEvent(context.Background())
+					if err == io.EOF {
+						break
+					}
+					require.NoError(t, err)
+					positions = append(positions, ev.Header.LogPos-ev.Header.EventSize)
+				}
+				stream.Close()
+				if start == 4 {
+					assert.Equal(t, want, positions)
+				} else {
+					assert.Equal(t, want[len(want)/2:], positions)
+				}
+			}
+
+			// The stored size ends probes at the last event rather than the first
+			target := f.End.Add(-time.Second)
+			finder := &Finder{Streamer: streamer, Sizes: map[string]int64{f.Name: int64(len(tt.data))}}
+			res := finder.Find([]string{f.Name}, target)
+			assert.Equal(t, f.Name, res.File)
+			assert.True(t, res.Exact())
+		})
+	}
+}
+
+func TestFinderWithFileStreamer(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 10, FileSize: 8 << 10, Rate: 0.5, Seed: 1})
+	stored := make(memoryFiles, len(files))
+	names := make([]string, len(files))
+	for i, f := range files {
+		stored[f.Name] = f.Data
+		names[i] = f.Name
+	}
+
+	// Without sizes, each probe reads to the end of the file
+	finder := &Finder{Streamer: FileStreamer{Reader: stored}}
+	for _, f := range files {
+		target := f.Start.Add(f.End.Sub(f.Start) / 2).Truncate(time.Second).Add(500 * time.Millisecond)
+		res := finder.Find(names, target)
+		assert.Equal(t, f.Name, res.File, "target %s", target)
+		assert.True(t, res.Exact(), "target %s", target)
+	}
+
+	pos, err := finder.Locate(files[3].Name, files[3].End, AlignEvent, 0)
+	require.NoError(t, err)
+	assert.Equal(t, files[3].Name, pos.File)
+	assert.WithinDuration(t, files[3].End, pos.Timestamp, 0)
+}
+
+func TestFinderCorruptEvents(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 3, FileSize: 8 << 10, Rate: 0.5, Seed: 1})
+	stored := make(memoryFiles, len(files))
+	names := make([]string, len(files))
+	for i, f := range files {
+		stored[f.Name] = bytes.Clone(f.Data)
+		names[i] = f.Name
+	}
+	// Flip a byte in the body of an event halfway through the second file
+	bad := files[1].Events[len(files[1].Events)/2]
+	stored[files[1].Name][bad.Pos+replication.EventHeaderSize] ^= 0xff
+
+	var corrupt []Corruption
+	finder := &Finder{Streamer: FileStreamer{Reader: stored}, OnCorrupt: func(c Corruption) { corrupt = append(corrupt, c) }}
+	r, err := (&searchRun{Finder: finder}).timeRange(files[1].Name)
+	require.NoError(t, err)
+	assert.Equal(t, TruncatedCorrupt, r.truncated)
+	assert.False(t, r.end.After(bad.Time), "range ends before the corrupt event")
+	assert.Equal(t, []Corruption{{File: files[1].Name, Pos: bad.Pos}}, corrupt)
+
+	// Corrupt ranges are not cached, so the corruption is reported again
+	_, err = (&searchRun{Finder: finder}).timeRange(files[1].Name)
+	require.NoError(t, err)
+	assert.Len(t, corrupt, 2)
+
+	// The search still uses the timestamps read before the corrupt event
+	res := finder.Find(names, files[1].Start.Add(time.Second))
+	assert.Equal(t, files[1].Name, res.File)
+	assert.True(t, res.Exact())
+
+	stream, err := FileStreamer{Reader: stored}.StreamFrom(files[1].Name, bad.Pos)
+	require.NoError(t, err)
+	defer stream.Close()
+	_, err = stream.GetEvent(context.Background()) // format description
+	require.NoError(t, err)
+	_, err = stream.GetEvent(context.Background())
+	assert.ErrorIs(t, err, replication.ErrChecksumMismatch)
+}
+
+func TestFinderSkipCorrupt(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 10, FileSize: 8 << 10, Rate: 0.5, Seed: 1})
+	stored := make(memoryFiles, len(files))
+	names := make([]string, len(files))
+	for i, f := range files {
+		stored[f.Name] = f.Data
+		names[i] = f.Name
+	}
+	// Damage the file probed first with garbage, and cut off others inside their format
+	// descriptions
+	stored[files[4].Name] = []byte("not a binlog")
+	stored[files[5].Name] = files[5].Data[:30]
+	stored[files[2].Name] = files[2].Data[:30]
+
+	for _, want := range []*binlogtest.File{files[1], files[3], files[6], files[9]} {
+		var skipped []string
+		finder := &Finder{Streamer: FileStreamer{Reader: stored}, SkipCorrupt: true, OnProbe: func(p Probe) {
+			if p.Decision == DecisionSkipped {
+				skipped = append(skipped, p.File)
+			}
+		}}
+		target := want.Start.Add(want.End.Sub(want.Start) / 2).Truncate(time.Second).Add(500 * time.Millisecond)
+		file, exact, err := finder.Search(names, target)
+		require.NoError(t, err)
+		assert.Equal(t, want.Name, file)
+		assert.True(t, exact)
+		assert.Contains(t, skipped, files[4].Name, "the first probe is skipped")
+	}
+
+	assert.True(t, damaged(&parseError{file: files[5].Name, err: io.ErrUnexpectedEOF}))
+	assert.False(t, damaged(os.ErrNotExist))
+}
+
+func TestSummarizeFile(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 2, FileSize: 8 << 10, Rate: 0.5, Seed: 1})
+	f := files[0]
+	finder := &Finder{Streamer: FileStreamer{Reader: memoryFiles{f.Name: f.Data}}}
+	probed, err := (&searchRun{Finder: finder}).timeRange(f.Name)
+	require.NoError(t, err)
+	middle := f.Start.Add(f.End.Sub(f.Start) / 2).Truncate(time.Second)
+	want, err := finder.Locate(f.Name, middle, AlignTransaction, 0)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		target   time.Time
+		position *Position
+	}{
+		{name: "No target"},
+		{name: "Target in file", target: middle, position: &want},
+		{name: "Target after file", target: files[1].End},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A plain reader, as for a file piped to stdin
+			summary, err := SummarizeFile(bytes.NewBufferString(string(f.Data)), f.Name, tt.target, AlignTransaction, TimestampHeader, OriginFilter{})
+			require.NoError(t, err)
+			assert.WithinDuration(t, probed.start, summary.Start, 0)
+			assert.WithinDuration(t, probed.end, summary.End, 0)
+			assert.Equal(t, len(f.Events), summary.Events)
+			assert.Equal(t, f.Size(), summary.Bytes+4)
+			assert.Equal(t, tt.position, summary.Position)
+		})
+	}
+
+	_, err = SummarizeFile(bytes.NewBufferString("not a binlog"), "stdin", time.Time{}, AlignTransaction, TimestampHeader, OriginFilter{})
+	assert.ErrorContains(t, err, "not a binlog file")
+}
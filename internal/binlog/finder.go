@@ -0,0 +1,817 @@
+package binlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/bits"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// Decisions recorded for each probe during a search
+const (
+	// DecisionMatch means the target time falls within the probed file
+	DecisionMatch = "match"
+	// DecisionEarlier means the target is before the probed file, so earlier files are searched next
+	DecisionEarlier = "search-earlier"
+	// DecisionLater means the target is after the probed file, so later files are searched next
+	DecisionLater = "search-later"
+	// DecisionClosest means the only candidate file does not contain the target but is the closest
+	DecisionClosest = "closest"
+	// DecisionEpoch means the probed file is the head of an epoch considered for the search
+	DecisionEpoch = "epoch-head"
+	// DecisionError means the file could not be probed
+	DecisionError = "error"
+	// DecisionSkipped means the file is corrupt or cannot be parsed and Finder.SkipCorrupt
+	// left it out of the search
+	DecisionSkipped = "skipped"
+	// DecisionBoundary means the probed file shares the target second with the matched file across a rotation
+	DecisionBoundary = "boundary-candidate"
+)
+
+// Preference chooses between two files that both contain the target second
+type Preference int
+
+const (
+	// PreferFirst picks the older file, whose last events are in the target second
+	PreferFirst Preference = iota
+	// PreferLast picks the newer file, whose first events are in the target second
+	PreferLast
+)
+
+// ParsePreference parses a preference name as used on the command line
+func ParsePreference(s string) (Preference, error) {
+	switch s {
+	case "first":
+		return PreferFirst, nil
+	case "last":
+		return PreferLast, nil
+	default:
+		return 0, fmt.Errorf("unknown preference %q (expected first or last)", s)
+	}
+}
+
+// String returns the command line name of the preference
+func (p Preference) String() string {
+	switch p {
+	case PreferFirst:
+		return "first"
+	case PreferLast:
+		return "last"
+	default:
+		return fmt.Sprintf("Preference(%d)", int(p))
+	}
+}
+
+// Probe describes a single binlog file inspected during a search
+type Probe struct {
+	File     string
+	Start    time.Time
+	End      time.Time
+	Decision string
+	Err      error
+	// Remaining estimates how many more probes the binary search needs
+	Remaining int
+	// Truncated names the limit that stopped the probe before the end of the file
+	// (TruncatedEvents, TruncatedBytes, TruncatedTimeout or TruncatedError), making End
+	// a lower bound
+	Truncated string
+	// Cached is set when the range was taken from Finder.Known or Finder.Cache instead
+	// of being probed
+	Cached bool
+}
+
+// TimeRange is the span of event times in a binlog file
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+	// Truncated is set as for Probe.Truncated when the range was probed
+	Truncated string
+}
+
+// Finder searches binlog files on a MySQL server for a point in time
+type Finder struct {
+	// Config is used to open a replication connection for every probe, and identifies
+	// the server in cache keys
+	Config replication.BinlogSyncerConfig
+	// Streamer, if set, reads the binlogs instead of replication connections opened
+	// from Config
+	Streamer EventStreamer
+	// Lister, if set, lists the binlogs again when one is purged during a search, instead
+	// of SHOW BINARY LOGS on the server in Config
+	Lister BinlogLister
+	// Prefer chooses the file returned when the target second spans a rotation
+	Prefer Preference
+	// Source selects which event timestamps are compared against the target time
+	Source TimestampSource
+	// Settings, if set, are used instead of DefaultSettings to read the binlogs: how far
+	// and how long each file is probed, which events are passed on, and how connections
+	// are opened, retried and paced
+	Settings *Settings
+	// ProbeTimeout, if set, bounds the probe of each file instead of the timeout of the
+	// Settings
+	ProbeTimeout time.Duration
+	// Context, if set, is the context the Finder's reads run under, such as that of the
+	// request a search answers or one canceled when the program is interrupted, so that
+	// they stop once it is canceled or its deadline passes
+	Context context.Context
+	// Logger, if set, receives the search's logs instead of the default logger
+	Logger *slog.Logger
+	// Sizes, if set, holds the size of each file as listed by SHOW BINARY LOGS. Probes stop
+	// at the listed size, so the active file is read without waiting for new events.
+	Sizes map[string]int64
+	// Known, if set, holds time ranges already known for some files, e.g. from a cache.
+	// These files are not probed; the newest file should not be included as it keeps growing.
+	Known map[string]TimeRange
+	// Cache remembers probed ranges across calls to Find, for files listed in Sizes. When
+	// nil, the Finder keeps its own in-memory cache; use a shared implementation to keep
+	// ranges across processes.
+	Cache Cache
+	// ServerUUID, if set, is the @@server_uuid of the server in Config, keeping the ranges
+	// cached for a server rebuilt behind the same address apart from those of the old one
+	ServerUUID string
+	// OnProbe, if set, is called after each binlog file is probed
+	OnProbe func(Probe)
+	// OnEvent, if set, is called with the running count of events read while probing a file.
+	// It is called by the goroutine reading the file, so never after the call reading it,
+	// such as Search or Locate, has returned.
+	OnEvent func(file string, events int)
+	// Stats, if set, accumulates the files probed and the events and bytes read
+	Stats *Stats
+	// OnGap, if set, is called when the target time falls between two consecutive files.
+	// Search only checks for a gap when OnGap is set; Find always does.
+	OnGap func(Gap)
+	// SkipGapConfirm reports a gap only when the probe of the preceding file read it to its
+	// end. By default a preceding file whose probe the scan limits stopped early is read to
+	// its end to tell a gap from events the probe did not reach, which costs a full read.
+	SkipGapConfirm bool
+	// SkipCorrupt leaves files that fail a checksum or cannot be parsed before any
+	// timestamp is read out of the search, treating their ranges as unknown, instead of
+	// counting them towards the errors that end it. Skipped files are reported to OnProbe
+	// with DecisionSkipped.
+	SkipCorrupt bool
+	// OnCorrupt, if set, is called for each probed file with an event failing its CRC32
+	// checksum, whether or not the probe found timestamps before it
+	OnCorrupt func(Corruption)
+	// Position makes Find also locate the position of the target time in the file found,
+	// when the file contains it, for Result.Position
+	Position bool
+	// Align is the boundary the positions located by Find are snapped to
+	Align Alignment
+
+	cacheOnce sync.Once
+	cache     Cache
+}
+
+// searchRun is a single search by a Finder, recording what it met along the way for the
+// Result of Find. A Finder may run concurrent searches, so this is kept apart from it.
+type searchRun struct {
+	*Finder
+	// gaps checks for gaps even without OnGap, for the Result of Find
+	gaps    bool
+	probes  []Probe
+	corrupt []Corruption
+	gap     *Gap
+	// starts holds the first event times of the files probed, which tell restarted
+	// histories apart
+	starts map[string]time.Time
+}
+
+// Gap is a period with no events between the last event of one binlog file and the
+// first event of the next, e.g. while the server was down or binary logging was disabled
+type Gap struct {
+	Before string
+	After  string
+	// Start is the last event time in Before, End the first event time in After
+	Start time.Time
+	End   time.Time
+}
+
+// fileRange is the time range found by probing a binlog file
+type fileRange struct {
+	start, end time.Time
+	truncated  string
+	// cached is set when the range was taken from Known rather than probed
+	cached bool
+	// corrupt is the first event failing its checksum, which ended the probe
+	corrupt *Corruption
+}
+
+// probe describes the range as a probe of the given file
+func (r fileRange) probe(file, decision string) Probe {
+	return Probe{File: file, Start: r.start, End: r.end, Decision: decision, Truncated: r.truncated, Cached: r.cached}
+}
+
+// timeRange probes a binlog file for its time range using a fresh syncer, unless the
+// range is already known or cached
+func (f *searchRun) timeRange(binlogFile string) (fileRange, error) {
+	r, err := f.rangeOf(binlogFile)
+	if err == nil && !r.start.IsZero() {
+		if f.starts == nil {
+			f.starts = make(map[string]time.Time)
+		}
+		f.starts[binlogFile] = r.start
+	}
+	return r, err
+}
+
+// rangeOf implements timeRange
+func (f *searchRun) rangeOf(binlogFile string) (fileRange, error) {
+	if known, ok := f.Known[binlogFile]; ok {
+		f.Stats.addCached()
+		return fileRange{start: known.Start, end: known.End, truncated: known.Truncated, cached: true}, nil
+	}
+
+	cache := f.rangeCache()
+	key, cacheable := f.cacheKey(binlogFile)
+	if cacheable {
+		if cached, ok := cache.Get(key); ok {
+			f.Stats.addCached()
+			return fileRange{start: cached.Start, end: cached.End, truncated: cached.Truncated, cached: true}, nil
+		}
+	}
+
+	onEvent, done := f.reader(binlogFile)
+	// Create new syncer for each file to avoid "Sync is running" errors
+	r, err := getTimeRange(f.readContext(), f.streamer(), binlogFile, key.Size, f.probeTimeout(), f.settings().ScanLimits, f.Source, onEvent)
+	done(true)
+	var corrupt *Corruption
+	if errors.As(err, &corrupt) {
+		f.reportCorrupt(corrupt)
+	}
+	if err != nil {
+		return fileRange{}, err
+	}
+	if r.corrupt != nil {
+		// Not cached, so that every search reports the corruption
+		f.reportCorrupt(r.corrupt)
+		return r, nil
+	}
+
+	if cacheable {
+		cache.Put(key, TimeRange{Start: r.start, End: r.end, Truncated: r.truncated})
+	}
+	return r, nil
+}
+
+// cacheKey returns the range cache key of binlogFile, and whether its range may be cached
+// at all. The active file keeps growing, so only the size in the key tells a later probe
+// its range moved on; files of unknown size are not cached.
+func (f *Finder) cacheKey(binlogFile string) (CacheKey, bool) {
+	key := CacheKey{
+		Server:     net.JoinHostPort(f.Config.Host, strconv.Itoa(int(f.Config.Port))),
+		ServerUUID: f.ServerUUID,
+		Source:     f.Source,
+		Origin:     f.settings().Origin.String(),
+		File:       binlogFile,
+		Size:       f.Sizes[binlogFile],
+	}
+	return key, key.Size > 0
+}
+
+// reportCorrupt logs a checksum failure and passes it to the OnCorrupt callback, if any
+func (f *searchRun) reportCorrupt(c *Corruption) {
+	f.logger().Warn("Binlog event failed its checksum", "file", c.File, "position", c.Pos)
+	f.corrupt = append(f.corrupt, *c)
+	if f.OnCorrupt != nil {
+		f.OnCorrupt(*c)
+	}
+}
+
+// reader returns the hook passed to the functions reading a file, which forwards event
+// counts to OnEvent, and a function adding what was read to Stats once reading is done
+func (f *Finder) reader(binlogFile string) (func(events int, bytes int64), func(probe bool)) {
+	var events int
+	var bytes int64
+	onEvent := func(e int, b int64) {
+		events, bytes = e, b
+		if f.OnEvent != nil {
+			f.OnEvent(binlogFile, e)
+		}
+	}
+	return onEvent, func(probe bool) { f.Stats.add(probe, events, bytes) }
+}
+
+// logger returns the configured logger, or the default one
+func (f *Finder) logger() *slog.Logger {
+	if f.Logger != nil {
+		return f.Logger
+	}
+	return slog.Default()
+}
+
+// settings returns the configured settings, or DefaultSettings
+func (f *Finder) settings() Settings {
+	return settingsOrDefault(f.Settings)
+}
+
+// probeTimeout returns the ProbeTimeout, or else the probe timeout of the Settings
+func (f *Finder) probeTimeout() time.Duration {
+	if f.ProbeTimeout > 0 {
+		return f.ProbeTimeout
+	}
+	return f.settings().ProbeTimeout
+}
+
+// server returns the server in Config, connected to with the Finder's settings and context
+func (f *Finder) server() Server {
+	return Server{Config: f.Config, Settings: f.Settings, Context: f.readContext()}
+}
+
+// streamer returns the configured streamer, with the origin filter and throttle of the
+// Settings applied, or one connecting to the server in Config
+func (f *Finder) streamer() EventStreamer {
+	if f.Streamer != nil {
+		return f.settings().streamer(f.Streamer)
+	}
+	return f.server()
+}
+
+// lister returns the configured lister, or one querying the server in Config
+func (f *Finder) lister() BinlogLister {
+	if f.Lister != nil {
+		return f.Lister
+	}
+	return f.server()
+}
+
+// rangeCache returns the configured cache, or the Finder's own in-memory one
+func (f *Finder) rangeCache() Cache {
+	if f.Cache != nil {
+		return f.Cache
+	}
+	f.cacheOnce.Do(func() { f.cache = NewMemoryCache() })
+	return f.cache
+}
+
+// remainingProbes estimates the probes a binary search needs for the window [left, right]
+func remainingProbes(left, right int) int {
+	if right < left {
+		return 0
+	}
+	return bits.Len(uint(right - left + 1))
+}
+
+// report logs a probe's decision and passes it to the OnProbe callback, if any
+func (f *searchRun) report(p Probe) {
+	f.logger().Debug("Search decision", "file", p.File, "decision", p.Decision,
+		"start", p.Start.Format("2006-01-02 15:04:05"), "end", p.End.Format("2006-01-02 15:04:05"), "error", p.Err)
+	f.probes = append(f.probes, p)
+	if f.OnProbe != nil {
+		f.OnProbe(p)
+	}
+}
+
+// maxRefetches limits how many times a search restarts after files are purged under it
+const maxRefetches = 3
+
+// errPurged is returned by search when a probed file no longer exists on the server
+var errPurged = errors.New("binlog purged during search")
+
+// errRestarted is returned by search when a probed file starts before a file listed
+// before it, so that the history restarted somewhere in between
+var errRestarted = errors.New("binlog history restarted")
+
+// isPurged reports whether err means the requested binlog file is no longer available,
+// typically because it was purged between listing and reading it
+func isPurged(err error) bool {
+	var myErr *mysql.MyError
+	return errors.As(err, &myErr) && myErr.Code == mysql.ER_MASTER_FATAL_ERROR_READING_BINLOG
+}
+
+// Find performs a binary search on binlog files to find which contains the target timestamp,
+// and describes how well the file found matches it and what the search met on the way.
+// If a file is purged while the search runs, the file list is fetched again and the search restarted.
+func (f *Finder) Find(binlogFiles []string, targetTime time.Time) Result {
+	run := &searchRun{Finder: f, gaps: true}
+	file, exact, err := run.find(binlogFiles, targetTime)
+	return run.result(file, exact, err, targetTime)
+}
+
+// Search is like Find, but returns only the file found and whether it contains the target
+// time, along with why the target time is not within any file: ErrNoBinlogs, or a
+// RangeError wrapping ErrTimestampBeforeRetention or ErrTimestampInFuture. Other errors
+// are returned when files keep being purged under the search or the list cannot be
+// refreshed, and context.Canceled, with no file, once the Finder's Context is canceled.
+func (f *Finder) Search(binlogFiles []string, targetTime time.Time) (string, bool, error) {
+	return (&searchRun{Finder: f}).find(binlogFiles, targetTime)
+}
+
+// find implements Search, starting the search again when a file is purged under it, or
+// once the files probed reveal a restarted history, which then splits the files into
+// epochs. Each restart splits off one more epoch, so they are bounded by the files.
+func (f *searchRun) find(binlogFiles []string, targetTime time.Time) (string, bool, error) {
+	for refetches := 0; ; {
+		file, exact, err := f.search(binlogFiles, targetTime)
+		if errors.Is(err, errRestarted) {
+			f.logger().Info("Searching again in the epochs split by start time", "error", err)
+			f.restart()
+			continue
+		}
+		if !errors.Is(err, errPurged) || refetches == maxRefetches {
+			return file, exact, err
+		}
+		refetches++
+
+		f.logger().Warn("Binlog was purged during the search, refreshing the file list", "error", err)
+		files, err := f.lister().ListBinlogs()
+		if err != nil {
+			f.logger().Warn("Could not refresh binlog files", "error", err)
+			return file, exact, err
+		}
+		binlogFiles = make([]string, 0, len(files))
+		for _, info := range files {
+			binlogFiles = append(binlogFiles, info.Name)
+		}
+		f.restart()
+	}
+}
+
+// restart forgets what a search found before it started again, so that the Result of
+// Find describes the last search alone. The start times are kept for SplitEpochs.
+func (f *searchRun) restart() {
+	f.probes, f.corrupt, f.gap = nil, nil, nil
+}
+
+// search implements Search for a fixed file list. It returns errPurged, along with the
+// best answer so far, if a probed file has been purged from the server.
+func (f *searchRun) search(binlogFiles []string, targetTime time.Time) (file string, exact bool, err error) {
+	if len(binlogFiles) == 0 {
+		f.logger().Warn("No binlog files provided")
+		return "", false, ErrNoBinlogs
+	}
+
+	f.logger().Info("Searching binlog files", "files", len(binlogFiles), "target", targetTime.Format("2006-01-02 15:04:05"))
+
+	// The newest file is still being written, which bounds interpolation from above
+	oldest, newest := binlogFiles[0], binlogFiles[len(binlogFiles)-1]
+
+	// Timestamps are only ordered within a single history, so restrict the search to one epoch
+	if epochs := SplitEpochs(binlogFiles, f.knownStarts()); len(epochs) > 1 {
+		binlogFiles = f.selectEpoch(epochs, targetTime)
+	}
+	// A target outside the epoch may be in a history restarted within it. A target past
+	// the lower bound of a truncated range is no sign of one, nor worth a read to rule out.
+	var truncated bool
+	defer func() {
+		var rangeErr *RangeError
+		if !exact && file != "" && !truncated && (err == nil || errors.As(err, &rangeErr)) && f.restarted(binlogFiles) {
+			err = fmt.Errorf("%w: before %s", errRestarted, binlogFiles[len(binlogFiles)-1])
+		}
+	}()
+	var now time.Time
+	if binlogFiles[len(binlogFiles)-1] == newest {
+		now = time.Now()
+	}
+
+	// Track files that we've checked successfully
+	validFiles := make(map[string]struct{})
+	timeRanges := make(map[string]fileRange)
+
+	// If only one file, check if it contains the target time
+	if len(binlogFiles) == 1 {
+		r, err := f.timeRange(binlogFiles[0])
+		if err != nil {
+			f.logger().Warn("Could not get time range", "file", binlogFiles[0], "error", err)
+			f.report(Probe{File: binlogFiles[0], Err: err, Decision: DecisionError})
+			if isPurged(err) {
+				return binlogFiles[0], false, fmt.Errorf("%w: %s", errPurged, binlogFiles[0])
+			}
+			if interrupted(err) {
+				return "", false, err
+			}
+			return binlogFiles[0], false, nil
+		}
+
+		f.logger().Info("Probed binlog time range",
+			"file", binlogFiles[0],
+			"start", r.start.Format("2006-01-02 15:04:05"),
+			"end", r.end.Format("2006-01-02 15:04:05"),
+			"truncated", r.truncated,
+			"cached", r.cached)
+
+		validFiles[binlogFiles[0]] = struct{}{}
+		timeRanges[binlogFiles[0]] = r
+
+		if !targetTime.Before(r.start) && !targetTime.After(r.end) {
+			f.report(r.probe(binlogFiles[0], DecisionMatch))
+			return binlogFiles[0], true, nil
+		}
+
+		f.report(r.probe(binlogFiles[0], DecisionClosest))
+		return binlogFiles[0], false, outside(binlogFiles[0], r, oldest, newest, targetTime)
+	}
+
+	// Binary search
+	left, right := 0, len(binlogFiles)-1
+	var errorCount int
+	var purged error
+
+	// Interpolation steps alternate with binary ones whenever they fail to halve the
+	// window, so uneven write rates never make the search worse than twice binary search
+	interpolate, lastWidth := true, 0
+
+	for left <= right {
+		mid := left + (right-left)/2
+		width := right - left + 1
+		if lastWidth > 0 && width > lastWidth/2 {
+			interpolate = false
+		}
+		lastWidth = 0
+		if guess, ok := f.interpolate(binlogFiles, left, right, timeRanges, targetTime, now); ok && interpolate {
+			mid, lastWidth = guess, width
+			f.logger().Debug("Interpolation step", "left", binlogFiles[left], "right", binlogFiles[right], "guess", binlogFiles[mid])
+		} else {
+			interpolate = true
+			f.logger().Debug("Binary search step", "left", binlogFiles[left], "right", binlogFiles[right], "mid", binlogFiles[mid])
+		}
+
+		// Check if we already processed this file
+		if _, exists := timeRanges[binlogFiles[mid]]; !exists {
+			r, err := f.timeRange(binlogFiles[mid])
+			if err != nil {
+				f.logger().Warn("Could not get time range", "file", binlogFiles[mid], "error", err)
+				// A purged file shifts the whole list, so the search has to start again
+				if isPurged(err) {
+					f.report(Probe{File: binlogFiles[mid], Err: err, Decision: DecisionError})
+					purged = fmt.Errorf("%w: %s", errPurged, binlogFiles[mid])
+					break
+				}
+				// Nothing is probed once interrupted, so the search has no answer
+				if interrupted(err) {
+					f.report(Probe{File: binlogFiles[mid], Err: err, Decision: DecisionError})
+					return "", false, err
+				}
+				// Carry on as if the damaged file was not in the list
+				if f.SkipCorrupt && damaged(err) {
+					skipped := binlogFiles[mid]
+					binlogFiles = slices.Delete(slices.Clone(binlogFiles), mid, mid+1)
+					right--
+					f.report(Probe{File: skipped, Err: err, Decision: DecisionSkipped, Remaining: remainingProbes(left, right)})
+					if len(binlogFiles) == 0 {
+						return "", false, fmt.Errorf("%w: every file was skipped as corrupt", ErrNoBinlogs)
+					}
+					continue
+				}
+				errorCount++
+				// If we've had too many errors, return what we have
+				if errorCount > 3 {
+					f.logger().Warn("Too many errors encountered, stopping search", "errors", errorCount)
+					f.report(Probe{File: binlogFiles[mid], Err: err, Decision: DecisionError})
+					break
+				}
+
+				// Try to continue with the search
+				if mid > 0 {
+					right = mid - 1
+				} else {
+					left = mid + 1
+				}
+				f.report(Probe{File: binlogFiles[mid], Err: err, Decision: DecisionError, Remaining: remainingProbes(left, right)})
+				continue
+			}
+
+			f.logger().Info("Probed binlog time range",
+				"file", binlogFiles[mid],
+				"start", r.start.Format("2006-01-02 15:04:05"),
+				"end", r.end.Format("2006-01-02 15:04:05"),
+				"truncated", r.truncated,
+				"cached", r.cached)
+
+			if wentBack(binlogFiles, mid, r, timeRanges) {
+				return binlogFiles[mid], false, fmt.Errorf("%w: %s", errRestarted, binlogFiles[mid])
+			}
+			validFiles[binlogFiles[mid]] = struct{}{}
+			timeRanges[binlogFiles[mid]] = r
+		}
+
+		timeRange := timeRanges[binlogFiles[mid]]
+		start, end := timeRange.start, timeRange.end
+
+		// Target time is within this binlog's range
+		if !targetTime.Before(start) && !targetTime.After(end) {
+			f.report(timeRange.probe(binlogFiles[mid], DecisionMatch))
+			return f.breakTie(binlogFiles, mid, start, end, timeRanges, targetTime), true, nil
+		}
+
+		// Target time is before this binlog
+		if targetTime.Before(start) {
+			right = mid - 1
+			p := timeRange.probe(binlogFiles[mid], DecisionEarlier)
+			p.Remaining = remainingProbes(left, right)
+			f.report(p)
+		} else {
+			// Target time is after this binlog
+			left = mid + 1
+			p := timeRange.probe(binlogFiles[mid], DecisionLater)
+			p.Remaining = remainingProbes(left, right)
+			f.report(p)
+		}
+	}
+
+	// If we didn't find an exact match, return the closest binlog that's before the target time
+	if len(validFiles) > 0 {
+		// Find the closest valid file that's before the target time
+		var closestFile string
+		var closestEnd time.Time
+
+		for file := range validFiles {
+			timeRange := timeRanges[file]
+			if !targetTime.Before(timeRange.end) {
+				if closestFile == "" || timeRange.end.After(closestEnd) {
+					closestFile = file
+					closestEnd = timeRange.end
+				}
+			}
+		}
+
+		if closestFile != "" {
+			if purged != nil {
+				return closestFile, false, purged
+			}
+			f.checkGap(binlogFiles, closestFile, timeRanges, targetTime)
+			truncated = timeRanges[closestFile].truncated != ""
+			return closestFile, false, outside(closestFile, timeRanges[closestFile], oldest, newest, targetTime)
+		}
+	}
+
+	// If no match found, return the first file
+	if purged != nil {
+		return binlogFiles[0], false, purged
+	}
+	if r, ok := timeRanges[binlogFiles[0]]; ok {
+		return binlogFiles[0], false, outside(binlogFiles[0], r, oldest, newest, targetTime)
+	}
+	return binlogFiles[0], false, nil
+}
+
+// outside returns a RangeError if the target time is outside the range r of file because
+// it is before the oldest file or after the newest
+func outside(file string, r fileRange, oldest, newest string, targetTime time.Time) error {
+	switch {
+	case file == oldest && targetTime.Before(r.start):
+		return &RangeError{File: file, Bound: r.start, err: ErrTimestampBeforeRetention}
+	// A truncated probe only found a lower bound of the end time
+	case file == newest && r.truncated == "" && targetTime.After(r.end):
+		return &RangeError{File: file, Bound: r.end, err: ErrTimestampInFuture}
+	}
+	return nil
+}
+
+// interpolate guesses which file in binlogFiles[left:right+1] contains the target time,
+// assuming a steady write rate between the end of the probed file before the window and
+// the start of the probed file after it (or now, for a window ending at the active file).
+// Files are weighted by size when Sizes is set, and equally otherwise. It returns false
+// when either bound is unknown.
+func (f *Finder) interpolate(binlogFiles []string, left, right int, timeRanges map[string]fileRange, targetTime, now time.Time) (int, bool) {
+	if left == 0 {
+		return 0, false
+	}
+	before, ok := timeRanges[binlogFiles[left-1]]
+	if !ok {
+		return 0, false
+	}
+	upper := now
+	if right < len(binlogFiles)-1 {
+		after, ok := timeRanges[binlogFiles[right+1]]
+		if !ok {
+			return 0, false
+		}
+		upper = after.start
+	}
+	if !targetTime.After(before.end) || !targetTime.Before(upper) {
+		return 0, false
+	}
+
+	weight := func(file string) float64 {
+		if size := f.Sizes[file]; size > 0 {
+			return float64(size)
+		}
+		return 1
+	}
+	var total float64
+	for _, file := range binlogFiles[left : right+1] {
+		total += weight(file)
+	}
+
+	goal := total * float64(targetTime.Sub(before.end)) / float64(upper.Sub(before.end))
+	var cumulative float64
+	for i := left; i <= right; i++ {
+		cumulative += weight(binlogFiles[i])
+		if cumulative >= goal {
+			return i, true
+		}
+	}
+	return right, true
+}
+
+// checkGap records a gap, and reports it to OnGap, if the target time is after the last
+// event of the closest preceding file and before the first event of the file following it
+func (f *searchRun) checkGap(binlogFiles []string, closest string, timeRanges map[string]fileRange, targetTime time.Time) {
+	if f.OnGap == nil && !f.gaps {
+		return
+	}
+
+	i := slices.Index(binlogFiles, closest)
+	// Past the newest file the events simply haven't been written yet
+	if i < 0 || i == len(binlogFiles)-1 {
+		return
+	}
+	next := binlogFiles[i+1]
+
+	nextRange, ok := timeRanges[next]
+	if !ok {
+		var err error
+		if nextRange, err = f.timeRange(next); err != nil {
+			f.logger().Warn("Could not get time range", "file", next, "error", err)
+			return
+		}
+	}
+
+	if !targetTime.Before(nextRange.start) {
+		return
+	}
+
+	last := timeRanges[closest].end
+	if timeRanges[closest].truncated != "" {
+		if f.SkipGapConfirm {
+			return
+		}
+		// The probed end time is sampled, so read the preceding file to its end to be sure
+		// there really are no events at the target time
+		onEvent, done := f.reader(closest)
+		var err error
+		last, err = lastEventTime(f.readContext(), f.streamer(), closest, f.Sizes[closest], f.Source, onEvent)
+		done(false)
+		if err != nil {
+			f.logger().Warn("Could not verify gap after binlog", "file", closest, "error", err)
+			return
+		}
+	}
+
+	if targetTime.After(last) {
+		gap := Gap{Before: closest, After: next, Start: last, End: nextRange.start}
+		f.logger().Info("Target time falls in a gap between binlogs", "before", gap.Before, "after", gap.After,
+			"start", gap.Start.Format("2006-01-02 15:04:05"), "end", gap.End.Format("2006-01-02 15:04:05"))
+		f.gap = &gap
+		if f.OnGap != nil {
+			f.OnGap(gap)
+		}
+	}
+}
+
+// breakTie checks whether the file at i shares the target second with a neighbor, which
+// happens when the server rotated during that second, and returns the file chosen by Prefer
+func (f *searchRun) breakTie(binlogFiles []string, i int, start, end time.Time, timeRanges map[string]fileRange, targetTime time.Time) string {
+	other := -1
+	var otherRange fileRange
+	switch {
+	case start.Equal(targetTime) && i > 0:
+		// The probed end time is sampled, so read the previous file to its end
+		prev := binlogFiles[i-1]
+		onEvent, done := f.reader(prev)
+		last, err := lastEventTime(f.readContext(), f.streamer(), prev, f.Sizes[prev], f.Source, onEvent)
+		done(false)
+		if err != nil {
+			f.logger().Warn("Could not read the end of the previous binlog", "file", prev, "error", err)
+			break
+		}
+		if last.Equal(targetTime) {
+			other = i - 1
+			otherRange = fileRange{start: timeRanges[prev].start, end: last}
+		}
+	case end.Equal(targetTime) && i < len(binlogFiles)-1:
+		next := binlogFiles[i+1]
+		nextRange, ok := timeRanges[next]
+		if !ok {
+			var err error
+			if nextRange, err = f.timeRange(next); err != nil {
+				f.logger().Warn("Could not get time range", "file", next, "error", err)
+				break
+			}
+		}
+		if nextRange.start.Equal(targetTime) {
+			other, otherRange = i+1, nextRange
+		}
+	}
+
+	if other < 0 {
+		return binlogFiles[i]
+	}
+
+	f.report(otherRange.probe(binlogFiles[other], DecisionBoundary))
+	first, last := binlogFiles[min(i, other)], binlogFiles[max(i, other)]
+	f.logger().Info("Target second spans a rotation", "first", first, "last", last, "prefer", f.Prefer.String())
+	if f.Prefer == PreferLast {
+		return last
+	}
+	return first
+}
@@ -0,0 +1,125 @@
+package binlog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePreference(t *testing.T) {
+	for _, prefer := range []Preference{PreferFirst, PreferLast} {
+		parsed, err := ParsePreference(prefer.String())
+		assert.NoError(t, err)
+		assert.Equal(t, prefer, parsed)
+	}
+
+	_, err := ParsePreference("middle")
+	assert.Error(t, err)
+}
+
+func TestIsPurged(t *testing.T) {
+	purged := &mysql.MyError{Code: mysql.ER_MASTER_FATAL_ERROR_READING_BINLOG, Message: "Could not find first log file name in binary log index file"}
+
+	assert.True(t, isPurged(purged))
+	assert.True(t, isPurged(fmt.Errorf("failed to get event: %w", purged)))
+	assert.False(t, isPurged(&mysql.MyError{Code: mysql.ER_ACCESS_DENIED_ERROR}))
+	assert.False(t, isPurged(errors.New("timeout")))
+}
+
+func TestInterpolate(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	files := []string{"binlog.000001", "binlog.000002", "binlog.000003", "binlog.000004", "binlog.000005", "binlog.000006"}
+	ranges := map[string]fileRange{
+		"binlog.000001": {start: base, end: base.Add(time.Hour)},
+		"binlog.000006": {start: base.Add(5 * time.Hour), end: base.Add(6 * time.Hour)},
+	}
+
+	tests := []struct {
+		name     string
+		sizes    map[string]int64
+		left     int
+		right    int
+		target   time.Time
+		now      time.Time
+		expected int
+		ok       bool
+	}{
+		{name: "Equal weights", left: 1, right: 4, target: base.Add(3*time.Hour + 30*time.Minute), expected: 3, ok: true},
+		{name: "Weighted by size", sizes: map[string]int64{"binlog.000002": 700, "binlog.000003": 100, "binlog.000004": 100, "binlog.000005": 100},
+			left: 1, right: 4, target: base.Add(3*time.Hour + 30*time.Minute), expected: 1, ok: true},
+		{name: "Bounded by now", left: 1, right: 5, target: base.Add(90 * time.Minute), now: base.Add(6 * time.Hour), expected: 1, ok: true},
+		{name: "No upper bound", left: 1, right: 5, target: base.Add(90 * time.Minute)},
+		{name: "No lower bound", left: 0, right: 4, target: base.Add(90 * time.Minute)},
+		{name: "Outside the bounds", left: 1, right: 4, target: base.Add(7 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Finder{Sizes: tt.sizes}
+			guess, ok := f.interpolate(files, tt.left, tt.right, ranges, tt.target, tt.now)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, tt.expected, guess)
+			}
+		})
+	}
+}
+
+// quietLogs discards the search's logs for the rest of a benchmark
+func quietLogs(b *testing.B) {
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	b.Cleanup(func() { slog.SetDefault(prev) })
+}
+
+// BenchmarkFind measures how many files a search probes over generated binlogs, with the
+// time ranges served from Known so no server is needed
+func BenchmarkFind(b *testing.B) {
+	quietLogs(b)
+	for _, dist := range []binlogtest.Distribution{binlogtest.Steady, binlogtest.Poisson, binlogtest.Diurnal, binlogtest.Bursty} {
+		for _, count := range []int{10, 100, 1000} {
+			b.Run(fmt.Sprintf("%s/%d", dist, count), func(b *testing.B) {
+				files := binlogtest.Generate(binlogtest.Options{Files: count, FileSize: 16 << 10, Rate: 5, Distribution: dist, Seed: 1})
+				names := make([]string, len(files))
+				known := make(map[string]TimeRange, len(files))
+				sizes := make(map[string]int64, len(files))
+				for i, f := range files {
+					names[i] = f.Name
+					known[f.Name] = TimeRange{Start: f.Start, End: f.End}
+					sizes[f.Name] = f.Size()
+				}
+
+				var probes int
+				finder := &Finder{
+					Config:  replication.BinlogSyncerConfig{ServerID: 100, Host: "db", Port: 3306},
+					Known:   known,
+					Sizes:   sizes,
+					OnProbe: func(Probe) { probes++ },
+				}
+				rng := rand.New(rand.NewSource(1))
+				span := files[len(files)-1].End.Sub(files[0].Start)
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					// Half a second off the whole seconds in the headers, so no target
+					// spans a rotation and needs the server to break the tie
+					target := files[0].Start.Add(time.Duration(rng.Int63n(int64(span/time.Second))) * time.Second).Add(500 * time.Millisecond)
+					res := finder.Find(names, target)
+					if res.File == "" {
+						b.Fatalf("no file found for %s", target)
+					}
+				}
+				b.ReportMetric(float64(probes)/float64(b.N), "probes/op")
+			})
+		}
+	}
+}
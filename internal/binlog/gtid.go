@@ -0,0 +1,227 @@
+package binlog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// mariadbGTIDPattern matches a single MariaDB GTID, domain-server-sequence
+var mariadbGTIDPattern = regexp.MustCompile(`^\d+-\d+-\d+$`)
+
+// ParseGTID parses a MariaDB GTID, e.g. 0-1-12345, or else a MySQL GTID set, e.g.
+// 3e11fa47-71ca-11e1-9e33-c80aa9429562:23
+func ParseGTID(gtid string) (mysql.GTIDSet, error) {
+	if mariadbGTIDPattern.MatchString(gtid) {
+		return mysql.ParseMariadbGTIDSet(gtid)
+	}
+	return mysql.ParseMysqlGTIDSet(gtid)
+}
+
+// GetPreviousGTIDs returns the GTIDs written before a binlog file, as recorded at its head by
+// the PREVIOUS_GTIDS event on MySQL or the GTID_LIST event on MariaDB
+func GetPreviousGTIDs(syncer *replication.BinlogSyncer, binlogFile string) (mysql.GTIDSet, error) {
+	return previousGTIDs(context.Background(), newSyncerStreamer(syncer), binlogFile)
+}
+
+// previousGTIDs implements GetPreviousGTIDs
+func previousGTIDs(ctx context.Context, streamer EventStreamer, binlogFile string) (mysql.GTIDSet, error) {
+	ctx, cancel := withReadTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	stream, err := streamer.StreamFrom(binlogFile, 4)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start sync from %s: %w", binlogFile, err)
+	}
+	defer stream.Close()
+
+	// PREVIOUS_GTIDS and GTID_LIST follow the rotate and format description events, so they are always near the start
+	for i := 0; i < 10; i++ {
+		ev, err := stream.GetEvent(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get event: %w", err)
+		}
+		traceEvent(binlogFile, ev)
+
+		switch e := ev.Event.(type) {
+		case *replication.PreviousGTIDsEvent:
+			return mysql.ParseMysqlGTIDSet(e.GTIDSets)
+		case *replication.MariadbGTIDListEvent:
+			set := &mysql.MariadbGTIDSet{Sets: make(map[uint32]map[uint32]*mysql.MariadbGTID)}
+			for i := range e.GTIDs {
+				if err := set.AddSet(&e.GTIDs[i]); err != nil {
+					return nil, fmt.Errorf("invalid GTID list in %s: %w", binlogFile, err)
+				}
+			}
+			return set, nil
+		case *replication.GTIDEvent, *replication.MariadbGTIDEvent:
+			// Reached the first transaction without seeing the header, so GTIDs are not in use
+			return nil, fmt.Errorf("no PREVIOUS_GTIDS or GTID_LIST event found in %s", binlogFile)
+		}
+	}
+
+	return nil, fmt.Errorf("no PREVIOUS_GTIDS or GTID_LIST event found in %s", binlogFile)
+}
+
+// includesGTID reports whether the GTIDs written before a file include the target. A
+// MariaDB GTID list holds the last sequence number of each domain and server; sequence
+// numbers grow across the servers of a domain, so any of them at or past the target's
+// includes it, even when a failover changed the server ID since.
+func includesGTID(previous, target mysql.GTIDSet) bool {
+	list, ok := previous.(*mysql.MariadbGTIDSet)
+	if !ok {
+		return previous.Contain(target)
+	}
+	want, ok := target.(*mysql.MariadbGTIDSet)
+	if !ok {
+		return false
+	}
+	for domain, servers := range want.Sets {
+		for _, gtid := range servers {
+			included := false
+			for _, last := range list.Sets[domain] {
+				included = included || last.SequenceNumber >= gtid.SequenceNumber
+			}
+			if !included {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isMariaDB(set mysql.GTIDSet) bool {
+	_, ok := set.(*mysql.MariadbGTIDSet)
+	return ok
+}
+
+// gtidFlavor names the kind of GTIDs in the set, for errors
+func gtidFlavor(set mysql.GTIDSet) string {
+	if isMariaDB(set) {
+		return "MariaDB domain-server-sequence"
+	}
+	return "MySQL UUID:sequence"
+}
+
+// FindGTIDPosition scans a binlog file for the GTID event of the given transaction
+// and returns the position at which it starts, with the event's time
+func FindGTIDPosition(syncer *replication.BinlogSyncer, binlogFile string, gtid mysql.GTIDSet) (Position, error) {
+	return findGTIDPosition(context.Background(), newSyncerStreamer(syncer), binlogFile, gtid)
+}
+
+// findGTIDPosition implements FindGTIDPosition
+func findGTIDPosition(ctx context.Context, streamer EventStreamer, binlogFile string, gtid mysql.GTIDSet) (Position, error) {
+	ctx, cancel := withReadTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	stream, err := streamer.StreamFrom(binlogFile, 4)
+	if err != nil {
+		return Position{}, fmt.Errorf("failed to start sync from %s: %w", binlogFile, err)
+	}
+	defer stream.Close()
+
+	for {
+		ev, err := stream.GetEvent(ctx)
+		if err != nil {
+			return Position{}, fmt.Errorf("GTID %s not found in %s: %w", gtid, binlogFile, err)
+		}
+		if isHeartbeat(ev) {
+			return Position{}, fmt.Errorf("%w: %s is not in %s", ErrGTIDNotFound, gtid, binlogFile)
+		}
+		traceEvent(binlogFile, ev)
+
+		// Stop once the stream moves on to the next file
+		if rotate, ok := ev.Event.(*replication.RotateEvent); ok && ev.Header.Timestamp > 0 && string(rotate.NextLogName) != binlogFile {
+			return Position{}, fmt.Errorf("%w: %s is not in %s", ErrGTIDNotFound, gtid, binlogFile)
+		}
+
+		var next mysql.GTIDSet
+		switch e := ev.Event.(type) {
+		case *replication.GTIDEvent:
+			next, err = e.GTIDNext()
+		case *replication.MariadbGTIDEvent:
+			next, err = e.GTIDNext()
+		default:
+			continue
+		}
+		if err != nil {
+			return Position{}, fmt.Errorf("failed to decode GTID event: %w", err)
+		}
+		// A MariaDB GTID set would also contain every earlier sequence number
+		if isMariaDB(gtid) && !gtid.Equal(next) || !isMariaDB(gtid) && !gtid.Contain(next) {
+			continue
+		}
+		return Position{
+			File:      binlogFile,
+			Pos:       ev.Header.LogPos - ev.Header.EventSize,
+			Timestamp: time.Unix(int64(ev.Header.Timestamp), 0),
+			GTID:      next.String(),
+			ServerID:  ev.Header.ServerID,
+		}, nil
+	}
+}
+
+// ResolveGTID finds the binlog file and position where the given GTID was written, as
+// LocateGTID does
+func ResolveGTID(syncerConfig replication.BinlogSyncerConfig, binlogFiles []string, gtid string) (string, uint32, error) {
+	pos, err := LocateGTID(syncerConfig, binlogFiles, gtid)
+	return pos.File, pos.Pos, err
+}
+
+// LocateGTID finds the position of the transaction with the given GTID, a MySQL GTID or a
+// MariaDB domain-server-sequence GTID, and the time it was written. It binary searches the
+// GTIDs recorded at the head of each file, which only grow from one file to the next, and
+// returns an error wrapping ErrGTIDPurged when the transaction predates the oldest file, or
+// ErrGTIDNotFound when it is not where the GTIDs place it.
+func LocateGTID(syncerConfig replication.BinlogSyncerConfig, binlogFiles []string, gtid string) (Position, error) {
+	return locateGTID(context.Background(), Server{Config: syncerConfig}, binlogFiles, gtid)
+}
+
+// LocateGTID is LocateGTID reading through the Finder's streamer, under its Context
+func (f *Finder) LocateGTID(binlogFiles []string, gtid string) (Position, error) {
+	return locateGTID(f.readContext(), f.streamer(), binlogFiles, gtid)
+}
+
+// locateGTID implements LocateGTID
+func locateGTID(ctx context.Context, streamer EventStreamer, binlogFiles []string, gtid string) (Position, error) {
+	target, err := ParseGTID(gtid)
+	if err != nil {
+		return Position{}, fmt.Errorf("invalid GTID %q: %w", gtid, err)
+	}
+	if len(binlogFiles) == 0 {
+		return Position{}, fmt.Errorf("no binlog files provided")
+	}
+
+	// Find the first file whose previous GTIDs already include the target;
+	// the transaction was written to the file just before it
+	left, right := 0, len(binlogFiles)
+	for left < right {
+		mid := left + (right-left)/2
+		previous, err := previousGTIDs(ctx, streamer, binlogFiles[mid])
+		if err != nil {
+			return Position{}, err
+		}
+		if isMariaDB(previous) != isMariaDB(target) {
+			return Position{}, fmt.Errorf("invalid GTID %q: %s records %s GTIDs", gtid, binlogFiles[mid], gtidFlavor(previous))
+		}
+		if includesGTID(previous, target) {
+			right = mid
+		} else {
+			left = mid + 1
+		}
+	}
+
+	if left == 0 {
+		return Position{}, fmt.Errorf("%w: %s was written before %s", ErrGTIDPurged, gtid, binlogFiles[0])
+	}
+
+	binlogFile := binlogFiles[left-1]
+	slog.Info("Located binlog for GTID", "gtid", gtid, "file", binlogFile)
+
+	return findGTIDPosition(ctx, streamer, binlogFile, target)
+}
@@ -0,0 +1,56 @@
+package binlog
+
+import (
+	"testing"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGTID(t *testing.T) {
+	gtid, err := ParseGTID("0-1-12345")
+	require.NoError(t, err)
+	assert.IsType(t, &mysql.MariadbGTIDSet{}, gtid)
+	assert.Equal(t, "0-1-12345", gtid.String())
+
+	gtid, err = ParseGTID("3e11fa47-71ca-11e1-9e33-c80aa9429562:23")
+	require.NoError(t, err)
+	assert.IsType(t, &mysql.MysqlGTIDSet{}, gtid)
+
+	_, err = ParseGTID("0-1")
+	assert.Error(t, err)
+}
+
+func TestIncludesGTID(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous string
+		target   string
+		expected bool
+	}{
+		{"MySQL included", "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-30", "3e11fa47-71ca-11e1-9e33-c80aa9429562:23", true},
+		{"MySQL not yet written", "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-20", "3e11fa47-71ca-11e1-9e33-c80aa9429562:23", false},
+		{"MariaDB included", "0-1-12400", "0-1-12345", true},
+		{"MariaDB same sequence", "0-1-12345", "0-1-12345", true},
+		{"MariaDB not yet written", "0-1-12300", "0-1-12345", false},
+		{"MariaDB after a failover", "0-1-12300,0-2-12400", "0-1-12345", true},
+		{"MariaDB other domain", "1-1-12400", "0-1-12345", false},
+		{"MariaDB empty list", "", "0-1-12345", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := ParseGTID(tt.target)
+			require.NoError(t, err)
+			var previous mysql.GTIDSet
+			if isMariaDB(target) {
+				previous, err = mysql.ParseMariadbGTIDSet(tt.previous)
+			} else {
+				previous, err = mysql.ParseMysqlGTIDSet(tt.previous)
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, includesGTID(previous, target))
+		})
+	}
+}
@@ -0,0 +1,118 @@
+//go:build integration
+
+package binlog
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/minuteman3/binlog-find-time/mysqltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegrationFind(t *testing.T) {
+	server := mysqltest.Start(t, mysqltest.Options{})
+	batches := server.WriteBatches(t, 5, 20)
+	cfg := server.SyncerConfig()
+
+	files, err := GetBinlogFiles(cfg)
+	require.NoError(t, err)
+
+	for _, batch := range batches {
+		t.Run(batch.File, func(t *testing.T) {
+			f := &Finder{Config: cfg}
+			for _, target := range []time.Time{batch.Start, batch.End} {
+				res := f.Find(files, target)
+				assert.Equal(t, batch.File, res.File, "target %s", target)
+				assert.True(t, res.Exact(), "target %s", target)
+			}
+		})
+	}
+
+	t.Run("Before the oldest file", func(t *testing.T) {
+		res := (&Finder{Config: cfg}).Find(files, batches[0].Start.Add(-24*time.Hour))
+		assert.Equal(t, files[0], res.File)
+		assert.Equal(t, MatchBeforeOldest, res.MatchQuality)
+	})
+}
+
+func TestIntegrationPosition(t *testing.T) {
+	server := mysqltest.Start(t, mysqltest.Options{})
+	batches := server.WriteBatches(t, 2, 50)
+	cfg := server.SyncerConfig()
+
+	listed, err := ListBinlogs(cfg)
+	require.NoError(t, err)
+	sizes := make(map[string]int64, len(listed))
+	for _, f := range listed {
+		sizes[f.Name] = f.Size
+	}
+
+	for _, batch := range batches {
+		t.Run(batch.File, func(t *testing.T) {
+			located, err := LocatePosition(replication.NewBinlogSyncer(cfg), batch.File, batch.End, AlignTransaction, TimestampHeader, 0)
+			require.NoError(t, err)
+			assert.Equal(t, batch.File, located.File)
+			assert.NotEmpty(t, located.GTID, "transaction-aligned positions start at a GTID")
+			assert.False(t, located.Timestamp.Before(batch.End))
+
+			seeked, err := SeekPosition(cfg, batch.File, sizes[batch.File], batch.End, AlignTransaction, TimestampHeader, 0)
+			require.NoError(t, err)
+			assert.Equal(t, located, seeked, "seeking and scanning must agree")
+		})
+	}
+}
+
+func TestIntegrationTimeRange(t *testing.T) {
+	server := mysqltest.Start(t, mysqltest.Options{})
+	batch := server.WriteBatches(t, 1, 10)[0]
+	server.Rotate(t)
+
+	r, err := GetTimeRangeForBinlog(replication.NewBinlogSyncer(server.SyncerConfig()), batch.File)
+	require.NoError(t, err)
+	assert.Empty(t, r.Truncated)
+	assert.False(t, r.Start.After(batch.Start))
+	assert.False(t, r.End.Before(batch.End))
+}
+
+func TestIntegrationAuthPlugins(t *testing.T) {
+	server := mysqltest.Start(t, mysqltest.Options{})
+	batch := server.WriteBatches(t, 1, 1)[0]
+
+	for _, plugin := range []string{AuthNativePassword, AuthCachingSHA2Password, AuthSHA256Password} {
+		t.Run(plugin, func(t *testing.T) {
+			user := "binlog_" + plugin
+			server.Exec(t, fmt.Sprintf("CREATE USER '%s'@'%%' IDENTIFIED WITH %s BY 'secret'", user, plugin))
+			server.Exec(t, fmt.Sprintf("GRANT REPLICATION SLAVE, REPLICATION CLIENT ON *.* TO '%s'@'%%'", user))
+			cfg := server.SyncerConfig()
+			cfg.User, cfg.Password = user, "secret"
+
+			// Emptying the caching_sha2_password cache makes each connection authenticate
+			// in full, with the server's public key as TLS is not used
+			server.Exec(t, "FLUSH PRIVILEGES")
+			_, err := GetTimeRangeForBinlog(replication.NewBinlogSyncer(cfg), batch.File)
+			require.NoError(t, err, "replication connection")
+
+			server.Exec(t, "FLUSH PRIVILEGES")
+			files, err := ListBinlogs(cfg)
+			require.NoError(t, err, "SQL connection")
+			assert.NotEmpty(t, files)
+		})
+	}
+}
+
+func TestIntegrationConnectionAttributes(t *testing.T) {
+	server := mysqltest.Start(t, mysqltest.Options{})
+	settings := &Settings{Connection: ConnectionOptions{Attributes: map[string]string{"program_name": "binlog-find-time", "purpose": "timestamp-probe"}}}
+
+	db, err := Server{Config: server.SyncerConfig(), Settings: settings}.openDB()
+	require.NoError(t, err)
+	defer closeDB(db)
+	var purpose string
+	err = db.QueryRow("SELECT ATTR_VALUE FROM performance_schema.session_connect_attrs WHERE PROCESSLIST_ID = CONNECTION_ID() AND ATTR_NAME = 'purpose'").Scan(&purpose)
+	require.NoError(t, err)
+	assert.Equal(t, "timestamp-probe", purpose)
+}
@@ -0,0 +1,153 @@
+package binlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// EventKind is a family of binlog event types looked for by NextEvent, such as every
+// version of the write rows event
+type EventKind string
+
+// Event kinds that can be looked for
+const (
+	KindWriteRows  EventKind = "WRITE_ROWS"
+	KindUpdateRows EventKind = "UPDATE_ROWS"
+	KindDeleteRows EventKind = "DELETE_ROWS"
+	KindTableMap   EventKind = "TABLE_MAP"
+	KindGTID       EventKind = "GTID"
+	KindXID        EventKind = "XID"
+	KindQuery      EventKind = "QUERY"
+	KindRotate     EventKind = "ROTATE"
+)
+
+// eventKinds maps each kind to the event types it covers, across MySQL and MariaDB
+var eventKinds = map[EventKind][]replication.EventType{
+	KindWriteRows: {replication.WRITE_ROWS_EVENTv0, replication.WRITE_ROWS_EVENTv1, replication.WRITE_ROWS_EVENTv2,
+		replication.MARIADB_WRITE_ROWS_COMPRESSED_EVENT_V1},
+	KindUpdateRows: {replication.UPDATE_ROWS_EVENTv0, replication.UPDATE_ROWS_EVENTv1, replication.UPDATE_ROWS_EVENTv2,
+		replication.PARTIAL_UPDATE_ROWS_EVENT, replication.MARIADB_UPDATE_ROWS_COMPRESSED_EVENT_V1},
+	KindDeleteRows: {replication.DELETE_ROWS_EVENTv0, replication.DELETE_ROWS_EVENTv1, replication.DELETE_ROWS_EVENTv2,
+		replication.MARIADB_DELETE_ROWS_COMPRESSED_EVENT_V1},
+	KindTableMap: {replication.TABLE_MAP_EVENT},
+	KindGTID:     {replication.GTID_EVENT, replication.GTID_TAGGED_LOG_EVENT, replication.MARIADB_GTID_EVENT},
+	KindXID:      {replication.XID_EVENT},
+	KindQuery:    {replication.QUERY_EVENT, replication.MARIADB_QUERY_COMPRESSED_EVENT},
+	KindRotate:   {replication.ROTATE_EVENT},
+}
+
+// ErrEventNotFound is returned by NextEvent when no event of the kind follows the target
+// time in the binlogs
+var ErrEventNotFound = errors.New("no event of the kind after the target time")
+
+// ParseEventKind parses an event kind as used on the command line, in either case and
+// with or without the _EVENT suffix
+func ParseEventKind(s string) (EventKind, error) {
+	kind := EventKind(strings.TrimSuffix(strings.ToUpper(s), "_EVENT"))
+	if _, ok := eventKinds[kind]; !ok {
+		return "", fmt.Errorf("unknown event type %q (expected WRITE_ROWS, UPDATE_ROWS, DELETE_ROWS, TABLE_MAP, GTID, XID, QUERY or ROTATE)", s)
+	}
+	return kind, nil
+}
+
+// matches reports whether the event is of the kind
+func (k EventKind) matches(ev *replication.BinlogEvent) bool {
+	return slices.Contains(eventKinds[k], ev.Header.EventType)
+}
+
+// NextEvent reads the binlogs from the event starting at from, carrying on through the
+// files after it in files, and returns the first event of the kind at or after the target
+// time. Events in a compressed transaction payload are looked at too, and share the
+// payload's position. Event times are taken from the Finder's timestamp source.
+func (f *Finder) NextEvent(files []string, from Position, targetTime time.Time, kind EventKind) (EventSummary, error) {
+	onEvent, done := f.reader(from.File)
+	defer done(false)
+	return nextEvent(f.readContext(), &countingStreamer{streamer: f.streamer(), onEvent: onEvent}, files, from, targetTime, kind, f.Source)
+}
+
+// nextEvent implements NextEvent, opening a stream for each file so that streams over
+// stored files, which end with the file, and replication streams are read alike
+func nextEvent(ctx context.Context, streamer EventStreamer, files []string, from Position, targetTime time.Time, kind EventKind, source TimestampSource) (EventSummary, error) {
+	ctx, cancel := withReadTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	i := slices.Index(files, from.File)
+	if i < 0 {
+		return EventSummary{}, fmt.Errorf("%s is not one of the binlog files", from.File)
+	}
+	pos := max(from.Pos, 4)
+	for ; i < len(files); i, pos = i+1, 4 {
+		ev, err := nextEventIn(ctx, streamer, files[i], pos, targetTime, kind, source)
+		if err == nil || !errors.Is(err, io.EOF) {
+			return ev, err
+		}
+	}
+	return EventSummary{}, fmt.Errorf("%s from %s: %w", kind, from, ErrEventNotFound)
+}
+
+// nextEventIn looks for the event in a single file, returning io.EOF at its end
+func nextEventIn(ctx context.Context, streamer EventStreamer, binlogFile string, pos uint32, targetTime time.Time, kind EventKind, source TimestampSource) (EventSummary, error) {
+	stream, err := streamer.StreamFrom(binlogFile, pos)
+	if err != nil {
+		return EventSummary{}, fmt.Errorf("failed to start sync from %s:%d: %w", binlogFile, pos, err)
+	}
+	defer stream.Close()
+
+	// As in scanToTime, commit timestamps carried by GTID events date the whole transaction
+	var txTime time.Time
+	for {
+		ev, err := stream.GetEvent(ctx)
+		if err != nil {
+			return EventSummary{}, err
+		}
+		// Caught up with the server, which has written nothing more to look at
+		if isHeartbeat(ev) {
+			return EventSummary{}, io.EOF
+		}
+		traceEvent(binlogFile, ev)
+
+		_, isRotate := ev.Event.(*replication.RotateEvent)
+		// The fake rotate at the start of a replication stream is not in the file
+		if isRotate && ev.Header.Timestamp == 0 {
+			continue
+		}
+		evTime := time.Unix(int64(ev.Header.Timestamp), 0)
+		if source != TimestampHeader {
+			if t, ok := eventTime(ev, source); ok {
+				txTime = t
+			}
+			if !txTime.IsZero() {
+				evTime = txTime
+			}
+		}
+
+		start := ev.Header.LogPos - ev.Header.EventSize
+		if !evTime.Before(targetTime) {
+			if kind.matches(ev) {
+				summary := summarize(binlogFile, start, ev)
+				summary.Timestamp = evTime
+				return summary, nil
+			}
+			if payload, ok := ev.Event.(*replication.TransactionPayloadEvent); ok {
+				for _, inner := range payload.Events {
+					if kind.matches(inner) {
+						summary := summarize(binlogFile, start, inner)
+						summary.Timestamp, summary.InPayload = evTime, true
+						return summary, nil
+					}
+				}
+			}
+		}
+		// The rest of the stream is the next file, read by the caller
+		if isRotate {
+			return EventSummary{}, io.EOF
+		}
+	}
+}
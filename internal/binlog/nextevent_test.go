@@ -0,0 +1,113 @@
+package binlog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEventKind(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected EventKind
+	}{
+		{"WRITE_ROWS", KindWriteRows},
+		{"xid", KindXID},
+		{"ROTATE_EVENT", KindRotate},
+		{"gtid", KindGTID},
+	}
+	for _, tt := range tests {
+		kind, err := ParseEventKind(tt.input)
+		require.NoError(t, err, tt.input)
+		assert.Equal(t, tt.expected, kind)
+	}
+
+	_, err := ParseEventKind("FORMAT_DESCRIPTION")
+	assert.Error(t, err)
+}
+
+func TestNextEvent(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 3, FileSize: 4 << 10, Rate: 0.5, Seed: 1})
+	stored := make(memoryFiles, len(files))
+	names := make([]string, len(files))
+	for i, f := range files {
+		stored[f.Name] = f.Data
+		names[i] = f.Name
+	}
+	streamer := FileStreamer{Reader: stored}
+
+	// first returns the first generated event of the type at or after target, from the
+	// event at from on
+	first := func(typ replication.EventType, from Position, target time.Time) (string, binlogtest.Event) {
+		reached := false
+		for _, f := range files {
+			reached = reached || f.Name == from.File
+			for _, ev := range f.Events {
+				if reached && ev.Type == typ && !ev.Time.Truncate(time.Second).Before(target) &&
+					(f.Name != from.File || ev.Pos >= from.Pos) {
+					return f.Name, ev
+				}
+			}
+		}
+		t.Fatalf("no %s at or after %s", typ, target)
+		return "", binlogtest.Event{}
+	}
+
+	tests := []struct {
+		name   string
+		from   Position
+		target time.Time
+		kind   EventKind
+		typ    replication.EventType
+	}{
+		{"Next XID in the same file", Position{File: names[0]}, files[0].Start.Add(10 * time.Second), KindXID, replication.XID_EVENT},
+		{"Next rotate at the end of the file", Position{File: names[0]}, files[0].Start.Add(10 * time.Second), KindRotate, replication.ROTATE_EVENT},
+		{"Carries on into the next file", Position{File: names[0]}, files[1].Start, KindGTID, replication.GTID_EVENT},
+		{"From a position", Position{File: names[1], Pos: files[1].Events[5].Pos}, files[1].Start, KindQuery, replication.QUERY_EVENT},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, want := first(tt.typ, tt.from, tt.target)
+			ev, err := nextEvent(context.Background(), streamer, names, tt.from, tt.target, tt.kind, TimestampHeader)
+			require.NoError(t, err)
+			assert.Equal(t, file, ev.File)
+			assert.Equal(t, want.Pos, ev.Pos)
+			assert.Equal(t, tt.typ.String(), ev.Type)
+			assert.False(t, ev.Timestamp.Before(tt.target))
+		})
+	}
+
+	t.Run("Nothing after the target", func(t *testing.T) {
+		_, err := nextEvent(context.Background(), streamer, names, Position{File: names[0]}, files[2].End.Add(time.Second), KindXID, TimestampHeader)
+		assert.ErrorIs(t, err, ErrEventNotFound)
+	})
+	t.Run("No rotate after the newest file", func(t *testing.T) {
+		_, err := nextEvent(context.Background(), streamer, names, Position{File: names[2]}, files[2].Start, KindRotate, TimestampHeader)
+		assert.ErrorIs(t, err, ErrEventNotFound)
+	})
+}
+
+func TestNextEventInPayload(t *testing.T) {
+	f := binlogtest.Generate(binlogtest.Options{FileSize: 4 << 10, Rate: 0.5, Seed: 1, Compress: true})[0]
+	streamer := FileStreamer{Reader: memoryFiles{f.Name: f.Data}}
+
+	target := f.Start.Add(10 * time.Second)
+	var want binlogtest.Event
+	for _, ev := range f.Events {
+		if ev.Type == replication.TRANSACTION_PAYLOAD_EVENT && !ev.Time.Truncate(time.Second).Before(target) {
+			want = ev
+			break
+		}
+	}
+
+	ev, err := nextEvent(context.Background(), streamer, []string{f.Name}, Position{File: f.Name}, target, KindXID, TimestampHeader)
+	require.NoError(t, err)
+	assert.Equal(t, want.Pos, ev.Pos, "events in a payload share its position")
+	assert.Equal(t, replication.XID_EVENT.String(), ev.Type)
+	assert.True(t, ev.InPayload)
+}
@@ -0,0 +1,145 @@
+package binlog
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// defaultServerID is the replica server ID used for replication connections unless
+// overridden with WithServerID
+const defaultServerID = 100
+
+// Option configures a Finder built by NewFinder
+type Option func(*Finder)
+
+// NewFinder returns a Finder for the server described by dsn, a go-sql-driver/mysql DSN
+// such as "user:password@tcp(host:3306)/". The DSN's timeout, readTimeout and tls
+// parameters apply to the replication connections; the database name is ignored.
+func NewFinder(dsn string, opts ...Option) (*Finder, error) {
+	cfg, err := syncerConfigFromDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	f := &Finder{Config: cfg}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f, nil
+}
+
+// syncerConfigFromDSN converts a go-sql-driver/mysql DSN to a replication config
+func syncerConfigFromDSN(dsn string) (replication.BinlogSyncerConfig, error) {
+	dsnCfg, err := mysqldriver.ParseDSN(dsn)
+	if err != nil {
+		return replication.BinlogSyncerConfig{}, fmt.Errorf("invalid DSN: %w", err)
+	}
+	if dsnCfg.Net != "tcp" {
+		return replication.BinlogSyncerConfig{}, fmt.Errorf("unsupported DSN network %q: replication needs tcp", dsnCfg.Net)
+	}
+
+	host, portStr, err := net.SplitHostPort(dsnCfg.Addr)
+	if err != nil {
+		return replication.BinlogSyncerConfig{}, fmt.Errorf("invalid DSN address %q: %w", dsnCfg.Addr, err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return replication.BinlogSyncerConfig{}, fmt.Errorf("invalid DSN port %q: %w", portStr, err)
+	}
+
+	cfg := replication.BinlogSyncerConfig{
+		ServerID:    defaultServerID,
+		Flavor:      "mysql",
+		Host:        host,
+		Port:        uint16(port),
+		User:        dsnCfg.User,
+		Password:    dsnCfg.Passwd,
+		Dialer:      (&net.Dialer{Timeout: dsnCfg.Timeout}).DialContext,
+		ReadTimeout: dsnCfg.ReadTimeout,
+		TLSConfig:   dsnCfg.TLS,
+	}
+	// As for the CLI, heartbeats end scans of the active file and keep an idle stream
+	// from tripping the read timeout
+	cfg.HeartbeatPeriod = DefaultHeartbeatPeriod
+	if cfg.ReadTimeout > 0 {
+		cfg.HeartbeatPeriod = min(cfg.HeartbeatPeriod, cfg.ReadTimeout/2)
+	}
+	return cfg, nil
+}
+
+// WithFlavor sets the server flavor, "mysql" (the default) or "mariadb"
+func WithFlavor(flavor string) Option {
+	return func(f *Finder) { f.Config.Flavor = flavor }
+}
+
+// WithServerID sets the server ID the Finder registers as when reading binlogs. It must
+// be unique among the replicas of the server.
+func WithServerID(id uint32) Option {
+	return func(f *Finder) { f.Config.ServerID = id }
+}
+
+// WithTimeout bounds the probe of each binlog file; see Finder.ProbeTimeout
+func WithTimeout(timeout time.Duration) Option {
+	return func(f *Finder) { f.ProbeTimeout = timeout }
+}
+
+// WithSettings sets how the binlogs are read; see Finder.Settings
+func WithSettings(settings Settings) Option {
+	return func(f *Finder) { f.Settings = &settings }
+}
+
+// WithCache sets the cache of probed time ranges; see Finder.Cache
+func WithCache(cache Cache) Option {
+	return func(f *Finder) { f.Cache = cache }
+}
+
+// WithLogger sets the logger for the search's logs; see Finder.Logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(f *Finder) { f.Logger = logger }
+}
+
+// WithTimestampSource selects which event timestamps are compared against the target
+func WithTimestampSource(source TimestampSource) Option {
+	return func(f *Finder) { f.Source = source }
+}
+
+// WithPreference chooses the file returned when the target second spans a rotation
+func WithPreference(prefer Preference) Option {
+	return func(f *Finder) { f.Prefer = prefer }
+}
+
+// WithStreamer reads binlogs through streamer instead of replication connections
+func WithStreamer(streamer EventStreamer) Option {
+	return func(f *Finder) { f.Streamer = streamer }
+}
+
+// WithLister lists binlogs through lister instead of SHOW BINARY LOGS
+func WithLister(lister BinlogLister) Option {
+	return func(f *Finder) { f.Lister = lister }
+}
+
+// WithStats accumulates the work done by searches into stats
+func WithStats(stats *Stats) Option {
+	return func(f *Finder) { f.Stats = stats }
+}
+
+// WithSkipCorrupt leaves corrupt or unparsable files out of searches; see Finder.SkipCorrupt
+func WithSkipCorrupt() Option {
+	return func(f *Finder) { f.SkipCorrupt = true }
+}
+
+// WithPosition makes Find also locate the position of the target time, snapped to align;
+// see Finder.Position
+func WithPosition(align Alignment) Option {
+	return func(f *Finder) { f.Position, f.Align = true, align }
+}
+
+// Binlogs lists the binlog files on the server, through the Lister if one is set
+func (f *Finder) Binlogs() ([]FileInfo, error) {
+	return f.lister().ListBinlogs()
+}
@@ -0,0 +1,109 @@
+package binlog
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFinderDSN(t *testing.T) {
+	tests := []struct {
+		name        string
+		dsn         string
+		host        string
+		port        uint16
+		user        string
+		password    string
+		readTimeout time.Duration
+		heartbeat   time.Duration
+		wantErr     bool
+	}{
+		{name: "Host and port", dsn: "repl:secret@tcp(db.example.com:3307)/", host: "db.example.com", port: 3307, user: "repl", password: "secret", heartbeat: DefaultHeartbeatPeriod},
+		{name: "Default port", dsn: "repl@tcp(db)/", host: "db", port: 3306, user: "repl", heartbeat: DefaultHeartbeatPeriod},
+		{name: "Read timeout", dsn: "repl@tcp(db:3306)/?readTimeout=30s", host: "db", port: 3306, user: "repl", readTimeout: 30 * time.Second, heartbeat: DefaultHeartbeatPeriod},
+		{name: "Short read timeout", dsn: "repl@tcp(db:3306)/?readTimeout=600ms", host: "db", port: 3306, user: "repl", readTimeout: 600 * time.Millisecond, heartbeat: 300 * time.Millisecond},
+		{name: "Unix socket", dsn: "repl@unix(/var/run/mysqld/mysqld.sock)/", wantErr: true},
+		{name: "Malformed", dsn: "repl@tcp(db", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFinder(tt.dsn)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.host, f.Config.Host)
+			assert.Equal(t, tt.port, f.Config.Port)
+			assert.Equal(t, tt.user, f.Config.User)
+			assert.Equal(t, tt.password, f.Config.Password)
+			assert.Equal(t, tt.readTimeout, f.Config.ReadTimeout)
+			assert.Equal(t, tt.heartbeat, f.Config.HeartbeatPeriod)
+			assert.Equal(t, uint32(defaultServerID), f.Config.ServerID)
+			assert.Equal(t, "mysql", f.Config.Flavor)
+		})
+	}
+}
+
+func TestNewFinderOptions(t *testing.T) {
+	cache := NewMemoryCache()
+	stats := &Stats{}
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	f, err := NewFinder("repl@tcp(db:3306)/",
+		WithFlavor("mariadb"),
+		WithServerID(4242),
+		WithTimeout(5*time.Second),
+		WithSettings(Settings{ScanLimits: ScanLimits{MaxEvents: 10}}),
+		WithCache(cache),
+		WithLogger(logger),
+		WithTimestampSource(TimestampImmediateCommit),
+		WithPreference(PreferLast),
+		WithStats(stats),
+		WithSkipCorrupt(),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "mariadb", f.Config.Flavor)
+	assert.Equal(t, uint32(4242), f.Config.ServerID)
+	assert.Equal(t, 5*time.Second, f.ProbeTimeout)
+	assert.Equal(t, &Settings{ScanLimits: ScanLimits{MaxEvents: 10}}, f.Settings)
+	assert.Same(t, cache, f.Cache)
+	assert.Same(t, logger, f.Logger)
+	assert.Equal(t, TimestampImmediateCommit, f.Source)
+	assert.Equal(t, PreferLast, f.Prefer)
+	assert.Same(t, stats, f.Stats)
+	assert.True(t, f.SkipCorrupt)
+}
+
+func TestNewFinderSearch(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 5, FileSize: 4 << 10, Rate: 0.5, Seed: 1})
+	streamer := newFakeStreamer(t, files)
+	var logs bytes.Buffer
+
+	f, err := NewFinder("repl@tcp(db:3306)/",
+		WithStreamer(streamer),
+		WithLister(streamer),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+	)
+	require.NoError(t, err)
+
+	binlogs, err := f.Binlogs()
+	require.NoError(t, err)
+	names := make([]string, len(binlogs))
+	for i, b := range binlogs {
+		names[i] = b.Name
+	}
+
+	target := files[3].Start.Add(files[3].End.Sub(files[3].Start) / 2).Truncate(time.Second).Add(500 * time.Millisecond)
+	file, exact, err := f.Search(names, target)
+	require.NoError(t, err)
+	assert.Equal(t, files[3].Name, file)
+	assert.True(t, exact)
+	assert.Contains(t, logs.String(), "Searching binlog files")
+}
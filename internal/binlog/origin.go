@@ -0,0 +1,93 @@
+package binlog
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// OriginFilter restricts searches and previews to the events that originated on some of
+// the servers of a replication chain, told apart by the server_id in each event header.
+// In circular and multi-tier topologies, a server's binlogs also hold the events it
+// replicated, written with the timestamps of their origin.
+type OriginFilter struct {
+	// ServerID, if not 0, keeps only the events that originated on the server with this
+	// server_id
+	ServerID uint32
+	// Ignore lists server_ids whose events are skipped
+	Ignore []uint32
+}
+
+// String describes the filter, e.g. "server_id=1 ignore=2,3", the same for filters
+// passing the same events, or "" for the zero filter. Cached time ranges are kept apart
+// by it, as the filter changes which event times a probe reads.
+func (f OriginFilter) String() string {
+	var parts []string
+	if f.ServerID != 0 {
+		parts = append(parts, "server_id="+strconv.FormatUint(uint64(f.ServerID), 10))
+	}
+	if len(f.Ignore) > 0 {
+		ignore := slices.Clone(f.Ignore)
+		slices.Sort(ignore)
+		ignore = slices.Compact(ignore)
+		ids := make([]string, len(ignore))
+		for i, id := range ignore {
+			ids[i] = strconv.FormatUint(uint64(id), 10)
+		}
+		parts = append(parts, "ignore="+strings.Join(ids, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+// keeps reports whether the filter passes the event. Events describing the file rather
+// than a change, such as the format description and rotate events, carry the server_id
+// of the server writing the file and are always passed.
+func (f OriginFilter) keeps(ev *replication.BinlogEvent) bool {
+	switch ev.Header.EventType {
+	case replication.FORMAT_DESCRIPTION_EVENT, replication.ROTATE_EVENT, replication.STOP_EVENT,
+		replication.PREVIOUS_GTIDS_EVENT, replication.HEARTBEAT_EVENT, replication.HEARTBEAT_LOG_EVENT_V2,
+		replication.MARIADB_GTID_LIST_EVENT, replication.MARIADB_BINLOG_CHECKPOINT_EVENT, replication.MARIADB_START_ENCRYPTION_EVENT:
+		return true
+	}
+	if f.ServerID != 0 && ev.Header.ServerID != f.ServerID {
+		return false
+	}
+	return !slices.Contains(f.Ignore, ev.Header.ServerID)
+}
+
+// filters reports whether the filter skips any event
+func (f OriginFilter) filters() bool {
+	return f.ServerID != 0 || len(f.Ignore) > 0
+}
+
+// originStreamer applies an origin filter to the streams it opens
+type originStreamer struct {
+	EventStreamer
+	filter OriginFilter
+}
+
+func (s originStreamer) StreamFrom(binlogFile string, pos uint32) (EventStream, error) {
+	stream, err := s.EventStreamer.StreamFrom(binlogFile, pos)
+	if err != nil {
+		return nil, err
+	}
+	return originStream{stream, s.filter}, nil
+}
+
+// originStream skips the events its filter does not pass
+type originStream struct {
+	EventStream
+	filter OriginFilter
+}
+
+func (s originStream) GetEvent(ctx context.Context) (*replication.BinlogEvent, error) {
+	for {
+		ev, err := s.EventStream.GetEvent(ctx)
+		if err != nil || s.filter.keeps(ev) {
+			return ev, err
+		}
+	}
+}
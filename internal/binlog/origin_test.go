@@ -0,0 +1,57 @@
+package binlog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOriginFilter(t *testing.T) {
+	// A server with server_id 1 writing its own transactions and, at 106, one replicated
+	// from server 2
+	var events []*replication.BinlogEvent
+	for _, tx := range []struct{ pos, ts, serverID uint32 }{{100, 104, 1}, {200, 106, 2}, {300, 108, 1}} {
+		for _, ev := range transaction(tx.pos, tx.ts) {
+			ev.Header.ServerID = tx.serverID
+			events = append(events, ev)
+		}
+	}
+	fde := &replication.BinlogEvent{Header: &replication.EventHeader{EventType: replication.FORMAT_DESCRIPTION_EVENT, ServerID: 1, LogPos: 100}, Event: &replication.FormatDescriptionEvent{}}
+
+	tests := []struct {
+		name     string
+		filter   OriginFilter
+		expected uint32
+		origin   uint32
+	}{
+		{"No filter", OriginFilter{}, 200, 2},
+		{"Origin", OriginFilter{ServerID: 1}, 300, 1},
+		{"Ignored", OriginFilter{Ignore: []uint32{2, 3}}, 300, 1},
+		{"Other origin", OriginFilter{ServerID: 2}, 200, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := originStream{&fakeStream{events: append([]*replication.BinlogEvent{fde}, events...)}, tt.filter}
+
+			pos, err := scanToTime(context.Background(), stream, "mysql-bin.000001", time.Unix(105, 0), AlignTransaction, TimestampHeader, 0, false)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, pos.Pos)
+			assert.Equal(t, tt.origin, pos.ServerID)
+		})
+	}
+
+	// Events describing the file pass whatever their server_id
+	assert.True(t, OriginFilter{ServerID: 2}.keeps(fde))
+}
+
+func TestOriginFilterString(t *testing.T) {
+	assert.Equal(t, "", OriginFilter{}.String())
+	assert.Equal(t, "server_id=1", OriginFilter{ServerID: 1}.String())
+	assert.Equal(t, "server_id=1 ignore=2,3", OriginFilter{ServerID: 1, Ignore: []uint32{3, 2, 3}}.String())
+	assert.Equal(t, OriginFilter{Ignore: []uint32{2, 3}}.String(), OriginFilter{Ignore: []uint32{3, 2}}.String())
+}
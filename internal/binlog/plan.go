@@ -0,0 +1,154 @@
+package binlog
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// PlanStep is a file a search is expected to look at, in the order it does
+type PlanStep struct {
+	File string
+	// Cached is set when the file's range is already known, so the file is not read
+	Cached bool
+	// Estimated is set when the step after this one follows from a range estimated from
+	// the sizes and known ranges of the surrounding files, rather than a known one
+	Estimated bool
+	// Bytes is the most a probe of the file reads: its listed size, capped by the
+	// MaxBytes scan limit. It is 0 for cached files and files of unknown size.
+	Bytes int64
+}
+
+// Plan is the expected course of a search, made without reading any binlog
+type Plan struct {
+	Steps []PlanStep
+	// Undecided estimates the probes left after the last step when its range could not
+	// be estimated, so the course of the search depends on what its probe finds
+	Undecided int
+	// Bytes is the most the planned probes read in total
+	Bytes int64
+}
+
+// errUnplanned stops a planned search at a file whose range is neither known nor estimated
+var errUnplanned = errors.New("range unknown until probed")
+
+// unplannedStreamer is the streamer of a planned search, which never reads a file
+type unplannedStreamer struct{}
+
+func (unplannedStreamer) StreamFrom(string, uint32) (EventStream, error) {
+	return nil, errUnplanned
+}
+
+// Plan works out the files a search for targetTime would probe, in order, without reading
+// any binlog. Ranges in Known or Cache are used as the search uses them. The ranges of
+// other files are estimated assuming a steady write rate, weighted by size, between the
+// nearest known ranges before and after them, with now as the end of the newest file
+// (zero if it is not being written). The plan is cut short at the first file whose range
+// cannot be estimated.
+func (f *Finder) Plan(binlogFiles []string, targetTime, now time.Time) Plan {
+	known := make(map[string]TimeRange)
+	for _, file := range binlogFiles {
+		if r, ok := f.Known[file]; ok {
+			known[file] = r
+		} else if key, cacheable := f.cacheKey(file); cacheable {
+			if r, ok := f.rangeCache().Get(key); ok {
+				known[file] = r
+			}
+		}
+	}
+	estimated := estimateRanges(binlogFiles, f.Sizes, known, now)
+	ranges := make(map[string]TimeRange, len(known)+len(estimated))
+	for file, r := range estimated {
+		ranges[file] = r
+	}
+	for file, r := range known {
+		ranges[file] = r
+	}
+
+	limits := f.settings().ScanLimits
+	var plan Plan
+	planner := &Finder{Config: f.Config, Settings: f.Settings, Prefer: f.Prefer, Source: f.Source, Sizes: f.Sizes, Known: ranges,
+		Streamer: unplannedStreamer{}, Lister: f.Lister, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	planner.OnProbe = func(p Probe) {
+		// The search goes on past an unplanned file as if it could not be read, which
+		// it would not
+		if plan.Undecided > 0 {
+			return
+		}
+		step := PlanStep{File: p.File}
+		_, step.Cached = known[p.File]
+		_, step.Estimated = estimated[p.File]
+		if !step.Cached {
+			step.Bytes = f.Sizes[p.File]
+			if limits.MaxBytes > 0 && step.Bytes > limits.MaxBytes {
+				step.Bytes = limits.MaxBytes
+			}
+		}
+		plan.Steps = append(plan.Steps, step)
+		plan.Bytes += step.Bytes
+		if errors.Is(p.Err, errUnplanned) {
+			plan.Undecided = max(p.Remaining, 1)
+		}
+	}
+	(&searchRun{Finder: planner}).search(binlogFiles, targetTime)
+	return plan
+}
+
+// estimateRanges estimates the ranges of the files not in known by placing them along a
+// steady write rate between the nearest known ranges on each side, with files weighted by
+// size as for interpolation. The newest file ends at now unless now is zero.
+func estimateRanges(binlogFiles []string, sizes map[string]int64, known map[string]TimeRange, now time.Time) map[string]TimeRange {
+	type anchor struct {
+		offset float64
+		time   time.Time
+	}
+	weight := func(file string) float64 {
+		if size := sizes[file]; size > 0 {
+			return float64(size)
+		}
+		return 1
+	}
+
+	// Files span consecutive offsets, and every known range pins its file's start and end
+	offsets := make([]float64, len(binlogFiles)+1)
+	var anchors []anchor
+	for i, file := range binlogFiles {
+		offsets[i+1] = offsets[i] + weight(file)
+		if r, ok := known[file]; ok {
+			anchors = append(anchors, anchor{offsets[i], r.Start}, anchor{offsets[i+1], r.End})
+		}
+	}
+	if !now.IsZero() {
+		anchors = append(anchors, anchor{offsets[len(binlogFiles)], now})
+	}
+
+	// at returns the time interpolated at offset, if anchors surround it
+	at := func(offset float64) (time.Time, bool) {
+		for i := 1; i < len(anchors); i++ {
+			before, after := anchors[i-1], anchors[i]
+			if offset < before.offset || offset > after.offset || !after.time.After(before.time) {
+				continue
+			}
+			if after.offset == before.offset {
+				return before.time, true
+			}
+			share := (offset - before.offset) / (after.offset - before.offset)
+			return before.time.Add(time.Duration(share * float64(after.time.Sub(before.time)))), true
+		}
+		return time.Time{}, false
+	}
+
+	estimated := make(map[string]TimeRange)
+	for i, file := range binlogFiles {
+		if _, ok := known[file]; ok {
+			continue
+		}
+		start, okStart := at(offsets[i])
+		end, okEnd := at(offsets[i+1])
+		if okStart && okEnd {
+			estimated[file] = TimeRange{Start: start, End: end}
+		}
+	}
+	return estimated
+}
@@ -0,0 +1,89 @@
+package binlog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlan(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 16, FileSize: 8 << 10, Rate: 0.5, Distribution: binlogtest.Steady, Seed: 1})
+	names := make([]string, len(files))
+	sizes := make(map[string]int64, len(files))
+	all := make(map[string]TimeRange, len(files))
+	for i, f := range files {
+		names[i] = f.Name
+		sizes[f.Name] = f.Size()
+		all[f.Name] = TimeRange{Start: f.Start, End: f.End}
+	}
+	want := files[5]
+	target := want.Start.Add(want.End.Sub(want.Start) / 2).Truncate(time.Second).Add(500 * time.Millisecond)
+
+	t.Run("Every range known", func(t *testing.T) {
+		var probed []string
+		finder := &Finder{Known: all, Sizes: sizes, OnProbe: func(p Probe) { probed = append(probed, p.File) }}
+		plan := finder.Plan(names, target, time.Time{})
+
+		// The plan follows the search exactly, without reading a file
+		res := finder.Find(names, target)
+		assert.Equal(t, want.Name, res.File)
+		assert.True(t, res.Exact())
+		var planned []string
+		for _, step := range plan.Steps {
+			planned = append(planned, step.File)
+			assert.True(t, step.Cached)
+		}
+		assert.Equal(t, probed, planned)
+		assert.Zero(t, plan.Bytes)
+		assert.Zero(t, plan.Undecided)
+	})
+
+	t.Run("Ranges estimated", func(t *testing.T) {
+		first, last := files[0], files[len(files)-1]
+		known := map[string]TimeRange{first.Name: all[first.Name], last.Name: all[last.Name]}
+		finder := &Finder{Known: known, Sizes: sizes}
+		plan := finder.Plan(names, target, time.Time{})
+
+		assert.Zero(t, plan.Undecided)
+		assert.Equal(t, want.Name, plan.Steps[len(plan.Steps)-1].File, "the steady write rate leads to the file holding the target")
+		var bytes int64
+		for _, step := range plan.Steps {
+			assert.False(t, step.Cached)
+			assert.True(t, step.Estimated)
+			assert.Equal(t, sizes[step.File], step.Bytes)
+			bytes += step.Bytes
+		}
+		assert.Equal(t, bytes, plan.Bytes)
+	})
+
+	t.Run("Nothing known", func(t *testing.T) {
+		finder := &Finder{Sizes: sizes}
+		plan := finder.Plan(names, target, time.Time{})
+
+		// Only the first probe is certain, halving the files left to search
+		mid := names[(len(names)-1)/2]
+		assert.Equal(t, []PlanStep{{File: mid, Bytes: sizes[mid]}}, plan.Steps)
+		assert.Equal(t, sizes[mid], plan.Bytes)
+		assert.Equal(t, remainingProbes(0, (len(names)-1)/2-1), plan.Undecided)
+	})
+}
+
+func TestEstimateRanges(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	files := []string{"binlog.000001", "binlog.000002", "binlog.000003", "binlog.000004"}
+	sizes := map[string]int64{"binlog.000001": 100, "binlog.000002": 300, "binlog.000003": 100, "binlog.000004": 100}
+	known := map[string]TimeRange{"binlog.000001": {Start: base, End: base.Add(time.Hour)}}
+
+	// Without now, nothing bounds the files after the known one
+	assert.Empty(t, estimateRanges(files, sizes, known, time.Time{}))
+
+	// The 500 bytes after the known file span the 5 hours to now
+	estimated := estimateRanges(files, sizes, known, base.Add(6*time.Hour))
+	assert.Equal(t, map[string]TimeRange{
+		"binlog.000002": {Start: base.Add(time.Hour), End: base.Add(4 * time.Hour)},
+		"binlog.000003": {Start: base.Add(4 * time.Hour), End: base.Add(5 * time.Hour)},
+		"binlog.000004": {Start: base.Add(5 * time.Hour), End: base.Add(6 * time.Hour)},
+	}, estimated)
+}
@@ -0,0 +1,160 @@
+package binlog
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// Managed platforms recognized by detectPlatform. Self-managed servers have no platform.
+const (
+	PlatformRDS    = "Amazon RDS"
+	PlatformAurora = "Amazon Aurora"
+)
+
+// detectPlatform returns the managed platform the server runs on, or "" if it is
+// self-managed or cannot be told
+func detectPlatform(db *sql.DB) string {
+	var version, comment, basedir sql.NullString
+	if err := db.QueryRow("SELECT @@version, @@version_comment, @@basedir").Scan(&version, &comment, &basedir); err != nil {
+		return ""
+	}
+	return platformOf(version.String, comment.String, basedir.String)
+}
+
+// platformOf recognizes a managed platform from the server's version, version comment and
+// base directory. Aurora MySQL 2 reports versions like 5.7.mysql_aurora.2.11.2, and both
+// Aurora and RDS install mysqld under /rdsdbbin, Aurora in a directory named after its
+// version.
+func platformOf(version, comment, basedir string) string {
+	for _, s := range []string{version, comment, basedir} {
+		if strings.Contains(strings.ToLower(s), "aurora") {
+			return PlatformAurora
+		}
+	}
+	if strings.HasPrefix(basedir, "/rdsdbbin/") {
+		return PlatformRDS
+	}
+	return ""
+}
+
+// DetectFlavor returns the replication flavor of the server, "mariadb" or "mysql". The
+// flavor decides how the replication connection registers: registered as a MySQL replica,
+// MariaDB replaces its GTID events with BEGIN statements.
+func DetectFlavor(cfg replication.BinlogSyncerConfig) (string, error) {
+	return Server{Config: cfg}.DetectFlavor()
+}
+
+// DetectFlavor is DetectFlavor on the server in Config
+func (s Server) DetectFlavor() (string, error) {
+	db, err := s.openDB()
+	if err != nil {
+		return "", err
+	}
+	defer closeDB(db)
+
+	var version string
+	if err := db.QueryRow("SELECT VERSION()").Scan(&version); err != nil {
+		return "", fmt.Errorf("failed to get server version: %w", err)
+	}
+	return flavorOf(version), nil
+}
+
+// detectedFlavors caches the flavor detected for each HOST:PORT, which fleet searches
+// look up concurrently
+var detectedFlavors sync.Map
+
+// NewSyncer returns a syncer for cfg. If cfg leaves the flavor unset, it is detected from
+// the server's version the first time a syncer is created for its HOST:PORT, as the
+// syncer parses events for the flavor it is created with.
+func NewSyncer(cfg replication.BinlogSyncerConfig) *replication.BinlogSyncer {
+	return Server{Config: cfg}.NewSyncer()
+}
+
+// NewSyncer is NewSyncer for the server in Config, connecting with the Settings to detect
+// its flavor
+func (s Server) NewSyncer() *replication.BinlogSyncer {
+	cfg := s.Config
+	cfg.Flavor = s.syncerFlavor()
+	return replication.NewBinlogSyncer(cfg)
+}
+
+// syncerFlavor returns the flavor of the config or else the one detected from the
+// server, assuming mysql if it cannot be told
+func (s Server) syncerFlavor() string {
+	if s.Config.Flavor != "" {
+		return s.Config.Flavor
+	}
+	addr := net.JoinHostPort(s.Config.Host, strconv.Itoa(int(s.Config.Port)))
+	if flavor, ok := detectedFlavors.Load(addr); ok {
+		return flavor.(string)
+	}
+	flavor, err := s.DetectFlavor()
+	if err != nil {
+		// Connecting again for the stream reports the error
+		slog.Debug("Could not detect the server flavor, assuming mysql", "host", addr, "error", err)
+		flavor = mysql.MySQLFlavor
+	}
+	detectedFlavors.Store(addr, flavor)
+	return flavor
+}
+
+// flavorOf returns the replication flavor of a server version
+func flavorOf(version string) string {
+	if strings.Contains(strings.ToLower(version), "mariadb") {
+		return mysql.MariaDBFlavor
+	}
+	return mysql.MySQLFlavor
+}
+
+// rdsRetentionHours returns the binlog retention hours set with
+// mysql.rds_set_configuration. ok is false when it is NULL, so the platform purges each
+// binlog as soon as no replica or backup needs it.
+func rdsRetentionHours(db *sql.DB) (hours int, ok bool, err error) {
+	rows, err := queryRows(db, "CALL mysql.rds_show_configuration")
+	if err != nil {
+		return 0, false, err
+	}
+	for _, row := range rows {
+		if !strings.EqualFold(row["name"], "binlog retention hours") {
+			continue
+		}
+		if row["value"] == "" {
+			return 0, false, nil
+		}
+		hours, err := strconv.Atoi(row["value"])
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid binlog retention hours %q: %w", row["value"], err)
+		}
+		return hours, true, nil
+	}
+	return 0, false, errors.New("mysql.rds_show_configuration has no binlog retention hours")
+}
+
+// enableBinlogRemediation explains how to turn binary logging on for the platform
+func enableBinlogRemediation(platform string) string {
+	switch platform {
+	case PlatformAurora:
+		return "Set binlog_format to ROW in the DB cluster parameter group (Aurora leaves it OFF by default) and reboot the writer instance"
+	case PlatformRDS:
+		return "RDS only writes binlogs while automated backups are on; set a backup retention period of at least 1 day on the instance"
+	}
+	return "Enable binary logging by setting log_bin and server_id in my.cnf and restarting mysqld"
+}
+
+// binlogDisabledError returns ErrBinlogDisabled with the fix for the server's platform
+func binlogDisabledError(db *sql.DB) error {
+	platform := detectPlatform(db)
+	if platform != "" {
+		return fmt.Errorf("%w on %s: %s", ErrBinlogDisabled, platform, enableBinlogRemediation(platform))
+	}
+	return fmt.Errorf("%w: %s", ErrBinlogDisabled, enableBinlogRemediation(platform))
+}
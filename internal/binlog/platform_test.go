@@ -0,0 +1,48 @@
+package binlog
+
+import (
+	"testing"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlatformOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  string
+		comment  string
+		basedir  string
+		expected string
+	}{
+		{"Self-managed", "8.0.36", "MySQL Community Server - GPL", "/usr/", ""},
+		{"MariaDB", "10.11.6-MariaDB", "mariadb.org binary distribution", "/usr", ""},
+		{"RDS", "8.0.35", "Source distribution", "/rdsdbbin/mysql-8.0.35.R2/", PlatformRDS},
+		{"Aurora MySQL 2", "5.7.mysql_aurora.2.11.2", "MySQL Community Server (GPL)", "/rdsdbbin/oscar-5.7.mysql_aurora.2.11.2.0.0/", PlatformAurora},
+		{"Aurora MySQL 3", "8.0.28", "Source distribution", "/rdsdbbin/oscar-8.0.mysql_aurora.3.04.0.0.0/", PlatformAurora},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, platformOf(tt.version, tt.comment, tt.basedir))
+		})
+	}
+}
+
+func TestFlavorOf(t *testing.T) {
+	assert.Equal(t, "mysql", flavorOf("8.0.36"))
+	assert.Equal(t, "mysql", flavorOf("8.0.36-28"))
+	assert.Equal(t, "mariadb", flavorOf("10.11.6-MariaDB-log"))
+	assert.Equal(t, "mariadb", flavorOf("5.5.5-10.6.16-MariaDB"))
+}
+
+func TestSyncerFlavor(t *testing.T) {
+	// No server listens on the host, so any detection would fall back to mysql
+	cfg := replication.BinlogSyncerConfig{Host: "db.invalid", Port: 3306}
+	detectedFlavors.Store("db.invalid:3306", "mariadb")
+	defer detectedFlavors.Delete("db.invalid:3306")
+
+	assert.Equal(t, "mariadb", Server{Config: cfg}.syncerFlavor(), "the flavor detected for the host")
+	cfg.Flavor = "mysql"
+	assert.Equal(t, "mysql", Server{Config: cfg}.syncerFlavor(), "the configured flavor")
+}
@@ -0,0 +1,309 @@
+package binlog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// Alignment controls which boundary a located position is snapped to
+type Alignment int
+
+const (
+	// AlignTransaction snaps to the start of the transaction containing the located event,
+	// so replaying from the position never begins in the middle of a transaction
+	AlignTransaction Alignment = iota
+	// AlignEvent reports the start of the first event at or after the target time
+	AlignEvent
+	// AlignNone reports the offset bisecting the file narrowed the target down to, without
+	// reading on to snap it to the event at the target time: an event boundary at most
+	// about 1 MiB before it. Files too small to bisect, or read from their start, report
+	// the first event at or after the target time as AlignEvent does.
+	AlignNone
+)
+
+// ParseAlignment parses an alignment name as used on the command line
+func ParseAlignment(s string) (Alignment, error) {
+	switch s {
+	case "transaction":
+		return AlignTransaction, nil
+	case "event":
+		return AlignEvent, nil
+	case "none":
+		return AlignNone, nil
+	default:
+		return 0, fmt.Errorf("unknown alignment %q (expected transaction, event or none)", s)
+	}
+}
+
+// String returns the command line name of the alignment
+func (a Alignment) String() string {
+	switch a {
+	case AlignTransaction:
+		return "transaction"
+	case AlignEvent:
+		return "event"
+	case AlignNone:
+		return "none"
+	default:
+		return fmt.Sprintf("Alignment(%d)", int(a))
+	}
+}
+
+// Position is a coordinate within the binlog stream
+type Position struct {
+	File string
+	Pos  uint32
+	// GTID of the transaction starting at Pos, empty when GTIDs are not in use
+	GTID string
+	// Timestamp of the event at Pos, zero when the position is the start of a file
+	Timestamp time.Time
+	// ServerID is the server_id of the server the event at Pos originated on, 0 when the
+	// position is the start of a file
+	ServerID uint32
+	// Bracket holds the events on either side of the target time the position was located
+	// for, whatever its alignment
+	Bracket *Bracket
+}
+
+// Bracket is the pair of events around a target time. Either is nil when the scan read
+// none: Before when it started after the target, After when no event follows the target
+// in the file.
+type Bracket struct {
+	// Before is the last event, in file order, at or before the target time; its end is
+	// where mysqlbinlog --stop-position replays up to it
+	Before *EventSummary
+	// After is the first event at or after the target time, where replay from it starts
+	After *EventSummary
+}
+
+// String formats the position as file:pos
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d", p.File, p.Pos)
+}
+
+// LocatePosition scans a binlog file for the first event at or after the target time
+// and returns its position, snapped according to align. If every event in the file is
+// older than the target, the start of the next file is returned. Event times are taken
+// from source.
+//
+// Timestamps may be out of order by up to slack, as written by multi-threaded replica
+// appliers: an older event within slack after the located one moves the position past it.
+func LocatePosition(syncer *replication.BinlogSyncer, binlogFile string, targetTime time.Time, align Alignment, source TimestampSource, slack time.Duration) (Position, error) {
+	return locatePosition(context.Background(), newSyncerStreamer(syncer), binlogFile, 4, targetTime, align, source, slack)
+}
+
+// Locate is LocatePosition reading through the Finder's streamer, with event times taken
+// from its timestamp source. What it reads is added to Stats.
+func (f *Finder) Locate(binlogFile string, targetTime time.Time, align Alignment, slack time.Duration) (Position, error) {
+	onEvent, done := f.reader(binlogFile)
+	defer done(false)
+	return locatePosition(f.readContext(), &countingStreamer{streamer: f.streamer(), onEvent: onEvent}, binlogFile, 4, targetTime, align, f.Source, slack)
+}
+
+// locatePosition implements LocatePosition, scanning from the event starting at pos
+func locatePosition(ctx context.Context, streamer EventStreamer, binlogFile string, pos uint32, targetTime time.Time, align Alignment, source TimestampSource, slack time.Duration) (Position, error) {
+	// Scanning is sequential, so allow much longer than a range probe
+	ctx, cancel := withReadTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	stream, err := streamer.StreamFrom(binlogFile, pos)
+	if err != nil {
+		return Position{}, fmt.Errorf("failed to start sync from %s:%d: %w", binlogFile, pos, err)
+	}
+	defer stream.Close()
+
+	return scanToTime(ctx, stream, binlogFile, targetTime, align, source, slack, false)
+}
+
+// WaitForPosition streams from the start of a binlog file, following rotations and
+// waiting for new events, until the server writes an event at or after the target time.
+// It returns the position snapped according to align, or an error once ctx is done.
+func WaitForPosition(ctx context.Context, syncer *replication.BinlogSyncer, binlogFile string, targetTime time.Time, align Alignment, source TimestampSource) (Position, error) {
+	streamer := newSyncerStreamer(syncer)
+	streamer.ctx = ctx
+	return waitForPosition(ctx, streamer, binlogFile, targetTime, align, source)
+}
+
+// WaitForPosition is WaitForPosition reading through the Finder's streamer, with event
+// times taken from its timestamp source
+func (f *Finder) WaitForPosition(ctx context.Context, binlogFile string, targetTime time.Time, align Alignment) (Position, error) {
+	return waitForPosition(ctx, f.streamer(), binlogFile, targetTime, align, f.Source)
+}
+
+// waitForPosition implements WaitForPosition
+func waitForPosition(ctx context.Context, streamer EventStreamer, binlogFile string, targetTime time.Time, align Alignment, source TimestampSource) (Position, error) {
+	stream, err := streamer.StreamFrom(binlogFile, 4)
+	if err != nil {
+		return Position{}, fmt.Errorf("failed to start sync from %s: %w", binlogFile, err)
+	}
+	defer stream.Close()
+
+	// Waiting out a slack window would delay every answer, so live events are taken in order
+	return scanToTime(ctx, stream, binlogFile, targetTime, align, source, 0, true)
+}
+
+// scanToTime reads events until one is at or after the target time, then keeps reading
+// until an event is at least slack past it, so that an older event written out of order
+// moves the result past it. When follow is false, reaching the end of the file returns
+// the start of the next file instead, and the events in the target second are read on to
+// its end for the bracket of the position.
+func scanToTime(ctx context.Context, stream EventStream, binlogFile string, targetTime time.Time, align Alignment, source TimestampSource, slack time.Duration, follow bool) (Position, error) {
+	// Start of the transaction currently being read, if any
+	var txStart Position
+	inTx := false
+	// Commit timestamps are only carried by GTID events, so the other events of a
+	// transaction take the time of the GTID event that started it
+	var txTime time.Time
+	// Position located so far, confirmed once an event is at least slack past the target
+	var located *Position
+	confirmed := false
+	var before, after *bracketEvent
+	done := func(pos Position) (Position, error) {
+		if before != nil || after != nil {
+			pos.Bracket = &Bracket{Before: before.summary(), After: after.summary()}
+		}
+		return pos, nil
+	}
+
+	for {
+		ev, err := stream.GetEvent(ctx)
+		if err != nil {
+			// An unconfirmed position may still move, unless the stream simply ended
+			if located != nil && !interrupted(err) {
+				return done(*located)
+			}
+			return Position{}, fmt.Errorf("target time not reached in %s: %w", binlogFile, err)
+		}
+		// Once caught up with the server, no event written out of order can still come
+		if isHeartbeat(ev) {
+			if located != nil {
+				return done(*located)
+			}
+			if !follow {
+				return Position{}, fmt.Errorf("target time not reached in %s: %w", binlogFile, io.EOF)
+			}
+			continue
+		}
+		traceEvent(binlogFile, ev)
+
+		start := ev.Header.LogPos - ev.Header.EventSize
+		evTime := time.Unix(int64(ev.Header.Timestamp), 0)
+		if source != TimestampHeader {
+			if t, ok := eventTime(ev, source); ok {
+				txTime = t
+			}
+			// Without commit timestamps (before MySQL 8.0.1), fall back to the header
+			if !txTime.IsZero() {
+				evTime = txTime
+			}
+		}
+
+		switch e := ev.Event.(type) {
+		case *replication.RotateEvent:
+			// A real rotate at the end of the file means every event was older than the target
+			if ev.Header.Timestamp > 0 && !follow {
+				if located != nil {
+					return done(*located)
+				}
+				return done(Position{File: string(e.NextLogName), Pos: uint32(e.Position)})
+			}
+			binlogFile = string(e.NextLogName)
+			inTx = false
+			continue
+		case *replication.FormatDescriptionEvent, *replication.PreviousGTIDsEvent,
+			*replication.MariadbGTIDListEvent, *replication.MariadbBinlogCheckPointEvent:
+			continue
+		case *replication.GTIDEvent:
+			txStart = Position{File: binlogFile, Pos: start, Timestamp: evTime, ServerID: ev.Header.ServerID}
+			if ev.Header.EventType == replication.GTID_EVENT {
+				if next, err := e.GTIDNext(); err == nil {
+					txStart.GTID = next.String()
+				}
+			}
+			inTx = true
+		case *replication.MariadbGTIDEvent:
+			// MariaDB writes no BEGIN after its GTID events
+			txStart = Position{File: binlogFile, Pos: start, Timestamp: evTime, GTID: e.GTID.String(), ServerID: ev.Header.ServerID}
+			inTx = true
+		case *replication.QueryEvent:
+			// Without GTIDs, a transaction starts at its BEGIN statement
+			if strings.EqualFold(string(e.Query), "BEGIN") && !inTx {
+				txStart = Position{File: binlogFile, Pos: start, Timestamp: evTime, ServerID: ev.Header.ServerID}
+				inTx = true
+			}
+		}
+
+		if !evTime.After(targetTime) {
+			before = &bracketEvent{binlogFile, start, evTime, ev}
+		}
+		if confirmed {
+			if evTime.After(targetTime) {
+				return done(*located)
+			}
+			continue
+		}
+
+		switch {
+		case evTime.Before(targetTime):
+			// An event older than the target after the located one was written out of order
+			located, after = nil, nil
+		case located == nil:
+			pos := Position{File: binlogFile, Pos: start, Timestamp: evTime, ServerID: ev.Header.ServerID}
+			if align == AlignTransaction && inTx {
+				pos = txStart
+			} else if inTx && txStart.Pos == start {
+				pos.GTID = txStart.GTID
+			}
+			located = &pos
+			after = &bracketEvent{binlogFile, start, evTime, ev}
+		}
+		if located != nil && !evTime.Before(targetTime.Add(slack)) {
+			if follow || evTime.After(targetTime) {
+				return done(*located)
+			}
+			confirmed = true
+		}
+
+		if endsTransaction(ev) {
+			inTx = false
+		}
+	}
+}
+
+// endsTransaction reports whether the event commits the current transaction
+func endsTransaction(ev *replication.BinlogEvent) bool {
+	switch e := ev.Event.(type) {
+	case *replication.XIDEvent:
+		return true
+	case *replication.QueryEvent:
+		// COMMIT ends DML transactions; DDL statements are transactions on their own
+		return !strings.EqualFold(string(e.Query), "BEGIN")
+	case *replication.TransactionPayloadEvent:
+		// A compressed payload holds the rest of the transaction, commit included
+		return true
+	}
+	return false
+}
+
+// bracketEvent is an event read by scanToTime, summarized only if it ends up in the bracket
+type bracketEvent struct {
+	file  string
+	start uint32
+	time  time.Time
+	ev    *replication.BinlogEvent
+}
+
+// summary describes the event with its time from the scan's timestamp source
+func (b *bracketEvent) summary() *EventSummary {
+	if b == nil {
+		return nil
+	}
+	summary := summarize(b.file, b.start, b.ev)
+	summary.Timestamp = b.time
+	return &summary
+}
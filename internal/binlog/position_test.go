@@ -0,0 +1,245 @@
+package binlog
+
+import (
+	"context"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAlignment(t *testing.T) {
+	for _, align := range []Alignment{AlignTransaction, AlignEvent, AlignNone} {
+		parsed, err := ParseAlignment(align.String())
+		assert.NoError(t, err)
+		assert.Equal(t, align, parsed)
+	}
+
+	_, err := ParseAlignment("statement")
+	assert.Error(t, err)
+}
+
+// transaction returns the GTID and XID events of a transaction starting at pos, written at ts
+func transaction(pos uint32, ts uint32) []*replication.BinlogEvent {
+	return []*replication.BinlogEvent{
+		{Header: &replication.EventHeader{EventType: replication.ANONYMOUS_GTID_EVENT, Timestamp: ts, LogPos: pos + 50, EventSize: 50}, Event: &replication.GTIDEvent{}},
+		{Header: &replication.EventHeader{EventType: replication.XID_EVENT, Timestamp: ts, LogPos: pos + 80, EventSize: 30}, Event: &replication.XIDEvent{}},
+	}
+}
+
+func TestScanToTimeSlack(t *testing.T) {
+	var events []*replication.BinlogEvent
+	for _, tx := range []struct{ pos, ts uint32 }{{100, 104}, {200, 106}, {300, 103}, {400, 107}, {500, 112}} {
+		events = append(events, transaction(tx.pos, tx.ts)...)
+	}
+	target := time.Unix(105, 0)
+
+	tests := []struct {
+		name     string
+		slack    time.Duration
+		expected uint32
+	}{
+		{"No slack stops at the first newer transaction", 0, 200},
+		{"Slack skips past an inverted transaction", 5 * time.Second, 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos, err := scanToTime(context.Background(), &fakeStream{events: events}, "mysql-bin.000001", target, AlignTransaction, TimestampHeader, tt.slack, false)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, pos.Pos)
+		})
+	}
+}
+
+func TestScanToTimeHeartbeat(t *testing.T) {
+	heartbeat := &replication.BinlogEvent{
+		Header: &replication.EventHeader{EventType: replication.HEARTBEAT_LOG_EVENT_V2, LogPos: 380},
+		Event:  &replication.GenericEvent{},
+	}
+	var events []*replication.BinlogEvent
+	for _, tx := range []struct{ pos, ts uint32 }{{100, 104}, {200, 106}, {300, 108}} {
+		events = append(events, transaction(tx.pos, tx.ts)...)
+	}
+	events = append(events, heartbeat)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The server has sent everything, so no slack is waited out
+	pos, err := scanToTime(ctx, &fakeStream{events: slices.Clone(events)}, "mysql-bin.000001", time.Unix(105, 0), AlignTransaction, TimestampHeader, time.Minute, false)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(200), pos.Pos)
+
+	// A target past the last event ends at the heartbeat rather than the deadline
+	_, err = scanToTime(ctx, &fakeStream{events: slices.Clone(events)}, "mysql-bin.000001", time.Unix(110, 0), AlignTransaction, TimestampHeader, 0, false)
+	assert.ErrorIs(t, err, io.EOF)
+	assert.NoError(t, ctx.Err())
+}
+
+func TestScanToTimeBracket(t *testing.T) {
+	var events []*replication.BinlogEvent
+	for _, tx := range []struct{ pos, ts uint32 }{{100, 104}, {200, 105}, {300, 105}, {400, 107}} {
+		events = append(events, transaction(tx.pos, tx.ts)...)
+	}
+	events = append(events, &replication.BinlogEvent{
+		Header: &replication.EventHeader{EventType: replication.HEARTBEAT_LOG_EVENT_V2, LogPos: 480},
+		Event:  &replication.GenericEvent{},
+	})
+
+	tests := []struct {
+		name   string
+		target uint32
+		// before and after are the starts of the bracketing events, 0 for none
+		before, after uint32
+		pos           uint32
+	}{
+		{"Events at the target are read to the end of its second", 105, 350, 200, 200},
+		{"Target between transactions", 106, 350, 400, 400},
+		{"Target before the first event", 103, 0, 100, 100},
+		{"Target at the last event", 107, 450, 400, 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			pos, err := scanToTime(ctx, &fakeStream{events: slices.Clone(events)}, "mysql-bin.000001", time.Unix(int64(tt.target), 0), AlignTransaction, TimestampHeader, 0, false)
+			require.NoError(t, err)
+			assert.Equal(t, tt.pos, pos.Pos)
+			require.NotNil(t, pos.Bracket)
+			if tt.before == 0 {
+				assert.Nil(t, pos.Bracket.Before)
+			} else {
+				require.NotNil(t, pos.Bracket.Before)
+				assert.Equal(t, tt.before, pos.Bracket.Before.Pos)
+				assert.Equal(t, "XIDEvent", pos.Bracket.Before.Type)
+			}
+			require.NotNil(t, pos.Bracket.After)
+			assert.Equal(t, tt.after, pos.Bracket.After.Pos)
+		})
+	}
+}
+
+func TestScanToTimeSubSecond(t *testing.T) {
+	// Transactions committed at 105.2s, 105.6s and 106.1s, whose headers have whole seconds
+	var events []*replication.BinlogEvent
+	for i, commit := range []int64{105_200_000, 105_600_000, 106_100_000} {
+		pos := uint32(100 * (i + 1))
+		tx := transaction(pos, uint32(commit/1_000_000))
+		tx[0].Event = &replication.GTIDEvent{OriginalCommitTimestamp: uint64(commit)}
+		events = append(events, tx...)
+	}
+	events = append(events, &replication.BinlogEvent{
+		Header: &replication.EventHeader{EventType: replication.HEARTBEAT_LOG_EVENT_V2, LogPos: 380},
+		Event:  &replication.GenericEvent{},
+	})
+	target := time.UnixMicro(105_500_000)
+
+	tests := []struct {
+		name     string
+		source   TimestampSource
+		expected uint32
+	}{
+		// The first event whose timestamp is at or after the target, however precise
+		{"Microsecond commit timestamps", TimestampOriginalCommit, 200},
+		{"Whole-second header timestamps count the target's second as before it", TimestampHeader, 300},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			pos, err := scanToTime(ctx, &fakeStream{events: slices.Clone(events)}, "mysql-bin.000001", target, AlignTransaction, tt.source, 0, false)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, pos.Pos)
+			assert.False(t, pos.Timestamp.Before(target))
+		})
+	}
+}
+
+func TestScanToTimeMariaDB(t *testing.T) {
+	// MariaDB starts transactions with its own GTID event and no BEGIN
+	var events []*replication.BinlogEvent
+	for i, tx := range []struct{ pos, ts uint32 }{{100, 104}, {200, 106}, {300, 108}} {
+		events = append(events,
+			&replication.BinlogEvent{Header: &replication.EventHeader{EventType: replication.MARIADB_GTID_EVENT, Timestamp: tx.ts, LogPos: tx.pos + 40, EventSize: 40},
+				Event: &replication.MariadbGTIDEvent{GTID: mysql.MariadbGTID{DomainID: 0, ServerID: 1, SequenceNumber: uint64(10 + i)}}},
+			&replication.BinlogEvent{Header: &replication.EventHeader{EventType: replication.WRITE_ROWS_EVENTv1, Timestamp: tx.ts, LogPos: tx.pos + 70, EventSize: 30}, Event: &replication.RowsEvent{}},
+			&replication.BinlogEvent{Header: &replication.EventHeader{EventType: replication.XID_EVENT, Timestamp: tx.ts, LogPos: tx.pos + 90, EventSize: 20}, Event: &replication.XIDEvent{}},
+		)
+	}
+
+	pos, err := scanToTime(context.Background(), &fakeStream{events: events}, "mariadb-bin.000001", time.Unix(105, 0), AlignTransaction, TimestampHeader, 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(200), pos.Pos)
+	assert.Equal(t, "0-1-11", pos.GTID)
+}
+
+func TestLocatePositionCompressed(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{FileSize: 64 << 10, Rate: 0.5, Compress: true, Seed: 1})
+	f := files[0]
+	finder := &Finder{Streamer: FileStreamer{Reader: memoryFiles{f.Name: f.Data}}}
+
+	// The GTID event of a transaction is followed by its compressed payload
+	var gtid, payload binlogtest.Event
+	for i, ev := range f.Events {
+		if ev.Type == replication.GTID_EVENT && i > len(f.Events)/2 {
+			gtid, payload = ev, f.Events[i+1]
+			break
+		}
+	}
+	require.Equal(t, replication.TRANSACTION_PAYLOAD_EVENT, payload.Type)
+	target := gtid.Time.Truncate(time.Second)
+
+	tests := []struct {
+		align    Alignment
+		expected uint32
+	}{
+		{AlignTransaction, gtid.Pos},
+		{AlignEvent, gtid.Pos},
+	}
+	for _, tt := range tests {
+		t.Run(tt.align.String(), func(t *testing.T) {
+			pos, err := finder.Locate(f.Name, target, tt.align, 0)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, pos.Pos)
+		})
+	}
+
+	// Every transaction is complete once its payload is read
+	stream, err := finder.streamer().StreamFrom(f.Name, payload.Pos)
+	require.NoError(t, err)
+	defer stream.Close()
+	_, err = stream.GetEvent(context.Background()) // format description
+	require.NoError(t, err)
+	ev, err := stream.GetEvent(context.Background())
+	require.NoError(t, err)
+	assert.True(t, endsTransaction(ev))
+	assert.Len(t, ev.Event.(*replication.TransactionPayloadEvent).Events, 3)
+}
+
+func TestFinderLocateStats(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{FileSize: 64 << 10, Rate: 0.5, Seed: 1})
+	f := files[0]
+	stats := &Stats{}
+	finder := &Finder{Streamer: FileStreamer{Reader: memoryFiles{f.Name: f.Data}}, Stats: stats}
+	var reported int
+	finder.OnEvent = func(file string, events int) { reported = events }
+
+	target := f.Start.Add(f.End.Sub(f.Start) / 2)
+	pos, err := finder.Locate(f.Name, target, AlignEvent, 0)
+	require.NoError(t, err)
+
+	// Everything before the position was read, without counting as a probe
+	load := stats.Snapshot()
+	assert.Zero(t, load.FilesProbed)
+	assert.Positive(t, load.Events)
+	assert.Equal(t, load.Events, reported, "every event read was reported before Locate returned")
+	assert.GreaterOrEqual(t, load.Bytes, int64(pos.Pos)-4)
+}
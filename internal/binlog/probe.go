@@ -0,0 +1,103 @@
+package binlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// ProbeFile reads a binlog file from its start, passing each event to fn until fn returns
+// false, the file ends or ctx is done, for logic of its own over a file's events, such as
+// stopping at the first DDL statement, without handling the connections. Events in a
+// compressed transaction payload follow the payload event, sharing its position, with
+// InPayload set. Event times are taken from the Finder's timestamp source.
+//
+// Reaching the end of the file, or of the events written so far in the active file, is
+// not an error. Reading also stops when the Finder's Context is canceled, and with
+// context.DeadlineExceeded once its probe timeout has passed.
+func (f *Finder) ProbeFile(ctx context.Context, binlogFile string, fn func(ev EventSummary) bool) error {
+	// AfterFunc cancels in a goroutine of its own, which may come too late for a short file
+	base := f.readContext()
+	if err := base.Err(); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(base, cancel)
+	defer stop()
+	ctx, cancelProbe := probeContext(ctx, f.probeTimeout())
+	defer cancelProbe()
+
+	onEvent, done := f.reader(binlogFile)
+	defer done(true)
+	return probeFile(ctx, f.streamer(), binlogFile, f.Source, fn, onEvent)
+}
+
+// probeFile implements ProbeFile, returning nil once fn stops it or the file ends
+func probeFile(ctx context.Context, streamer EventStreamer, binlogFile string, source TimestampSource, fn func(ev EventSummary) bool, onEvent func(events int, bytes int64)) error {
+	stream, err := streamer.StreamFrom(binlogFile, 4)
+	if err != nil {
+		return fmt.Errorf("failed to start sync from %s: %w", binlogFile, err)
+	}
+	defer stream.Close()
+
+	// As in scanToTime, commit timestamps carried by GTID events date the whole transaction
+	var txTime time.Time
+	var events int
+	var bytes int64
+	for {
+		ev, err := stream.GetEvent(ctx)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", binlogFile, err)
+		}
+		// Caught up with the server, which has written nothing more to the file yet
+		if isHeartbeat(ev) {
+			return nil
+		}
+		traceEvent(binlogFile, ev)
+		events, bytes = events+1, bytes+int64(ev.Header.EventSize)
+		onEvent(events, bytes)
+
+		_, isRotate := ev.Event.(*replication.RotateEvent)
+		// The fake rotate at the start of a replication stream is not in the file
+		if isRotate && ev.Header.Timestamp == 0 {
+			continue
+		}
+		evTime := time.Unix(int64(ev.Header.Timestamp), 0)
+		if source != TimestampHeader {
+			if t, ok := eventTime(ev, source); ok {
+				txTime = t
+			}
+			if !txTime.IsZero() {
+				evTime = txTime
+			}
+		}
+
+		start := ev.Header.LogPos - ev.Header.EventSize
+		summary := summarize(binlogFile, start, ev)
+		summary.Timestamp = evTime
+		if !fn(summary) {
+			return nil
+		}
+		if payload, ok := ev.Event.(*replication.TransactionPayloadEvent); ok {
+			for _, inner := range payload.Events {
+				summary := summarize(binlogFile, start, inner)
+				summary.Timestamp, summary.InPayload = evTime, true
+				if !fn(summary) {
+					return nil
+				}
+			}
+		}
+		// The rest of the stream is the next file
+		if isRotate {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package binlog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFinderProbeFile(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 2, FileSize: 4 << 10, Rate: 0.5, Seed: 1})
+	stored := make(memoryFiles, len(files))
+	for _, f := range files {
+		stored[f.Name] = f.Data
+	}
+
+	t.Run("Every event in the file", func(t *testing.T) {
+		stats := &Stats{}
+		finder := &Finder{Streamer: FileStreamer{Reader: stored}, Stats: stats}
+		var events []EventSummary
+		err := finder.ProbeFile(context.Background(), files[0].Name, func(ev EventSummary) bool {
+			events = append(events, ev)
+			return true
+		})
+		require.NoError(t, err)
+		require.Len(t, events, len(files[0].Events))
+		for i, want := range files[0].Events {
+			assert.Equal(t, files[0].Name, events[i].File)
+			assert.Equal(t, want.Pos, events[i].Pos)
+			assert.Equal(t, want.Type.String(), events[i].Type)
+		}
+		assert.Equal(t, replication.ROTATE_EVENT.String(), events[len(events)-1].Type, "the file ends with its rotate event")
+		assert.Equal(t, 1, stats.Snapshot().FilesProbed)
+		assert.Equal(t, len(events), stats.Snapshot().Events)
+	})
+
+	t.Run("Stops when the callback returns false", func(t *testing.T) {
+		finder := &Finder{Streamer: FileStreamer{Reader: stored}}
+		var events []EventSummary
+		err := finder.ProbeFile(context.Background(), files[1].Name, func(ev EventSummary) bool {
+			events = append(events, ev)
+			return ev.Type != replication.XID_EVENT.String()
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, events)
+		assert.Equal(t, replication.XID_EVENT.String(), events[len(events)-1].Type)
+		assert.Less(t, len(events), len(files[1].Events))
+	})
+
+	t.Run("Events in compressed payloads", func(t *testing.T) {
+		compressed := binlogtest.Generate(binlogtest.Options{FileSize: 2 << 10, Rate: 0.5, Seed: 1, Compress: true})
+		finder := &Finder{Streamer: FileStreamer{Reader: memoryFiles{compressed[0].Name: compressed[0].Data}}}
+		var payload EventSummary
+		var inner []EventSummary
+		err := finder.ProbeFile(context.Background(), compressed[0].Name, func(ev EventSummary) bool {
+			if ev.InPayload {
+				inner = append(inner, ev)
+				return len(inner) < 3
+			}
+			payload = ev
+			return true
+		})
+		require.NoError(t, err)
+		require.Len(t, inner, 3)
+		assert.Equal(t, replication.TRANSACTION_PAYLOAD_EVENT.String(), payload.Type)
+		for _, ev := range inner {
+			assert.Equal(t, payload.Pos, ev.Pos)
+			assert.Equal(t, payload.Timestamp, ev.Timestamp)
+		}
+		assert.Contains(t, inner[0].Info, "BEGIN")
+		assert.Equal(t, replication.XID_EVENT.String(), inner[2].Type)
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		finder := &Finder{Streamer: FileStreamer{Reader: stored}}
+		ctx, cancel := context.WithCancel(context.Background())
+		var events int
+		err := finder.ProbeFile(ctx, files[0].Name, func(ev EventSummary) bool {
+			events++
+			cancel()
+			return true
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, events)
+	})
+
+	t.Run("Interrupted through the Finder's context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		finder := &Finder{Streamer: FileStreamer{Reader: stored}, Context: ctx}
+		err := finder.ProbeFile(context.Background(), files[0].Name, func(ev EventSummary) bool { return true })
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("Timed out", func(t *testing.T) {
+		finder := &Finder{Streamer: FileStreamer{Reader: stored}, ProbeTimeout: 20 * time.Millisecond}
+		err := finder.ProbeFile(context.Background(), files[0].Name, func(ev EventSummary) bool {
+			time.Sleep(10 * time.Millisecond)
+			return true
+		})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		finder := &Finder{Streamer: FileStreamer{Reader: stored}}
+		err := finder.ProbeFile(context.Background(), "binlog.000099", func(ev EventSummary) bool { return true })
+		assert.ErrorContains(t, err, "binlog.000099")
+	})
+}
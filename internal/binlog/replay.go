@@ -0,0 +1,38 @@
+package binlog
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ReplayBytes returns the number of bytes of binlog from start to stop: the rest of the
+// start file, every file in between and the stop file up to its position. A stop position
+// of 0 stands for the end of the stop file. files lists the binlogs in order, and sizes
+// must hold the size of each file read to its end.
+func ReplayBytes(files []string, sizes map[string]int64, start, stop Position) (int64, error) {
+	from := slices.Index(files, start.File)
+	to := slices.Index(files, stop.File)
+	switch {
+	case from < 0:
+		return 0, fmt.Errorf("%s is not a listed binlog", start.File)
+	case to < 0:
+		return 0, fmt.Errorf("%s is not a listed binlog", stop.File)
+	case from > to || from == to && stop.Pos != 0 && start.Pos > stop.Pos:
+		return 0, fmt.Errorf("%s:%d is past %s:%d", start.File, start.Pos, stop.File, stop.Pos)
+	}
+
+	end := int64(stop.Pos)
+	if stop.Pos == 0 {
+		to++
+		end = 0
+	}
+	var total int64
+	for _, file := range files[from:to] {
+		size, ok := sizes[file]
+		if !ok || size == 0 {
+			return 0, fmt.Errorf("size of %s unknown", file)
+		}
+		total += size
+	}
+	return total + end - int64(start.Pos), nil
+}
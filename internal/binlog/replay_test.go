@@ -0,0 +1,38 @@
+package binlog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayBytes(t *testing.T) {
+	files := []string{"mysql-bin.000001", "mysql-bin.000002", "mysql-bin.000003"}
+	sizes := map[string]int64{"mysql-bin.000001": 1000, "mysql-bin.000002": 2000, "mysql-bin.000003": 500}
+
+	tests := []struct {
+		name     string
+		start    Position
+		stop     Position
+		expected int64
+	}{
+		{"Across files", Position{File: "mysql-bin.000001", Pos: 400}, Position{File: "mysql-bin.000003", Pos: 300}, 600 + 2000 + 300},
+		{"Same file", Position{File: "mysql-bin.000002", Pos: 400}, Position{File: "mysql-bin.000002", Pos: 1500}, 1100},
+		{"To the end of the stop file", Position{File: "mysql-bin.000002", Pos: 400}, Position{File: "mysql-bin.000003"}, 1600 + 500},
+		{"To the end of the start file", Position{File: "mysql-bin.000003", Pos: 100}, Position{File: "mysql-bin.000003"}, 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := ReplayBytes(files, sizes, tt.start, tt.stop)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, n)
+		})
+	}
+
+	_, err := ReplayBytes(files, sizes, Position{File: "mysql-bin.000003", Pos: 100}, Position{File: "mysql-bin.000001", Pos: 100})
+	assert.ErrorContains(t, err, "is past")
+	_, err = ReplayBytes(files, map[string]int64{}, Position{File: "mysql-bin.000001", Pos: 100}, Position{File: "mysql-bin.000002", Pos: 100})
+	assert.ErrorContains(t, err, "size of mysql-bin.000001 unknown")
+}
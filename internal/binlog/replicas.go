@@ -0,0 +1,158 @@
+package binlog
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// Replica is a replica registered with a source server, as reported by SHOW REPLICAS
+type Replica struct {
+	ServerID uint32
+	// Host is only reported when the replica sets report_host
+	Host string
+	Port int
+}
+
+// DiscoverReplicas lists the replicas connected to the server described by cfg
+func DiscoverReplicas(cfg replication.BinlogSyncerConfig) ([]Replica, error) {
+	return Server{Config: cfg}.DiscoverReplicas()
+}
+
+// DiscoverReplicas is DiscoverReplicas on the server in Config
+func (s Server) DiscoverReplicas() ([]Replica, error) {
+	db, err := s.openDB()
+	if err != nil {
+		return nil, err
+	}
+	defer closeDB(db)
+
+	// SHOW REPLICAS replaced SHOW SLAVE HOSTS in MySQL 8.0.22
+	rows, err := queryRows(db, "SHOW REPLICAS", "SHOW SLAVE HOSTS")
+	if err != nil {
+		return nil, err
+	}
+
+	var replicas []Replica
+	for _, row := range rows {
+		serverID, _ := strconv.ParseUint(row["server_id"], 10, 32)
+		port, _ := strconv.Atoi(row["port"])
+		replicas = append(replicas, Replica{ServerID: uint32(serverID), Host: row["host"], Port: port})
+	}
+	return replicas, nil
+}
+
+// CheckReplicaHealth returns an error unless the server described by cfg is a replica
+// with binary logging enabled, log_replica_updates on so that its binlogs hold the writes
+// it replicates, and both replication threads running
+func CheckReplicaHealth(cfg replication.BinlogSyncerConfig) error {
+	return Server{Config: cfg}.CheckReplicaHealth()
+}
+
+// CheckReplicaHealth is CheckReplicaHealth on the server in Config
+func (s Server) CheckReplicaHealth() error {
+	db, err := s.openDB()
+	if err != nil {
+		return err
+	}
+	defer closeDB(db)
+
+	var logBin int
+	if err := db.QueryRow("SELECT @@GLOBAL.log_bin").Scan(&logBin); err != nil {
+		return fmt.Errorf("failed to check log_bin: %w", err)
+	}
+	if logBin == 0 {
+		return binlogDisabledError(db)
+	}
+
+	// log_replica_updates replaced log_slave_updates in MySQL 8.0.26
+	var logUpdates int
+	err = db.QueryRow("SELECT @@GLOBAL.log_replica_updates").Scan(&logUpdates)
+	if errorCode(err) == mysql.ER_UNKNOWN_SYSTEM_VARIABLE {
+		err = db.QueryRow("SELECT @@GLOBAL.log_slave_updates").Scan(&logUpdates)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check log_replica_updates: %w", err)
+	}
+	if logUpdates == 0 {
+		return errors.New("log_replica_updates is OFF, so the binlogs lack the replicated writes")
+	}
+
+	// SHOW REPLICA STATUS replaced SHOW SLAVE STATUS in MySQL 8.0.22
+	rows, err := queryRows(db, "SHOW REPLICA STATUS", "SHOW SLAVE STATUS")
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return errors.New("replication is not configured")
+	}
+	for _, row := range rows {
+		io := firstOf(row, "replica_io_running", "slave_io_running")
+		sqlThread := firstOf(row, "replica_sql_running", "slave_sql_running")
+		if !strings.EqualFold(io, "Yes") || !strings.EqualFold(sqlThread, "Yes") {
+			return fmt.Errorf("replication is not running (IO thread: %s, SQL thread: %s)", io, sqlThread)
+		}
+	}
+	return nil
+}
+
+// queryRows runs the first statement the server understands and returns every row as a
+// map keyed by lower-cased column name, as column sets vary between versions
+func queryRows(db *sql.DB, statements ...string) ([]map[string]string, error) {
+	var rows *sql.Rows
+	var err error
+	for _, stmt := range statements {
+		rows, err = db.Query(stmt)
+		// ER_PARSE_ERROR: the statement predates or postdates this server version
+		var mysqlErr *mysqldriver.MySQLError
+		if err == nil || !errors.As(err, &mysqlErr) || mysqlErr.Number != 1064 {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute %s: %w", statements[len(statements)-1], classify(err))
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+
+	var result []map[string]string
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		row := make(map[string]string, len(columns))
+		for i, column := range columns {
+			row[strings.ToLower(column)] = values[i].String
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return result, nil
+}
+
+// firstOf returns the value of the first of the given columns present in the row
+func firstOf(row map[string]string, columns ...string) string {
+	for _, column := range columns {
+		if v, ok := row[column]; ok {
+			return v
+		}
+	}
+	return ""
+}
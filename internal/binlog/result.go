@@ -0,0 +1,108 @@
+package binlog
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MatchQuality tells how the file found by Finder.Find relates to the target time
+type MatchQuality string
+
+const (
+	// MatchExact means the target time falls within the file's range
+	MatchExact MatchQuality = "exact"
+	// MatchGap means the target time falls in a gap with no events between the file and
+	// the next one, confirmed by reading the whole file
+	MatchGap MatchQuality = "gap"
+	// MatchClosest means no file was found to contain the target time, and the file is
+	// the closest one before it
+	MatchClosest MatchQuality = "closest"
+	// MatchBeforeOldest means the target time is before the first event of the oldest
+	// file, which is the file found
+	MatchBeforeOldest MatchQuality = "before-oldest"
+	// MatchAfterNewest means the target time is after the last event of the newest file,
+	// which is the file found
+	MatchAfterNewest MatchQuality = "after-newest"
+	// MatchNotFound means no file was found
+	MatchNotFound MatchQuality = "not-found"
+)
+
+// Result describes the file Finder.Find found for a target time and how it found it
+type Result struct {
+	// File is the binlog file found, empty with MatchNotFound
+	File string
+	// Position is where the target time is in File, located when Finder.Position is set
+	// and the match is exact
+	Position *Position
+	// GTID is that of the transaction at Position, if any
+	GTID string
+	// MatchQuality tells how File relates to the target time
+	MatchQuality MatchQuality
+	// FileRange is the time range of File as the search probed it, or took it from a
+	// cache. With MatchClosest and MatchAfterNewest, how far its end is before the target
+	// time tells how close the match is.
+	FileRange TimeRange
+	// Probes lists every file the search inspected, in order, as passed to Finder.OnProbe
+	Probes []Probe
+	// Warnings explains an inexact match and what else the search met that makes the
+	// answer less certain, such as files that could not be probed or truncated probes
+	Warnings []string
+}
+
+// Exact reports whether File contains the target time
+func (r Result) Exact() bool {
+	return r.MatchQuality == MatchExact
+}
+
+// result builds the Result of Find from what Search returned and what the run recorded
+func (f *searchRun) result(file string, exact bool, err error, targetTime time.Time) Result {
+	res := Result{File: file, Probes: f.probes}
+	for _, p := range f.probes {
+		switch {
+		case p.Decision == DecisionSkipped:
+			res.Warnings = append(res.Warnings, fmt.Sprintf("skipped %s: %v", p.File, p.Err))
+		case p.Err != nil:
+			res.Warnings = append(res.Warnings, fmt.Sprintf("could not probe %s: %v", p.File, p.Err))
+		case p.File == file:
+			res.FileRange = TimeRange{Start: p.Start, End: p.End, Truncated: p.Truncated}
+		}
+	}
+	for _, c := range f.corrupt {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("event at %s:%d failed its checksum", c.File, c.Pos))
+	}
+	if res.FileRange.Truncated != "" {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("the probe of %s stopped early (%s), so its end time is a lower bound", file, res.FileRange.Truncated))
+	}
+
+	var outside *RangeError
+	switch {
+	case file == "":
+		res.MatchQuality = MatchNotFound
+	case exact:
+		res.MatchQuality = MatchExact
+	case f.gap != nil:
+		res.MatchQuality = MatchGap
+		res.Warnings = append(res.Warnings, fmt.Sprintf("no events were written between %s, the end of %s, and %s, the start of %s",
+			f.gap.Start.Format("2006-01-02 15:04:05"), f.gap.Before, f.gap.End.Format("2006-01-02 15:04:05"), f.gap.After))
+	case errors.As(err, &outside) && errors.Is(outside, ErrTimestampBeforeRetention):
+		res.MatchQuality = MatchBeforeOldest
+	case errors.As(err, &outside):
+		res.MatchQuality = MatchAfterNewest
+	default:
+		res.MatchQuality = MatchClosest
+	}
+	if err != nil {
+		res.Warnings = append(res.Warnings, err.Error())
+	}
+
+	if f.Position && exact {
+		pos, err := f.Locate(file, targetTime, f.Align, 0)
+		if err != nil {
+			res.Warnings = append(res.Warnings, fmt.Sprintf("could not locate the position in %s: %v", file, err))
+		} else {
+			res.Position, res.GTID = &pos, pos.GTID
+		}
+	}
+	return res
+}
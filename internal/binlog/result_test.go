@@ -0,0 +1,118 @@
+package binlog
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFinderFindResult(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 5, FileSize: 8 << 10, Rate: 0.5, Seed: 1})
+	streamer := newFakeStreamer(t, files)
+	names := make([]string, len(files))
+	sizes := make(map[string]int64, len(files))
+	for i, f := range files {
+		names[i] = f.Name
+		sizes[f.Name] = f.Size()
+	}
+	// Half a second off the whole seconds in the headers, so no target spans a rotation
+	middle := files[2].Start.Add(files[2].End.Sub(files[2].Start) / 2).Truncate(time.Second).Add(500 * time.Millisecond)
+
+	t.Run("Exact", func(t *testing.T) {
+		finder := &Finder{Streamer: streamer, Sizes: sizes, Position: true}
+		res := finder.Find(names, middle)
+		assert.Equal(t, files[2].Name, res.File)
+		assert.Equal(t, MatchExact, res.MatchQuality)
+		assert.True(t, res.Exact())
+		assert.WithinRange(t, middle, res.FileRange.Start, res.FileRange.End)
+		assert.Empty(t, res.Warnings)
+		require.NotEmpty(t, res.Probes)
+		assert.Equal(t, DecisionMatch, res.Probes[len(res.Probes)-1].Decision)
+
+		require.NotNil(t, res.Position)
+		assert.Equal(t, files[2].Name, res.Position.File)
+		assert.False(t, res.Position.Timestamp.Before(middle.Truncate(time.Second)))
+		assert.NotEmpty(t, res.GTID)
+		assert.Equal(t, res.Position.GTID, res.GTID)
+	})
+
+	t.Run("Without position", func(t *testing.T) {
+		res := (&Finder{Streamer: streamer, Sizes: sizes}).Find(names, middle)
+		assert.Equal(t, MatchExact, res.MatchQuality)
+		assert.Nil(t, res.Position)
+		assert.Empty(t, res.GTID)
+	})
+
+	t.Run("Before the oldest file", func(t *testing.T) {
+		res := (&Finder{Streamer: streamer, Sizes: sizes, Position: true}).Find(names, files[0].Start.Add(-time.Hour))
+		assert.Equal(t, files[0].Name, res.File)
+		assert.Equal(t, MatchBeforeOldest, res.MatchQuality)
+		assert.Nil(t, res.Position, "only exact matches are located")
+		require.Len(t, res.Warnings, 1)
+		assert.Contains(t, res.Warnings[0], ErrTimestampBeforeRetention.Error())
+	})
+
+	t.Run("After the newest file", func(t *testing.T) {
+		res := (&Finder{Streamer: streamer, Sizes: sizes}).Find(names, files[4].End.Add(time.Hour))
+		assert.Equal(t, files[4].Name, res.File)
+		assert.Equal(t, MatchAfterNewest, res.MatchQuality)
+		assert.True(t, files[4].End.Equal(res.FileRange.End), "range ends at %s", res.FileRange.End)
+	})
+
+	t.Run("Truncated range", func(t *testing.T) {
+		known := map[string]TimeRange{files[2].Name: {Start: files[2].Start, End: files[2].End, Truncated: TruncatedEvents}}
+		res := (&Finder{Streamer: streamer, Sizes: sizes, Known: known}).Find(names, middle)
+		assert.Equal(t, MatchExact, res.MatchQuality)
+		assert.Equal(t, TruncatedEvents, res.FileRange.Truncated)
+		require.Len(t, res.Warnings, 1)
+		assert.Contains(t, res.Warnings[0], "lower bound")
+	})
+
+	t.Run("No files", func(t *testing.T) {
+		res := (&Finder{Streamer: streamer}).Find(nil, middle)
+		assert.Empty(t, res.File)
+		assert.Equal(t, MatchNotFound, res.MatchQuality)
+		assert.Equal(t, []string{ErrNoBinlogs.Error()}, res.Warnings)
+	})
+}
+
+func TestFinderFindGap(t *testing.T) {
+	// The server was down for an hour between the second file and the third
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := binlogtest.Generate(binlogtest.Options{Files: 2, FileSize: 4 << 10, Rate: 0.5, Seed: 1, Start: day})
+	after := binlogtest.Generate(binlogtest.Options{Files: 2, FileSize: 4 << 10, Rate: 0.5, Seed: 2, Start: before[1].End.Add(time.Hour)})
+	stored := make(memoryFiles)
+	var names []string
+	for i, f := range append(before, after...) {
+		name := fmt.Sprintf("binlog.%06d", i+1)
+		stored[name] = f.Data
+		names = append(names, name)
+	}
+
+	target := before[1].End.Add(30 * time.Minute)
+
+	// Without OnGap, and from the probed ranges alone
+	stats := &Stats{}
+	res := (&Finder{Streamer: FileStreamer{Reader: stored}, Stats: stats}).Find(names, target)
+	assert.Equal(t, names[1], res.File)
+	assert.Equal(t, MatchGap, res.MatchQuality)
+	require.NotEmpty(t, res.Warnings)
+	assert.Contains(t, res.Warnings[0], "no events were written")
+	assert.Equal(t, stats.Snapshot().FilesProbed, len(res.Probes), "a file was read besides the probes")
+
+	// Probes cut short by the scan limits end before the last event
+	settings := &Settings{ProbeTimeout: DefaultProbeTimeout, ScanLimits: ScanLimits{MaxEvents: 5}}
+	var gap *Gap
+	res = (&Finder{Streamer: FileStreamer{Reader: stored}, Settings: settings, OnGap: func(g Gap) { gap = &g }}).Find(names, target)
+	assert.Equal(t, MatchGap, res.MatchQuality, "the rest of the file confirms the gap")
+	require.NotNil(t, gap)
+	assert.True(t, before[1].End.Equal(gap.Start))
+
+	res = (&Finder{Streamer: FileStreamer{Reader: stored}, Settings: settings, SkipGapConfirm: true}).Find(names, target)
+	assert.Equal(t, names[1], res.File)
+	assert.Equal(t, MatchClosest, res.MatchQuality)
+}
@@ -0,0 +1,82 @@
+package binlog
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// RetryPolicy controls how connections to the server are retried after transient errors
+type RetryPolicy struct {
+	// Retries is the number of attempts made after the first one fails
+	Retries int
+	// Backoff is the delay before the first retry, doubled for every further retry
+	Backoff time.Duration
+}
+
+// withRetry calls fn until it succeeds, fails with a permanent error, or the retries of
+// policy are used up. Once ctx is canceled, fn is not called again.
+func withRetry(ctx context.Context, policy RetryPolicy, op string, fn func() error) error {
+	backoff := policy.Backoff
+	for attempt := 0; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := fn()
+		if err == nil || attempt >= policy.Retries || !isTransient(err) {
+			return classify(err)
+		}
+		slog.Warn("Transient error, retrying", "operation", op, "attempt", attempt+1, "backoff", backoff, "error", err)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// isTransient reports whether err is worth retrying: network failures, dropped
+// connections, and the server refusing connections while overloaded or shutting down
+func isTransient(err error) bool {
+	switch errorCode(err) {
+	case 0:
+	case mysql.ER_CON_COUNT_ERROR, mysql.ER_TOO_MANY_USER_CONNECTIONS, mysql.ER_SERVER_SHUTDOWN:
+		return true
+	default:
+		// Authentication, privilege and purged binlog errors won't go away by retrying
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysqldriver.ErrInvalidConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
+
+// startSyncAt starts streaming a binlog file from the event starting at pos, retrying
+// transient errors as policy allows
+func startSyncAt(ctx context.Context, policy RetryPolicy, syncer *replication.BinlogSyncer, binlogFile string, pos uint32) (*replication.BinlogStreamer, error) {
+	var streamer *replication.BinlogStreamer
+	err := withRetry(ctx, policy, "start replication from "+binlogFile, func() error {
+		var err error
+		streamer, err = startSyncer(syncer, mysql.Position{Name: binlogFile, Pos: pos})
+		return err
+	})
+	return streamer, err
+}
@@ -0,0 +1,69 @@
+package binlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"Too many connections", &mysqldriver.MySQLError{Number: mysql.ER_CON_COUNT_ERROR}, true},
+		{"Server shutting down", &mysql.MyError{Code: mysql.ER_SERVER_SHUTDOWN}, true},
+		{"Connection refused", fmt.Errorf("dial: %w", syscall.ECONNREFUSED), true},
+		{"Dropped connection", mysqldriver.ErrInvalidConn, true},
+		{"Unexpected EOF", io.ErrUnexpectedEOF, true},
+		{"Access denied", &mysqldriver.MySQLError{Number: mysql.ER_ACCESS_DENIED_ERROR}, false},
+		{"Purged binlog", &mysql.MyError{Code: mysql.ER_MASTER_FATAL_ERROR_READING_BINLOG}, false},
+		{"Other error", errors.New("invalid GTID"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isTransient(tt.err))
+		})
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	policy := RetryPolicy{Retries: 2}
+
+	var calls int
+	err := withRetry(context.Background(), policy, "test", func() error {
+		calls++
+		if calls < 3 {
+			return io.EOF
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = withRetry(context.Background(), policy, "test", func() error {
+		calls++
+		return io.EOF
+	})
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	denied := &mysqldriver.MySQLError{Number: mysql.ER_ACCESS_DENIED_ERROR}
+	err = withRetry(context.Background(), policy, "test", func() error {
+		calls++
+		return denied
+	})
+	assert.ErrorIs(t, err, denied)
+	assert.Equal(t, 1, calls)
+}
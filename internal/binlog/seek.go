@@ -0,0 +1,230 @@
+package binlog
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+const (
+	// seekWindow is the span of a file left to scan sequentially once bisection has
+	// narrowed down the target
+	seekWindow = 1 << 20
+	// seekRows is how many events are listed after each skip to find a boundary to snap to
+	seekRows = 100
+	// initialEventSize is the assumed average event size until the first skip measures it
+	initialEventSize = 256
+)
+
+// eventSeeker finds event boundaries within a single binlog file
+type eventSeeker interface {
+	// boundaryAfter returns the start of the first event to snap to at or after roughly
+	// offset, reading forward from the boundary at from. ok is false when there is none
+	// before the end of the file.
+	boundaryAfter(from, offset uint32) (pos uint32, ok bool, err error)
+	// timeAt returns the time of the event starting at pos
+	timeAt(pos uint32) (time.Time, error)
+}
+
+// seekStart bisects the byte range of a file of the given size, snapping each midpoint to
+// an event boundary, and returns the latest boundary found that is older than the target
+// by more than slack. Scanning from there reaches the target within about seekWindow bytes.
+func seekStart(s eventSeeker, size int64, targetTime time.Time, slack time.Duration) (uint32, error) {
+	lo, hi := uint32(4), uint32(min(size, int64(^uint32(0))))
+	for hi-lo > seekWindow {
+		mid := lo + (hi-lo)/2
+		pos, ok, err := s.boundaryAfter(lo, mid)
+		if err != nil {
+			return lo, err
+		}
+		// Nothing to snap to in the upper half, e.g. inside a huge transaction
+		if !ok || pos <= lo || pos >= hi {
+			hi = mid
+			continue
+		}
+
+		t, err := s.timeAt(pos)
+		if err != nil {
+			return lo, err
+		}
+		slog.Debug("Seek step", "pos", pos, "time", t.Format("2006-01-02 15:04:05.999999"), "lo", lo, "hi", hi)
+		if t.Before(targetTime.Add(-slack)) {
+			lo = pos
+		} else {
+			hi = pos
+		}
+	}
+	return lo, nil
+}
+
+// serverSeeker finds event boundaries with SHOW BINLOG EVENTS, which the server evaluates
+// without sending the skipped events, and reads event times over a replication stream.
+// A dump can only start at an event boundary, so byte offsets are converted to event
+// counts using the average event size seen so far.
+type serverSeeker struct {
+	ctx context.Context
+	// timeout bounds each read of the event at a boundary, as a probe is bounded
+	timeout   time.Duration
+	db        *sql.DB
+	streamer  EventStreamer
+	file      string
+	align     Alignment
+	source    TimestampSource
+	eventSize float64
+}
+
+func (s *serverSeeker) boundaryAfter(from, offset uint32) (uint32, bool, error) {
+	skip := int(float64(offset-from) / s.eventSize)
+	query := fmt.Sprintf("SHOW BINLOG EVENTS IN '%s' FROM %d LIMIT %d, %d",
+		strings.ReplaceAll(s.file, "'", "''"), from, skip, seekRows)
+	rows, err := queryRows(s.db, query)
+	if err != nil {
+		return 0, false, err
+	}
+	if len(rows) == 0 {
+		return 0, false, nil
+	}
+
+	first, _ := strconv.ParseUint(rows[0]["pos"], 10, 32)
+	if skip > 0 && uint32(first) > from {
+		s.eventSize = float64(uint32(first)-from) / float64(skip)
+	}
+
+	i := snapIndex(rows, s.align)
+	if i < 0 {
+		return 0, false, nil
+	}
+	pos, err := strconv.ParseUint(rows[i]["pos"], 10, 32)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid position %q: %w", rows[i]["pos"], err)
+	}
+	return uint32(pos), true, nil
+}
+
+// snapIndex returns the first of the SHOW BINLOG EVENTS rows a scan can start from:
+// any event, or for transaction alignment the first transaction start. Returns -1 if
+// there is none.
+func snapIndex(rows []map[string]string, align Alignment) int {
+	if align != AlignTransaction {
+		return 0
+	}
+	gtids := false
+	for _, row := range rows {
+		if isGTIDEventType(row["event_type"]) {
+			gtids = true
+			break
+		}
+	}
+	for i, row := range rows {
+		if gtids && isGTIDEventType(row["event_type"]) {
+			return i
+		}
+		// Without GTIDs, a transaction starts at its BEGIN statement
+		if !gtids && row["event_type"] == "Query" && strings.EqualFold(row["info"], "BEGIN") {
+			return i
+		}
+	}
+	return -1
+}
+
+// isGTIDEventType reports whether a SHOW BINLOG EVENTS event type starts a transaction
+// with a GTID: Gtid and Anonymous_Gtid on MySQL, Gtid on MariaDB
+func isGTIDEventType(eventType string) bool {
+	switch strings.ToLower(eventType) {
+	case "gtid", "anonymous_gtid", "gtid_tagged":
+		return true
+	}
+	return false
+}
+
+func (s *serverSeeker) timeAt(pos uint32) (time.Time, error) {
+	ctx, cancel := probeContext(s.ctx, s.timeout)
+	defer cancel()
+
+	stream, err := s.streamer.StreamFrom(s.file, pos)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to start sync from %s:%d: %w", s.file, pos, err)
+	}
+	defer stream.Close()
+
+	for {
+		ev, err := stream.GetEvent(ctx)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to get event at %s:%d: %w", s.file, pos, err)
+		}
+		traceEvent(s.file, ev)
+
+		// The rotate and format description events sent ahead of the requested position
+		// are not part of it
+		switch ev.Event.(type) {
+		case *replication.RotateEvent, *replication.FormatDescriptionEvent:
+			continue
+		}
+		if t, ok := eventTime(ev, s.source); ok {
+			return t, nil
+		}
+		return time.Unix(int64(ev.Header.Timestamp), 0), nil
+	}
+}
+
+// SeekPosition locates the same position as LocatePosition, but bisects the file by
+// restarting the dump at event boundaries near computed byte offsets, and only scans the
+// last stretch before the target sequentially. It needs the file size as listed by
+// SHOW BINARY LOGS; small files are scanned sequentially as by LocatePosition.
+func SeekPosition(cfg replication.BinlogSyncerConfig, binlogFile string, size int64, targetTime time.Time, align Alignment, source TimestampSource, slack time.Duration) (Position, error) {
+	server := Server{Config: cfg}
+	return seekPosition(context.Background(), server, server, DefaultSettings.ProbeTimeout, binlogFile, size, targetTime, align, source, slack)
+}
+
+// Seek is SeekPosition on the server in Config, with the file size taken from Sizes and
+// event times from the Finder's timestamp source. What it streams is added to Stats.
+func (f *Finder) Seek(binlogFile string, targetTime time.Time, align Alignment, slack time.Duration) (Position, error) {
+	onEvent, done := f.reader(binlogFile)
+	defer done(false)
+	server := f.server()
+	streamer := &countingStreamer{streamer: server, onEvent: onEvent}
+	return seekPosition(f.readContext(), server, streamer, f.probeTimeout(), binlogFile, f.Sizes[binlogFile], targetTime, align, f.Source, slack)
+}
+
+// seekPosition implements SeekPosition, querying server and streaming through streamer,
+// with each read at a boundary bounded by timeout
+func seekPosition(ctx context.Context, server Server, streamer EventStreamer, timeout time.Duration, binlogFile string, size int64, targetTime time.Time, align Alignment, source TimestampSource, slack time.Duration) (Position, error) {
+	if size <= seekWindow {
+		return locatePosition(ctx, streamer, binlogFile, 4, targetTime, align, source, slack)
+	}
+	db, err := server.openDB()
+	if err != nil {
+		return Position{}, err
+	}
+	defer closeDB(db)
+	s := &serverSeeker{ctx: ctx, timeout: timeout, db: db, streamer: streamer, file: binlogFile, align: align, source: source, eventSize: initialEventSize}
+	return seek(ctx, s, streamer, binlogFile, size, targetTime, align, source, slack)
+}
+
+// seek bisects a file with s, then scans from the boundary found to the target, unless
+// align is AlignNone, which takes the boundary as it is
+func seek(ctx context.Context, s eventSeeker, streamer EventStreamer, binlogFile string, size int64, targetTime time.Time, align Alignment, source TimestampSource, slack time.Duration) (Position, error) {
+	start, err := seekStart(s, size, targetTime, slack)
+	if err != nil {
+		// The sequential scan from the best boundary so far still finds the position
+		slog.Warn("Could not bisect binlog, scanning from the last known boundary", "file", binlogFile, "pos", start, "error", err)
+	} else if align == AlignNone {
+		pos := Position{File: binlogFile, Pos: start}
+		if start == 4 {
+			return pos, nil
+		}
+		if pos.Timestamp, err = s.timeAt(start); err == nil {
+			return pos, nil
+		}
+		slog.Warn("Could not read the event at the seek position, scanning from it", "file", binlogFile, "pos", start, "error", err)
+	}
+	slog.Info("Scanning binlog from seek position", "file", binlogFile, "pos", start, "size", size)
+
+	return locatePosition(ctx, streamer, binlogFile, start, targetTime, align, source, slack)
+}
@@ -0,0 +1,144 @@
+package binlog
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSeeker is a file of equally sized events written one second apart
+type fakeSeeker struct {
+	eventSize uint32
+	events    uint32
+	start     time.Time
+	reads     int
+}
+
+func (f *fakeSeeker) boundaryAfter(from, offset uint32) (uint32, bool, error) {
+	i := (offset - 4 + f.eventSize - 1) / f.eventSize
+	if i >= f.events {
+		return 0, false, nil
+	}
+	return 4 + i*f.eventSize, true, nil
+}
+
+func (f *fakeSeeker) timeAt(pos uint32) (time.Time, error) {
+	f.reads++
+	return f.start.Add(time.Duration((pos-4)/f.eventSize) * time.Second), nil
+}
+
+// fileSeeker seeks within a generated binlog file, reading times from its event headers
+type fileSeeker struct {
+	file  *binlogtest.File
+	reads int
+}
+
+func (f *fileSeeker) boundaryAfter(from, offset uint32) (uint32, bool, error) {
+	events := f.file.Events
+	i := sort.Search(len(events), func(i int) bool { return events[i].Pos >= offset })
+	if i == len(events) {
+		return 0, false, nil
+	}
+	return events[i].Pos, true, nil
+}
+
+func (f *fileSeeker) timeAt(pos uint32) (time.Time, error) {
+	f.reads++
+	return time.Unix(int64(binary.LittleEndian.Uint32(f.file.Data[pos:])), 0), nil
+}
+
+func TestSeekStart(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := &fakeSeeker{eventSize: 1000, events: 100000, start: start}
+	size := int64(4 + s.eventSize*s.events)
+
+	target := start.Add(70000 * time.Second)
+	pos, err := seekStart(s, size, target, 0)
+	require.NoError(t, err)
+
+	targetPos := 4 + 70000*s.eventSize
+	assert.Less(t, pos, targetPos, "seek must stop before the target")
+	assert.LessOrEqual(t, targetPos-pos, uint32(seekWindow), "seek must stop within the window")
+	assert.LessOrEqual(t, s.reads, 10, "seek must take logarithmically many reads")
+
+	t.Run("Slack", func(t *testing.T) {
+		pos, err := seekStart(s, size, target, time.Hour)
+		require.NoError(t, err)
+		assert.Less(t, pos, 4+(70000-3600)*s.eventSize)
+	})
+
+	t.Run("Before the file", func(t *testing.T) {
+		pos, err := seekStart(s, size, start.Add(-time.Hour), 0)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(4), pos)
+	})
+}
+
+func TestSeekAlignNone(t *testing.T) {
+	file := binlogtest.Generate(binlogtest.Options{FileSize: 8 << 20, Rate: 200, Seed: 1})[0]
+	streamer := FileStreamer{Reader: memoryFiles{file.Name: file.Data}}
+	target := file.Start.Add(file.End.Sub(file.Start) / 2)
+
+	located, err := seek(context.Background(), &fileSeeker{file: file}, streamer, file.Name, file.Size(), target, AlignEvent, TimestampHeader, 0)
+	require.NoError(t, err)
+	raw, err := seek(context.Background(), &fileSeeker{file: file}, streamer, file.Name, file.Size(), target, AlignNone, TimestampHeader, 0)
+	require.NoError(t, err)
+
+	// The bisected offset is an event boundary left as it is, short of the target
+	assert.Less(t, raw.Pos, located.Pos)
+	assert.LessOrEqual(t, located.Pos-raw.Pos, uint32(seekWindow))
+	assert.True(t, raw.Timestamp.Before(target))
+	assert.True(t, slices.ContainsFunc(file.Events, func(e binlogtest.Event) bool { return e.Pos == raw.Pos }))
+}
+
+func TestSnapIndex(t *testing.T) {
+	gtidRows := []map[string]string{
+		{"event_type": "Query", "info": "BEGIN"},
+		{"event_type": "Xid", "info": "COMMIT /* xid=12 */"},
+		{"event_type": "Gtid", "info": "SET @@SESSION.GTID_NEXT= 'uuid:5'"},
+	}
+	plainRows := []map[string]string{
+		{"event_type": "Write_rows", "info": "table_id: 90 flags: STMT_END_F"},
+		{"event_type": "Query", "info": "BEGIN"},
+	}
+
+	assert.Equal(t, 2, snapIndex(gtidRows, AlignTransaction))
+	assert.Equal(t, 1, snapIndex(plainRows, AlignTransaction))
+	assert.Equal(t, 0, snapIndex(plainRows, AlignEvent))
+	assert.Equal(t, -1, snapIndex(plainRows[:1], AlignTransaction))
+}
+
+// BenchmarkSeekStart measures how many event times bisecting a generated binlog reads
+func BenchmarkSeekStart(b *testing.B) {
+	quietLogs(b)
+	for _, dist := range []binlogtest.Distribution{binlogtest.Steady, binlogtest.Poisson, binlogtest.Diurnal, binlogtest.Bursty} {
+		b.Run(fmt.Sprint(dist), func(b *testing.B) {
+			file := binlogtest.Generate(binlogtest.Options{FileSize: 64 << 20, Rate: 200, Distribution: dist, Seed: 1})[0]
+			s := &fileSeeker{file: file}
+			rng := rand.New(rand.NewSource(1))
+			span := file.End.Sub(file.Start)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				target := file.Start.Add(time.Duration(rng.Int63n(int64(span))))
+				pos, err := seekStart(s, file.Size(), target, 0)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if t, _ := s.timeAt(pos); pos > 4 && !t.Before(target) {
+					b.Fatalf("seek to %s stopped at %d, which is not before the target", target, pos)
+				}
+			}
+			b.ReportMetric(float64(s.reads)/float64(b.N), "reads/op")
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package binlog
+
+import "time"
+
+// Settings tune how binlogs are read from a server: how long and how far each file is
+// probed, which events are passed on, and how connections are opened, retried and paced.
+// Every Finder and Server carries its own, so that one process can read several servers
+// with different settings.
+type Settings struct {
+	// ProbeTimeout bounds the probe of each file for its time range, with 0 for no limit
+	ProbeTimeout time.Duration
+	// ScanLimits caps how much of each file is read when probing its time range
+	ScanLimits ScanLimits
+	// Origin restricts the events read to those that originated on some servers
+	Origin OriginFilter
+	// Connection tunes the SQL connections opened to the server
+	Connection ConnectionOptions
+	// Retry is how connections to the server are retried after transient errors
+	Retry RetryPolicy
+	// Throttle limits how fast events are read from each stream, in bytes per second of
+	// event data, with 0 to read them as fast as they are sent
+	Throttle int64
+}
+
+// DefaultSettings probe the first 1000 events of each file for up to DefaultProbeTimeout,
+// pass every event on as fast as it is sent, and retry nothing
+var DefaultSettings = Settings{ProbeTimeout: DefaultProbeTimeout, ScanLimits: DefaultScanLimits}
+
+// settingsOrDefault returns *s, or DefaultSettings when s is nil
+func settingsOrDefault(s *Settings) Settings {
+	if s == nil {
+		return DefaultSettings
+	}
+	return *s
+}
+
+// streamer returns streamer with the origin filter and throttle applied to its streams
+func (s Settings) streamer(streamer EventStreamer) EventStreamer {
+	if s.Origin.filters() {
+		streamer = originStreamer{streamer, s.Origin}
+	}
+	if s.Throttle > 0 {
+		streamer = throttledStreamer{streamer, s.Throttle}
+	}
+	return streamer
+}
@@ -0,0 +1,31 @@
+package binlog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettingsStreamer(t *testing.T) {
+	streamer := &fakeStreamer{}
+	assert.Equal(t, EventStreamer(streamer), DefaultSettings.streamer(streamer), "nothing to apply")
+
+	settings := Settings{Origin: OriginFilter{ServerID: 1}, Throttle: 1000}
+	throttled, ok := settings.streamer(streamer).(throttledStreamer)
+	require.True(t, ok)
+	assert.EqualValues(t, 1000, throttled.rate)
+	filtered, ok := throttled.EventStreamer.(originStreamer)
+	require.True(t, ok)
+	assert.Equal(t, settings.Origin, filtered.filter)
+}
+
+func TestFinderSettings(t *testing.T) {
+	// Finders in one process keep their settings, and their cached ranges, apart
+	filtered := &Finder{Settings: &Settings{Origin: OriginFilter{ServerID: 2}}, Sizes: map[string]int64{"binlog.000001": 1024}}
+	unfiltered := &Finder{Sizes: filtered.Sizes}
+	filteredKey, _ := filtered.cacheKey("binlog.000001")
+	unfilteredKey, _ := unfiltered.cacheKey("binlog.000001")
+	assert.NotEqual(t, filteredKey, unfilteredKey)
+	assert.Equal(t, DefaultScanLimits, unfiltered.settings().ScanLimits)
+}
@@ -0,0 +1,53 @@
+package binlog
+
+import "sync"
+
+// Stats counts the load searches put on the server. It is safe for concurrent use; read
+// the totals with Snapshot.
+type Stats struct {
+	mu     sync.Mutex
+	totals StatsSnapshot
+}
+
+// StatsSnapshot holds the totals counted by Stats
+type StatsSnapshot struct {
+	// FilesProbed counts the probes that read a file for its time range
+	FilesProbed int `json:"files_probed"`
+	// FilesCached counts the time ranges taken from Finder.Known or Finder.Cache
+	FilesCached int `json:"files_cached"`
+	// Events and Bytes count everything read, including files read to their end to
+	// confirm a gap or a tie, and the reads of Locate, Seek and NextEvent across a rotation
+	Events int   `json:"events_read"`
+	Bytes  int64 `json:"bytes_read"`
+}
+
+// Snapshot returns the totals counted so far
+func (s *Stats) Snapshot() StatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.totals
+}
+
+// add records a read of a file, counted as a probe if probe is set
+func (s *Stats) add(probe bool, events int, bytes int64) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if probe {
+		s.totals.FilesProbed++
+	}
+	s.totals.Events += events
+	s.totals.Bytes += bytes
+}
+
+// addCached records a time range that did not need a probe
+func (s *Stats) addCached() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.totals.FilesCached++
+}
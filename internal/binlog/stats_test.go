@@ -0,0 +1,48 @@
+package binlog
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsConcurrent(t *testing.T) {
+	stats := &Stats{}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				stats.add(j%2 == 0, 10, 1000)
+				stats.addCached()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, StatsSnapshot{FilesProbed: 400, FilesCached: 800, Events: 8000, Bytes: 800000}, stats.Snapshot())
+}
+
+func TestStatsNil(t *testing.T) {
+	var stats *Stats
+	stats.add(true, 1, 1)
+	stats.addCached()
+}
+
+func TestFinderCountsCachedRanges(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	stats := &Stats{}
+	f := &Finder{
+		Config: replication.BinlogSyncerConfig{ServerID: 100, Host: "db", Port: 3306},
+		Known:  map[string]TimeRange{"binlog.000001": {Start: start, End: start.Add(time.Hour)}},
+		Stats:  stats,
+	}
+
+	res := f.Find([]string{"binlog.000001"}, start.Add(30*time.Minute))
+	assert.Equal(t, "binlog.000001", res.File)
+	assert.Equal(t, StatsSnapshot{FilesCached: 1}, stats.Snapshot())
+}
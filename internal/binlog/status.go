@@ -0,0 +1,137 @@
+package binlog
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// Status is the server's current binlog coordinate, as reported by SHOW BINARY LOG STATUS
+type Status struct {
+	// File is the binlog currently being written
+	File     string `json:"file"`
+	Position uint32 `json:"position"`
+	// ExecutedGTIDSet is empty when GTIDs are not in use or not reported (MariaDB)
+	ExecutedGTIDSet string `json:"executed_gtid_set,omitempty"`
+}
+
+// String formats the status as file:pos
+func (s Status) String() string {
+	return fmt.Sprintf("%s:%d", s.File, s.Position)
+}
+
+// GetBinlogStatus returns the file and position the server is currently writing
+func GetBinlogStatus(cfg replication.BinlogSyncerConfig) (*Status, error) {
+	return Server{Config: cfg}.BinlogStatus()
+}
+
+// BinlogStatus is GetBinlogStatus on the server in Config
+func (s Server) BinlogStatus() (*Status, error) {
+	db, err := s.openDB()
+	if err != nil {
+		return nil, err
+	}
+	defer closeDB(db)
+
+	var version string
+	if err := db.QueryRow("SELECT VERSION()").Scan(&version); err != nil {
+		return nil, fmt.Errorf("failed to get server version: %w", err)
+	}
+
+	stmt := statusStatement(version)
+	rows, err := db.Query(stmt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute %s: %w", stmt, classify(err))
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error iterating rows: %w", err)
+		}
+		// No row means binary logging is disabled
+		return nil, binlogDisabledError(db)
+	}
+
+	// Columns vary between versions and flavors, so scan everything and pick by name
+	values := make([]sql.NullString, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	var status Status
+	for i, column := range columns {
+		switch strings.ToLower(column) {
+		case "file":
+			status.File = values[i].String
+		case "position":
+			pos, err := strconv.ParseUint(values[i].String, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid position %q: %w", values[i].String, err)
+			}
+			status.Position = uint32(pos)
+		case "executed_gtid_set":
+			status.ExecutedGTIDSet = strings.ReplaceAll(values[i].String, "\n", "")
+		}
+	}
+	return &status, nil
+}
+
+// statusStatement returns the statement reporting the current binlog coordinate for a
+// server version: MySQL 8.2 added SHOW BINARY LOG STATUS and 8.4 removed SHOW MASTER STATUS
+func statusStatement(version string) string {
+	if strings.Contains(strings.ToLower(version), "mariadb") {
+		return "SHOW MASTER STATUS"
+	}
+
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return "SHOW MASTER STATUS"
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return "SHOW MASTER STATUS"
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "SHOW MASTER STATUS"
+	}
+
+	if major > 8 || (major == 8 && minor >= 2) {
+		return "SHOW BINARY LOG STATUS"
+	}
+	return "SHOW MASTER STATUS"
+}
+
+// GetServerUUID returns the server's @@server_uuid, which changes when a server is rebuilt
+// even if it keeps its host name and binlog names. MariaDB has no server UUID.
+func GetServerUUID(cfg replication.BinlogSyncerConfig) (string, error) {
+	return Server{Config: cfg}.ServerUUID()
+}
+
+// ServerUUID is GetServerUUID on the server in Config
+func (s Server) ServerUUID() (string, error) {
+	db, err := s.openDB()
+	if err != nil {
+		return "", err
+	}
+	defer closeDB(db)
+
+	var uuid string
+	if err := db.QueryRow("SELECT @@GLOBAL.server_uuid").Scan(&uuid); err != nil {
+		return "", fmt.Errorf("failed to get server UUID: %w", err)
+	}
+	return uuid, nil
+}
@@ -0,0 +1,28 @@
+package binlog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusStatement(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected string
+	}{
+		{"5.7.44-log", "SHOW MASTER STATUS"},
+		{"8.0.36", "SHOW MASTER STATUS"},
+		{"8.2.0", "SHOW BINARY LOG STATUS"},
+		{"8.4.3-commercial", "SHOW BINARY LOG STATUS"},
+		{"9.1.0", "SHOW BINARY LOG STATUS"},
+		{"10.11.6-MariaDB-log", "SHOW MASTER STATUS"},
+		{"unknown", "SHOW MASTER STATUS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			assert.Equal(t, tt.expected, statusStatement(tt.version))
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package binlog
+
+import (
+	"context"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// BinlogLister lists the binlog files available to search, oldest first
+type BinlogLister interface {
+	ListBinlogs() ([]FileInfo, error)
+}
+
+// EventStream reads the events of a binlog in order. Like a replication stream, it may
+// start with a rotate event without a timestamp, and it carries on into the next file
+// after a rotate event unless closed. A stream over a stored file may instead end with
+// io.EOF.
+type EventStream interface {
+	// GetEvent returns the next event, waiting for one until ctx is done
+	GetEvent(ctx context.Context) (*replication.BinlogEvent, error)
+	Close()
+}
+
+// EventStreamer opens event streams. The search code reads binlogs only through this
+// interface, so it can run against something other than a live server.
+type EventStreamer interface {
+	// StreamFrom starts reading binlogFile at the event starting at pos, where 4 is the
+	// start of the file
+	StreamFrom(binlogFile string, pos uint32) (EventStream, error)
+}
+
+// Server lists and streams the binlogs of a MySQL server, opening a replication
+// connection for every stream
+type Server struct {
+	Config replication.BinlogSyncerConfig
+	// Settings, if set, are used instead of DefaultSettings to connect to the server and
+	// read its events
+	Settings *Settings
+	// Context, if set, is the context the connections and reads run under, so that they
+	// stop once it is canceled
+	Context context.Context
+}
+
+// settings returns the configured settings, or DefaultSettings
+func (s Server) settings() Settings {
+	return settingsOrDefault(s.Settings)
+}
+
+// context returns the configured context, or one that is never canceled
+func (s Server) context() context.Context {
+	if s.Context != nil {
+		return s.Context
+	}
+	return context.Background()
+}
+
+// StreamFrom starts a binlog dump, retrying transient errors. Event checksums are
+// verified, so corrupt events end the stream with replication.ErrChecksumMismatch.
+// Heartbeats are requested every DefaultHeartbeatPeriod unless the config sets a period.
+func (s Server) StreamFrom(binlogFile string, pos uint32) (EventStream, error) {
+	s.Config.VerifyChecksum = true
+	if s.Config.HeartbeatPeriod == 0 {
+		s.Config.HeartbeatPeriod = DefaultHeartbeatPeriod
+	}
+	settings := s.settings()
+	// Create new syncer for each stream to avoid "Sync is running" errors
+	streamer := syncerStreamer{syncer: s.NewSyncer(), ctx: s.context(), retry: settings.Retry}
+	return settings.streamer(streamer).StreamFrom(binlogFile, pos)
+}
+
+// syncerStreamer streams from a syncer created by the caller, for the functions that
+// take one. Closing the stream closes the syncer, so it opens a single stream.
+type syncerStreamer struct {
+	syncer *replication.BinlogSyncer
+	// ctx and retry are the context and policy the stream is started with
+	ctx   context.Context
+	retry RetryPolicy
+}
+
+// newSyncerStreamer streams from syncer with DefaultSettings
+func newSyncerStreamer(syncer *replication.BinlogSyncer) syncerStreamer {
+	return syncerStreamer{syncer: syncer, ctx: context.Background(), retry: DefaultSettings.Retry}
+}
+
+func (s syncerStreamer) StreamFrom(binlogFile string, pos uint32) (EventStream, error) {
+	streamer, err := startSyncAt(s.ctx, s.retry, s.syncer, binlogFile, pos)
+	if err != nil {
+		s.syncer.Close()
+		return nil, err
+	}
+	return syncerStream{streamer, s.syncer}, nil
+}
+
+// syncerStream is a replication stream that closes its syncer
+type syncerStream struct {
+	*replication.BinlogStreamer
+	syncer *replication.BinlogSyncer
+}
+
+func (s syncerStream) Close() {
+	s.syncer.Close()
+}
+
+// countingStreamer passes the totals of the events and bytes read through the streams it
+// opens to onEvent, so that reads of several streams count as one
+type countingStreamer struct {
+	streamer EventStreamer
+	onEvent  func(events int, bytes int64)
+	events   int
+	bytes    int64
+}
+
+func (c *countingStreamer) StreamFrom(binlogFile string, pos uint32) (EventStream, error) {
+	stream, err := c.streamer.StreamFrom(binlogFile, pos)
+	if err != nil {
+		return nil, err
+	}
+	return countingStream{stream, c}, nil
+}
+
+// countingStream counts the events read from a stream, leaving out heartbeats
+type countingStream struct {
+	EventStream
+	counts *countingStreamer
+}
+
+func (s countingStream) GetEvent(ctx context.Context) (*replication.BinlogEvent, error) {
+	ev, err := s.EventStream.GetEvent(ctx)
+	if err == nil && !isHeartbeat(ev) {
+		c := s.counts
+		c.events, c.bytes = c.events+1, c.bytes+int64(ev.Header.EventSize)
+		c.onEvent(c.events, c.bytes)
+	}
+	return ev, err
+}
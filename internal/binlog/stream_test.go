@@ -0,0 +1,211 @@
+package binlog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStream replays a list of events, then waits for new ones like the active file
+type fakeStream struct {
+	events []*replication.BinlogEvent
+}
+
+func (s *fakeStream) GetEvent(ctx context.Context) (*replication.BinlogEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(s.events) == 0 {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	ev := s.events[0]
+	s.events = s.events[1:]
+	return ev, nil
+}
+
+func (s *fakeStream) Close() {}
+
+// fakeStreamer lists and streams generated binlogs, each stream preceded by the rotate
+// event without a timestamp a server sends at the start of a dump
+type fakeStreamer struct {
+	names []string
+	files map[string][]*replication.BinlogEvent
+}
+
+func newFakeStreamer(t testing.TB, files []*binlogtest.File) *fakeStreamer {
+	s := &fakeStreamer{files: make(map[string][]*replication.BinlogEvent, len(files))}
+	for _, f := range files {
+		s.names = append(s.names, f.Name)
+		p := replication.NewBinlogParser()
+		err := p.ParseReader(bytes.NewReader(f.Data[len(replication.BinLogFileHeader):]), func(ev *replication.BinlogEvent) error {
+			s.files[f.Name] = append(s.files[f.Name], ev)
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	return s
+}
+
+// purge removes a file, as PURGE BINARY LOGS would
+func (s *fakeStreamer) purge(binlogFile string) {
+	delete(s.files, binlogFile)
+	s.names = slices.DeleteFunc(s.names, func(name string) bool { return name == binlogFile })
+}
+
+func (s *fakeStreamer) ListBinlogs() ([]FileInfo, error) {
+	files := make([]FileInfo, len(s.names))
+	for i, name := range s.names {
+		files[i] = FileInfo{Name: name}
+	}
+	return files, nil
+}
+
+func (s *fakeStreamer) StreamFrom(binlogFile string, pos uint32) (EventStream, error) {
+	events, ok := s.files[binlogFile]
+	if !ok {
+		return nil, &mysql.MyError{Code: mysql.ER_MASTER_FATAL_ERROR_READING_BINLOG, Message: "Could not find first log file name in binary log index file"}
+	}
+	rotate := &replication.BinlogEvent{
+		Header: &replication.EventHeader{EventType: replication.ROTATE_EVENT},
+		Event:  &replication.RotateEvent{Position: uint64(pos), NextLogName: []byte(binlogFile)},
+	}
+	stream := &fakeStream{events: []*replication.BinlogEvent{rotate}}
+	for _, ev := range events {
+		if ev.Header.LogPos-ev.Header.EventSize >= pos || ev.Header.EventType == replication.FORMAT_DESCRIPTION_EVENT {
+			stream.events = append(stream.events, ev)
+		}
+	}
+	return stream, nil
+}
+
+func TestFinderWithStreamer(t *testing.T) {
+	for _, dist := range []binlogtest.Distribution{binlogtest.Steady, binlogtest.Poisson, binlogtest.Bursty} {
+		t.Run(dist.String(), func(t *testing.T) {
+			files := binlogtest.Generate(binlogtest.Options{Files: 20, FileSize: 8 << 10, Rate: 0.5, Distribution: dist, Seed: 1})
+			streamer := newFakeStreamer(t, files)
+			names := make([]string, len(files))
+			sizes := make(map[string]int64, len(files))
+			for i, f := range files {
+				names[i] = f.Name
+				sizes[f.Name] = f.Size()
+			}
+
+			for _, f := range files {
+				// Half a second off the whole seconds in the headers, so no target spans a rotation
+				target := f.Start.Add(f.End.Sub(f.Start) / 2).Truncate(time.Second).Add(500 * time.Millisecond)
+				finder := &Finder{Streamer: streamer, Sizes: sizes}
+				res := finder.Find(names, target)
+				assert.Equal(t, f.Name, res.File, "target %s", target)
+				assert.True(t, res.Exact(), "target %s", target)
+			}
+		})
+	}
+}
+
+func TestFinderRefreshesPurgedFiles(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 5, FileSize: 4 << 10, Rate: 0.5, Seed: 1})
+	streamer := newFakeStreamer(t, files)
+	names := slices.Clone(streamer.names)
+	// The file probed first disappears after the caller listed the files
+	streamer.purge(files[2].Name)
+
+	finder := &Finder{Streamer: streamer, Lister: streamer}
+	target := files[3].Start.Add(files[3].End.Sub(files[3].Start) / 2).Truncate(time.Second).Add(500 * time.Millisecond)
+	res := finder.Find(names, target)
+	assert.Equal(t, files[3].Name, res.File)
+	assert.True(t, res.Exact())
+	assert.Len(t, names, 5, "the caller's list must not be modified")
+	// Only the search of the refreshed list is described
+	for _, p := range res.Probes {
+		assert.NotEqual(t, files[2].Name, p.File)
+	}
+	assert.Empty(t, res.Warnings)
+}
+
+func TestLocatePositionWithStreamer(t *testing.T) {
+	file := binlogtest.Generate(binlogtest.Options{FileSize: 64 << 10, Rate: 10, Seed: 1})[0]
+	streamer := newFakeStreamer(t, []*binlogtest.File{file})
+
+	target := file.Start.Add(10 * time.Second)
+	pos, err := locatePosition(context.Background(), streamer, file.Name, 4, target, AlignTransaction, TimestampHeader, 0)
+	require.NoError(t, err)
+
+	// The located transaction is the first one written at or after the target
+	var want binlogtest.Event
+	for _, ev := range file.Events {
+		if ev.Type == replication.GTID_EVENT && !ev.Time.Truncate(time.Second).Before(target) {
+			want = ev
+			break
+		}
+	}
+	assert.Equal(t, want.Pos, pos.Pos)
+	assert.Equal(t, fmt.Sprintf("3e11fa47-71ca-11e1-9e33-c80aa9429562:%d", 1+indexOfGTID(file, want.Pos)), pos.GTID)
+}
+
+// indexOfGTID returns how many transactions precede the one starting at pos
+func indexOfGTID(file *binlogtest.File, pos uint32) int {
+	n := 0
+	for _, ev := range file.Events {
+		if ev.Pos == pos {
+			return n
+		}
+		if ev.Type == replication.GTID_EVENT {
+			n++
+		}
+	}
+	return -1
+}
+
+func TestSearchOutsideFiles(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{Files: 5, FileSize: 4 << 10, Rate: 0.5, Seed: 1})
+	streamer := newFakeStreamer(t, files)
+	sizes := make(map[string]int64, len(files))
+	for _, f := range files {
+		sizes[f.Name] = f.Size()
+	}
+	names := slices.Clone(streamer.names)
+
+	tests := []struct {
+		name   string
+		files  []string
+		target time.Time
+		file   string
+		err    error
+		// bound is the oldest or newest event time reported for a target outside the files
+		bound time.Time
+	}{
+		{"Before the oldest file", names, files[0].Start.Add(-time.Hour), files[0].Name, ErrTimestampBeforeRetention, files[0].Start},
+		{"After the newest event", names, files[4].End.Add(time.Hour), files[4].Name, ErrTimestampInFuture, files[4].End},
+		{"Single file", names[:1], files[0].Start.Add(-time.Hour), files[0].Name, ErrTimestampBeforeRetention, files[0].Start},
+		{"Within the files", names, files[2].Start.Add(files[2].End.Sub(files[2].Start) / 2), files[2].Name, nil, time.Time{}},
+		{"No files", nil, files[0].Start, "", ErrNoBinlogs, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := &Finder{Streamer: streamer, Sizes: sizes}
+			file, _, err := finder.Search(tt.files, tt.target)
+			assert.Equal(t, tt.file, file)
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.err)
+			}
+			var outside *RangeError
+			if !tt.bound.IsZero() && assert.ErrorAs(t, err, &outside) {
+				assert.Equal(t, tt.file, outside.File)
+				// The format description event heading a file may predate its first transaction
+				assert.WithinDuration(t, tt.bound, outside.Bound, 5*time.Second)
+			}
+		})
+	}
+}
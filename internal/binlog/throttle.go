@@ -0,0 +1,173 @@
+package binlog
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// throttleBurst is how much unused rate a stream may save up, so that reading resumes
+// at the limit rather than in a burst after waiting for new events
+const throttleBurst = time.Second
+
+// throttle paces the events read from a single replication stream
+type throttle struct {
+	rate int64
+	// next is when the stream is next allowed to read
+	next time.Time
+}
+
+// throttledStreamer paces the streams it opens to rate bytes per second of event data
+type throttledStreamer struct {
+	EventStreamer
+	rate int64
+}
+
+func (s throttledStreamer) StreamFrom(binlogFile string, pos uint32) (EventStream, error) {
+	stream, err := s.EventStreamer.StreamFrom(binlogFile, pos)
+	if err != nil {
+		return nil, err
+	}
+	return &throttledStream{EventStream: stream, pace: &throttle{rate: s.rate}}, nil
+}
+
+// throttledStream waits after each event until reading on keeps it within its rate
+type throttledStream struct {
+	EventStream
+	pace *throttle
+}
+
+func (s *throttledStream) GetEvent(ctx context.Context) (*replication.BinlogEvent, error) {
+	ev, err := s.EventStream.GetEvent(ctx)
+	if err == nil {
+		s.pace.wait(ctx, ev.Header.EventSize)
+	}
+	return ev, err
+}
+
+// wait accounts for an event of the given size and sleeps until reading on keeps the
+// stream within its rate, or until ctx is done. The sleep is added to the deadline of a
+// context made by withReadTimeout.
+func (t *throttle) wait(ctx context.Context, size uint32) {
+	d := t.delay(time.Now(), size)
+	if d <= 0 {
+		return
+	}
+	if read, ok := ctx.Value(readTimeoutKey{}).(*readTimeout); ok {
+		read.extend(d)
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// delay accounts for an event of the given size read at now and returns how long to
+// wait before reading the next one
+func (t *throttle) delay(now time.Time, size uint32) time.Duration {
+	if t.rate <= 0 {
+		return 0
+	}
+	if earliest := now.Add(-throttleBurst); t.next.Before(earliest) {
+		t.next = earliest
+	}
+	t.next = t.next.Add(time.Duration(int64(size) * int64(time.Second) / t.rate))
+	return t.next.Sub(now)
+}
+
+// readTimeoutKey finds the readTimeout of a context made by withReadTimeout
+type readTimeoutKey struct{}
+
+// readTimeout is a context whose deadline is pushed back by the time the throttle sleeps,
+// so that a low rate makes a long read slower instead of making it time out
+type readTimeout struct {
+	context.Context
+	done  chan struct{}
+	timer *time.Timer
+
+	mu       sync.Mutex
+	deadline time.Time
+	err      error
+}
+
+// withReadTimeout is context.WithTimeout for reads paced by a throttle: the time spent
+// waiting for the throttle does not count towards timeout
+func withReadTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	c := &readTimeout{Context: parent, done: make(chan struct{}), deadline: time.Now().Add(timeout)}
+	c.mu.Lock()
+	c.timer = time.AfterFunc(timeout, c.expire)
+	c.mu.Unlock()
+	// AfterFunc runs in a goroutine of its own, too late for a read that is quickly done
+	if err := parent.Err(); err != nil {
+		c.cancel(err)
+	}
+	stop := context.AfterFunc(parent, func() { c.cancel(parent.Err()) })
+	return c, func() {
+		stop()
+		c.cancel(context.Canceled)
+	}
+}
+
+// expire ends the read once the deadline passes, unless extend pushed it back meanwhile
+func (c *readTimeout) expire() {
+	c.mu.Lock()
+	deadline := c.deadline
+	c.mu.Unlock()
+	if time.Now().Before(deadline) {
+		return
+	}
+	c.cancel(context.DeadlineExceeded)
+}
+
+// extend pushes the deadline back by d
+func (c *readTimeout) extend(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.deadline = c.deadline.Add(d)
+	c.timer.Reset(time.Until(c.deadline))
+}
+
+func (c *readTimeout) cancel(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	c.timer.Stop()
+	close(c.done)
+}
+
+func (c *readTimeout) Deadline() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.deadline, true
+}
+
+func (c *readTimeout) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *readTimeout) Err() error {
+	// The parent may be done before AfterFunc has passed it on
+	if err := c.Context.Err(); err != nil {
+		c.cancel(err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *readTimeout) Value(key any) any {
+	if key == (readTimeoutKey{}) {
+		return c
+	}
+	return c.Context.Value(key)
+}
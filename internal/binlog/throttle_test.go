@@ -0,0 +1,83 @@
+package binlog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottleDelay(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Unlimited", func(t *testing.T) {
+		th := &throttle{}
+		assert.Zero(t, th.delay(now, 1<<30))
+	})
+
+	t.Run("Burst then paced", func(t *testing.T) {
+		th := &throttle{rate: 1000}
+		// A second of saved-up rate lets the first 1000 bytes through at once
+		assert.Equal(t, -500*time.Millisecond, th.delay(now, 500))
+		assert.Equal(t, time.Duration(0), th.delay(now, 500))
+		assert.Equal(t, 250*time.Millisecond, th.delay(now, 250))
+		assert.Equal(t, 500*time.Millisecond, th.delay(now, 250))
+	})
+
+	t.Run("Idle time does not accumulate", func(t *testing.T) {
+		th := &throttle{rate: 1000}
+		th.delay(now, 1000)
+		later := now.Add(time.Hour)
+		assert.Equal(t, time.Duration(0), th.delay(later, 1000))
+		assert.Equal(t, time.Second, th.delay(later, 1000))
+	})
+}
+
+func TestWithReadTimeout(t *testing.T) {
+	t.Run("Expires", func(t *testing.T) {
+		ctx, cancel := withReadTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		<-ctx.Done()
+		assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+	})
+
+	t.Run("Throttle sleeps push the deadline back", func(t *testing.T) {
+		ctx, cancel := withReadTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		th := &throttle{rate: 1000}
+		th.delay(time.Now(), 1000)
+		// Sleeps 100ms, past the deadline it started with
+		th.wait(ctx, 100)
+		assert.NoError(t, ctx.Err())
+		<-ctx.Done()
+		assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+	})
+
+	t.Run("Parent canceled", func(t *testing.T) {
+		parent, cancelParent := context.WithCancel(context.Background())
+		ctx, cancel := withReadTimeout(parent, time.Minute)
+		defer cancel()
+		cancelParent()
+		<-ctx.Done()
+		assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	})
+}
+
+// A rate that makes reading a file take longer than the probe timeout slows the probe
+// down instead of cutting it short, as for the fixed deadlines of other reads
+func TestThrottledProbeOutlastsTimeout(t *testing.T) {
+	files := binlogtest.Generate(binlogtest.Options{FileSize: 4 << 10, Rate: 0.5, Seed: 1})
+	streamer := newFakeStreamer(t, files)
+	// The first second of the rate is let through at once, so reading takes about a second
+	throttled := throttledStreamer{streamer, files[0].Size() / 2}
+
+	start := time.Now()
+	r, err := getTimeRange(context.Background(), throttled, files[0].Name, files[0].Size(), 200*time.Millisecond, DefaultScanLimits, TimestampHeader, nil)
+	require.NoError(t, err)
+	assert.Greater(t, time.Since(start), 500*time.Millisecond, "the read was not throttled")
+	assert.Empty(t, r.truncated)
+	assert.True(t, files[0].End.Equal(r.end), "range ends at %s", r.end)
+}
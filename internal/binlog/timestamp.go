@@ -0,0 +1,95 @@
+package binlog
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// TimestampSource selects which timestamp of an event is compared against the target time
+type TimestampSource int
+
+const (
+	// TimestampHeader uses the event header timestamp, which has one second precision
+	TimestampHeader TimestampSource = iota
+	// TimestampOriginalCommit uses the original_commit_timestamp carried by GTID events on
+	// MySQL 8.0.1+: the time, in microseconds, the transaction committed on its original source
+	TimestampOriginalCommit
+	// TimestampImmediateCommit uses the immediate_commit_timestamp carried by GTID events on
+	// MySQL 8.0.1+: the time the transaction committed on this server. On a replica it
+	// trails the original commit by the replication lag.
+	TimestampImmediateCommit
+)
+
+// ParseTimestampSource parses a timestamp source name as used on the command line
+func ParseTimestampSource(s string) (TimestampSource, error) {
+	switch s {
+	case "header":
+		return TimestampHeader, nil
+	case "original-commit":
+		return TimestampOriginalCommit, nil
+	case "immediate-commit":
+		return TimestampImmediateCommit, nil
+	default:
+		return 0, fmt.Errorf("unknown timestamp source %q (expected header, immediate-commit or original-commit)", s)
+	}
+}
+
+// String returns the command line name of the timestamp source
+func (s TimestampSource) String() string {
+	switch s {
+	case TimestampHeader:
+		return "header"
+	case TimestampOriginalCommit:
+		return "original-commit"
+	case TimestampImmediateCommit:
+		return "immediate-commit"
+	default:
+		return fmt.Sprintf("TimestampSource(%d)", int(s))
+	}
+}
+
+// Resolution returns the precision of the timestamps: a second for event headers and a
+// microsecond for commit timestamps
+func (s TimestampSource) Resolution() time.Duration {
+	if s == TimestampHeader {
+		return time.Second
+	}
+	return time.Microsecond
+}
+
+// Next returns the earliest timestamp after t the source can record, so that the events
+// at or before t are those before it
+func (s TimestampSource) Next(t time.Time) time.Time {
+	return t.Truncate(s.Resolution()).Add(s.Resolution())
+}
+
+// eventTime returns the time of an event according to source, and whether the event has one.
+// Only GTID events carry commit timestamps, and only when written by MySQL 8.0.1 or later.
+func eventTime(ev *replication.BinlogEvent, source TimestampSource) (time.Time, bool) {
+	if source == TimestampHeader {
+		if ev.Header.Timestamp == 0 {
+			return time.Time{}, false
+		}
+		return time.Unix(int64(ev.Header.Timestamp), 0), true
+	}
+
+	var gtid *replication.GTIDEvent
+	switch e := ev.Event.(type) {
+	case *replication.GTIDEvent:
+		gtid = e
+	case *replication.GtidTaggedLogEvent:
+		gtid = &e.GTIDEvent
+	default:
+		return time.Time{}, false
+	}
+
+	switch {
+	case source == TimestampOriginalCommit && gtid.OriginalCommitTimestamp > 0:
+		return gtid.OriginalCommitTime(), true
+	case source == TimestampImmediateCommit && gtid.ImmediateCommitTimestamp > 0:
+		return gtid.ImmediateCommitTime(), true
+	}
+	return time.Time{}, false
+}
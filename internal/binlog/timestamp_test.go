@@ -0,0 +1,62 @@
+package binlog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimestampSource(t *testing.T) {
+	for _, source := range []TimestampSource{TimestampHeader, TimestampOriginalCommit, TimestampImmediateCommit} {
+		parsed, err := ParseTimestampSource(source.String())
+		assert.NoError(t, err)
+		assert.Equal(t, source, parsed)
+	}
+
+	_, err := ParseTimestampSource("wallclock")
+	assert.Error(t, err)
+}
+
+func TestEventTime(t *testing.T) {
+	header := replication.EventHeader{Timestamp: 1680352245}
+	original := time.Date(2023, 4, 1, 12, 30, 44, 123456000, time.UTC)
+	immediate := time.Date(2023, 4, 1, 12, 30, 45, 654321000, time.UTC)
+	gtid := &replication.BinlogEvent{Header: &header, Event: &replication.GTIDEvent{
+		OriginalCommitTimestamp:  uint64(original.UnixMicro()),
+		ImmediateCommitTimestamp: uint64(immediate.UnixMicro()),
+	}}
+	query := &replication.BinlogEvent{Header: &header, Event: &replication.QueryEvent{Query: []byte("BEGIN")}}
+	preCommitTimestamps := &replication.BinlogEvent{Header: &header, Event: &replication.GTIDEvent{}}
+
+	tests := []struct {
+		name     string
+		event    *replication.BinlogEvent
+		source   TimestampSource
+		expected time.Time
+		ok       bool
+	}{
+		{"Header", gtid, TimestampHeader, time.Unix(1680352245, 0), true},
+		{"Original commit", gtid, TimestampOriginalCommit, original, true},
+		{"Immediate commit", gtid, TimestampImmediateCommit, immediate, true},
+		{"Commit time of non-GTID event", query, TimestampOriginalCommit, time.Time{}, false},
+		{"GTID event without commit timestamps", preCommitTimestamps, TimestampImmediateCommit, time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := eventTime(tt.event, tt.source)
+			assert.Equal(t, tt.ok, ok)
+			assert.True(t, tt.expected.Equal(got), "expected %s, got %s", tt.expected, got)
+		})
+	}
+}
+
+func TestTimestampSourceNext(t *testing.T) {
+	target := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, target.Add(time.Second), TimestampHeader.Next(target))
+	assert.Equal(t, target.Add(time.Second), TimestampHeader.Next(target.Add(500*time.Millisecond)))
+	assert.Equal(t, target.Add(time.Microsecond), TimestampOriginalCommit.Next(target))
+	assert.Equal(t, target.Add(2*time.Microsecond), TimestampImmediateCommit.Next(target.Add(1500*time.Nanosecond)))
+}
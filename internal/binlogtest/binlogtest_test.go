@@ -0,0 +1,118 @@
+package binlogtest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateParses(t *testing.T) {
+	for _, dist := range []Distribution{Steady, Poisson, Diurnal, Bursty} {
+		t.Run(dist.String(), func(t *testing.T) {
+			files := Generate(Options{Files: 3, FileSize: 64 << 10, Distribution: dist, Seed: 1})
+			require.Len(t, files, 3)
+
+			for i, f := range files {
+				p := replication.NewBinlogParser()
+				p.SetVerifyChecksum(true)
+				var events []*replication.BinlogEvent
+				err := p.ParseReader(bytes.NewReader(f.Data[len(replication.BinLogFileHeader):]), func(ev *replication.BinlogEvent) error {
+					events = append(events, ev)
+					return nil
+				})
+				require.NoError(t, err)
+				require.Len(t, events, len(f.Events))
+
+				var last time.Time
+				for j, ev := range events {
+					assert.Equal(t, f.Events[j].Type, ev.Header.EventType)
+					assert.Equal(t, f.Events[j].Pos+f.Events[j].Size, ev.Header.LogPos)
+					ts := time.Unix(int64(ev.Header.Timestamp), 0)
+					assert.False(t, ts.Before(last), "timestamps must not go backwards")
+					last = ts
+				}
+
+				if i < len(files)-1 {
+					rotate, ok := events[len(events)-1].Event.(*replication.RotateEvent)
+					require.True(t, ok, "every file but the last ends with a rotate")
+					assert.Equal(t, files[i+1].Name, string(rotate.NextLogName))
+				}
+				assert.GreaterOrEqual(t, f.Size(), int64(64<<10))
+				assert.False(t, f.End.Before(f.Start))
+			}
+		})
+	}
+}
+
+func TestGenerateCommitTimestamps(t *testing.T) {
+	files := Generate(Options{Rate: 1000, Distribution: Poisson, Seed: 2})
+	p := replication.NewBinlogParser()
+	var gtids int
+	err := p.ParseReader(bytes.NewReader(files[0].Data[len(replication.BinLogFileHeader):]), func(ev *replication.BinlogEvent) error {
+		if g, ok := ev.Event.(*replication.GTIDEvent); ok {
+			gtids++
+			assert.Equal(t, g.ImmediateCommitTime().Unix(), int64(ev.Header.Timestamp))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Positive(t, gtids)
+}
+
+func TestGenerateRate(t *testing.T) {
+	files := Generate(Options{Files: 4, Rate: 50, Distribution: Poisson, Seed: 3})
+	var transactions int
+	for _, f := range files {
+		for _, ev := range f.Events {
+			if ev.Type == replication.XID_EVENT {
+				transactions++
+			}
+		}
+	}
+	elapsed := files[len(files)-1].End.Sub(files[0].Start).Seconds()
+	assert.InDelta(t, 50, float64(transactions)/elapsed, 5)
+}
+
+func TestWriteDir(t *testing.T) {
+	dir := t.TempDir()
+	files := Generate(Options{Files: 2, FileSize: 4 << 10})
+	require.NoError(t, WriteDir(dir, files))
+
+	index, err := os.ReadFile(filepath.Join(dir, "binlog.index"))
+	require.NoError(t, err)
+	assert.Equal(t, "./binlog.000001\n./binlog.000002\n", string(index))
+
+	var events int
+	err = replication.NewBinlogParser().ParseFile(filepath.Join(dir, "binlog.000002"), 0, func(*replication.BinlogEvent) error {
+		events++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, len(files[1].Events), events)
+}
+
+func TestGenerateCompressed(t *testing.T) {
+	files := Generate(Options{FileSize: 16 << 10, Compress: true, Seed: 4})
+	p := replication.NewBinlogParser()
+	p.SetVerifyChecksum(true)
+	var payloads int
+	err := p.ParseReader(bytes.NewReader(files[0].Data[len(replication.BinLogFileHeader):]), func(ev *replication.BinlogEvent) error {
+		payload, ok := ev.Event.(*replication.TransactionPayloadEvent)
+		if !ok {
+			return nil
+		}
+		payloads++
+		require.Len(t, payload.Events, 3)
+		assert.Equal(t, "BEGIN", string(payload.Events[0].Event.(*replication.QueryEvent).Query))
+		assert.Equal(t, replication.XID_EVENT, payload.Events[2].Header.EventType)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Positive(t, payloads)
+}
@@ -0,0 +1,194 @@
+// Package chaos serves generated binlogs through a source that fails the way servers and
+// networks do, so that the serve daemon can be soak tested under sustained faults:
+// dropped connections, slow responses and binlogs purged while they are searched. It
+// backs the hidden --chaos option of serve, exercising the reconnects, the invalidation
+// of cached ranges and the degraded answers that real servers only cause now and then.
+package chaos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+)
+
+const (
+	// files binlogs are generated, of which the oldest window are listed at first and one
+	// more rotates in on every Advance
+	files  = 64
+	window = 16
+	// fileSize and txRate make each file hold a couple of minutes of transactions
+	fileSize = 256 << 10
+	txRate   = 5
+	// maxDelay is the longest a slow response holds back its first event, past the
+	// default probe timeout
+	maxDelay = 10 * time.Second
+)
+
+// errDropped is returned when a connection drops; like a reset connection, it is transient
+var errDropped = fmt.Errorf("chaos: connection dropped: %w", syscall.ECONNRESET)
+
+// Source is a binlog.BinlogLister and binlog.EventStreamer over generated binlogs, which
+// rotate and are purged as Advance is called, and whose listings and streams fail at random
+type Source struct {
+	rate float64
+	data map[string][]byte
+
+	mu    sync.Mutex
+	rng   *rand.Rand
+	names []string
+	// first and last delimit the files listed, names[first:last]
+	first, last int
+}
+
+// New returns a Source whose listings and streams each fail with probability rate, from
+// 0 to 1. Its newest file ends about now, and seed makes the binlogs and faults
+// reproducible.
+func New(rate float64, seed int64) *Source {
+	opts := binlogtest.Options{Files: window, FileSize: fileSize, Rate: txRate, Seed: seed}
+	// The files listed at first are generated again, to end now
+	listed := binlogtest.Generate(opts)
+	opts.Files, opts.Start = files, time.Now().Add(-listed[window-1].End.Sub(listed[0].Start))
+	generated := binlogtest.Generate(opts)
+
+	s := &Source{rate: rate, data: make(map[string][]byte, files), rng: rand.New(rand.NewSource(seed)), last: window}
+	for _, f := range generated {
+		s.names = append(s.names, f.Name)
+		s.data[f.Name] = f.Data
+	}
+	return s
+}
+
+// Run advances the source every interval until ctx is done
+func (s *Source) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Advance()
+		}
+	}
+}
+
+// Advance rotates the next generated file in, while there is one, and with probability
+// rate purges the oldest file, keeping at least two
+func (s *Source) Advance() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last < len(s.names) {
+		s.last++
+	}
+	if s.last-s.first > 2 && s.fault() {
+		slog.Info("Chaos: purging binlog", "file", s.names[s.first])
+		s.first++
+	}
+}
+
+// ListBinlogs lists the files rotated in and not purged yet, oldest first
+func (s *Source) ListBinlogs() ([]binlog.FileInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fault() {
+		return nil, errDropped
+	}
+	files := make([]binlog.FileInfo, 0, s.last-s.first)
+	for _, name := range s.names[s.first:s.last] {
+		files = append(files, binlog.FileInfo{Name: name, Size: int64(len(s.data[name]))})
+	}
+	return files, nil
+}
+
+// StreamFrom streams a listed file, failing like a server for files purged or not yet
+// written. The connection may drop at once or after some events, or the first event may
+// be slow to come.
+func (s *Source) StreamFrom(binlogFile string, pos uint32) (binlog.EventStream, error) {
+	s.mu.Lock()
+	i := slices.Index(s.names, binlogFile)
+	listed := i >= s.first && i < s.last
+	dropped := s.fault()
+	slow, drop := s.fault(), s.fault()
+	delay := time.Duration(s.rng.Int63n(int64(maxDelay)))
+	dropAfter := 1 + s.rng.Intn(64)
+	s.mu.Unlock()
+
+	if !listed {
+		return nil, &mysql.MyError{Code: mysql.ER_MASTER_FATAL_ERROR_READING_BINLOG, State: "HY000",
+			Message: "Could not find first log file name in binary log index file"}
+	}
+	if dropped {
+		return nil, errDropped
+	}
+	stream, err := binlog.FileStreamer{Reader: reader(s.data)}.StreamFrom(binlogFile, pos)
+	if err != nil {
+		return nil, err
+	}
+	faulty := &faultyStream{EventStream: stream}
+	if slow {
+		faulty.delay = delay
+	}
+	if drop {
+		faulty.dropAfter = dropAfter
+	}
+	return faulty, nil
+}
+
+// fault reports whether a fault is injected, with probability rate. s.mu must be held.
+func (s *Source) fault() bool {
+	return s.rng.Float64() < s.rate
+}
+
+// reader reads the generated files, for binlog.FileStreamer
+type reader map[string][]byte
+
+func (r reader) OpenAt(binlogFile string, offset int64) (io.ReadCloser, error) {
+	data, ok := r[binlogFile]
+	if !ok {
+		return nil, fmt.Errorf("no binlog %s", binlogFile)
+	}
+	if offset < 0 || offset > int64(len(data)) {
+		return nil, fmt.Errorf("offset %d is outside %s, of %d bytes", offset, binlogFile, len(data))
+	}
+	return io.NopCloser(bytes.NewReader(data[offset:])), nil
+}
+
+// faultyStream holds back its first event for delay, and drops the connection once
+// dropAfter events have been read, unless they are zero
+type faultyStream struct {
+	binlog.EventStream
+	delay     time.Duration
+	dropAfter int
+	events    int
+}
+
+func (s *faultyStream) GetEvent(ctx context.Context) (*replication.BinlogEvent, error) {
+	if s.delay > 0 {
+		timer := time.NewTimer(s.delay)
+		s.delay = 0
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if s.dropAfter > 0 && s.events == s.dropAfter {
+		return nil, errDropped
+	}
+	s.events++
+	return s.EventStream.GetEvent(ctx)
+}
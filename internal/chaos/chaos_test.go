@@ -0,0 +1,86 @@
+package chaos
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+func names(files []binlog.FileInfo) []string {
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.Name
+	}
+	return names
+}
+
+func TestSourceWithoutFaults(t *testing.T) {
+	s := New(0, 1)
+	files, err := s.ListBinlogs()
+	require.NoError(t, err)
+	require.Len(t, files, window)
+
+	// The files listed span about half an hour up to now
+	finder := &binlog.Finder{Streamer: s, Lister: s}
+	res := finder.Find(names(files), time.Now().Add(-15*time.Minute))
+	assert.Equal(t, binlog.MatchExact, res.MatchQuality, res.Warnings)
+
+	s.Advance()
+	files, err = s.ListBinlogs()
+	require.NoError(t, err)
+	assert.Len(t, files, window+1, "a file rotated in and none was purged")
+}
+
+func TestSourceFaults(t *testing.T) {
+	s := New(1, 1)
+	_, err := s.ListBinlogs()
+	assert.ErrorIs(t, err, syscall.ECONNRESET)
+
+	oldest := s.names[0]
+	s.Advance()
+	_, err = s.StreamFrom(oldest, 4)
+	var myErr *mysql.MyError
+	require.ErrorAs(t, err, &myErr, "the oldest file was purged")
+	assert.EqualValues(t, mysql.ER_MASTER_FATAL_ERROR_READING_BINLOG, myErr.Code)
+
+	_, err = s.StreamFrom(s.names[1], 4)
+	assert.ErrorIs(t, err, syscall.ECONNRESET)
+	_, err = s.StreamFrom(s.names[len(s.names)-1], 4)
+	assert.ErrorAs(t, err, &myErr, "the newest file is not written yet")
+}
+
+func TestReaderOffset(t *testing.T) {
+	r := reader{"binlog.000001": make([]byte, 100)}
+	rc, err := r.OpenAt("binlog.000001", 100)
+	require.NoError(t, err)
+	_ = rc.Close()
+	_, err = r.OpenAt("binlog.000001", 101)
+	assert.ErrorContains(t, err, "offset 101")
+}
+
+func TestFaultyStream(t *testing.T) {
+	s := New(0, 1)
+	stream, err := s.StreamFrom(s.names[0], 4)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	faulty := &faultyStream{EventStream: stream, delay: time.Hour, dropAfter: 2}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = faulty.GetEvent(ctx)
+	assert.ErrorIs(t, err, context.Canceled, "a slow response is cut short by the context")
+
+	for range 2 {
+		_, err = faulty.GetEvent(context.Background())
+		require.NoError(t, err)
+	}
+	_, err = faulty.GetEvent(context.Background())
+	assert.ErrorIs(t, err, syscall.ECONNRESET)
+}
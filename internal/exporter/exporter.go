@@ -0,0 +1,206 @@
+// Package exporter publishes binlog retention coverage as Prometheus metrics.
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+const namespace = "binlog_find_time"
+
+// Coverage summarises the binlogs retained by a single server
+type Coverage struct {
+	Oldest time.Time
+	Newest time.Time
+	Files  int
+	Bytes  int64
+}
+
+// RetentionAlert reports a server's retention falling below, or recovering to, the minimum
+type RetentionAlert struct {
+	Server string
+	// Low is set when the retention fell below the minimum, and cleared when it recovered
+	Low          bool
+	Retention    time.Duration
+	MinRetention time.Duration
+	Coverage     Coverage
+}
+
+// Exporter periodically probes each target server and records its binlog coverage
+type Exporter struct {
+	// MinRetention, if set, has OnRetention called when the retention of a server falls
+	// below it, and again once it recovers, rather than on every refresh
+	MinRetention time.Duration
+	OnRetention  func(RetentionAlert)
+
+	// mu guards targets, which SetTargets replaces
+	mu      sync.Mutex
+	targets []binlog.Server
+	// low holds the servers whose retention is below MinRetention
+	low map[string]bool
+
+	oldest      *prometheus.GaugeVec
+	newest      *prometheus.GaugeVec
+	retention   *prometheus.GaugeVec
+	files       *prometheus.GaugeVec
+	bytes       *prometheus.GaugeVec
+	up          *prometheus.GaugeVec
+	lastRefresh *prometheus.GaugeVec
+}
+
+// New creates an Exporter for the given servers, each read with its own settings
+func New(targets []binlog.Server) *Exporter {
+	gauge := func(name, help string) *prometheus.GaugeVec {
+		return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      name,
+			Help:      help,
+		}, []string{"server"})
+	}
+
+	return &Exporter{
+		targets:     targets,
+		low:         make(map[string]bool),
+		oldest:      gauge("oldest_event_timestamp_seconds", "Unix timestamp of the first event in the oldest retained binlog."),
+		newest:      gauge("newest_event_timestamp_seconds", "Unix timestamp of the last event in the newest binlog."),
+		retention:   gauge("retention_seconds", "Time span covered by the retained binlogs."),
+		files:       gauge("binlog_files", "Number of retained binlog files."),
+		bytes:       gauge("binlog_bytes", "Total size of the retained binlog files in bytes."),
+		up:          gauge("up", "Whether the last refresh of the server succeeded."),
+		lastRefresh: gauge("last_refresh_timestamp_seconds", "Unix timestamp of the last refresh attempt."),
+	}
+}
+
+// Register adds the exporter's metrics to the registry
+func (e *Exporter) Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{e.oldest, e.newest, e.retention, e.files, e.bytes, e.up, e.lastRefresh} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetTargets replaces the servers probed from the next refresh on, e.g. when the
+// configuration is reloaded, and removes the metrics of servers no longer probed
+func (e *Exporter) SetTargets(targets []binlog.Server) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	kept := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		kept[ServerLabel(t.Config)] = true
+	}
+	for _, t := range e.targets {
+		if server := ServerLabel(t.Config); !kept[server] {
+			for _, g := range []*prometheus.GaugeVec{e.oldest, e.newest, e.retention, e.files, e.bytes, e.up, e.lastRefresh} {
+				g.DeleteLabelValues(server)
+			}
+		}
+	}
+	e.targets = targets
+}
+
+// Run refreshes every target immediately and then on each interval until the context is cancelled
+func (e *Exporter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		e.mu.Lock()
+		targets := e.targets
+		e.mu.Unlock()
+		for _, target := range targets {
+			e.refresh(target)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh probes a single server and updates its metrics
+func (e *Exporter) refresh(target binlog.Server) {
+	server := ServerLabel(target.Config)
+	e.lastRefresh.WithLabelValues(server).SetToCurrentTime()
+
+	coverage, err := Measure(target)
+	if err != nil {
+		slog.Warn("Could not refresh binlog coverage", "server", server, "error", err)
+		e.up.WithLabelValues(server).Set(0)
+		return
+	}
+
+	e.Record(server, coverage)
+	e.checkRetention(server, coverage)
+}
+
+// checkRetention calls OnRetention when the retention of a server crosses MinRetention
+func (e *Exporter) checkRetention(server string, c Coverage) {
+	if e.MinRetention <= 0 || e.OnRetention == nil {
+		return
+	}
+	retention := c.Newest.Sub(c.Oldest)
+	low := retention < e.MinRetention
+	if low == e.low[server] {
+		return
+	}
+	e.low[server] = low
+	e.OnRetention(RetentionAlert{Server: server, Low: low, Retention: retention, MinRetention: e.MinRetention, Coverage: c})
+}
+
+// Record sets the metrics for a server from its measured coverage
+func (e *Exporter) Record(server string, c Coverage) {
+	e.up.WithLabelValues(server).Set(1)
+	e.files.WithLabelValues(server).Set(float64(c.Files))
+	e.bytes.WithLabelValues(server).Set(float64(c.Bytes))
+	e.oldest.WithLabelValues(server).Set(float64(c.Oldest.Unix()))
+	e.newest.WithLabelValues(server).Set(float64(c.Newest.Unix()))
+	e.retention.WithLabelValues(server).Set(c.Newest.Sub(c.Oldest).Seconds())
+}
+
+// Measure lists a server's binlogs, reads the first event of the oldest file and reads the
+// newest file to its end, whatever the scan limits, for the time of its last event
+func Measure(server binlog.Server) (Coverage, error) {
+	files, err := server.ListBinlogs()
+	if err != nil {
+		return Coverage{}, err
+	}
+	if len(files) == 0 {
+		return Coverage{}, fmt.Errorf("no binlog files found")
+	}
+
+	c := Coverage{Files: len(files)}
+	finder := &binlog.Finder{Config: server.Config, Settings: server.Settings, Context: server.Context, Source: binlog.TimestampHeader, Sizes: make(map[string]int64, len(files))}
+	for _, f := range files {
+		c.Bytes += f.Size
+		finder.Sizes[f.Name] = f.Size
+	}
+
+	c.Oldest, err = finder.StartTime(files[0].Name)
+	if err != nil {
+		return Coverage{}, err
+	}
+	// A capped probe would report a time before the last event as the newest
+	newest := files[len(files)-1]
+	c.Newest, err = finder.EndTime(newest.Name)
+	if err != nil {
+		return Coverage{}, err
+	}
+	return c, nil
+}
+
+// ServerLabel returns the value of the server label for a target
+func ServerLabel(cfg replication.BinlogSyncerConfig) string {
+	return fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+}
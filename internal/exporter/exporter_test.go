@@ -0,0 +1,76 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+func TestRecord(t *testing.T) {
+	e := New(nil)
+	require.NoError(t, e.Register(prometheus.NewRegistry()))
+
+	oldest := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	e.Record("db1:3306", Coverage{
+		Oldest: oldest,
+		Newest: oldest.Add(72 * time.Hour),
+		Files:  3,
+		Bytes:  3072,
+	})
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(e.up.WithLabelValues("db1:3306")))
+	assert.Equal(t, float64(3), testutil.ToFloat64(e.files.WithLabelValues("db1:3306")))
+	assert.Equal(t, float64(3072), testutil.ToFloat64(e.bytes.WithLabelValues("db1:3306")))
+	assert.Equal(t, float64(oldest.Unix()), testutil.ToFloat64(e.oldest.WithLabelValues("db1:3306")))
+	assert.Equal(t, (72 * time.Hour).Seconds(), testutil.ToFloat64(e.retention.WithLabelValues("db1:3306")))
+}
+
+func TestCheckRetention(t *testing.T) {
+	var alerts []RetentionAlert
+	e := New(nil)
+	e.MinRetention = 48 * time.Hour
+	e.OnRetention = func(a RetentionAlert) { alerts = append(alerts, a) }
+
+	oldest := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	coverage := func(retention time.Duration) Coverage {
+		return Coverage{Oldest: oldest, Newest: oldest.Add(retention)}
+	}
+
+	// Only crossing the minimum alerts, in either direction
+	e.checkRetention("db1:3306", coverage(72*time.Hour))
+	e.checkRetention("db1:3306", coverage(24*time.Hour))
+	e.checkRetention("db1:3306", coverage(12*time.Hour))
+	e.checkRetention("db2:3306", coverage(12*time.Hour))
+	e.checkRetention("db1:3306", coverage(50*time.Hour))
+
+	require.Len(t, alerts, 3)
+	assert.Equal(t, RetentionAlert{Server: "db1:3306", Low: true, Retention: 24 * time.Hour, MinRetention: 48 * time.Hour, Coverage: coverage(24 * time.Hour)}, alerts[0])
+	assert.Equal(t, "db2:3306", alerts[1].Server)
+	assert.True(t, alerts[1].Low)
+	assert.Equal(t, "db1:3306", alerts[2].Server)
+	assert.False(t, alerts[2].Low)
+	assert.Equal(t, 50*time.Hour, alerts[2].Retention)
+}
+
+func TestSetTargets(t *testing.T) {
+	db1 := binlog.Server{Config: replication.BinlogSyncerConfig{Host: "db1", Port: 3306}}
+	db2 := binlog.Server{Config: replication.BinlogSyncerConfig{Host: "db2", Port: 3306}}
+	e := New([]binlog.Server{db1, db2})
+	registry := prometheus.NewRegistry()
+	require.NoError(t, e.Register(registry))
+	e.Record("db1:3306", Coverage{Files: 1})
+	e.Record("db2:3306", Coverage{Files: 2})
+
+	// The metrics of a server no longer monitored are removed
+	e.SetTargets([]binlog.Server{db1})
+	assert.Equal(t, []binlog.Server{db1}, e.targets)
+	assert.Equal(t, 1, testutil.CollectAndCount(e.files))
+	assert.Equal(t, float64(1), testutil.ToFloat64(e.files.WithLabelValues("db1:3306")))
+}
@@ -0,0 +1,275 @@
+package grpcserver
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Credentials are the bearer tokens and basic authentication users a client may present
+// in its Authorization header, or gRPC authorization metadata. Either kind is accepted.
+type Credentials struct {
+	// mu guards the tokens and users, which Replace swaps while serving
+	mu     sync.RWMutex
+	Tokens []string
+	// Users maps user names to passwords
+	Users map[string]string
+}
+
+// Replace swaps in the tokens and users of other, e.g. read again when the configuration
+// is reloaded
+func (c *Credentials) Replace(other *Credentials) {
+	other.mu.RLock()
+	tokens, users := other.Tokens, other.Users
+	other.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Tokens, c.Users = tokens, users
+}
+
+// ReadCredentials reads the bearer tokens in tokenFile, one per line so that a token can
+// be rotated without downtime, and the users in usersFile as USER:PASSWORD lines. Either
+// file may be empty. Blank lines and lines starting with # are skipped.
+func ReadCredentials(tokenFile, usersFile string) (*Credentials, error) {
+	c := &Credentials{Users: make(map[string]string)}
+	if tokenFile != "" {
+		lines, err := readLines(tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		c.Tokens = lines
+	}
+	if usersFile != "" {
+		lines, err := readLines(usersFile)
+		if err != nil {
+			return nil, err
+		}
+		for i, line := range lines {
+			user, password, ok := strings.Cut(line, ":")
+			if !ok || user == "" || password == "" {
+				return nil, fmt.Errorf("%s:%d: expected USER:PASSWORD", usersFile, i+1)
+			}
+			c.Users[user] = password
+		}
+	}
+	if len(c.Tokens) == 0 && len(c.Users) == 0 {
+		return nil, fmt.Errorf("no tokens or users in the credential files")
+	}
+	return c, nil
+}
+
+// readLines returns the lines of a file that are neither blank nor comments
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+	return lines, nil
+}
+
+// Authorized reports whether an Authorization header value carries valid credentials.
+// Every token or password is compared in constant time.
+func (c *Credentials) Authorized(authorization string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	scheme, value, _ := strings.Cut(authorization, " ")
+	switch strings.ToLower(scheme) {
+	case "bearer":
+		ok := false
+		for _, token := range c.Tokens {
+			ok = subtle.ConstantTimeCompare([]byte(value), []byte(token)) == 1 || ok
+		}
+		return ok
+	case "basic":
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return false
+		}
+		user, password, _ := strings.Cut(string(decoded), ":")
+		want, known := c.Users[user]
+		// An unknown user takes as long to refuse as a wrong password
+		ok := subtle.ConstantTimeCompare([]byte(password), []byte(want)) == 1
+		return known && ok
+	default:
+		return false
+	}
+}
+
+// UnaryInterceptor refuses gRPC calls without valid credentials with UNAUTHENTICATED.
+// Health checks are let through, as Kubernetes probes cannot send credentials.
+func (c *Credentials) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := c.check(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor refuses streaming gRPC calls, such as server reflection, the same way
+func (c *Credentials) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := c.check(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// check returns UNAUTHENTICATED unless the call carries valid credentials or is a health check
+func (c *Credentials) check(ctx context.Context, fullMethod string) error {
+	if strings.HasPrefix(fullMethod, "/"+healthpb.Health_ServiceDesc.ServiceName+"/") {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, authorization := range md.Get("authorization") {
+		if c.Authorized(authorization) {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or invalid credentials")
+}
+
+// Middleware refuses HTTP requests without valid credentials with 401
+func (c *Credentials) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !c.Authorized(r.Header.Get("Authorization")) {
+			c.mu.RLock()
+			tokens, users := len(c.Tokens), len(c.Users)
+			c.mu.RUnlock()
+			if tokens > 0 {
+				w.Header().Add("WWW-Authenticate", "Bearer")
+			}
+			if users > 0 {
+				w.Header().Add("WWW-Authenticate", `Basic realm="binlog-find-time"`)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "missing or invalid credentials"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// TLS is the TLS config of the listeners: the certificate they serve and the CAs client
+// certificates must be signed by, which Replace swaps while serving
+type TLS struct {
+	current atomic.Pointer[tls.Config]
+}
+
+// ServerTLS reads the TLS config of the listeners, serving the certificate in certFile
+// with the key in keyFile. The pair is read again when certFile changes, so renewed
+// certificates are served without a restart. With clientCAFile, clients must present a
+// certificate signed by one of its CAs (mutual TLS).
+func ServerTLS(certFile, keyFile, clientCAFile string) (*TLS, error) {
+	keyPair := &reloadingKeyPair{certFile: certFile, keyFile: keyFile}
+	if _, err := keyPair.get(); err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return keyPair.get() },
+	}
+	if clientCAFile != "" {
+		pem, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", clientCAFile)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	t := &TLS{}
+	t.current.Store(cfg)
+	return t, nil
+}
+
+// Replace swaps in the certificate and client CAs of other, e.g. read again from other
+// files when the configuration is reloaded. Handshakes already done keep the old ones.
+func (t *TLS) Replace(other *TLS) {
+	t.current.Store(other.current.Load())
+}
+
+// Config returns the config of a listener offering nextProtos by ALPN, e.g. "h2" for
+// gRPC. Every handshake uses the certificate and client CAs current at the time.
+func (t *TLS) Config(nextProtos ...string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: nextProtos,
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return t.current.Load().GetCertificate(hello)
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cfg := t.current.Load().Clone()
+			cfg.NextProtos = nextProtos
+			return cfg, nil
+		},
+	}
+}
+
+// reloadingKeyPair loads a certificate and key, again whenever the certificate file's
+// modification time changes
+type reloadingKeyPair struct {
+	certFile, keyFile string
+
+	mu       sync.Mutex
+	modified time.Time
+	cert     *tls.Certificate
+}
+
+// get returns the current certificate. A pair that fails to load, e.g. while its files
+// are being replaced, keeps the previous one in use.
+func (k *reloadingKeyPair) get() (*tls.Certificate, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	info, err := os.Stat(k.certFile)
+	if err != nil && k.cert == nil {
+		return nil, fmt.Errorf("failed to read certificate: %w", err)
+	}
+	if err != nil || info.ModTime().Equal(k.modified) {
+		return k.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(k.certFile, k.keyFile)
+	if err != nil {
+		if k.cert != nil {
+			return k.cert, nil
+		}
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	k.cert, k.modified = &cert, info.ModTime()
+	return k.cert, nil
+}
@@ -0,0 +1,229 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// writeFile writes a file in the test's temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func basic(user, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+}
+
+func TestReadCredentials(t *testing.T) {
+	tokens := writeFile(t, "tokens", "# current\nsecret-1\n\n  secret-2  \n")
+	users := writeFile(t, "users", "alice:pa:ss\n")
+
+	c, err := ReadCredentials(tokens, users)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"secret-1", "secret-2"}, c.Tokens)
+	assert.Equal(t, map[string]string{"alice": "pa:ss"}, c.Users)
+
+	_, err = ReadCredentials("", writeFile(t, "users", "alice\n"))
+	assert.ErrorContains(t, err, "expected USER:PASSWORD")
+	_, err = ReadCredentials(writeFile(t, "tokens", "# none yet\n"), "")
+	assert.Error(t, err)
+	_, err = ReadCredentials(filepath.Join(t.TempDir(), "missing"), "")
+	assert.Error(t, err)
+}
+
+func TestCredentialsAuthorized(t *testing.T) {
+	c := &Credentials{Tokens: []string{"secret-1", "secret-2"}, Users: map[string]string{"alice": "pass"}}
+
+	tests := []struct {
+		authorization string
+		expected      bool
+	}{
+		{"Bearer secret-1", true},
+		{"bearer secret-2", true},
+		{"Bearer secret", false},
+		{"Bearer ", false},
+		{basic("alice", "pass"), true},
+		{basic("alice", "wrong"), false},
+		{basic("bob", ""), false},
+		{"Basic !!!", false},
+		{"secret-1", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, c.Authorized(tt.authorization), tt.authorization)
+	}
+}
+
+func TestCredentialsReplace(t *testing.T) {
+	c := &Credentials{Tokens: []string{"old"}}
+	c.Replace(&Credentials{Tokens: []string{"new"}, Users: map[string]string{"alice": "pass"}})
+	assert.False(t, c.Authorized("Bearer old"))
+	assert.True(t, c.Authorized("Bearer new"))
+	assert.True(t, c.Authorized(basic("alice", "pass")))
+}
+
+func TestCredentialsMiddleware(t *testing.T) {
+	c := &Credentials{Tokens: []string{"secret"}}
+	h := c.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/servers", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+	assert.JSONEq(t, `{"error":"missing or invalid credentials"}`, rec.Body.String())
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/servers", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func TestCredentialsUnaryInterceptor(t *testing.T) {
+	c := &Credentials{Users: map[string]string{"alice": "pass"}}
+	intercept := c.UnaryInterceptor()
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+	find := &grpc.UnaryServerInfo{FullMethod: "/binlogfind.v1.BinlogFind/Find"}
+
+	_, err := intercept(context.Background(), nil, find, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", basic("alice", "pass")))
+	resp, err := intercept(ctx, nil, find, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+
+	// Probes cannot authenticate
+	resp, err = intercept(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+}
+
+// serverStream is a grpc.ServerStream carrying only a context
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s serverStream) Context() context.Context { return s.ctx }
+
+func TestCredentialsStreamInterceptor(t *testing.T) {
+	c := &Credentials{Tokens: []string{"secret"}}
+	intercept := c.StreamInterceptor()
+	handler := func(any, grpc.ServerStream) error { return nil }
+	reflection := &grpc.StreamServerInfo{FullMethod: "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"}
+
+	err := intercept(nil, serverStream{ctx: context.Background()}, reflection, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer secret"))
+	assert.NoError(t, intercept(nil, serverStream{ctx: ctx}, reflection, handler))
+	assert.NoError(t, intercept(nil, serverStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/grpc.health.v1.Health/Watch"}, handler))
+}
+
+// writeCert writes a self-signed certificate for the name and its key, returning their paths
+func writeCert(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestServerTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "server")
+	clientCert, clientKey := writeCert(t, dir, "client")
+
+	serverTLS, err := ServerTLS(certFile, keyFile, clientCert)
+	require.NoError(t, err)
+	cfg := serverTLS.Config("h2")
+	forClient, err := cfg.GetConfigForClient(nil)
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, forClient.ClientAuth)
+	assert.Equal(t, []string{"h2"}, forClient.NextProtos)
+
+	first, err := cfg.GetCertificate(nil)
+	require.NoError(t, err)
+
+	// A renewed certificate is served once its file changes
+	certPEM, err := os.ReadFile(clientCert)
+	require.NoError(t, err)
+	keyPEM, err := os.ReadFile(clientKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0o600))
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0o600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	renewed, err := cfg.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Certificate[0], renewed.Certificate[0])
+
+	// A broken pair keeps the previous one in use
+	require.NoError(t, os.WriteFile(certFile, []byte("garbage"), 0o600))
+	require.NoError(t, os.Chtimes(certFile, later.Add(time.Minute), later.Add(time.Minute)))
+	current, err := cfg.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, renewed.Certificate[0], current.Certificate[0])
+
+	// A reload swaps in other files, and drops the client CAs it no longer names
+	otherCert, otherKey := writeCert(t, dir, "other")
+	other, err := ServerTLS(otherCert, otherKey, "")
+	require.NoError(t, err)
+	serverTLS.Replace(other)
+	replaced, err := cfg.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, current.Certificate[0], replaced.Certificate[0])
+	forClient, err = cfg.GetConfigForClient(nil)
+	require.NoError(t, err)
+	assert.Equal(t, tls.NoClientCert, forClient.ClientAuth)
+
+	_, err = ServerTLS(certFile, keyFile, "")
+	assert.Error(t, err)
+	_, err = ServerTLS(clientCert, clientKey, keyFile)
+	assert.ErrorContains(t, err, "no certificates")
+}
@@ -0,0 +1,56 @@
+package grpcserver
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/minuteman3/binlog-find-time/api/binlogfindpb"
+)
+
+// healthInterval is how often the health of the BinlogFind service is re-evaluated
+const healthInterval = time.Second
+
+// Health returns a grpc.health.v1 service reporting the server process as SERVING, and
+// the BinlogFind service as SERVING once lookups can be answered: always when probing a
+// single server, and once any server of a time index has been indexed. The status is
+// kept current until the context is cancelled, when both turn NOT_SERVING.
+func (s *Server) Health(ctx context.Context) *health.Server {
+	hs := health.NewServer()
+	s.updateHealth(hs)
+	go func() {
+		ticker := time.NewTicker(healthInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				hs.Shutdown()
+				return
+			case <-ticker.C:
+				s.updateHealth(hs)
+			}
+		}
+	}()
+	return hs
+}
+
+// updateHealth sets the status of the BinlogFind service from the state of the index
+func (s *Server) updateHealth(hs *health.Server) {
+	status := healthpb.HealthCheckResponse_SERVING
+	if s.index != nil && !s.indexed() {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	hs.SetServingStatus(binlogfindpb.BinlogFind_ServiceDesc.ServiceName, status)
+}
+
+// indexed reports whether any server of the index has been indexed
+func (s *Server) indexed() bool {
+	for _, snapshot := range s.index.Snapshots() {
+		if !snapshot.Updated.IsZero() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package grpcserver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/minuteman3/binlog-find-time/internal/archive"
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+	"github.com/minuteman3/binlog-find-time/internal/timeindex"
+)
+
+// healthStatus returns the health status of a service
+func healthStatus(t *testing.T, hs healthpb.HealthServer, service string) healthpb.HealthCheckResponse_ServingStatus {
+	t.Helper()
+	resp, err := hs.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	require.NoError(t, err)
+	return resp.GetStatus()
+}
+
+func TestHealth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	hs := New(binlog.Server{Config: replication.BinlogSyncerConfig{Host: "db1", Port: 3306}}).Health(ctx)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, healthStatus(t, hs, ""))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, healthStatus(t, hs, "binlogfind.v1.BinlogFind"))
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		return healthStatus(t, hs, "binlogfind.v1.BinlogFind") == healthpb.HealthCheckResponse_NOT_SERVING
+	}, 5*healthInterval, healthInterval/10)
+}
+
+func TestHealthIndexed(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range binlogtest.Generate(binlogtest.Options{Files: 2, FileSize: 4 << 10, Seed: 1}) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, f.Name), f.Data, 0o600))
+	}
+	a := archive.NewDir(dir)
+	db1 := timeindex.Target{Config: replication.BinlogSyncerConfig{Host: "db1", Port: 3306}, Streamer: a, Lister: a}
+	db2 := timeindex.Target{Config: replication.BinlogSyncerConfig{Host: "db2", Port: 3306}, Streamer: a, Lister: a}
+	index := timeindex.New([]timeindex.Target{db1, db2})
+	s := NewIndexed(index)
+	hs := s.Health(context.Background())
+
+	// The process is alive, but nothing can be answered before the first refresh
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, healthStatus(t, hs, ""))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, healthStatus(t, hs, "binlogfind.v1.BinlogFind"))
+
+	// One indexed server is enough to answer for it
+	require.NoError(t, index.Refresh(db2))
+	s.updateHealth(hs)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, healthStatus(t, hs, "binlogfind.v1.BinlogFind"))
+}
@@ -0,0 +1,116 @@
+package grpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/minuteman3/binlog-find-time/api/binlogfindpb"
+	"github.com/minuteman3/binlog-find-time/api/openapi"
+)
+
+// Handler returns the REST form of the service: each method answers GET requests with its
+// fields as query parameters, timestamps in RFC 3339, and its response as JSON with the
+// field names of the proto file. Errors are JSON objects with an error field. The API is
+// described by the OpenAPI document served at /openapi.json.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/find", handle(func(ctx context.Context, q *query) (proto.Message, error) {
+		return s.Find(ctx, &binlogfindpb.FindRequest{Timestamp: q.timestamp("timestamp"), Server: q.Get("server")})
+	}))
+	mux.HandleFunc("GET /v1/range", handle(func(ctx context.Context, q *query) (proto.Message, error) {
+		return s.Range(ctx, &binlogfindpb.RangeRequest{Start: q.timestamp("start"), End: q.timestamp("end"), Server: q.Get("server")})
+	}))
+	mux.HandleFunc("GET /v1/binlogs", handle(func(ctx context.Context, q *query) (proto.Message, error) {
+		return s.ListBinlogs(ctx, &binlogfindpb.ListBinlogsRequest{Server: q.Get("server")})
+	}))
+	mux.HandleFunc("GET /v1/gtid", handle(func(ctx context.Context, q *query) (proto.Message, error) {
+		return s.ResolveGTID(ctx, &binlogfindpb.ResolveGTIDRequest{Gtid: q.Get("gtid"), Server: q.Get("server")})
+	}))
+	mux.HandleFunc("GET /v1/servers", handle(func(ctx context.Context, _ *query) (proto.Message, error) {
+		return s.ListServers(ctx, &binlogfindpb.ListServersRequest{})
+	}))
+	mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(openapi.JSON)
+	})
+	return mux
+}
+
+// query holds the query parameters of a request, and the first one failing to parse
+type query struct {
+	*http.Request
+	err error
+}
+
+// Get returns a query parameter
+func (q *query) Get(key string) string {
+	return q.URL.Query().Get(key)
+}
+
+// timestamp parses an RFC 3339 query parameter, returning nil when it is missing so that
+// the method reports it as required
+func (q *query) timestamp(key string) *timestamppb.Timestamp {
+	v := q.Get(key)
+	if v == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		if q.err == nil {
+			q.err = status.Errorf(codes.InvalidArgument, "invalid %s, expected RFC 3339: %v", key, err)
+		}
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// handle adapts a method call to an HTTP handler
+func handle(call func(ctx context.Context, q *query) (proto.Message, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := &query{Request: r}
+		resp, err := call(r.Context(), q)
+		// Parse errors are found while building the request, so they are reported rather than
+		// the method's complaint about the missing field
+		if q.err != nil {
+			err = q.err
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			w.WriteHeader(httpStatus(status.Code(err)))
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": status.Convert(err).Message()})
+			return
+		}
+		data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(resp)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(append(data, '\n'))
+	}
+}
+
+// httpStatus maps a gRPC code to the HTTP status of the REST response
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
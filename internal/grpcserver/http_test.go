@@ -0,0 +1,71 @@
+package grpcserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/minuteman3/binlog-find-time/api/openapi"
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+func TestHandler(t *testing.T) {
+	h := New(binlog.Server{Config: replication.BinlogSyncerConfig{Host: "db1", Port: 3306, ServerID: 100, Flavor: "mysql"}}).Handler()
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+		body   string
+	}{
+		{"Missing timestamp", "/v1/find", http.StatusBadRequest, `{"error":"timestamp is required"}`},
+		{"Timestamp not in RFC 3339", "/v1/find?timestamp=2023-04-01+12:30:45", http.StatusBadRequest, "expected RFC 3339"},
+		{"Range with end before start", "/v1/range?start=2023-04-01T12:00:00Z&end=2023-04-01T11:00:00Z", http.StatusBadRequest, "end must not be before start"},
+		{"Unknown server", "/v1/find?timestamp=2023-04-01T12:00:00Z&server=db2:3306", http.StatusNotFound, "unknown server"},
+		{"Unknown method", "/v1/status", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.body)
+		})
+	}
+}
+
+func TestHandlerResponse(t *testing.T) {
+	h := New(binlog.Server{Config: replication.BinlogSyncerConfig{Host: "db1", Port: 3306}}).Handler()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/servers", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"servers":[{"server":"db1:3306"}]}`, rec.Body.String())
+}
+
+func TestHandlerServesOpenAPI(t *testing.T) {
+	h := New(binlog.Server{Config: replication.BinlogSyncerConfig{Host: "127.0.0.1", Port: 1}}).Handler()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, string(openapi.JSON), rec.Body.String())
+
+	// Every path the document describes is routed; nothing listens on port 1, so the calls
+	// fail fast
+	var doc struct {
+		Paths map[string]map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(openapi.JSON, &doc))
+	require.NotEmpty(t, doc.Paths)
+	for path := range doc.Paths {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.NotEqual(t, "404 page not found\n", rec.Body.String(), path)
+	}
+}
@@ -0,0 +1,284 @@
+// Package grpcserver implements the BinlogFind gRPC service on top of the binlog package.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/minuteman3/binlog-find-time/api/binlogfindpb"
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+	"github.com/minuteman3/binlog-find-time/internal/timeindex"
+)
+
+// Server answers BinlogFind requests against a single MySQL server, or the servers of a
+// time index
+type Server struct {
+	binlogfindpb.UnimplementedBinlogFindServer
+
+	// mu guards target and cache, which SetTarget replaces
+	mu     sync.RWMutex
+	target binlog.Server
+	// cache is shared by all requests, so that closed files are not probed again
+	cache binlog.Cache
+	// index, if set, answers requests from the time index of its servers instead, so that
+	// only the newest file of a server is probed
+	index *timeindex.Index
+}
+
+// New creates a Server that searches the binlogs of the MySQL server target, read with its
+// settings
+func New(target binlog.Server) *Server {
+	return &Server{target: target, cache: binlog.NewMemoryCache()}
+}
+
+// SetTarget replaces the MySQL server of a Server created with New, e.g. when the
+// configuration is reloaded. The ranges it remembered are dropped with the old target.
+func (s *Server) SetTarget(target binlog.Server) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.target, s.cache = target, binlog.NewMemoryCache()
+}
+
+// NewIndexed creates a Server answering requests for the servers of the index, which is
+// refreshed by the caller
+func NewIndexed(index *timeindex.Index) *Server {
+	return &Server{index: index}
+}
+
+// Find returns the binlog file containing, or closest preceding, the requested timestamp
+func (s *Server) Find(ctx context.Context, req *binlogfindpb.FindRequest) (*binlogfindpb.FindResponse, error) {
+	if err := checkTimestamp("timestamp", req.GetTimestamp()); err != nil {
+		return nil, err
+	}
+
+	l, err := s.lookup(req.GetServer())
+	if err != nil {
+		return nil, err
+	}
+	binlogFiles, err := l.binlogFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	return l.find(ctx, binlogFiles, req.GetTimestamp())
+}
+
+// ListBinlogs returns every binlog file the server currently retains
+func (s *Server) ListBinlogs(_ context.Context, req *binlogfindpb.ListBinlogsRequest) (*binlogfindpb.ListBinlogsResponse, error) {
+	l, err := s.lookup(req.GetServer())
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &binlogfindpb.ListBinlogsResponse{}
+	for _, f := range l.files {
+		resp.Files = append(resp.Files, &binlogfindpb.BinlogFile{
+			Name:      f.Name,
+			Size:      f.Size,
+			Encrypted: f.Encrypted,
+		})
+	}
+	return resp, nil
+}
+
+// Range returns the binlog files spanning the requested time window
+func (s *Server) Range(ctx context.Context, req *binlogfindpb.RangeRequest) (*binlogfindpb.RangeResponse, error) {
+	if err := checkTimestamp("start", req.GetStart()); err != nil {
+		return nil, err
+	}
+	if err := checkTimestamp("end", req.GetEnd()); err != nil {
+		return nil, err
+	}
+	if req.GetEnd().AsTime().Before(req.GetStart().AsTime()) {
+		return nil, status.Error(codes.InvalidArgument, "end must not be before start")
+	}
+
+	l, err := s.lookup(req.GetServer())
+	if err != nil {
+		return nil, err
+	}
+	binlogFiles, err := l.binlogFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	start, err := l.find(ctx, binlogFiles, req.GetStart())
+	if err != nil {
+		return nil, err
+	}
+	end, err := l.find(ctx, binlogFiles, req.GetEnd())
+	if err != nil {
+		return nil, err
+	}
+
+	// Files are in server index order, which is not necessarily lexical order
+	resp := &binlogfindpb.RangeResponse{Start: start, End: end}
+	inRange := false
+	for _, f := range binlogFiles {
+		if f == start.File {
+			inRange = true
+		}
+		if inRange {
+			resp.Files = append(resp.Files, f)
+		}
+		if f == end.File {
+			break
+		}
+	}
+	return resp, nil
+}
+
+// ResolveGTID returns the binlog coordinates at which the requested GTID was written
+func (s *Server) ResolveGTID(ctx context.Context, req *binlogfindpb.ResolveGTIDRequest) (*binlogfindpb.ResolveGTIDResponse, error) {
+	if req.GetGtid() == "" {
+		return nil, status.Error(codes.InvalidArgument, "gtid is required")
+	}
+
+	l, err := s.lookup(req.GetServer())
+	if err != nil {
+		return nil, err
+	}
+	binlogFiles, err := l.binlogFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	l.finder.Context = ctx
+	pos, err := l.finder.LocateGTID(binlogFiles, req.GetGtid())
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "failed to resolve GTID: %v", err)
+	}
+	return &binlogfindpb.ResolveGTIDResponse{File: pos.File, Position: pos.Pos}, nil
+}
+
+// ListServers returns the servers requests are answered for, with the state of their index
+func (s *Server) ListServers(_ context.Context, _ *binlogfindpb.ListServersRequest) (*binlogfindpb.ListServersResponse, error) {
+	resp := &binlogfindpb.ListServersResponse{}
+	if s.index == nil {
+		resp.Servers = append(resp.Servers, &binlogfindpb.IndexedServer{Server: s.name()})
+		return resp, nil
+	}
+	for _, snapshot := range s.index.Snapshots() {
+		server := &binlogfindpb.IndexedServer{
+			Server:       snapshot.Target.Name(),
+			Indexed:      true,
+			Files:        int32(len(snapshot.Files)),
+			IndexedFiles: int32(len(snapshot.Ranges)),
+		}
+		if !snapshot.Updated.IsZero() {
+			server.Updated = timestamppb.New(snapshot.Updated)
+		}
+		if snapshot.Err != nil {
+			server.Error = snapshot.Err.Error()
+		}
+		resp.Servers = append(resp.Servers, server)
+	}
+	return resp, nil
+}
+
+// lookup is what a request needs to search the binlogs of one server
+type lookup struct {
+	config replication.BinlogSyncerConfig
+	files  []binlog.FileInfo
+	finder *binlog.Finder
+}
+
+// lookup lists the binlogs of the requested server, by HOST:PORT or empty for the first
+func (s *Server) lookup(server string) (lookup, error) {
+	if s.index != nil {
+		snapshot, err := s.index.Snapshot(server)
+		switch {
+		case errors.Is(err, timeindex.ErrUnknownServer):
+			return lookup{}, status.Errorf(codes.NotFound, "%v", err)
+		case err != nil:
+			return lookup{}, status.Errorf(codes.Unavailable, "%v", err)
+		}
+		return lookup{config: snapshot.Target.Config, files: snapshot.Files, finder: snapshot.Finder()}, nil
+	}
+
+	s.mu.RLock()
+	target, cache := s.target, s.cache
+	s.mu.RUnlock()
+	if server != "" && server != s.name() {
+		return lookup{}, status.Errorf(codes.NotFound, "unknown server %s", server)
+	}
+	files, err := target.ListBinlogs()
+	if err != nil {
+		return lookup{}, status.Errorf(serverErrorCode(err), "failed to get binlog files: %v", err)
+	}
+	// The sizes and server UUID key the cached ranges, so that those of a file that grew
+	// or of a rebuilt server are not reused. MariaDB has no server UUID.
+	uuid, _ := target.ServerUUID()
+	sizes := make(map[string]int64, len(files))
+	for _, f := range files {
+		sizes[f.Name] = f.Size
+	}
+	finder := &binlog.Finder{Config: target.Config, Settings: target.Settings, Cache: cache, Sizes: sizes, ServerUUID: uuid}
+	return lookup{config: target.Config, files: files, finder: finder}, nil
+}
+
+// name returns the HOST:PORT of the single server
+func (s *Server) name() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return net.JoinHostPort(s.target.Config.Host, strconv.Itoa(int(s.target.Config.Port)))
+}
+
+// binlogFiles returns the names of the files, failing if there are none
+func (l lookup) binlogFiles() ([]string, error) {
+	if len(l.files) == 0 {
+		return nil, status.Error(codes.FailedPrecondition, "no binlog files found")
+	}
+	binlogFiles := make([]string, len(l.files))
+	for i, f := range l.files {
+		binlogFiles[i] = f.Name
+	}
+	return binlogFiles, nil
+}
+
+// find runs the binary search for a single timestamp, locating its position in the file
+// found when the file contains it. Its reads stop once ctx, that of the request, is done.
+func (l lookup) find(ctx context.Context, binlogFiles []string, ts *timestamppb.Timestamp) (*binlogfindpb.FindResponse, error) {
+	// Confirming a gap would read the whole file before it, and the response cannot
+	// report one anyway
+	l.finder.Position, l.finder.SkipGapConfirm, l.finder.Context = true, true, ctx
+	res := l.finder.Find(binlogFiles, ts.AsTime())
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	if res.File == "" {
+		return nil, status.Error(codes.NotFound, "no binlog containing the target timestamp was found")
+	}
+	resp := &binlogfindpb.FindResponse{File: res.File, ExactMatch: res.Exact(), Gtid: res.GTID}
+	if res.Position != nil {
+		resp.Position = res.Position.Pos
+	}
+	return resp, nil
+}
+
+// serverErrorCode maps a failure talking to MySQL to a gRPC code
+func serverErrorCode(err error) codes.Code {
+	if errors.Is(err, binlog.ErrPermissionDenied) {
+		return codes.PermissionDenied
+	}
+	return codes.Unavailable
+}
+
+// checkTimestamp validates a required timestamp field
+func checkTimestamp(field string, ts *timestamppb.Timestamp) error {
+	if ts == nil {
+		return status.Errorf(codes.InvalidArgument, "%s is required", field)
+	}
+	if err := ts.CheckValid(); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid %s: %v", field, err)
+	}
+	return nil
+}
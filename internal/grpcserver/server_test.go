@@ -0,0 +1,126 @@
+package grpcserver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/minuteman3/binlog-find-time/api/binlogfindpb"
+	"github.com/minuteman3/binlog-find-time/internal/archive"
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+	"github.com/minuteman3/binlog-find-time/internal/timeindex"
+)
+
+func TestServerValidatesRequests(t *testing.T) {
+	s := New(binlog.Server{Config: replication.BinlogSyncerConfig{ServerID: 100, Flavor: "mysql"}})
+	ctx := context.Background()
+	start := timestamppb.New(time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC))
+	end := timestamppb.New(time.Date(2023, 1, 1, 11, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{
+			name: "Find without timestamp",
+			call: func() error {
+				_, err := s.Find(ctx, &binlogfindpb.FindRequest{})
+				return err
+			},
+		},
+		{
+			name: "Range without end",
+			call: func() error {
+				_, err := s.Range(ctx, &binlogfindpb.RangeRequest{Start: start})
+				return err
+			},
+		},
+		{
+			name: "Range with end before start",
+			call: func() error {
+				_, err := s.Range(ctx, &binlogfindpb.RangeRequest{Start: start, End: end})
+				return err
+			},
+		},
+		{
+			name: "ResolveGTID without gtid",
+			call: func() error {
+				_, err := s.ResolveGTID(ctx, &binlogfindpb.ResolveGTIDRequest{})
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, codes.InvalidArgument, status.Code(tt.call()))
+		})
+	}
+}
+
+func TestIndexedServer(t *testing.T) {
+	dir := t.TempDir()
+	files := binlogtest.Generate(binlogtest.Options{Files: 3, FileSize: 4 << 10, Rate: 0.5, Seed: 1})
+	for _, f := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, f.Name), f.Data, 0o600))
+	}
+	a := archive.NewDir(dir)
+	target := timeindex.Target{Config: replication.BinlogSyncerConfig{Host: "db1", Port: 3306}, Streamer: a, Lister: a}
+	index := timeindex.New([]timeindex.Target{target})
+	s := NewIndexed(index)
+	ctx := context.Background()
+	ts := timestamppb.New(files[1].Start.Add(5 * time.Second))
+
+	// Nothing is answered before the first refresh
+	_, err := s.Find(ctx, &binlogfindpb.FindRequest{Timestamp: ts})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	require.NoError(t, index.Refresh(target))
+	resp, err := s.Find(ctx, &binlogfindpb.FindRequest{Timestamp: ts, Server: "db1:3306"})
+	require.NoError(t, err)
+	assert.Equal(t, files[1].Name, resp.GetFile())
+	assert.True(t, resp.GetExactMatch())
+	// A transaction a few seconds into the file
+	assert.Greater(t, resp.GetPosition(), uint32(4))
+	assert.Less(t, int(resp.GetPosition()), len(files[1].Data))
+	assert.NotEmpty(t, resp.GetGtid())
+
+	_, err = s.Find(ctx, &binlogfindpb.FindRequest{Timestamp: ts, Server: "db2:3306"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// A request given up on stops reading
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = s.Find(canceled, &binlogfindpb.FindRequest{Timestamp: ts})
+	assert.Equal(t, codes.Canceled, status.Code(err))
+
+	servers, err := s.ListServers(ctx, &binlogfindpb.ListServersRequest{})
+	require.NoError(t, err)
+	require.Len(t, servers.GetServers(), 1)
+	assert.Equal(t, "db1:3306", servers.GetServers()[0].GetServer())
+	assert.EqualValues(t, 3, servers.GetServers()[0].GetFiles())
+	assert.EqualValues(t, 2, servers.GetServers()[0].GetIndexedFiles())
+}
+
+func TestServerSetTarget(t *testing.T) {
+	s := New(binlog.Server{Config: replication.BinlogSyncerConfig{Host: "db1", Port: 3306}})
+	s.SetTarget(binlog.Server{Config: replication.BinlogSyncerConfig{Host: "db2", Port: 3306}})
+
+	servers, err := s.ListServers(context.Background(), &binlogfindpb.ListServersRequest{})
+	require.NoError(t, err)
+	require.Len(t, servers.GetServers(), 1)
+	assert.Equal(t, "db2:3306", servers.GetServers()[0].GetServer())
+
+	_, err = s.Find(context.Background(), &binlogfindpb.FindRequest{Timestamp: timestamppb.Now(), Server: "db1:3306"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,286 @@
+// Package openapigen generates the Go client of the REST API from its OpenAPI document.
+// It understands the subset of OpenAPI the document uses: GET operations with query
+// parameters, and object schemas of strings, integers, booleans, arrays and references.
+package openapigen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// document is the part of an OpenAPI document the generator reads
+type document struct {
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components struct {
+		Parameters map[string]parameter `json:"parameters"`
+		Schemas    map[string]schema    `json:"schemas"`
+	} `json:"components"`
+}
+
+type operation struct {
+	OperationID string      `json:"operationId"`
+	Summary     string      `json:"summary"`
+	Parameters  []parameter `json:"parameters"`
+	Responses   map[string]struct {
+		Content map[string]struct {
+			Schema schema `json:"schema"`
+		} `json:"content"`
+	} `json:"responses"`
+}
+
+type parameter struct {
+	Ref         string `json:"$ref"`
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+	Schema      schema `json:"schema"`
+}
+
+type schema struct {
+	Ref         string     `json:"$ref"`
+	Type        string     `json:"type"`
+	Format      string     `json:"format"`
+	Description string     `json:"description"`
+	Nullable    bool       `json:"nullable"`
+	Items       *schema    `json:"items"`
+	Properties  properties `json:"properties"`
+}
+
+// property is a named property of an object schema
+type property struct {
+	name   string
+	schema schema
+}
+
+// properties keeps the properties of an object schema in document order, so that the
+// generated fields follow it
+type properties []property
+
+// UnmarshalJSON decodes an object of schemas in order
+func (p *properties) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var s schema
+		if err := dec.Decode(&s); err != nil {
+			return err
+		}
+		*p = append(*p, property{name: tok.(string), schema: s})
+	}
+	return nil
+}
+
+// Generate returns the source of the client's types and methods, in package pkg, for the
+// OpenAPI document in spec. The methods call the get method of the hand-written Client.
+func Generate(spec []byte, pkg string) ([]byte, error) {
+	var doc document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+
+	var b bytes.Buffer
+
+	names := make([]string, 0, len(doc.Components.Schemas))
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s := doc.Components.Schemas[name]
+		if s.Type != "object" {
+			return nil, fmt.Errorf("schema %s: only objects are supported", name)
+		}
+		comment(&b, s.Description)
+		fmt.Fprintf(&b, "type %s struct {\n", name)
+		for _, p := range s.Properties {
+			typ, tag, err := goType(p.schema)
+			if err != nil {
+				return nil, fmt.Errorf("schema %s, property %s: %w", name, p.name, err)
+			}
+			comment(&b, p.schema.Description)
+			fmt.Fprintf(&b, "%s %s `json:\"%s%s,omitempty\"`\n", goName(p.name), typ, p.name, tag)
+		}
+		fmt.Fprintf(&b, "}\n\n")
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		for method, op := range doc.Paths[path] {
+			if method != "get" {
+				return nil, fmt.Errorf("%s %s: only GET operations are supported", method, path)
+			}
+			if err := writeOperation(&b, doc, path, op); err != nil {
+				return nil, fmt.Errorf("%s: %w", op.OperationID, err)
+			}
+		}
+	}
+
+	// time is only imported when a schema or parameter holds a date-time
+	imports := []string{"context", "net/url"}
+	if bytes.Contains(b.Bytes(), []byte("time.")) {
+		imports = append(imports, "time")
+	}
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by openapigen from api/openapi/openapi.json. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+	for _, imp := range imports {
+		fmt.Fprintf(&out, "%q\n", imp)
+	}
+	fmt.Fprintf(&out, ")\n\n")
+	out.Write(b.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid code: %w", err)
+	}
+	return src, nil
+}
+
+// writeOperation writes the parameters type and method of an operation
+func writeOperation(b *bytes.Buffer, doc document, path string, op operation) error {
+	var params []parameter
+	for _, p := range op.Parameters {
+		if p.Ref != "" {
+			ref, ok := doc.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+			if !ok {
+				return fmt.Errorf("unknown parameter %s", p.Ref)
+			}
+			p = ref
+		}
+		if p.In != "query" {
+			return fmt.Errorf("parameter %s: only query parameters are supported", p.Name)
+		}
+		params = append(params, p)
+	}
+	resp, ok := op.Responses["200"].Content["application/json"]
+	if !ok || resp.Schema.Ref == "" {
+		return fmt.Errorf("no JSON response schema")
+	}
+	result := refName(resp.Schema.Ref)
+
+	paramsType := op.OperationID + "Params"
+	if len(params) > 0 {
+		fmt.Fprintf(b, "// %s holds the parameters of %s\n", paramsType, op.OperationID)
+		fmt.Fprintf(b, "type %s struct {\n", paramsType)
+		for _, p := range params {
+			typ, _, err := goType(p.Schema)
+			if err != nil {
+				return fmt.Errorf("parameter %s: %w", p.Name, err)
+			}
+			description := p.Description
+			if p.Required {
+				description = strings.TrimSpace("Required. " + description)
+			}
+			comment(b, description)
+			fmt.Fprintf(b, "%s %s\n", goName(p.Name), typ)
+		}
+		fmt.Fprintf(b, "}\n\n")
+	}
+
+	summary := op.Summary
+	if summary != "" {
+		summary = string(unicode.ToLower(rune(summary[0]))) + summary[1:]
+	}
+	comment(b, op.OperationID+" "+summary)
+	if len(params) > 0 {
+		fmt.Fprintf(b, "func (c *Client) %s(ctx context.Context, params %s) (*%s, error) {\n", op.OperationID, paramsType, result)
+	} else {
+		fmt.Fprintf(b, "func (c *Client) %s(ctx context.Context) (*%s, error) {\n", op.OperationID, result)
+	}
+	fmt.Fprintf(b, "query := url.Values{}\n")
+	for _, p := range params {
+		field := "params." + goName(p.Name)
+		value, zero := field, `""`
+		if p.Schema.Format == "date-time" {
+			value, zero = field+".Format(time.RFC3339Nano)", ""
+		}
+		// Unset parameters are left out, so the API reports a missing required one
+		if zero == "" {
+			fmt.Fprintf(b, "if !%s.IsZero() {\nquery.Set(%q, %s)\n}\n", field, p.Name, value)
+		} else {
+			fmt.Fprintf(b, "if %s != %s {\nquery.Set(%q, %s)\n}\n", field, zero, p.Name, value)
+		}
+	}
+	fmt.Fprintf(b, "var resp %s\n", result)
+	fmt.Fprintf(b, "if err := c.get(ctx, %q, query, &resp); err != nil {\nreturn nil, err\n}\n", path)
+	fmt.Fprintf(b, "return &resp, nil\n}\n\n")
+	return nil
+}
+
+// goType returns the Go type of a schema, and the option its JSON tag needs
+func goType(s schema) (string, string, error) {
+	if s.Ref != "" {
+		return refName(s.Ref), "", nil
+	}
+	var typ, tag string
+	switch {
+	case s.Type == "string" && s.Format == "date-time":
+		typ = "time.Time"
+	case s.Type == "string" && s.Format == "int64":
+		// 64-bit integers are strings in JSON, as protojson writes them
+		typ, tag = "int64", ",string"
+	case s.Type == "string":
+		typ = "string"
+	case s.Type == "boolean":
+		typ = "bool"
+	case s.Type == "integer" && (s.Format == "int32" || s.Format == "uint32"):
+		typ = s.Format
+	case s.Type == "integer":
+		typ = "int64"
+	case s.Type == "array" && s.Items != nil:
+		item, _, err := goType(*s.Items)
+		if err != nil {
+			return "", "", err
+		}
+		return "[]" + item, "", nil
+	default:
+		return "", "", fmt.Errorf("unsupported schema type %q", s.Type)
+	}
+	if s.Nullable {
+		typ = "*" + typ
+	}
+	return typ, tag, nil
+}
+
+// refName returns the name of the schema a reference points to
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// initialisms are written in upper case in Go names
+var initialisms = map[string]bool{"id": true, "gtid": true, "url": true, "http": true}
+
+// goName returns the exported Go name of a snake_case name
+func goName(name string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(name, "_") {
+		if initialisms[word] {
+			b.WriteString(strings.ToUpper(word))
+		} else if word != "" {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
+
+// comment writes a doc comment, if there is text for one
+func comment(b *bytes.Buffer, text string) {
+	if text != "" {
+		fmt.Fprintf(b, "// %s\n", text)
+	}
+}
@@ -0,0 +1,54 @@
+package openapigen
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/minuteman3/binlog-find-time/api/openapi"
+)
+
+func TestGenerateMatchesCheckedInClient(t *testing.T) {
+	src, err := Generate(openapi.JSON, "restclient")
+	require.NoError(t, err)
+	checkedIn, err := os.ReadFile("../../api/restclient/client.gen.go")
+	require.NoError(t, err)
+	assert.Equal(t, string(checkedIn), string(src), "api/restclient is stale, run go generate ./api/restclient")
+}
+
+func TestGenerateUnsupported(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		expected string
+	}{
+		{"Not JSON", `openapi: 3.0.3`, "invalid OpenAPI document"},
+		{"Schema not an object", `{"components":{"schemas":{"Name":{"type":"string"}}}}`, "only objects are supported"},
+		{"Unsupported property", `{"components":{"schemas":{"R":{"type":"object","properties":{"n":{"type":"number"}}}}}}`, `unsupported schema type "number"`},
+		{"POST operation", `{"paths":{"/v1/x":{"post":{"operationId":"X"}}}}`, "only GET operations are supported"},
+		{"Path parameter", `{"paths":{"/v1/x/{id}":{"get":{"operationId":"X","parameters":[{"name":"id","in":"path","schema":{"type":"string"}}]}}}}`, "only query parameters are supported"},
+		{"Unknown parameter", `{"paths":{"/v1/x":{"get":{"operationId":"X","parameters":[{"$ref":"#/components/parameters/missing"}]}}}}`, "unknown parameter"},
+		{"No response schema", `{"paths":{"/v1/x":{"get":{"operationId":"X"}}}}`, "no JSON response schema"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Generate([]byte(tt.spec), "restclient")
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func TestGoName(t *testing.T) {
+	tests := map[string]string{
+		"server":        "Server",
+		"exact_match":   "ExactMatch",
+		"indexed_files": "IndexedFiles",
+		"gtid":          "GTID",
+		"source_url":    "SourceURL",
+	}
+	for name, expected := range tests {
+		assert.Equal(t, expected, goName(name), name)
+	}
+}
@@ -0,0 +1,157 @@
+// Package orchestrator looks up MySQL topologies in the HTTP API of Orchestrator, so that
+// searches can name a cluster rather than a host that changes on every failover.
+package orchestrator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// InstanceKey identifies an instance by the address Orchestrator knows it by
+type InstanceKey struct {
+	Hostname string
+	Port     int
+}
+
+// nullInt64 is a nullable number as Orchestrator encodes it
+type nullInt64 struct {
+	Int64 int64
+	Valid bool
+}
+
+// Instance is the part of Orchestrator's description of an instance that host selection
+// needs. Field names follow its API, including the misspelled thread fields of
+// Orchestrator 3; older versions report the threads as Slave_IO_Running and
+// Slave_SQL_Running.
+type Instance struct {
+	Key       InstanceKey
+	MasterKey InstanceKey
+	ReadOnly  bool
+	// LogBinEnabled and LogReplicationUpdatesEnabled tell whether the instance's binlogs
+	// hold what it replicates from its primary
+	LogBinEnabled                bool
+	LogReplicationUpdatesEnabled bool
+	IsLastCheckValid             bool
+	IsUpToDate                   bool
+	IsDowntimed                  bool
+	ReplicationIOThreadRuning    bool
+	ReplicationSQLThreadRuning   bool
+	SlaveIORunning               bool `json:"Slave_IO_Running"`
+	SlaveSQLRunning              bool `json:"Slave_SQL_Running"`
+	ReplicationLagSeconds        nullInt64
+}
+
+// replicating reports whether both replication threads of the instance are running
+func (i Instance) replicating() bool {
+	return (i.ReplicationIOThreadRuning || i.SlaveIORunning) && (i.ReplicationSQLThreadRuning || i.SlaveSQLRunning)
+}
+
+// ErrNoInstance is returned when no instance of a cluster fits the selection
+var ErrNoInstance = errors.New("no suitable instance")
+
+// Client queries an Orchestrator API
+type Client struct {
+	client *http.Client
+	// base is the URL of the API, such as http://orchestrator:3000/api
+	base *url.URL
+}
+
+// New returns a client of the API at base, accessed through client. Credentials in base
+// are sent as basic authentication with every request, as Orchestrator's
+// AuthenticationMethod "basic" expects.
+func New(client *http.Client, base *url.URL) *Client {
+	return &Client{client: client, base: base}
+}
+
+// Cluster lists the instances of the cluster with the given alias, or cluster name
+func (c *Client) Cluster(alias string) ([]Instance, error) {
+	u := c.base.JoinPath("cluster", "alias", alias)
+	var instances []Instance
+	if err := c.get(u, &instances); err != nil {
+		return nil, err
+	}
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("cluster %q: %w", alias, ErrNoInstance)
+	}
+	return instances, nil
+}
+
+// get fetches u and decodes its JSON body into v
+func (c *Client) get(u *url.URL, v any) error {
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if user := u.User; user != nil {
+		password, _ := user.Password()
+		req.SetBasicAuth(user.Username(), password)
+		req.URL.User = nil
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query %s: %w", u.Redacted(), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to query %s: %s", u.Redacted(), resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode the response of %s: %w", u.Redacted(), err)
+	}
+	return nil
+}
+
+// Primary returns the primary of a cluster: the instance replicating from none of the
+// others or, of co-primaries replicating from each other, the writable one
+func Primary(instances []Instance) (Instance, error) {
+	inCluster := func(key InstanceKey) bool {
+		return slices.ContainsFunc(instances, func(i Instance) bool { return i.Key == key })
+	}
+	writable := func(i Instance) bool { return !i.ReadOnly && i.IsLastCheckValid }
+
+	var top []Instance
+	for _, i := range instances {
+		if !inCluster(i.MasterKey) {
+			top = append(top, i)
+		}
+	}
+	if len(top) == 0 {
+		top = instances
+	}
+	if i := slices.IndexFunc(top, writable); i >= 0 {
+		return top[i], nil
+	}
+	if len(top) == 1 {
+		return top[0], nil
+	}
+	return Instance{}, fmt.Errorf("no writable primary among %d co-primaries: %w", len(top), ErrNoInstance)
+}
+
+// BestReplica returns the replica with the least lag among those whose binlogs hold the
+// cluster's writes up to date: binary logging with log_replica_updates, both replication
+// threads running, a valid last check and no downtime
+func BestReplica(instances []Instance) (Instance, error) {
+	primary, err := Primary(instances)
+	if err != nil {
+		return Instance{}, err
+	}
+	var best *Instance
+	for i := range instances {
+		r := &instances[i]
+		if r.Key == primary.Key || !r.LogBinEnabled || !r.LogReplicationUpdatesEnabled || !r.replicating() ||
+			!r.IsLastCheckValid || !r.IsUpToDate || r.IsDowntimed || !r.ReplicationLagSeconds.Valid {
+			continue
+		}
+		if best == nil || r.ReplicationLagSeconds.Int64 < best.ReplicationLagSeconds.Int64 {
+			best = r
+		}
+	}
+	if best == nil {
+		return Instance{}, fmt.Errorf("no up-to-date replica with binary logging and log_replica_updates: %w", ErrNoInstance)
+	}
+	return *best, nil
+}
@@ -0,0 +1,110 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replica returns a healthy replica of primary with the given lag
+func replica(host string, primary string, lag int64) Instance {
+	return Instance{
+		Key:                          InstanceKey{host, 3306},
+		MasterKey:                    InstanceKey{primary, 3306},
+		ReadOnly:                     true,
+		LogBinEnabled:                true,
+		LogReplicationUpdatesEnabled: true,
+		IsLastCheckValid:             true,
+		IsUpToDate:                   true,
+		ReplicationIOThreadRuning:    true,
+		ReplicationSQLThreadRuning:   true,
+		ReplicationLagSeconds:        nullInt64{lag, true},
+	}
+}
+
+func TestPrimary(t *testing.T) {
+	primary := Instance{Key: InstanceKey{"db1", 3306}, LogBinEnabled: true, IsLastCheckValid: true}
+
+	tests := []struct {
+		name      string
+		instances []Instance
+		expected  string
+	}{
+		{"Top of the topology", []Instance{replica("db2", "db1", 0), primary, replica("db3", "db2", 0)}, "db1"},
+		{"Primary replicating from outside the cluster", []Instance{replica("db1", "upstream", 0), replica("db2", "db1", 0)}, "db1"},
+		{"Writable co-primary", []Instance{replica("db1", "db2", 0), func() Instance {
+			i := replica("db2", "db1", 0)
+			i.ReadOnly = false
+			return i
+		}()}, "db2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i, err := Primary(tt.instances)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, i.Key.Hostname)
+		})
+	}
+
+	_, err := Primary([]Instance{replica("db1", "db2", 0), replica("db2", "db1", 0)})
+	assert.ErrorIs(t, err, ErrNoInstance, "read-only co-primaries")
+}
+
+func TestBestReplica(t *testing.T) {
+	primary := Instance{Key: InstanceKey{"db1", 3306}, LogBinEnabled: true, IsLastCheckValid: true}
+	noUpdates := replica("db2", "db1", 0)
+	noUpdates.LogReplicationUpdatesEnabled = false
+	stopped := replica("db3", "db1", 0)
+	stopped.ReplicationSQLThreadRuning = false
+	downtimed := replica("db4", "db1", 0)
+	downtimed.IsDowntimed = true
+	legacy := replica("db6", "db1", 1)
+	legacy.ReplicationIOThreadRuning, legacy.ReplicationSQLThreadRuning = false, false
+	legacy.SlaveIORunning, legacy.SlaveSQLRunning = true, true
+
+	i, err := BestReplica([]Instance{primary, noUpdates, stopped, downtimed, replica("db5", "db1", 3), legacy})
+	require.NoError(t, err)
+	assert.Equal(t, "db6", i.Key.Hostname, "the least lagging of the replicas whose binlogs are complete")
+
+	_, err = BestReplica([]Instance{primary, noUpdates, stopped})
+	assert.ErrorIs(t, err, ErrNoInstance)
+}
+
+func TestClientCluster(t *testing.T) {
+	instances := []Instance{{Key: InstanceKey{"db1", 3306}}, replica("db2", "db1", 0)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "ops" || password != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/api/cluster/alias/main" {
+			// Orchestrator answers unknown clusters with an error status
+			http.Error(w, `{"Code":"ERROR"}`, http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(instances)
+	}))
+	defer srv.Close()
+
+	base, err := url.Parse(srv.URL + "/api")
+	require.NoError(t, err)
+	base.User = url.UserPassword("ops", "secret")
+	client := New(srv.Client(), base)
+
+	got, err := client.Cluster("main")
+	require.NoError(t, err)
+	assert.Equal(t, instances, got)
+
+	_, err = client.Cluster("other")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret", "credentials are redacted")
+
+	base.User = nil
+	_, err = New(srv.Client(), base).Cluster("main")
+	assert.ErrorContains(t, err, "401")
+}
@@ -0,0 +1,121 @@
+// Package rangecache stores the time ranges of a server's binlog files on disk, so that
+// searches can skip probing files whose ranges are already known.
+package rangecache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// Entry is the time range of a single binlog file
+type Entry struct {
+	File string `json:"file"`
+	// Size is the file size when it was probed; a different size means the entry is stale
+	Size  int64     `json:"size"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Index is the cached time index of one server's binlogs
+type Index struct {
+	// Server is the HOST:PORT the ranges were probed on
+	Server string `json:"server"`
+	// ServerUUID identifies the server instance, so a rebuilt server behind the same
+	// address never reuses the ranges of its predecessor
+	ServerUUID string `json:"server_uuid"`
+	// Source is the timestamp source the ranges were probed with
+	Source string `json:"source"`
+	// Origin is the origin filter the ranges were probed through, as
+	// binlog.OriginFilter.String, empty when every event was read
+	Origin  string    `json:"origin,omitempty"`
+	Updated time.Time `json:"updated"`
+	Files   []Entry   `json:"files"`
+}
+
+// DefaultDir returns the directory cache files are kept in by default
+func DefaultDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "binlog-find-time")
+	}
+	return filepath.Join(dir, "binlog-find-time")
+}
+
+// Path returns the cache file for a server in dir
+func Path(dir, server string) string {
+	name := strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '[', ']':
+			return '_'
+		}
+		return r
+	}, server)
+	return filepath.Join(dir, name+".json")
+}
+
+// Load reads the index at path. A missing file is reported as an error wrapping fs.ErrNotExist.
+func Load(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var index Index
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("invalid cache file %s: %w", path, err)
+	}
+	return &index, nil
+}
+
+// Save writes the index to path, creating its directory if needed. The file is replaced
+// atomically, so a concurrent search never reads a partial index.
+func (i *Index) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	data, err := json.MarshalIndent(i, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Ranges returns the cached ranges by file name, in the form used by binlog.Finder.Known.
+// Only entries for the same server instance whose files still have the cached size are
+// returned; anything else must be probed again.
+func (i *Index) Ranges(serverUUID string, sizes map[string]int64) map[string]binlog.TimeRange {
+	if serverUUID == "" || i.ServerUUID != serverUUID {
+		return nil
+	}
+	ranges := make(map[string]binlog.TimeRange, len(i.Files))
+	for _, e := range i.Files {
+		if size, ok := sizes[e.File]; ok && size == e.Size {
+			ranges[e.File] = binlog.TimeRange{Start: e.Start, End: e.End}
+		}
+	}
+	return ranges
+}
+
+// IsNotExist reports whether err means there is no cache file yet
+func IsNotExist(err error) bool {
+	return errors.Is(err, fs.ErrNotExist)
+}
@@ -0,0 +1,57 @@
+package rangecache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+func TestPath(t *testing.T) {
+	assert.Equal(t, filepath.Join("cache", "db.example.com_3306.json"), Path("cache", "db.example.com:3306"))
+	assert.Equal(t, filepath.Join("cache", "___1__3306.json"), Path("cache", "[::1]:3306"))
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := Path(t.TempDir(), "db:3306")
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := Load(path)
+	assert.True(t, IsNotExist(err))
+
+	index := &Index{
+		Server:     "db:3306",
+		ServerUUID: "3e11fa47-71ca-11e1-9e33-c80aa9429562",
+		Source:     "header",
+		Updated:    start,
+		Files:      []Entry{{File: "binlog.000001", Size: 1024, Start: start, End: start.Add(time.Hour)}},
+	}
+	require.NoError(t, index.Save(path))
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, index, loaded)
+}
+
+func TestRanges(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	uuid := "3e11fa47-71ca-11e1-9e33-c80aa9429562"
+	index := &Index{
+		ServerUUID: uuid,
+		Files: []Entry{
+			{File: "binlog.000001", Size: 1024, Start: start, End: start.Add(time.Hour)},
+			{File: "binlog.000002", Size: 2048, Start: start.Add(time.Hour), End: start.Add(2 * time.Hour)},
+			{File: "binlog.000003", Size: 4096, Start: start.Add(2 * time.Hour), End: start.Add(3 * time.Hour)},
+		},
+	}
+	sizes := map[string]int64{"binlog.000002": 2048, "binlog.000003": 8192}
+
+	assert.Equal(t, map[string]binlog.TimeRange{"binlog.000002": {Start: start.Add(time.Hour), End: start.Add(2 * time.Hour)}},
+		index.Ranges(uuid, sizes), "purged and resized files must be probed again")
+	assert.Empty(t, index.Ranges("5b5a8d4e-0000-11ee-8000-0242ac120002", sizes), "a rebuilt server must not reuse the cache")
+	assert.Empty(t, index.Ranges("", sizes), "servers without a UUID must not use the cache")
+}
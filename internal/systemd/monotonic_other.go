@@ -0,0 +1,8 @@
+//go:build !linux
+
+package systemd
+
+// monotonicUsec returns 0 where systemd does not run
+func monotonicUsec() int64 {
+	return 0
+}
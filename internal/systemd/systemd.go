@@ -0,0 +1,94 @@
+// Package systemd implements the parts of the sd_notify protocol the daemons need to run
+// as Type=notify or Type=notify-reload services: readiness, reloading, stopping and
+// watchdog notifications. Outside systemd every notification is a no-op.
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Notify sends a state such as READY=1 to the service manager, reporting false without
+// an error when the process is not run by one
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	// A leading @ names a socket in the abstract namespace
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("failed to notify systemd: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("failed to notify systemd: %w", err)
+	}
+	return true, nil
+}
+
+// Ready tells the service manager that startup finished, with a status line shown by
+// systemctl status
+func Ready(status string) {
+	notify("READY=1\nSTATUS=" + status)
+}
+
+// Reloading tells the service manager that the configuration is being reloaded; Ready
+// ends the reload
+func Reloading() {
+	// Type=notify-reload services must say when the reload started
+	notify("RELOADING=1\nMONOTONIC_USEC=" + strconv.FormatInt(monotonicUsec(), 10))
+}
+
+// Stopping tells the service manager that the service is shutting down
+func Stopping() {
+	notify("STOPPING=1")
+}
+
+// notify sends a notification, logging a failure rather than failing the service for it
+func notify(state string) {
+	if _, err := Notify(state); err != nil {
+		slog.Warn("Could not notify systemd", "error", err)
+	}
+}
+
+// WatchdogInterval returns the interval within which the service manager expects a
+// watchdog ping, or 0 when the watchdog is not enabled for this process
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	// The variables are inherited by child processes, for which they are not meant
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// Watchdog pings the service manager's watchdog at half its interval until the context
+// is cancelled. It returns at once when the watchdog is not enabled.
+func Watchdog(ctx context.Context) {
+	interval := WatchdogInterval()
+	if interval == 0 {
+		return
+	}
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		notify("WATCHDOG=1")
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package systemd
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listen creates a notification socket and points NOTIFY_SOCKET at it
+func listen(t *testing.T) *net.UnixConn {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+	return conn
+}
+
+// receive returns the next notification
+func receive(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	return string(buf[:n])
+}
+
+func TestNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	sent, err := Notify("READY=1")
+	assert.NoError(t, err)
+	assert.False(t, sent)
+
+	conn := listen(t)
+	sent, err = Notify("READY=1")
+	require.NoError(t, err)
+	assert.True(t, sent)
+	assert.Equal(t, "READY=1", receive(t, conn))
+
+	Ready("Serving 2 servers")
+	assert.Equal(t, "READY=1\nSTATUS=Serving 2 servers", receive(t, conn))
+	Reloading()
+	assert.Regexp(t, `^RELOADING=1\nMONOTONIC_USEC=\d+$`, receive(t, conn))
+	Stopping()
+	assert.Equal(t, "STOPPING=1", receive(t, conn))
+
+	t.Setenv("NOTIFY_SOCKET", filepath.Join(t.TempDir(), "missing"))
+	_, err = Notify("READY=1")
+	assert.Error(t, err)
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		usec     string
+		pid      string
+		expected time.Duration
+	}{
+		{"Disabled", "", "", 0},
+		{"Enabled", "30000000", "", 30 * time.Second},
+		{"For this process", "2000000", strconv.Itoa(os.Getpid()), 2 * time.Second},
+		{"For another process", "2000000", "1", 0},
+		{"Invalid", "soon", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WATCHDOG_USEC", tt.usec)
+			t.Setenv("WATCHDOG_PID", tt.pid)
+			assert.Equal(t, tt.expected, WatchdogInterval())
+		})
+	}
+}
+
+func TestWatchdog(t *testing.T) {
+	conn := listen(t)
+	t.Setenv("WATCHDOG_USEC", "20000")
+	t.Setenv("WATCHDOG_PID", "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Watchdog(ctx)
+		close(done)
+	}()
+	assert.Equal(t, "WATCHDOG=1", receive(t, conn))
+	assert.Equal(t, "WATCHDOG=1", receive(t, conn))
+	cancel()
+	<-done
+
+	// Without the watchdog there is nothing to ping
+	t.Setenv("WATCHDOG_USEC", "")
+	Watchdog(context.Background())
+}
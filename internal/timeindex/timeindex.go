@@ -0,0 +1,266 @@
+// Package timeindex keeps the time ranges of the binlogs of a set of servers up to date in
+// memory, refreshing them as binlogs rotate, so that lookups are answered without probing.
+package timeindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+)
+
+// ErrUnknownServer is returned for lookups of a server that is not indexed
+var ErrUnknownServer = errors.New("server is not indexed")
+
+// ErrNotIndexed is returned for lookups of a server whose binlogs have not been indexed
+// yet, or never could be
+var ErrNotIndexed = errors.New("binlogs are not indexed yet")
+
+// Target is a server whose binlogs are indexed
+type Target struct {
+	// Config connects to the server, and identifies it as HOST:PORT
+	Config replication.BinlogSyncerConfig
+	// Streamer and Lister, if set, read the binlogs instead of the server in Config
+	Streamer binlog.EventStreamer
+	Lister   binlog.BinlogLister
+	// Source selects the event timestamps the ranges are made of
+	Source binlog.TimestampSource
+	// Settings, if set, are used instead of binlog.DefaultSettings to read the binlogs
+	Settings *binlog.Settings
+	// Context, if set, is the context the binlogs are read under
+	Context context.Context
+}
+
+// Name returns the HOST:PORT the target is looked up by
+func (t Target) Name() string {
+	return net.JoinHostPort(t.Config.Host, strconv.Itoa(int(t.Config.Port)))
+}
+
+// finder returns a Finder reading the target's binlogs
+func (t Target) finder() *binlog.Finder {
+	return &binlog.Finder{Config: t.Config, Streamer: t.Streamer, Lister: t.Lister, Source: t.Source, Settings: t.Settings, Context: t.Context}
+}
+
+// server returns the server in Config, connected to with the target's settings
+func (t Target) server() binlog.Server {
+	return binlog.Server{Config: t.Config, Settings: t.Settings, Context: t.Context}
+}
+
+// Snapshot is the index of one server as of its last successful refresh
+type Snapshot struct {
+	Target Target
+	// Files are the server's binlogs, oldest first
+	Files []binlog.FileInfo
+	// Ranges holds the time range of every file but the newest, from its first to its last
+	// event. The newest file keeps growing and is probed by lookups.
+	Ranges map[string]binlog.TimeRange
+	// ServerUUID is the @@server_uuid of the server the ranges were read from, empty for
+	// MariaDB and for targets read through a Streamer
+	ServerUUID string
+	// Source is the timestamp source of the ranges
+	Source binlog.TimestampSource
+	// Updated is when the snapshot was taken, zero if no refresh has succeeded yet
+	Updated time.Time
+	// Err is why the last refresh failed, if it did
+	Err error
+}
+
+// Names returns the names of the snapshot's files
+func (s Snapshot) Names() []string {
+	names := make([]string, len(s.Files))
+	for i, f := range s.Files {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// Finder returns a Finder searching the snapshot's files, which probes only the files
+// without a known range
+func (s Snapshot) Finder() *binlog.Finder {
+	f := s.Target.finder()
+	f.Known, f.ServerUUID, f.Source = s.Ranges, s.ServerUUID, s.Source
+	f.Sizes = make(map[string]int64, len(s.Files))
+	for _, file := range s.Files {
+		f.Sizes[file.Name] = file.Size
+	}
+	return f
+}
+
+// Index is the time index of the binlogs of a set of servers
+type Index struct {
+	targets []Target
+
+	mu        sync.RWMutex
+	snapshots map[string]*Snapshot
+}
+
+// New creates an empty index of the targets; Run or Refresh fills it
+func New(targets []Target) *Index {
+	return &Index{targets: targets, snapshots: make(map[string]*Snapshot)}
+}
+
+// Snapshots returns the index of every server, in the order they were given, with only
+// the Target set for servers not refreshed yet
+func (x *Index) Snapshots() []Snapshot {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	snapshots := make([]Snapshot, len(x.targets))
+	for i, t := range x.targets {
+		if s, ok := x.snapshots[t.Name()]; ok {
+			snapshots[i] = *s
+		} else {
+			snapshots[i] = Snapshot{Target: t}
+		}
+	}
+	return snapshots
+}
+
+// Targets returns the servers of the index
+func (x *Index) Targets() []Target {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return slices.Clone(x.targets)
+}
+
+// SetTargets replaces the servers of the index, e.g. when the configuration is reloaded.
+// Servers kept keep their index, refreshed with their new target from then on, and the
+// index of servers removed is dropped.
+func (x *Index) SetTargets(targets []Target) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	snapshots := make(map[string]*Snapshot, len(targets))
+	for _, t := range targets {
+		if s, ok := x.snapshots[t.Name()]; ok {
+			kept := *s
+			kept.Target = t
+			snapshots[t.Name()] = &kept
+		}
+	}
+	x.targets, x.snapshots = targets, snapshots
+}
+
+// Run refreshes every target immediately and then on each interval until the context is cancelled
+func (x *Index) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, target := range x.Targets() {
+			if err := x.Refresh(target); err != nil {
+				slog.Warn("Could not refresh binlog time index", "server", target.Name(), "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh lists the target's binlogs and probes each file closed since the last refresh
+// for its time range, so that a refresh after a rotation reads the file rotated away
+// from. A failed refresh keeps the previous index, with the error recorded.
+func (x *Index) Refresh(target Target) error {
+	name := target.Name()
+	x.mu.RLock()
+	previous := x.snapshots[name]
+	x.mu.RUnlock()
+
+	snapshot, err := refresh(target, previous)
+	if err != nil {
+		failed := &Snapshot{Target: target, Err: err}
+		if previous != nil {
+			*failed = *previous
+			failed.Err = err
+		}
+		snapshot = failed
+	}
+
+	x.mu.Lock()
+	// A server removed by SetTargets during the refresh stays removed
+	if slices.ContainsFunc(x.targets, func(t Target) bool { return t.Name() == name }) {
+		x.snapshots[name] = snapshot
+	}
+	x.mu.Unlock()
+	return err
+}
+
+// refresh builds the snapshot of a target, reusing the ranges of previous
+func refresh(target Target, previous *Snapshot) (*Snapshot, error) {
+	finder := target.finder()
+	files, err := finder.Binlogs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list binlogs: %w", err)
+	}
+	snapshot := &Snapshot{Target: target, Files: files, Ranges: make(map[string]binlog.TimeRange), Source: target.Source, Updated: time.Now()}
+	// A server rebuilt behind the same address writes its files again under the same
+	// names, so its UUID tells its ranges apart. MariaDB has none.
+	if target.Streamer == nil {
+		snapshot.ServerUUID, _ = target.server().ServerUUID()
+	}
+	if len(files) == 0 {
+		return snapshot, nil
+	}
+
+	// A closed file never changes, unless the server was rebuilt and the file written
+	// again, which leaves it smaller than it was
+	kept := make(map[string]binlog.TimeRange)
+	if previous != nil && previous.ServerUUID == snapshot.ServerUUID && previous.Source == snapshot.Source {
+		for _, f := range previous.Files {
+			if r, ok := previous.Ranges[f.Name]; ok {
+				kept[fmt.Sprintf("%s:%d", f.Name, f.Size)] = r
+			}
+		}
+	}
+	finder.Sizes = make(map[string]int64, len(files))
+	for _, f := range files {
+		finder.Sizes[f.Name] = f.Size
+	}
+	for _, f := range files[:len(files)-1] {
+		if r, ok := kept[fmt.Sprintf("%s:%d", f.Name, f.Size)]; ok {
+			snapshot.Ranges[f.Name] = r
+			continue
+		}
+		// Lookups take an indexed range as exact, and a gap after it as real
+		r, err := finder.FullTimeRange(f.Name)
+		if err != nil {
+			slog.Warn("Could not get time range", "server", target.Name(), "file", f.Name, "error", err)
+			continue
+		}
+		snapshot.Ranges[f.Name] = r
+	}
+	return snapshot, nil
+}
+
+// Snapshot returns the index of a server by HOST:PORT, or of the first one for an empty name
+func (x *Index) Snapshot(server string) (Snapshot, error) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if server == "" && len(x.targets) > 0 {
+		server = x.targets[0].Name()
+	}
+	snapshot, ok := x.snapshots[server]
+	if !ok {
+		for _, t := range x.targets {
+			if t.Name() == server {
+				return Snapshot{}, fmt.Errorf("%s: %w", server, ErrNotIndexed)
+			}
+		}
+		return Snapshot{}, fmt.Errorf("%s: %w", server, ErrUnknownServer)
+	}
+	if snapshot.Updated.IsZero() {
+		return *snapshot, fmt.Errorf("%s: %w: %w", server, ErrNotIndexed, snapshot.Err)
+	}
+	return *snapshot, nil
+}
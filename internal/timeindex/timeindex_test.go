@@ -0,0 +1,194 @@
+package timeindex
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/minuteman3/binlog-find-time/internal/archive"
+	"github.com/minuteman3/binlog-find-time/internal/binlog"
+	"github.com/minuteman3/binlog-find-time/internal/binlogtest"
+)
+
+// countingStreamer counts the streams opened on the binlogs
+type countingStreamer struct {
+	binlog.EventStreamer
+	streams atomic.Int32
+}
+
+func (s *countingStreamer) StreamFrom(binlogFile string, pos uint32) (binlog.EventStream, error) {
+	s.streams.Add(1)
+	return s.EventStreamer.StreamFrom(binlogFile, pos)
+}
+
+// failingLister fails to list the binlogs
+type failingLister struct{}
+
+func (failingLister) ListBinlogs() ([]binlog.FileInfo, error) {
+	return nil, errors.New("connection refused")
+}
+
+// fileStart returns the header time of the first event of a file
+func fileStart(f *binlogtest.File) time.Time {
+	return f.Events[0].Time.Truncate(time.Second)
+}
+
+// fileEnd returns the header time of the last event of a file
+func fileEnd(f *binlogtest.File) time.Time {
+	return f.Events[len(f.Events)-1].Time.Truncate(time.Second)
+}
+
+// assertRange checks the bounds of an indexed range
+func assertRange(t *testing.T, r binlog.TimeRange, start, end time.Time) {
+	t.Helper()
+	assert.WithinDuration(t, start, r.Start, 0)
+	assert.WithinDuration(t, end, r.End, 0)
+}
+
+func TestIndexRefresh(t *testing.T) {
+	dir := t.TempDir()
+	files := binlogtest.Generate(binlogtest.Options{Files: 3, FileSize: 4 << 10, Rate: 0.5, Seed: 1})
+	write := func(f *binlogtest.File) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, f.Name), f.Data, 0o600))
+	}
+	write(files[0])
+	write(files[1])
+
+	a := archive.NewDir(dir)
+	streamer := &countingStreamer{EventStreamer: a}
+	target := Target{Config: replication.BinlogSyncerConfig{Host: "db1", Port: 3306}, Streamer: streamer, Lister: a}
+	index := New([]Target{target})
+
+	_, err := index.Snapshot("")
+	assert.ErrorIs(t, err, ErrNotIndexed)
+	_, err = index.Snapshot("db2:3306")
+	assert.ErrorIs(t, err, ErrUnknownServer)
+
+	require.NoError(t, index.Refresh(target))
+	snapshot, err := index.Snapshot("db1:3306")
+	require.NoError(t, err)
+	assert.Equal(t, []string{files[0].Name, files[1].Name}, snapshot.Names())
+	require.Len(t, snapshot.Ranges, 1)
+	assertRange(t, snapshot.Ranges[files[0].Name], fileStart(files[0]), fileEnd(files[0]))
+	assert.Equal(t, binlog.TimestampHeader, snapshot.Source)
+	assert.EqualValues(t, 1, streamer.streams.Load(), "the newest file was read")
+
+	// After a rotation only the new file is read
+	write(files[2])
+	require.NoError(t, index.Refresh(target))
+	snapshot, err = index.Snapshot("")
+	require.NoError(t, err)
+	assert.Len(t, snapshot.Ranges, 2)
+	assertRange(t, snapshot.Ranges[files[1].Name], fileStart(files[1]), fileEnd(files[1]))
+	assert.EqualValues(t, 2, streamer.streams.Load())
+
+	// Lookups in indexed files read nothing
+	res := snapshot.Finder().Find(snapshot.Names(), files[0].Start.Add(10*time.Second))
+	assert.Equal(t, files[0].Name, res.File)
+	assert.True(t, res.Exact())
+	assert.EqualValues(t, 2, streamer.streams.Load())
+
+	// A failed refresh keeps the previous index
+	failing := target
+	failing.Lister = failingLister{}
+	require.Error(t, index.Refresh(failing))
+	snapshot, err = index.Snapshot("")
+	require.NoError(t, err)
+	assert.Len(t, snapshot.Files, 3)
+	assert.ErrorContains(t, snapshot.Err, "connection refused")
+}
+
+func TestIndexReadsPastScanLimits(t *testing.T) {
+	dir := t.TempDir()
+	files := binlogtest.Generate(binlogtest.Options{Files: 2, FileSize: 4 << 10, Rate: 0.5, Seed: 1})
+	for _, f := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, f.Name), f.Data, 0o600))
+	}
+	a := archive.NewDir(dir)
+	settings := binlog.DefaultSettings
+	settings.ScanLimits = binlog.ScanLimits{MaxEvents: 5}
+	target := Target{Config: replication.BinlogSyncerConfig{Host: "db1", Port: 3306}, Streamer: a, Lister: a, Settings: &settings}
+	index := New([]Target{target})
+	require.NoError(t, index.Refresh(target))
+	snapshot, err := index.Snapshot("")
+	require.NoError(t, err)
+	r := snapshot.Ranges[files[0].Name]
+	assert.Empty(t, r.Truncated)
+	assertRange(t, r, fileStart(files[0]), fileEnd(files[0]))
+}
+
+func TestIndexFirstRefreshFails(t *testing.T) {
+	target := Target{Config: replication.BinlogSyncerConfig{Host: "db1", Port: 3306}, Lister: failingLister{}}
+	index := New([]Target{target})
+	require.Error(t, index.Refresh(target))
+
+	_, err := index.Snapshot("")
+	assert.ErrorIs(t, err, ErrNotIndexed)
+	assert.ErrorContains(t, err, "connection refused")
+
+	snapshots := index.Snapshots()
+	require.Len(t, snapshots, 1)
+	assert.True(t, snapshots[0].Updated.IsZero())
+	assert.Error(t, snapshots[0].Err)
+}
+
+func TestIndexSetTargets(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range binlogtest.Generate(binlogtest.Options{Files: 2, FileSize: 4 << 10, Rate: 0.5, Seed: 1}) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, f.Name), f.Data, 0o600))
+	}
+	a := archive.NewDir(dir)
+	target := func(host, user string) Target {
+		return Target{Config: replication.BinlogSyncerConfig{Host: host, Port: 3306, User: user}, Streamer: a, Lister: a}
+	}
+	x := New([]Target{target("db1", "old"), target("db2", "old")})
+	require.NoError(t, x.Refresh(target("db1", "old")))
+	require.NoError(t, x.Refresh(target("db2", "old")))
+
+	// db1 keeps its index with the new credentials, db2 is dropped and db3 added
+	x.SetTargets([]Target{target("db1", "new"), target("db3", "new")})
+	assert.Equal(t, []string{"db1:3306", "db3:3306"}, []string{x.Targets()[0].Name(), x.Targets()[1].Name()})
+	s, err := x.Snapshot("db1:3306")
+	require.NoError(t, err)
+	assert.Equal(t, "new", s.Target.Config.User)
+	assert.Len(t, s.Files, 2)
+	_, err = x.Snapshot("db2:3306")
+	assert.ErrorIs(t, err, ErrUnknownServer)
+	_, err = x.Snapshot("db3:3306")
+	assert.ErrorIs(t, err, ErrNotIndexed)
+
+	// A refresh of a removed server, e.g. one in flight during the reload, is discarded
+	require.NoError(t, x.Refresh(target("db2", "old")))
+	_, err = x.Snapshot("db2:3306")
+	assert.ErrorIs(t, err, ErrUnknownServer)
+}
+
+func TestIndexGap(t *testing.T) {
+	// The server was down for an hour between the two files
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := binlogtest.Generate(binlogtest.Options{Files: 1, FileSize: 4 << 10, Rate: 0.5, Seed: 1, Start: start})[0]
+	after := binlogtest.Generate(binlogtest.Options{Files: 2, FileSize: 4 << 10, Rate: 0.5, Seed: 1, Start: start.Add(time.Hour)})[1]
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, before.Name), before.Data, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, after.Name), after.Data, 0o600))
+
+	a := archive.NewDir(dir)
+	target := Target{Config: replication.BinlogSyncerConfig{Host: "db1", Port: 3306}, Streamer: a, Lister: a}
+	index := New([]Target{target})
+	require.NoError(t, index.Refresh(target))
+	snapshot, err := index.Snapshot("")
+	require.NoError(t, err)
+	assertRange(t, snapshot.Ranges[before.Name], fileStart(before), fileEnd(before))
+
+	// A target in the gap is not within the first file
+	res := snapshot.Finder().Find(snapshot.Names(), fileEnd(before).Add(30*time.Minute))
+	assert.Equal(t, before.Name, res.File)
+	assert.False(t, res.Exact(), "a target between the files matched %s", res.MatchQuality)
+}
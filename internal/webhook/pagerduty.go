@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// PagerDutyURL is the endpoint of the PagerDuty Events API v2
+const PagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty event actions
+const (
+	PagerDutyTrigger = "trigger"
+	PagerDutyResolve = "resolve"
+)
+
+// PagerDutySeverities are the severities of PagerDuty alerts
+var PagerDutySeverities = []string{"critical", "error", "warning", "info"}
+
+// maxSummary is the longest summary PagerDuty accepts
+const maxSummary = 1024
+
+// PagerDutyEvent is an event of the PagerDuty Events API v2. Events with the same
+// DedupKey update one alert, which a resolve event closes.
+type PagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *PagerDutyPayload `json:"payload,omitempty"`
+}
+
+// PagerDutyPayload describes the alert of a trigger event
+type PagerDutyPayload struct {
+	Summary string `json:"summary"`
+	// Source is the affected system, e.g. the HOST:PORT of a server
+	Source        string `json:"source"`
+	Severity      string `json:"severity"`
+	Component     string `json:"component,omitempty"`
+	CustomDetails any    `json:"custom_details,omitempty"`
+}
+
+// NewPagerDutyTrigger returns a trigger event, cutting the summary to the length PagerDuty accepts
+func NewPagerDutyTrigger(routingKey, dedupKey string, payload PagerDutyPayload) PagerDutyEvent {
+	if len(payload.Summary) > maxSummary {
+		payload.Summary = payload.Summary[:maxSummary-3] + "..."
+	}
+	return PagerDutyEvent{RoutingKey: routingKey, EventAction: PagerDutyTrigger, DedupKey: dedupKey, Payload: &payload}
+}
+
+// NewPagerDutyResolve returns an event resolving the alert of the dedup key
+func NewPagerDutyResolve(routingKey, dedupKey string) PagerDutyEvent {
+	return PagerDutyEvent{RoutingKey: routingKey, EventAction: PagerDutyResolve, DedupKey: dedupKey}
+}
+
+// ParseSeverities parses a severity mapping such as WARNING=warning,CRITICAL=critical,
+// mapping statuses, upper-cased, to PagerDuty severities
+func ParseSeverities(s string) (map[string]string, error) {
+	severities := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		status, severity, ok := strings.Cut(pair, "=")
+		status, severity = strings.ToUpper(strings.TrimSpace(status)), strings.ToLower(strings.TrimSpace(severity))
+		if !ok || status == "" {
+			return nil, fmt.Errorf("invalid severity mapping %q, expected STATUS=SEVERITY", pair)
+		}
+		if !slices.Contains(PagerDutySeverities, severity) {
+			return nil, fmt.Errorf("invalid PagerDuty severity %q, expected one of %s", severity, strings.Join(PagerDutySeverities, ", "))
+		}
+		severities[status] = severity
+	}
+	return severities, nil
+}
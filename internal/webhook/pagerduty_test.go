@@ -0,0 +1,34 @@
+package webhook
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSeverities(t *testing.T) {
+	severities, err := ParseSeverities("WARNING=warning, critical=Critical,UNKNOWN=error,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"WARNING": "warning", "CRITICAL": "critical", "UNKNOWN": "error"}, severities)
+
+	severities, err = ParseSeverities("")
+	require.NoError(t, err)
+	assert.Empty(t, severities)
+
+	_, err = ParseSeverities("WARNING")
+	assert.ErrorContains(t, err, "expected STATUS=SEVERITY")
+	_, err = ParseSeverities("CRITICAL=page")
+	assert.ErrorContains(t, err, "invalid PagerDuty severity")
+}
+
+func TestPagerDutyEvents(t *testing.T) {
+	trigger := NewPagerDutyTrigger("key", "binlog-retention/db1:3306", PagerDutyPayload{Summary: strings.Repeat("x", 2000), Source: "db1:3306", Severity: "critical"})
+	assert.Len(t, trigger.Payload.Summary, maxSummary)
+
+	data, err := json.Marshal(NewPagerDutyResolve("key", "binlog-retention/db1:3306"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"routing_key":"key","event_action":"resolve","dedup_key":"binlog-retention/db1:3306"}`, string(data))
+}
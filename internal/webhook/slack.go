@@ -0,0 +1,6 @@
+package webhook
+
+// SlackMessage is the body of a message posted to a Slack incoming webhook
+type SlackMessage struct {
+	Text string `json:"text"`
+}
@@ -0,0 +1,114 @@
+// Package webhook delivers notifications as JSON POST requests, retrying failed deliveries,
+// to generic webhooks, Slack incoming webhooks and the PagerDuty Events API.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultTimeout bounds each delivery attempt when no Client is set
+const DefaultTimeout = 10 * time.Second
+
+// Notifier posts notifications to a webhook URL
+type Notifier struct {
+	URL string
+	// Retries is the number of attempts made after the first one fails
+	Retries int
+	// Backoff is the delay before the first retry, doubled for every further retry
+	Backoff time.Duration
+	// Client sends the requests, or a client with DefaultTimeout if nil
+	Client *http.Client
+}
+
+// Notification is the JSON body posted to the webhook
+type Notification struct {
+	// Event names what happened, e.g. target_reached or retention_low
+	Event string `json:"event"`
+	// Server is the HOST:PORT of the server it happened on
+	Server string    `json:"server"`
+	Time   time.Time `json:"time"`
+	// Data holds the details of the event
+	Data any `json:"data,omitempty"`
+}
+
+// statusError is the response of a delivery the webhook refused
+type statusError struct {
+	code int
+	body string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("webhook returned %d %s: %s", e.code, http.StatusText(e.code), e.body)
+}
+
+// retryable reports whether a delivery may succeed later: the webhook could not be
+// reached, failed, or asked to slow down. Other refusals won't go away by retrying.
+func (e *statusError) retryable() bool {
+	return e.code >= 500 || e.code == http.StatusTooManyRequests || e.code == http.StatusRequestTimeout
+}
+
+// Send posts the payload as JSON, e.g. a Notification, SlackMessage or PagerDutyEvent,
+// retrying failed deliveries, until it is delivered, the webhook refuses it, the retries
+// are used up, or the context is done
+func (n *Notifier) Send(ctx context.Context, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	backoff := n.Backoff
+	for attempt := 0; ; attempt++ {
+		err := n.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if statusErr, ok := err.(*statusError); (ok && !statusErr.retryable()) || attempt >= n.Retries {
+			return err
+		}
+		slog.Warn("Webhook delivery failed, retrying", "attempt", attempt+1, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post makes one delivery attempt, succeeding on any 2xx response
+func (n *Notifier) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// The path of a Slack webhook URL is its secret, so only the host is reported
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = req.URL.Scheme + "://" + req.URL.Host
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &statusError{code: resp.StatusCode, body: string(bytes.TrimSpace(respBody))}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// server answers deliveries with the given statuses in turn, then 204, counting them
+func server(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestSend(t *testing.T) {
+	var got Notification
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+	}))
+	defer srv.Close()
+
+	at := time.Date(2023, 4, 1, 12, 30, 45, 0, time.UTC)
+	n := &Notifier{URL: srv.URL}
+	require.NoError(t, n.Send(context.Background(), Notification{Event: "target_reached", Server: "db1:3306", Time: at, Data: map[string]string{"file": "mysql-bin.000032"}}))
+	assert.Equal(t, "target_reached", got.Event)
+	assert.Equal(t, "db1:3306", got.Server)
+	assert.True(t, at.Equal(got.Time))
+	assert.Equal(t, map[string]any{"file": "mysql-bin.000032"}, got.Data)
+}
+
+func TestSendRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		retries  int
+		calls    int32
+		err      string
+	}{
+		{"Delivered after failures", []int{http.StatusBadGateway, http.StatusTooManyRequests}, 3, 3, ""},
+		{"Retries used up", []int{500, 500, 500}, 2, 3, "500 Internal Server Error"},
+		{"Refused", []int{http.StatusNotFound}, 3, 1, "404 Not Found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := server(t, tt.statuses...)
+			n := &Notifier{URL: srv.URL, Retries: tt.retries, Backoff: time.Millisecond}
+			err := n.Send(context.Background(), Notification{Event: "retention_low"})
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.err)
+			}
+			assert.Equal(t, tt.calls, calls.Load())
+		})
+	}
+}
+
+func TestSendUnreachable(t *testing.T) {
+	srv, _ := server(t)
+	srv.Close()
+	n := &Notifier{URL: srv.URL, Retries: 1, Backoff: time.Millisecond}
+	assert.Error(t, n.Send(context.Background(), Notification{Event: "retention_low"}))
+
+	// Cancelling stops the retries
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	n = &Notifier{URL: srv.URL, Retries: 5, Backoff: time.Hour}
+	assert.Error(t, n.Send(ctx, Notification{Event: "retention_low"}))
+}
+
+func TestSendRedactsURL(t *testing.T) {
+	srv, _ := server(t)
+	srv.Close()
+	n := &Notifier{URL: srv.URL + "/services/T000/B000/secret"}
+	err := n.Send(context.Background(), SlackMessage{Text: "hello"})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret")
+}
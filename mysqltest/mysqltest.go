@@ -0,0 +1,227 @@
+//go:build integration
+
+// Package mysqltest runs MySQL in a container for integration tests of code that reads
+// binlogs, and writes traffic and rotations to it so searches have known answers. It
+// needs Docker; Start skips the test when Docker is not available.
+package mysqltest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/mysql"
+)
+
+// DefaultImage is the MySQL image started when Options.Image is empty
+const DefaultImage = "mysql:8.0.36"
+
+// Options configures the server started by Start
+type Options struct {
+	// Image is the MySQL image to run (default: DefaultImage)
+	Image string
+	// Args are extra server options, e.g. "--max-binlog-size=4096"
+	Args []string
+}
+
+// Server is a running MySQL container with binary logging and GTIDs enabled
+type Server struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	// DB is a connection pool to the server, closed when the test ends
+	DB *sql.DB
+}
+
+// Batch is a burst of transactions written to a single binlog file
+type Batch struct {
+	File string
+	// Start and End are the server times, to the second, around the transactions, so
+	// every transaction's event header time lies between them
+	Start, End time.Time
+}
+
+// Start runs a MySQL server for the rest of the test, skipping the test if Docker is not
+// available
+func Start(t *testing.T, opts Options) *Server {
+	t.Helper()
+	skipWithoutDocker(t)
+
+	if opts.Image == "" {
+		opts.Image = DefaultImage
+	}
+	args := append([]string{"--server-id=1", "--log-bin=binlog", "--binlog-format=ROW", "--gtid-mode=ON", "--enforce-gtid-consistency=ON"}, opts.Args...)
+
+	ctx := context.Background()
+	const password = "mysqltest"
+	container, err := mysql.Run(ctx, opts.Image,
+		mysql.WithUsername("root"),
+		mysql.WithPassword(password),
+		mysql.WithDatabase("mysqltest"),
+		testcontainers.CustomizeRequestOption(func(req *testcontainers.GenericContainerRequest) error {
+			req.Cmd = append(req.Cmd, args...)
+			return nil
+		}),
+	)
+	testcontainers.CleanupContainer(t, container)
+	if err != nil {
+		t.Fatalf("Failed to start MySQL: %v", err)
+	}
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get MySQL host: %v", err)
+	}
+	port, err := container.MappedPort(ctx, "3306/tcp")
+	if err != nil {
+		t.Fatalf("Failed to get MySQL port: %v", err)
+	}
+
+	s := &Server{Host: host, Port: port.Int(), User: "root", Password: password}
+	cfg := mysqldriver.NewConfig()
+	cfg.User = s.User
+	cfg.Passwd = s.Password
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	cfg.DBName = "mysqltest"
+	connector, err := mysqldriver.NewConnector(cfg)
+	if err != nil {
+		t.Fatalf("Invalid MySQL config: %v", err)
+	}
+	s.DB = sql.OpenDB(connector)
+	t.Cleanup(func() { s.DB.Close() })
+
+	s.Exec(t, "CREATE TABLE events (id BIGINT AUTO_INCREMENT PRIMARY KEY, payload VARCHAR(255) NOT NULL)")
+	return s
+}
+
+// skipWithoutDocker skips the test when Docker is not running. testcontainers panics
+// instead when it finds no Docker socket at all.
+func skipWithoutDocker(t *testing.T) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Skipf("Docker is not available: %v", r)
+		}
+	}()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+}
+
+// SyncerConfig returns a config for reading the server's binlogs
+func (s *Server) SyncerConfig() replication.BinlogSyncerConfig {
+	return replication.BinlogSyncerConfig{
+		ServerID: 100,
+		Flavor:   "mysql",
+		Host:     s.Host,
+		Port:     uint16(s.Port),
+		User:     s.User,
+		Password: s.Password,
+	}
+}
+
+// Exec runs a statement, failing the test if it fails
+func (s *Server) Exec(t testing.TB, query string, args ...any) {
+	t.Helper()
+	if _, err := s.DB.Exec(query, args...); err != nil {
+		t.Fatalf("Failed to run %q: %v", query, err)
+	}
+}
+
+// Now returns the server's clock
+func (s *Server) Now(t testing.TB) time.Time {
+	t.Helper()
+	var micros int64
+	if err := s.DB.QueryRow("SELECT CAST(UNIX_TIMESTAMP(NOW(6)) * 1000000 AS SIGNED)").Scan(&micros); err != nil {
+		t.Fatalf("Failed to read server time: %v", err)
+	}
+	return time.UnixMicro(micros)
+}
+
+// Insert writes rows into the events table, one transaction each
+func (s *Server) Insert(t testing.TB, rows int) {
+	t.Helper()
+	for i := 0; i < rows; i++ {
+		s.Exec(t, "INSERT INTO events (payload) VALUES (?)", fmt.Sprintf("row %d", i))
+	}
+}
+
+// Rotate closes the active binlog and returns the name of the new one
+func (s *Server) Rotate(t testing.TB) string {
+	t.Helper()
+	s.Exec(t, "FLUSH BINARY LOGS")
+	return s.ActiveFile(t)
+}
+
+// ActiveFile returns the binlog the server is writing
+func (s *Server) ActiveFile(t testing.TB) string {
+	t.Helper()
+	files := s.Binlogs(t)
+	if len(files) == 0 {
+		t.Fatal("The server has no binlogs")
+	}
+	return files[len(files)-1]
+}
+
+// Binlogs returns the names of the server's binlogs, oldest first
+func (s *Server) Binlogs(t testing.TB) []string {
+	t.Helper()
+	rows, err := s.DB.Query("SHOW BINARY LOGS")
+	if err != nil {
+		t.Fatalf("Failed to list binlogs: %v", err)
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		t.Fatalf("Failed to list binlogs: %v", err)
+	}
+	var files []string
+	for rows.Next() {
+		values := make([]any, len(cols))
+		var name string
+		values[0] = &name
+		for i := 1; i < len(values); i++ {
+			values[i] = new(sql.RawBytes)
+		}
+		if err := rows.Scan(values...); err != nil {
+			t.Fatalf("Failed to list binlogs: %v", err)
+		}
+		files = append(files, name)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("Failed to list binlogs: %v", err)
+	}
+	return files
+}
+
+// WriteBatches writes n batches of rows, each to a binlog of its own, and returns where
+// and when they were written. The server waits out the second of every rotation before
+// writing the next batch, so no two files share a second and each batch's times identify
+// its file.
+func (s *Server) WriteBatches(t testing.TB, n, rows int) []Batch {
+	t.Helper()
+	batches := make([]Batch, 0, n)
+	for i := 0; i < n; i++ {
+		file := s.Rotate(t)
+		waitNextSecond(s.Now(t))
+		start := s.Now(t).Truncate(time.Second)
+		s.Insert(t, rows)
+		end := s.Now(t).Truncate(time.Second)
+		batches = append(batches, Batch{File: file, Start: start, End: end})
+		waitNextSecond(s.Now(t))
+	}
+	return batches
+}
+
+// waitNextSecond sleeps until the second after now has begun
+func waitNextSecond(now time.Time) {
+	time.Sleep(now.Truncate(time.Second).Add(time.Second).Sub(now) + 10*time.Millisecond)
+}